@@ -3,17 +3,22 @@ package main
 import (
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"github.com/authelia/authelia/internal/audit"
 	"github.com/authelia/authelia/internal/authentication"
 	"github.com/authelia/authelia/internal/authorization"
 	"github.com/authelia/authelia/internal/commands"
 	"github.com/authelia/authelia/internal/configuration"
 	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/metrics"
 	"github.com/authelia/authelia/internal/middlewares"
 	"github.com/authelia/authelia/internal/notification"
+	"github.com/authelia/authelia/internal/oidc"
 	"github.com/authelia/authelia/internal/regulation"
 	"github.com/authelia/authelia/internal/server"
 	"github.com/authelia/authelia/internal/session"
@@ -75,20 +80,45 @@ func startServer() {
 
 	switch {
 	case config.Storage.PostgreSQL != nil:
-		storageProvider = storage.NewPostgreSQLProvider(*config.Storage.PostgreSQL)
+		storageProvider = storage.NewPostgreSQLProvider(*config.Storage.PostgreSQL, config.Storage.EncryptionKey)
 	case config.Storage.MySQL != nil:
-		storageProvider = storage.NewMySQLProvider(*config.Storage.MySQL)
+		storageProvider = storage.NewMySQLProvider(*config.Storage.MySQL, config.Storage.EncryptionKey)
 	case config.Storage.Local != nil:
-		storageProvider = storage.NewSQLiteProvider(config.Storage.Local.Path)
+		storageProvider = storage.NewSQLiteProvider(config.Storage.Local.Path, config.Storage.EncryptionKey)
 	default:
 		logger.Fatalf("Unrecognized storage backend")
 	}
 
+	migrator := storageProvider.(storage.Migrator)
+
+	if config.Storage.DisableAutomaticMigration {
+		version, _, err := migrator.SchemaInfo()
+		if err != nil {
+			logger.Fatalf("Unable to retrieve the storage schema version: %s", err)
+		}
+
+		switch {
+		case version < storage.SchemaLatestVersion:
+			logger.Fatalf("Storage schema is v%d but v%d is required, upgrade it with 'authelia storage migrate'", version, storage.SchemaLatestVersion)
+		case version > storage.SchemaLatestVersion:
+			logger.Fatalf("Storage schema is v%d which is newer than v%d supported by this version of Authelia", version, storage.SchemaLatestVersion)
+		}
+	} else if err := migrator.Migrate(); err != nil {
+		logger.Fatalf("Unable to upgrade the storage schema: %s", err)
+	}
+
+	if err := migrator.CheckEncryptionKey(); err != nil {
+		logger.Fatalf("Unable to check the storage encryption key: %s", err)
+	}
+
 	var userProvider authentication.UserProvider
 
 	switch {
 	case config.AuthenticationBackend.File != nil:
-		userProvider = authentication.NewFileUserProvider(config.AuthenticationBackend.File)
+		fileUserProvider := authentication.NewFileUserProvider(config.AuthenticationBackend.File)
+		reloadUsersDatabase(fileUserProvider, config.AuthenticationBackend.File.Watch)
+
+		userProvider = fileUserProvider
 	case config.AuthenticationBackend.Ldap != nil:
 		userProvider = authentication.NewLDAPUserProvider(*config.AuthenticationBackend.Ldap, autheliaCertPool)
 	default:
@@ -113,22 +143,77 @@ func startServer() {
 		}
 	}
 
+	var metricsProvider metrics.Provider
+
+	if config.Server.Metrics.Enabled {
+		metricsProvider = metrics.NewPrometheus()
+		userProvider = metrics.NewUserProvider(userProvider, metricsProvider)
+		storageProvider = metrics.NewStorageProvider(storageProvider, metricsProvider)
+		notifier = metrics.NewNotifier(notifier, metricsProvider)
+	}
+
+	var auditLogger audit.Logger
+
+	if config.AuditLog.Enabled {
+		jsonLogger, err := audit.NewLogger(config.AuditLog)
+		if err != nil {
+			logger.Fatalf("Error initializing the audit log: %s", err)
+		}
+
+		auditLogger = jsonLogger
+	}
+
 	clock := utils.RealClock{}
 	authorizer := authorization.NewAuthorizer(config.AccessControl)
 	sessionProvider := session.NewProvider(config.Session, autheliaCertPool)
 	regulator := regulation.NewRegulator(config.Regulation, storageProvider, clock)
+	passwordPolicy := authentication.NewPasswordPolicy(config.PasswordPolicy)
+
+	openIDConnectProvider, err := oidc.NewOpenIDConnectProvider(config.IdentityProviders.OIDC)
+	if err != nil {
+		logger.Fatalf("Error initializing the OpenID Connect provider: %s", err)
+	}
 
 	providers := middlewares.Providers{
 		Authorizer:      authorizer,
 		UserProvider:    userProvider,
 		Regulator:       regulator,
+		PasswordPolicy:  passwordPolicy,
 		StorageProvider: storageProvider,
 		Notifier:        notifier,
 		SessionProvider: sessionProvider,
+		OpenIDConnect:   openIDConnectProvider,
+		Metrics:         metricsProvider,
+		Audit:           auditLogger,
 	}
 	server.StartServer(*config, providers)
 }
 
+// reloadUsersDatabase reloads the users database on SIGHUP and, when watching is enabled, whenever the file changes.
+func reloadUsersDatabase(provider *authentication.FileUserProvider, watch bool) {
+	logger := logging.Logger()
+
+	if watch {
+		if _, err := provider.StartWatching(); err != nil {
+			logger.Fatalf("Error while starting to watch the users database: %s", err)
+		}
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for range signals {
+			if err := provider.Reload(); err != nil {
+				logger.Errorf("Unable to reload the users database, the previous one is kept: %s", err)
+				continue
+			}
+
+			logger.Info("Users database has been reloaded")
+		}
+	}()
+}
+
 func main() {
 	logger := logging.Logger()
 	rootCmd := &cobra.Command{
@@ -149,7 +234,7 @@ func main() {
 	}
 
 	rootCmd.AddCommand(versionCmd, commands.HashPasswordCmd,
-		commands.ValidateConfigCmd, commands.CertificatesCmd)
+		commands.ValidateConfigCmd, commands.CertificatesCmd, commands.StorageCmd, commands.UsersCmd)
 
 	if err := rootCmd.Execute(); err != nil {
 		logger.Fatal(err)
@@ -9,18 +9,24 @@ require (
 	github.com/asaskevich/govalidator v0.0.0-20200907205600-7a23bdc65eef
 	github.com/deckarep/golang-set v1.7.1
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
+	github.com/duo-labs/webauthn v0.0.0-20210727191636-9f1b88ef44cc
 	github.com/duosecurity/duo_api_golang v0.0.0-20201112143038-0e07e9f869e3
 	github.com/facebookgo/stack v0.0.0-20160209184415-751773369052 // indirect
 	github.com/fasthttp/router v1.3.11
 	github.com/fasthttp/session/v2 v2.3.1
+	github.com/fsnotify/fsnotify v1.4.9
+	github.com/fxamacker/cbor/v2 v2.2.0
 	github.com/go-ldap/ldap/v3 v3.3.0
+	github.com/go-redis/redis/v8 v8.3.4
 	github.com/go-sql-driver/mysql v1.6.0
 	github.com/golang/mock v1.5.0
 	github.com/jackc/pgx/v4 v4.11.0
 	github.com/mattn/go-sqlite3 v1.14.6
+	github.com/nbutton23/zxcvbn-go v0.0.0-20210217022336-fa2cb2858354
 	github.com/otiai10/copy v1.5.1
 	github.com/pelletier/go-toml v1.4.0 // indirect
 	github.com/pquerna/otp v1.3.0
+	github.com/prometheus/client_golang v1.9.0
 	github.com/simia-tech/crypt v0.4.3
 	github.com/sirupsen/logrus v1.8.1
 	github.com/spf13/cobra v1.1.3
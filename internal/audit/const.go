@@ -0,0 +1,26 @@
+package audit
+
+// The types of the events written to the audit log.
+const (
+	EventFirstFactor                = "first_factor"
+	EventSecondFactor               = "second_factor"
+	EventLogout                     = "logout"
+	EventPasswordReset              = "password_reset"
+	EventPasswordChange             = "password_change"
+	EventDeviceRegistration         = "device_registration"
+	EventDeviceRemoval              = "device_removal"
+	EventIdentityVerificationStart  = "identity_verification_start"
+	EventIdentityVerificationFinish = "identity_verification_finish"
+	EventAccessDenied               = "access_denied"
+	EventAdminAction                = "admin_action"
+	EventSessionRevocation          = "session_revocation"
+)
+
+// The outcomes of the events written to the audit log.
+const (
+	OutcomeSuccess      = "success"
+	OutcomeFailure      = "failure"
+	OutcomeBanned       = "banned"
+	OutcomeUnauthorized = "unauthorized"
+	OutcomeForbidden    = "forbidden"
+)
@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/logging"
+)
+
+// JSONLogger is an audit logger writing each event as a JSON object on its own line.
+type JSONLogger struct {
+	encoder *json.Encoder
+	mutex   sync.Mutex
+}
+
+// NewJSONLogger creates an audit logger writing the events to the given writer.
+func NewJSONLogger(writer io.Writer) *JSONLogger {
+	return &JSONLogger{encoder: json.NewEncoder(writer)}
+}
+
+// NewLogger creates the audit logger writing to the file of the configuration, or to stdout if none is configured.
+func NewLogger(configuration schema.AuditLogConfiguration) (*JSONLogger, error) {
+	if configuration.FilePath == "" {
+		return NewJSONLogger(os.Stdout), nil
+	}
+
+	f, err := os.OpenFile(configuration.FilePath, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewJSONLogger(f), nil
+}
+
+// Log writes the event to the audit log.
+func (l *JSONLogger) Log(event Event) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := l.encoder.Encode(event); err != nil {
+		logging.Logger().Errorf("Unable to write %s event of user %s to the audit log: %s", event.Type, event.Username, err)
+	}
+}
@@ -0,0 +1,75 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldWriteEventsAsJSONLines(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	logger := NewJSONLogger(buffer)
+
+	logger.Log(Event{
+		Time:      time.Unix(1600000000, 0).UTC(),
+		Type:      EventFirstFactor,
+		Username:  "john",
+		RemoteIP:  "10.0.0.1",
+		UserAgent: "curl/7.68.0",
+		TargetURL: "https://secure.example.com/",
+		Method:    "password",
+		Outcome:   OutcomeFailure,
+	})
+	logger.Log(Event{Type: EventLogout, Username: "john", Outcome: OutcomeSuccess})
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	require.Len(t, lines, 2)
+
+	assert.Equal(t, `{"time":"2020-09-13T12:26:40Z","event":"first_factor","username":"john","remote_ip":"10.0.0.1",`+
+		`"user_agent":"curl/7.68.0","target_url":"https://secure.example.com/","method":"password","outcome":"failure"}`, lines[0])
+
+	var event Event
+
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &event))
+	assert.Equal(t, EventLogout, event.Type)
+	assert.Equal(t, OutcomeSuccess, event.Outcome)
+}
+
+func TestShouldAppendEventsToTheConfiguredFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	require.NoError(t, ioutil.WriteFile(path, []byte("{}\n"), 0600))
+
+	logger, err := NewLogger(schema.AuditLogConfiguration{Enabled: true, FilePath: path})
+	require.NoError(t, err)
+
+	logger.Log(Event{Type: EventPasswordReset, Username: "john", Outcome: OutcomeSuccess})
+
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "{}\n{"))
+	assert.Contains(t, string(content), `"event":"password_reset"`)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestShouldFailToOpenAuditLogInMissingDirectory(t *testing.T) {
+	_, err := NewLogger(schema.AuditLogConfiguration{Enabled: true, FilePath: "/nonexistent/dir/audit.log"})
+	assert.Error(t, err)
+}
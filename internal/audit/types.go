@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"time"
+)
+
+// Event is a security event written to the audit log. The JSON representation of this structure is the schema of the
+// audit log and must therefore remain stable. Subject is only set for the actions performed by an administrator on
+// behalf of another user.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Type      string    `json:"event"`
+	Username  string    `json:"username"`
+	RemoteIP  string    `json:"remote_ip"`
+	UserAgent string    `json:"user_agent"`
+	TargetURL string    `json:"target_url"`
+	Method    string    `json:"method"`
+	Outcome   string    `json:"outcome"`
+	Subject   string    `json:"subject,omitempty"`
+}
+
+// Logger is an interface writing security events to the audit log.
+type Logger interface {
+	Log(event Event)
+}
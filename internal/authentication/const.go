@@ -2,6 +2,8 @@ package authentication
 
 import (
 	"errors"
+	"fmt"
+	"time"
 )
 
 // Level is the type representing a level of authentication.
@@ -23,10 +25,12 @@ const (
 	U2F = "u2f"
 	// Push Method using Duo application to receive push notifications.
 	Push = "mobile_push"
+	// EmailOTP Method using one-time passcodes sent by email.
+	EmailOTP = "email_otp"
 )
 
 // PossibleMethods is the set of all possible 2FA methods.
-var PossibleMethods = []string{TOTP, U2F, Push}
+var PossibleMethods = []string{TOTP, U2F, Push, EmailOTP}
 
 // CryptAlgo the crypt representation of an algorithm used in the prefix of the hash.
 type CryptAlgo string
@@ -54,6 +58,13 @@ var HashingPossibleSaltCharacters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJ
 // ErrUserNotFound indicates the user wasn't found in the authentication backend.
 var ErrUserNotFound = errors.New("user not found")
 
+// ErrUserDisabled indicates the account of the user has been disabled or has expired. It is a ErrUserNotFound so that
+// such users are handled as if they did not exist anymore.
+var ErrUserDisabled = fmt.Errorf("%w: the account is disabled", ErrUserNotFound)
+
+// ErrLDAPConnectionPoolTimeout indicates no connection of the LDAP pool has been released before the timeout.
+var ErrLDAPConnectionPoolTimeout = errors.New("timeout reached waiting for a connection of the LDAP pool")
+
 const argon2id = "argon2id"
 const sha512 = "sha512"
 
@@ -61,6 +72,10 @@ const testPassword = "my;secure*password"
 
 const fileAuthenticationMode = 0600
 
+// fileUserProviderReloadDelay is how long the file provider waits for the changes of the users database to settle
+// before reloading it.
+const fileUserProviderReloadDelay = 500 * time.Millisecond
+
 // OWASP recommends to escape some special characters.
 // https://github.com/OWASP/CheatSheetSeries/blob/master/cheatsheets/LDAP_Injection_Prevention_Cheat_Sheet.md
 const specialLDAPRunes = ",#+<>;\"="
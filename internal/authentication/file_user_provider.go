@@ -3,31 +3,44 @@ package authentication
 import (
 	_ "embed" // Embed users_database.template.yml.
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/asaskevich/govalidator"
+	"github.com/fsnotify/fsnotify"
 	"gopkg.in/yaml.v2"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/utils"
 )
 
 // FileUserProvider is a provider reading details from a file.
 type FileUserProvider struct {
 	configuration *schema.FileAuthenticationBackendConfiguration
 	database      *DatabaseModel
-	lock          *sync.Mutex
+	lock          *sync.RWMutex
+	clock         utils.Clock
 }
 
 // UserDetailsModel is the model of user details in the file database.
 type UserDetailsModel struct {
-	HashedPassword string   `yaml:"password" valid:"required"`
-	DisplayName    string   `yaml:"displayname" valid:"required"`
-	Email          string   `yaml:"email"`
-	Groups         []string `yaml:"groups"`
+	HashedPassword string     `yaml:"password" valid:"required"`
+	DisplayName    string     `yaml:"displayname" valid:"required"`
+	Email          string     `yaml:"email"`
+	Groups         []string   `yaml:"groups"`
+	Disabled       bool       `yaml:"disabled,omitempty"`
+	ExpiresAt      *time.Time `yaml:"expires_at,omitempty"`
+}
+
+// IsDisabled returns whether the account of the user has been disabled or has expired at the given time.
+func (m UserDetailsModel) IsDisabled(now time.Time) bool {
+	return m.Disabled || (m.ExpiresAt != nil && !now.Before(*m.ExpiresAt))
 }
 
 // DatabaseModel is the model of users file database.
@@ -63,7 +76,8 @@ func NewFileUserProvider(configuration *schema.FileAuthenticationBackendConfigur
 	return &FileUserProvider{
 		configuration: configuration,
 		database:      database,
-		lock:          &sync.Mutex{},
+		lock:          &sync.RWMutex{},
+		clock:         utils.RealClock{},
 	}
 }
 
@@ -121,9 +135,13 @@ func readDatabase(path string) (*DatabaseModel, error) {
 		return nil, fmt.Errorf("Unable to read database from file %s: %s", path, err)
 	}
 
+	return parseDatabase(content)
+}
+
+func parseDatabase(content []byte) (*DatabaseModel, error) {
 	db := DatabaseModel{}
 
-	err = yaml.Unmarshal(content, &db)
+	err := yaml.Unmarshal(content, &db)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to parse database: %s", err)
 	}
@@ -140,68 +158,250 @@ func readDatabase(path string) (*DatabaseModel, error) {
 	return &db, nil
 }
 
+// LoadDatabase reads the users database and checks the hashes of the passwords.
+func LoadDatabase(path string) (*DatabaseModel, error) {
+	database, err := readDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = checkPasswordHashes(database); err != nil {
+		return nil, err
+	}
+
+	return database, nil
+}
+
+// WriteDatabase replaces the users database by the given one. The file is replaced atomically so that a reader never
+// sees a partially written database, and it is not written at all if it would not be loaded back.
+func WriteDatabase(path string, database *DatabaseModel) error {
+	content, err := yaml.Marshal(database)
+	if err != nil {
+		return err
+	}
+
+	written, err := parseDatabase(content)
+	if err == nil {
+		err = checkPasswordHashes(written)
+	}
+
+	if err != nil {
+		return fmt.Errorf("Refusing to write an invalid users database: %s", err)
+	}
+
+	tmpfile, err := ioutil.TempFile(filepath.Dir(path), fmt.Sprintf(".%s.*", filepath.Base(path)))
+	if err != nil {
+		return fmt.Errorf("Unable to write the users database: %s", err)
+	}
+
+	// The temporary file is removed unless it has been renamed over the database.
+	defer os.Remove(tmpfile.Name())
+
+	if err = writeAndClose(tmpfile, content); err != nil {
+		return fmt.Errorf("Unable to write the users database: %s", err)
+	}
+
+	if err = os.Rename(tmpfile.Name(), path); err != nil {
+		return fmt.Errorf("Unable to write the users database: %s", err)
+	}
+
+	return nil
+}
+
+func writeAndClose(file *os.File, content []byte) error {
+	err := file.Chmod(fileAuthenticationMode)
+
+	if err == nil {
+		_, err = file.Write(content)
+	}
+
+	if err == nil {
+		err = file.Sync()
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// HashPasswordWithConfiguration hashes a password with the algorithm and the parameters of the configuration.
+func HashPasswordWithConfiguration(password string, configuration *schema.PasswordConfiguration) (string, error) {
+	algorithm, err := ConfigAlgoToCryptoAlgo(configuration.Algorithm)
+	if err != nil {
+		return "", err
+	}
+
+	return HashPassword(
+		password, "", algorithm, configuration.Iterations,
+		configuration.Memory*1024, configuration.Parallelism,
+		configuration.KeyLength, configuration.SaltLength)
+}
+
 // CheckUserPassword checks if provided password matches for the given user.
 func (p *FileUserProvider) CheckUserPassword(username string, password string) (bool, error) {
-	if details, ok := p.database.Users[username]; ok {
-		ok, err := CheckPassword(password, details.HashedPassword)
-		if err != nil {
-			return false, err
-		}
+	details, ok := p.getUser(username)
+	if !ok {
+		return false, ErrUserNotFound
+	}
 
-		return ok, nil
+	if details.IsDisabled(p.clock.Now()) {
+		return false, ErrUserDisabled
 	}
 
-	return false, ErrUserNotFound
+	ok, err := CheckPassword(password, details.HashedPassword)
+	if err != nil {
+		return false, err
+	}
+
+	return ok, nil
 }
 
 // GetDetails retrieve the groups a user belongs to.
 func (p *FileUserProvider) GetDetails(username string) (*UserDetails, error) {
-	if details, ok := p.database.Users[username]; ok {
-		return &UserDetails{
-			Username:    username,
-			DisplayName: details.DisplayName,
-			Groups:      details.Groups,
-			Emails:      []string{details.Email},
-		}, nil
+	details, ok := p.getUser(username)
+	if !ok {
+		return nil, ErrUserNotFound
 	}
 
-	return nil, fmt.Errorf("User '%s' does not exist in database", username)
+	if details.IsDisabled(p.clock.Now()) {
+		return nil, ErrUserDisabled
+	}
+
+	return &UserDetails{
+		Username:    username,
+		DisplayName: details.DisplayName,
+		Groups:      details.Groups,
+		Emails:      []string{details.Email},
+	}, nil
 }
 
 // UpdatePassword update the password of the given user.
 func (p *FileUserProvider) UpdatePassword(username string, newPassword string) error {
-	details, ok := p.database.Users[username]
+	details, ok := p.getUser(username)
 	if !ok {
 		return ErrUserNotFound
 	}
 
-	algorithm, err := ConfigAlgoToCryptoAlgo(p.configuration.Password.Algorithm)
+	if details.IsDisabled(p.clock.Now()) {
+		return ErrUserDisabled
+	}
+
+	hash, err := HashPasswordWithConfiguration(newPassword, p.configuration.Password)
 	if err != nil {
 		return err
 	}
 
-	hash, err := HashPassword(
-		newPassword, "", algorithm, p.configuration.Password.Iterations,
-		p.configuration.Password.Memory*1024, p.configuration.Password.Parallelism,
-		p.configuration.Password.KeyLength, p.configuration.Password.SaltLength)
+	p.lock.Lock()
+	defer p.lock.Unlock()
 
-	if err != nil {
-		return err
+	// The database might have been reloaded while hashing the password.
+	details, ok = p.database.Users[username]
+	if !ok {
+		return ErrUserNotFound
 	}
 
-	details.HashedPassword = hash
+	previous := details
 
-	p.lock.Lock()
+	details.HashedPassword = hash
 	p.database.Users[username] = details
 
-	b, err := yaml.Marshal(p.database)
+	if err = WriteDatabase(p.configuration.Path, p.database); err != nil {
+		// The database in use must keep matching the file.
+		p.database.Users[username] = previous
+
+		return err
+	}
+
+	return nil
+}
+
+// Reload reads the database again and swaps it in if it is valid. The database in use is kept when the file is
+// invalid so that a mistake while editing it does not lock every user out.
+func (p *FileUserProvider) Reload() error {
+	database, err := LoadDatabase(p.configuration.Path)
 	if err != nil {
-		p.lock.Unlock()
 		return err
 	}
 
-	err = ioutil.WriteFile(p.configuration.Path, b, fileAuthenticationMode)
+	p.lock.Lock()
+	p.database = database
 	p.lock.Unlock()
 
-	return err
+	return nil
+}
+
+// StartWatching reloads the database whenever the file changes until the returned watcher is closed. The directory
+// of the file is watched rather than the file itself since editors usually replace the file rather than writing it.
+func (p *FileUserProvider) StartWatching() (io.Closer, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("Unable to watch the users database: %s", err)
+	}
+
+	path := filepath.Clean(p.configuration.Path)
+
+	if err = watcher.Add(filepath.Dir(path)); err != nil {
+		_ = watcher.Close()
+		return nil, fmt.Errorf("Unable to watch the users database: %s", err)
+	}
+
+	go p.watch(watcher, path)
+
+	return watcher, nil
+}
+
+func (p *FileUserProvider) watch(watcher *fsnotify.Watcher, path string) {
+	logger := logging.Logger()
+
+	// A single change usually triggers several events, the database is reloaded once they stop.
+	var reload *time.Timer
+
+	defer func() {
+		if reload != nil {
+			reload.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			if filepath.Clean(event.Name) != path || event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+				continue
+			}
+
+			if reload == nil {
+				reload = time.AfterFunc(fileUserProviderReloadDelay, func() {
+					if err := p.Reload(); err != nil {
+						logger.Errorf("Unable to reload the users database, the previous one is kept: %s", err)
+						return
+					}
+
+					logger.Infof("Users database %s has been reloaded", path)
+				})
+			} else {
+				reload.Reset(fileUserProviderReloadDelay)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+
+			logger.Errorf("Error while watching the users database: %s", err)
+		}
+	}
+}
+
+func (p *FileUserProvider) getUser(username string) (UserDetailsModel, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	details, ok := p.database.Users[username]
+
+	return details, ok
 }
@@ -1,12 +1,15 @@
 package authentication
 
 import (
+	"errors"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -34,6 +37,19 @@ func WithDatabase(content []byte, f func(path string)) {
 	}
 }
 
+// testClock is a clock stopped at a given time.
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
 func TestShouldErrorPermissionsOnLocalFS(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("skipping test due to being on windows")
@@ -149,6 +165,29 @@ func TestShouldUpdatePassword(t *testing.T) {
 	})
 }
 
+func TestShouldReplaceDatabaseWhenUpdatingPassword(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		before, err := os.Stat(path)
+		require.NoError(t, err)
+
+		require.NoError(t, provider.UpdatePassword("harry", "newpassword"))
+
+		after, err := os.Stat(path)
+		require.NoError(t, err)
+
+		// The database has been renamed over rather than written in place.
+		assert.False(t, os.SameFile(before, after))
+
+		leftovers, err := filepath.Glob(filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".*"))
+		require.NoError(t, err)
+		assert.Empty(t, leftovers)
+	})
+}
+
 // Checks both that the hashing algo changes and that it removes {CRYPT} from the start.
 func TestShouldUpdatePasswordHashingAlgorithmToArgon2id(t *testing.T) {
 	WithDatabase(UserDatabaseContent, func(path string) {
@@ -189,6 +228,172 @@ func TestShouldUpdatePasswordHashingAlgorithmToSHA512(t *testing.T) {
 	})
 }
 
+func TestShouldRefuseDisabledUser(t *testing.T) {
+	WithDatabase(DisabledUsersDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		_, err := provider.CheckUserPassword("john", "password")
+		assert.Equal(t, ErrUserDisabled, err)
+		assert.True(t, errors.Is(err, ErrUserNotFound))
+
+		_, err = provider.GetDetails("john")
+		assert.Equal(t, ErrUserDisabled, err)
+
+		assert.Equal(t, ErrUserDisabled, provider.UpdatePassword("john", "newpassword"))
+	})
+}
+
+func TestShouldRefuseExpiredUser(t *testing.T) {
+	WithDatabase(DisabledUsersDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		clock := &testClock{now: time.Date(2021, 5, 31, 23, 59, 59, 0, time.UTC)}
+		provider.clock = clock
+
+		ok, err := provider.CheckUserPassword("harry", "password")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		clock.now = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+		_, err = provider.CheckUserPassword("harry", "password")
+		assert.Equal(t, ErrUserDisabled, err)
+
+		_, err = provider.GetDetails("harry")
+		assert.Equal(t, ErrUserDisabled, err)
+	})
+}
+
+func TestShouldNotAddAccountStatusWhenUpdatingPassword(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+		require.NoError(t, provider.UpdatePassword("john", "newpassword"))
+
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(content), "disabled")
+		assert.NotContains(t, string(content), "expires_at")
+	})
+}
+
+func TestShouldReloadDatabase(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		require.NoError(t, ioutil.WriteFile(path, UserDatabaseWithoutCryptContent, fileAuthenticationMode))
+		require.NoError(t, provider.Reload())
+
+		_, err := provider.CheckUserPassword("harry", "password")
+		assert.Equal(t, ErrUserNotFound, err)
+
+		_, err = provider.GetDetails("harry")
+		assert.Equal(t, ErrUserNotFound, err)
+
+		ok, err := provider.CheckUserPassword("james", "password")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+}
+
+func TestShouldKeepDatabaseWhenReloadingInvalidDatabase(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		require.NoError(t, ioutil.WriteFile(path, MalformedUserDatabaseContent, fileAuthenticationMode))
+		assert.EqualError(t, provider.Reload(), "Unable to parse database: yaml: line 4: mapping values are not allowed in this context")
+
+		require.NoError(t, ioutil.WriteFile(path, BadSHA512HashContent, fileAuthenticationMode))
+		assert.Error(t, provider.Reload())
+
+		ok, err := provider.CheckUserPassword("harry", "password")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+	})
+}
+
+func TestShouldReloadDatabaseWhenFileIsReplaced(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		watcher, err := provider.StartWatching()
+		require.NoError(t, err)
+
+		defer watcher.Close()
+
+		// Editors usually write a new file and rename it over the database.
+		require.NoError(t, ioutil.WriteFile(path+".tmp", UserDatabaseWithoutCryptContent, fileAuthenticationMode))
+		require.NoError(t, os.Rename(path+".tmp", path))
+
+		assert.Eventually(t, func() bool {
+			_, err := provider.GetDetails("harry")
+			return err != nil
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+}
+
+func TestShouldWriteDatabase(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		database, err := LoadDatabase(path)
+		require.NoError(t, err)
+
+		hash, err := HashPasswordWithConfiguration("newpassword", DefaultFileAuthenticationBackendConfiguration.Password)
+		require.NoError(t, err)
+
+		database.Users["alice"] = UserDetailsModel{HashedPassword: hash, DisplayName: "Alice", Groups: []string{"dev"}}
+		delete(database.Users, "harry")
+
+		require.NoError(t, WriteDatabase(path, database))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(fileAuthenticationMode), info.Mode().Perm())
+
+		config := DefaultFileAuthenticationBackendConfiguration
+		config.Path = path
+		provider := NewFileUserProvider(&config)
+
+		ok, err := provider.CheckUserPassword("alice", "newpassword")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+
+		_, err = provider.CheckUserPassword("harry", "password")
+		assert.Equal(t, ErrUserNotFound, err)
+
+		// No temporary file is left next to the database.
+		files, err := filepath.Glob(filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".*"))
+		require.NoError(t, err)
+		assert.Len(t, files, 0)
+	})
+}
+
+func TestShouldRefuseToWriteInvalidDatabase(t *testing.T) {
+	WithDatabase(UserDatabaseContent, func(path string) {
+		database, err := LoadDatabase(path)
+		require.NoError(t, err)
+
+		database.Users["alice"] = UserDetailsModel{HashedPassword: "not a hash", DisplayName: "Alice"}
+		assert.EqualError(t, WriteDatabase(path, database), "Refusing to write an invalid users database: Unable to parse hash of user alice: Hash key is not the last parameter, the hash is likely malformed (not a hash)")
+
+		assert.EqualError(t, WriteDatabase(path, &DatabaseModel{Users: map[string]UserDetailsModel{}}), "Refusing to write an invalid users database: Invalid schema of database: Users: non zero value required")
+
+		content, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, UserDatabaseContent, content)
+	})
+}
+
 func TestShouldRaiseWhenLoadingMalformedDatabaseForFirstTime(t *testing.T) {
 	WithDatabase(MalformedUserDatabaseContent, func(path string) {
 		config := DefaultFileAuthenticationBackendConfiguration
@@ -310,6 +515,21 @@ users:
     email: james.dean@authelia.com
 `)
 
+var DisabledUsersDatabaseContent = []byte(`
+users:
+  john:
+    password: "{CRYPT}$argon2id$v=19$m=65536,t=3,p=2$BpLnfgDsc2WD8F2q$o/vzA4myCqZZ36bUGsDY//8mKUYNZZaR0t4MFFSs+iM"
+    displayname: "John Doe"
+    email: john.doe@authelia.com
+    disabled: true
+
+  harry:
+    password: "{CRYPT}$argon2id$v=19$m=65536,t=3,p=2$BpLnfgDsc2WD8F2q$o/vzA4myCqZZ36bUGsDY//8mKUYNZZaR0t4MFFSs+iM"
+    displayname: "Harry Potter"
+    email: harry.potter@authelia.com
+    expires_at: 2021-06-01T00:00:00Z
+`)
+
 var MalformedUserDatabaseContent = []byte(`
 users
 john
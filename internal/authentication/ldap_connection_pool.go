@@ -0,0 +1,234 @@
+package authentication
+
+import (
+	"crypto/tls"
+	"sync"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// LDAPConnectionPool is a bounded pool of connections bound as the LDAP service user. The connections are dialed
+// through a LDAPConnectionFactory, the idle ones are closed after the idle timeout and checked before being reused when
+// they have been idle for longer than the health check interval.
+type LDAPConnectionPool struct {
+	dial                func() (LDAPConnection, error)
+	timeout             time.Duration
+	idleTimeout         time.Duration
+	healthCheckInterval time.Duration
+	clock               utils.Clock
+
+	// slots bounds the number of connections, idle or in use.
+	slots chan struct{}
+
+	mutex sync.Mutex
+	idle  []*ldapPooledConnection
+}
+
+// NewLDAPConnectionPool creates a pool of at most maxConnections connections opened with the dial function. The
+// requests wait at most timeout for a connection to be released when the pool is exhausted, a zero timeout meaning
+// they wait indefinitely.
+func NewLDAPConnectionPool(dial func() (LDAPConnection, error), maxConnections int, timeout, idleTimeout, healthCheckInterval time.Duration) *LDAPConnectionPool {
+	if maxConnections < 1 {
+		maxConnections = 1
+	}
+
+	return &LDAPConnectionPool{
+		dial:                dial,
+		timeout:             timeout,
+		idleTimeout:         idleTimeout,
+		healthCheckInterval: healthCheckInterval,
+		clock:               utils.RealClock{},
+		slots:               make(chan struct{}, maxConnections),
+	}
+}
+
+// Get returns an idle and healthy connection of the pool or a new one, waiting for a connection to be released when
+// the pool is exhausted. The connection must be given back with Put.
+func (p *LDAPConnectionPool) Get() (LDAPConnection, error) {
+	if err := p.acquire(); err != nil {
+		return nil, err
+	}
+
+	for conn := p.popIdle(); conn != nil; conn = p.popIdle() {
+		if p.isHealthy(conn) {
+			return conn, nil
+		}
+
+		conn.Close()
+	}
+
+	conn, err := p.dial()
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+
+	return &ldapPooledConnection{LDAPConnection: conn}, nil
+}
+
+// acquire takes a slot of the pool, waiting at most the timeout for a connection to be released.
+func (p *LDAPConnectionPool) acquire() error {
+	if p.timeout <= 0 {
+		p.slots <- struct{}{}
+		return nil
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-time.After(p.timeout):
+		return ErrLDAPConnectionPoolTimeout
+	}
+}
+
+// Put gives a connection back to the pool. The connections which met a network error are closed instead of being
+// reused, along with the idle ones.
+func (p *LDAPConnectionPool) Put(conn LDAPConnection) {
+	defer func() { <-p.slots }()
+
+	pooled, ok := conn.(*ldapPooledConnection)
+	if !ok {
+		conn.Close()
+		return
+	}
+
+	// A network error on a connection usually means the server went away, the idle connections are closed too so
+	// that the next ones are dialed again.
+	if pooled.broken {
+		conn.Close()
+		p.Close()
+
+		return
+	}
+
+	pooled.releasedAt = p.clock.Now()
+
+	p.mutex.Lock()
+	p.idle = append(p.idle, pooled)
+	p.mutex.Unlock()
+
+	p.closeExpired()
+}
+
+// Close closes the idle connections of the pool.
+func (p *LDAPConnectionPool) Close() {
+	p.mutex.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.mutex.Unlock()
+
+	for _, conn := range idle {
+		conn.Close()
+	}
+}
+
+// popIdle returns the most recently released connection which is not expired, the expired ones being closed.
+func (p *LDAPConnectionPool) popIdle() *ldapPooledConnection {
+	p.closeExpired()
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if len(p.idle) == 0 {
+		return nil
+	}
+
+	conn := p.idle[len(p.idle)-1]
+	p.idle = p.idle[:len(p.idle)-1]
+
+	return conn
+}
+
+// closeExpired closes the connections which have been idle for longer than the idle timeout.
+func (p *LDAPConnectionPool) closeExpired() {
+	if p.idleTimeout <= 0 {
+		return
+	}
+
+	now := p.clock.Now()
+
+	p.mutex.Lock()
+
+	// The idle connections are sorted by release time, the oldest first.
+	expired := 0
+	for expired < len(p.idle) && now.Sub(p.idle[expired].releasedAt) > p.idleTimeout {
+		expired++
+	}
+
+	closed := p.idle[:expired]
+	p.idle = append([]*ldapPooledConnection(nil), p.idle[expired:]...)
+
+	p.mutex.Unlock()
+
+	for _, conn := range closed {
+		conn.Close()
+	}
+}
+
+// isHealthy checks a connection which has been idle for longer than the health check interval by reading the root DSE.
+func (p *LDAPConnectionPool) isHealthy(conn *ldapPooledConnection) bool {
+	if p.clock.Now().Sub(conn.releasedAt) < p.healthCheckInterval {
+		return true
+	}
+
+	searchRequest := ldap.NewSearchRequest(
+		"", ldap.ScopeBaseObject, ldap.NeverDerefAliases,
+		1, 0, false, "(objectClass=*)", []string{"1.1"}, nil,
+	)
+
+	if _, err := conn.Search(searchRequest); err != nil {
+		logging.Logger().Debugf("Discarding an unhealthy LDAP connection of the pool: %s", err)
+		return false
+	}
+
+	return true
+}
+
+// ldapPooledConnection is a connection of the pool which keeps track of the network errors so that it is not reused
+// once broken.
+type ldapPooledConnection struct {
+	LDAPConnection
+
+	releasedAt time.Time
+	broken     bool
+}
+
+// isBroken returns whether the connection is a connection of a pool which met a network error.
+func isBroken(conn LDAPConnection) bool {
+	pooled, ok := conn.(*ldapPooledConnection)
+
+	return ok && pooled.broken
+}
+
+func (c *ldapPooledConnection) check(err error) error {
+	if ldap.IsErrorWithCode(err, ldap.ErrorNetwork) {
+		c.broken = true
+	}
+
+	return err
+}
+
+// Bind binds the connection to a username/password.
+func (c *ldapPooledConnection) Bind(username, password string) error {
+	return c.check(c.LDAPConnection.Bind(username, password))
+}
+
+// Search searches the ldap server.
+func (c *ldapPooledConnection) Search(searchRequest *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	result, err := c.LDAPConnection.Search(searchRequest)
+	return result, c.check(err)
+}
+
+// Modify modifies an ldap object.
+func (c *ldapPooledConnection) Modify(modifyRequest *ldap.ModifyRequest) error {
+	return c.check(c.LDAPConnection.Modify(modifyRequest))
+}
+
+// StartTLS requests the LDAP server upgrades to TLS encryption.
+func (c *ldapPooledConnection) StartTLS(config *tls.Config) error {
+	return c.check(c.LDAPConnection.StartTLS(config))
+}
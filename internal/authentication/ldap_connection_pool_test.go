@@ -0,0 +1,277 @@
+package authentication
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func newPooledLDAPUserProvider(mockFactory LDAPConnectionFactory) *LDAPUserProvider {
+	return NewLDAPUserProviderWithFactory(
+		schema.LDAPAuthenticationBackendConfiguration{
+			URL:                  "ldap://127.0.0.1:389",
+			User:                 "cn=admin,dc=example,dc=com",
+			Password:             "password",
+			UsernameAttribute:    "uid",
+			MailAttribute:        "mail",
+			DisplayNameAttribute: "displayname",
+			UsersFilter:          "uid={input}",
+			AdditionalUsersDN:    "ou=users",
+			BaseDN:               "dc=example,dc=com",
+			Pool: &schema.LDAPPoolConfiguration{
+				MaxConnections:      2,
+				IdleTimeout:         "5m",
+				HealthCheckInterval: "1m",
+			},
+			Timeout: "5s",
+		},
+		nil,
+		mockFactory)
+}
+
+func createSearchResultWithUserProfile() *ldap.SearchResult {
+	return &ldap.SearchResult{
+		Entries: []*ldap.Entry{
+			{
+				DN: "uid=test,dc=example,dc=com",
+				Attributes: []*ldap.EntryAttribute{
+					{
+						Name:   "displayname",
+						Values: []string{"John Doe"},
+					},
+					{
+						Name:   "uid",
+						Values: []string{"John"},
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestShouldReusePooledConnection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := newPooledLDAPUserProvider(mockFactory)
+
+	mockFactory.EXPECT().
+		DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+		Return(mockConn, nil)
+
+	mockConn.EXPECT().
+		Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+		Return(nil)
+
+	mockConn.EXPECT().
+		Search(gomock.Any()).
+		Return(createSearchResultWithUserProfile(), nil).
+		Times(4)
+
+	for i := 0; i < 2; i++ {
+		details, err := ldapClient.GetDetails("john")
+		require.NoError(t, err)
+
+		assert.Equal(t, "John", details.Username)
+	}
+}
+
+func TestShouldRetryOperationOnBrokenPooledConnection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockFactory := NewMockLDAPConnectionFactory(ctrl)
+	brokenConn := NewMockLDAPConnection(ctrl)
+	mockConn := NewMockLDAPConnection(ctrl)
+
+	ldapClient := newPooledLDAPUserProvider(mockFactory)
+
+	gomock.InOrder(
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(brokenConn, nil),
+		brokenConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		brokenConn.EXPECT().
+			Search(gomock.Any()).
+			Return(nil, ldap.NewError(ldap.ErrorNetwork, errors.New("connection closed"))),
+		brokenConn.EXPECT().
+			Close(),
+		mockFactory.EXPECT().
+			DialURL(gomock.Eq("ldap://127.0.0.1:389"), gomock.Any()).
+			Return(mockConn, nil),
+		mockConn.EXPECT().
+			Bind(gomock.Eq("cn=admin,dc=example,dc=com"), gomock.Eq("password")).
+			Return(nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(createSearchResultWithUserProfile(), nil),
+		mockConn.EXPECT().
+			Search(gomock.Any()).
+			Return(createSearchResultWithAttributeValues("group1"), nil),
+	)
+
+	details, err := ldapClient.GetDetails("john")
+	require.NoError(t, err)
+
+	assert.Equal(t, "John", details.Username)
+	assert.Equal(t, []string{"group1"}, details.Groups)
+}
+
+func TestShouldCloseIdleConnectionsAfterIdleTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	firstConn := NewMockLDAPConnection(ctrl)
+	secondConn := NewMockLDAPConnection(ctrl)
+	conns := []LDAPConnection{firstConn, secondConn}
+
+	pool := NewLDAPConnectionPool(func() (LDAPConnection, error) {
+		conn := conns[0]
+		conns = conns[1:]
+
+		return conn, nil
+	}, 2, 0, 5*time.Minute, time.Hour)
+
+	clock := &testClock{now: time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)}
+	pool.clock = clock
+
+	conn, err := pool.Get()
+	require.NoError(t, err)
+	pool.Put(conn)
+
+	clock.now = clock.now.Add(6 * time.Minute)
+
+	firstConn.EXPECT().Close()
+
+	conn, err = pool.Get()
+	require.NoError(t, err)
+
+	assert.Equal(t, secondConn, conn.(*ldapPooledConnection).LDAPConnection)
+}
+
+func TestShouldCheckHealthOfIdleConnections(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	healthyConn := NewMockLDAPConnection(ctrl)
+	unhealthyConn := NewMockLDAPConnection(ctrl)
+	conns := []LDAPConnection{healthyConn, unhealthyConn}
+
+	pool := NewLDAPConnectionPool(func() (LDAPConnection, error) {
+		conn := conns[0]
+		conns = conns[1:]
+
+		return conn, nil
+	}, 2, 0, time.Hour, time.Minute)
+
+	clock := &testClock{now: time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)}
+	pool.clock = clock
+
+	first, err := pool.Get()
+	require.NoError(t, err)
+
+	second, err := pool.Get()
+	require.NoError(t, err)
+
+	pool.Put(first)
+	pool.Put(second)
+
+	// The connections which have not been idle for long are not checked.
+	conn, err := pool.Get()
+	require.NoError(t, err)
+	assert.Equal(t, unhealthyConn, conn.(*ldapPooledConnection).LDAPConnection)
+	pool.Put(conn)
+
+	clock.now = clock.now.Add(2 * time.Minute)
+
+	gomock.InOrder(
+		unhealthyConn.EXPECT().
+			Search(gomock.Any()).
+			Return(nil, ldap.NewError(ldap.ErrorNetwork, errors.New("connection closed"))),
+		unhealthyConn.EXPECT().
+			Close(),
+		healthyConn.EXPECT().
+			Search(gomock.Any()).
+			Return(&ldap.SearchResult{}, nil),
+	)
+
+	conn, err = pool.Get()
+	require.NoError(t, err)
+	assert.Equal(t, healthyConn, conn.(*ldapPooledConnection).LDAPConnection)
+}
+
+func TestShouldBoundNumberOfPooledConnections(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dials := 0
+
+	pool := NewLDAPConnectionPool(func() (LDAPConnection, error) {
+		dials++
+		return NewMockLDAPConnection(ctrl), nil
+	}, 1, 0, time.Hour, time.Hour)
+
+	conn, err := pool.Get()
+	require.NoError(t, err)
+
+	released := make(chan LDAPConnection)
+
+	go func() {
+		conn, err := pool.Get()
+		assert.NoError(t, err)
+
+		released <- conn
+	}()
+
+	select {
+	case <-released:
+		t.Fatal("the pool should not open more connections than its maximum")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	pool.Put(conn)
+
+	select {
+	case other := <-released:
+		assert.Equal(t, conn, other)
+	case <-time.After(time.Second):
+		t.Fatal("the released connection should have been reused")
+	}
+
+	assert.Equal(t, 1, dials)
+}
+
+func TestShouldTimeoutWaitingForPooledConnection(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	pool := NewLDAPConnectionPool(func() (LDAPConnection, error) {
+		return NewMockLDAPConnection(ctrl), nil
+	}, 1, 50*time.Millisecond, time.Hour, time.Hour)
+
+	conn, err := pool.Get()
+	require.NoError(t, err)
+
+	other, err := pool.Get()
+	assert.Nil(t, other)
+	assert.Equal(t, ErrLDAPConnectionPoolTimeout, err)
+
+	// The slot of the request which timed out has not been taken.
+	pool.Put(conn)
+
+	conn, err = pool.Get()
+	require.NoError(t, err)
+	assert.NotNil(t, conn)
+}
@@ -20,6 +20,7 @@ type LDAPUserProvider struct {
 	tlsConfig         *tls.Config
 	dialOpts          ldap.DialOpt
 	connectionFactory LDAPConnectionFactory
+	pool              *LDAPConnectionPool
 	usersDN           string
 	groupsDN          string
 }
@@ -47,6 +48,15 @@ func NewLDAPUserProvider(configuration schema.LDAPAuthenticationBackendConfigura
 
 	provider.parseDynamicConfiguration()
 
+	if configuration.Pool != nil {
+		// Skip Error Check since validator checks it.
+		idleTimeout, _ := utils.ParseDurationString(configuration.Pool.IdleTimeout)
+		healthCheckInterval, _ := utils.ParseDurationString(configuration.Pool.HealthCheckInterval)
+		timeout, _ := utils.ParseDurationString(configuration.Timeout)
+
+		provider.pool = NewLDAPConnectionPool(provider.connectServiceUser, configuration.Pool.MaxConnections, timeout, idleTimeout, healthCheckInterval)
+	}
+
 	return provider
 }
 
@@ -118,26 +128,68 @@ func (p *LDAPUserProvider) connect(userDN string, password string) (LDAPConnecti
 	return conn, nil
 }
 
+func (p *LDAPUserProvider) connectServiceUser() (LDAPConnection, error) {
+	return p.connect(p.configuration.User, p.configuration.Password)
+}
+
+// withServiceConnection runs the operation with a connection bound as the service user. The connection is taken from
+// the pool when one is configured, and the operation is run again on a new connection when it fails because the pooled
+// connection was broken, for instance after a restart of the server.
+func (p *LDAPUserProvider) withServiceConnection(operation func(conn LDAPConnection) error) error {
+	if p.pool == nil {
+		conn, err := p.connectServiceUser()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		return operation(conn)
+	}
+
+	for attempt := 0; ; attempt++ {
+		conn, err := p.pool.Get()
+		if err != nil {
+			return err
+		}
+
+		err = operation(conn)
+		broken := isBroken(conn)
+
+		p.pool.Put(conn)
+
+		if err == nil || !broken || attempt > 0 {
+			return err
+		}
+
+		logging.Logger().Debugf("Retrying the LDAP operation on a new connection: %s", err)
+	}
+}
+
 // CheckUserPassword checks if provided password matches for the given user.
 func (p *LDAPUserProvider) CheckUserPassword(inputUsername string, password string) (bool, error) {
-	conn, err := p.connect(p.configuration.User, p.configuration.Password)
+	err := p.withServiceConnection(func(conn LDAPConnection) error {
+		return p.checkUserPassword(conn, inputUsername, password)
+	})
 	if err != nil {
 		return false, err
 	}
-	defer conn.Close()
 
+	return true, nil
+}
+
+func (p *LDAPUserProvider) checkUserPassword(conn LDAPConnection, inputUsername string, password string) error {
 	profile, err := p.getUserProfile(conn, inputUsername)
 	if err != nil {
-		return false, err
+		return err
 	}
 
 	userConn, err := p.connect(profile.DN, password)
 	if err != nil {
-		return false, fmt.Errorf("Authentication of user %s failed. Cause: %s", inputUsername, err)
+		return fmt.Errorf("Authentication of user %s failed. Cause: %s", inputUsername, err)
 	}
 	defer userConn.Close()
 
-	return true, nil
+	return nil
 }
 
 func (p *LDAPUserProvider) ldapEscape(inputUsername string) string {
@@ -239,14 +291,17 @@ func (p *LDAPUserProvider) resolveGroupsFilter(inputUsername string, profile *ld
 }
 
 // GetDetails retrieve the groups a user belongs to.
-func (p *LDAPUserProvider) GetDetails(inputUsername string) (*UserDetails, error) {
-	logger := logging.Logger()
+func (p *LDAPUserProvider) GetDetails(inputUsername string) (details *UserDetails, err error) {
+	err = p.withServiceConnection(func(conn LDAPConnection) (err error) {
+		details, err = p.getDetails(conn, inputUsername)
+		return err
+	})
 
-	conn, err := p.connect(p.configuration.User, p.configuration.Password)
-	if err != nil {
-		return nil, err
-	}
-	defer conn.Close()
+	return details, err
+}
+
+func (p *LDAPUserProvider) getDetails(conn LDAPConnection, inputUsername string) (*UserDetails, error) {
+	logger := logging.Logger()
 
 	profile, err := p.getUserProfile(conn, inputUsername)
 	if err != nil {
@@ -293,16 +348,20 @@ func (p *LDAPUserProvider) GetDetails(inputUsername string) (*UserDetails, error
 
 // UpdatePassword update the password of the given user.
 func (p *LDAPUserProvider) UpdatePassword(inputUsername string, newPassword string) error {
-	conn, err := p.connect(p.configuration.User, p.configuration.Password)
+	err := p.withServiceConnection(func(conn LDAPConnection) error {
+		return p.updatePassword(conn, inputUsername, newPassword)
+	})
 	if err != nil {
 		return fmt.Errorf("Unable to update password. Cause: %s", err)
 	}
-	defer conn.Close()
 
-	profile, err := p.getUserProfile(conn, inputUsername)
+	return nil
+}
 
+func (p *LDAPUserProvider) updatePassword(conn LDAPConnection, inputUsername string, newPassword string) error {
+	profile, err := p.getUserProfile(conn, inputUsername)
 	if err != nil {
-		return fmt.Errorf("Unable to update password. Cause: %s", err)
+		return err
 	}
 
 	modifyRequest := ldap.NewModifyRequest(profile.DN, nil)
@@ -318,11 +377,5 @@ func (p *LDAPUserProvider) UpdatePassword(inputUsername string, newPassword stri
 		modifyRequest.Replace("userPassword", []string{newPassword})
 	}
 
-	err = conn.Modify(modifyRequest)
-
-	if err != nil {
-		return fmt.Errorf("Unable to update password. Cause: %s", err)
-	}
-
-	return nil
+	return conn.Modify(modifyRequest)
 }
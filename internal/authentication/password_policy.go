@@ -0,0 +1,131 @@
+package authentication
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/nbutton23/zxcvbn-go"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// The rules of the password policy reported in the violations.
+const (
+	PasswordPolicyRuleMinLength = "min_length"
+	PasswordPolicyRuleMaxLength = "max_length"
+	PasswordPolicyRuleUppercase = "require_uppercase"
+	PasswordPolicyRuleLowercase = "require_lowercase"
+	PasswordPolicyRuleNumber    = "require_number"
+	PasswordPolicyRuleSpecial   = "require_special"
+	PasswordPolicyRuleMinScore  = "min_score"
+)
+
+// passwordPolicyMaxScoredLength is the number of characters of a password which are scored since zxcvbn takes too long
+// to score long passwords, only the beginning of the longer ones is scored.
+const passwordPolicyMaxScoredLength = 100
+
+// PasswordPolicyViolation is a rule of the password policy a password does not follow.
+type PasswordPolicyViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError is the error returned when a password does not follow the password policy.
+type PasswordPolicyError struct {
+	Violations []PasswordPolicyViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+
+	return fmt.Sprintf("password does not follow the password policy: %s", strings.Join(messages, ", "))
+}
+
+// PasswordPolicy checks the passwords chosen by the users against the configured rules, whatever the backend storing
+// them.
+type PasswordPolicy struct {
+	configuration schema.PasswordPolicyConfiguration
+}
+
+// NewPasswordPolicy creates a password policy from its configuration.
+func NewPasswordPolicy(configuration schema.PasswordPolicyConfiguration) *PasswordPolicy {
+	return &PasswordPolicy{configuration: configuration}
+}
+
+// Configuration returns the rules of the policy.
+func (p *PasswordPolicy) Configuration() schema.PasswordPolicyConfiguration {
+	return p.configuration
+}
+
+// Check returns a *PasswordPolicyError listing the rules the password does not follow, if any. The user inputs, such as
+// the username, lower the strength score of the passwords containing them.
+func (p *PasswordPolicy) Check(password string, userInputs ...string) error {
+	var violations []PasswordPolicyViolation
+
+	violate := func(rule, format string, args ...interface{}) {
+		violations = append(violations, PasswordPolicyViolation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+
+	if length < p.configuration.MinLength {
+		violate(PasswordPolicyRuleMinLength, "The password must be at least %d characters long", p.configuration.MinLength)
+	}
+
+	if p.configuration.MaxLength != 0 && length > p.configuration.MaxLength {
+		violate(PasswordPolicyRuleMaxLength, "The password must be at most %d characters long", p.configuration.MaxLength)
+	}
+
+	var hasUppercase, hasLowercase, hasNumber, hasSpecial bool
+
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUppercase = true
+		case unicode.IsLower(r):
+			hasLowercase = true
+		case unicode.IsDigit(r):
+			hasNumber = true
+		case !unicode.IsLetter(r):
+			hasSpecial = true
+		}
+	}
+
+	if p.configuration.RequireUppercase && !hasUppercase {
+		violate(PasswordPolicyRuleUppercase, "The password must contain an uppercase letter")
+	}
+
+	if p.configuration.RequireLowercase && !hasLowercase {
+		violate(PasswordPolicyRuleLowercase, "The password must contain a lowercase letter")
+	}
+
+	if p.configuration.RequireNumber && !hasNumber {
+		violate(PasswordPolicyRuleNumber, "The password must contain a number")
+	}
+
+	if p.configuration.RequireSpecial && !hasSpecial {
+		violate(PasswordPolicyRuleSpecial, "The password must contain a special character")
+	}
+
+	if p.configuration.MinScore > 0 {
+		scored := password
+		if length > passwordPolicyMaxScoredLength {
+			scored = string([]rune(password)[:passwordPolicyMaxScoredLength])
+		}
+
+		if zxcvbn.PasswordStrength(scored, userInputs).Score < p.configuration.MinScore {
+			violate(PasswordPolicyRuleMinScore, "The password is too easy to guess")
+		}
+	}
+
+	if len(violations) != 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+
+	return nil
+}
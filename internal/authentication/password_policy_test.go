@@ -0,0 +1,85 @@
+package authentication
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func violatedRules(t *testing.T, err error) []string {
+	var policyErr *PasswordPolicyError
+
+	require.True(t, errors.As(err, &policyErr))
+
+	rules := make([]string, 0, len(policyErr.Violations))
+	for _, violation := range policyErr.Violations {
+		rules = append(rules, violation.Rule)
+	}
+
+	return rules
+}
+
+func TestShouldAcceptAnyPasswordWithDefaultPasswordPolicy(t *testing.T) {
+	policy := NewPasswordPolicy(schema.DefaultPasswordPolicyConfiguration)
+
+	assert.NoError(t, policy.Check(""))
+	assert.NoError(t, policy.Check("password"))
+}
+
+func TestShouldCheckPasswordLength(t *testing.T) {
+	policy := NewPasswordPolicy(schema.PasswordPolicyConfiguration{MinLength: 8, MaxLength: 12})
+
+	assert.NoError(t, policy.Check("12345678"))
+	assert.NoError(t, policy.Check("123456789012"))
+
+	// The length is counted in characters rather than bytes.
+	assert.NoError(t, policy.Check("éééééééé"))
+
+	assert.Equal(t, []string{PasswordPolicyRuleMinLength}, violatedRules(t, policy.Check("1234567")))
+	assert.Equal(t, []string{PasswordPolicyRuleMaxLength}, violatedRules(t, policy.Check("1234567890123")))
+}
+
+func TestShouldCheckPasswordCharacterClasses(t *testing.T) {
+	policy := NewPasswordPolicy(schema.PasswordPolicyConfiguration{
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireNumber:    true,
+		RequireSpecial:   true,
+	})
+
+	assert.NoError(t, policy.Check("aB3!"))
+	assert.NoError(t, policy.Check("éÉ٣ "))
+
+	assert.Equal(t, []string{PasswordPolicyRuleUppercase, PasswordPolicyRuleNumber, PasswordPolicyRuleSpecial},
+		violatedRules(t, policy.Check("password")))
+	assert.Equal(t, []string{PasswordPolicyRuleLowercase}, violatedRules(t, policy.Check("PASSWORD1!")))
+}
+
+func TestShouldCheckPasswordStrength(t *testing.T) {
+	policy := NewPasswordPolicy(schema.PasswordPolicyConfiguration{MinScore: 3})
+
+	assert.NoError(t, policy.Check("correct horse battery staple"))
+
+	assert.Equal(t, []string{PasswordPolicyRuleMinScore}, violatedRules(t, policy.Check("password1")))
+	assert.Equal(t, []string{PasswordPolicyRuleMinScore}, violatedRules(t, policy.Check("johnsmith1984", "johnsmith")))
+}
+
+func TestShouldCheckStrengthOfBeginningOfLongPasswords(t *testing.T) {
+	policy := NewPasswordPolicy(schema.PasswordPolicyConfiguration{MinScore: 4})
+
+	assert.Equal(t, []string{PasswordPolicyRuleMinScore}, violatedRules(t, policy.Check(strings.Repeat("a", 101))))
+}
+
+func TestShouldListAllViolationsInPasswordPolicyError(t *testing.T) {
+	policy := NewPasswordPolicy(schema.PasswordPolicyConfiguration{MinLength: 8, RequireNumber: true})
+
+	err := policy.Check("abc")
+
+	assert.EqualError(t, err, "password does not follow the password policy: "+
+		"The password must be at least 8 characters long, The password must contain a number")
+}
@@ -0,0 +1,504 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/spf13/cobra"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/storage"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+const storageTOTPDeviceIDLength = 16
+const storageTOTPDefaultDeviceDescription = "Authenticator app"
+const storageEncryptionKeyMinLength = 20
+
+// storageNewEncryptionKeyEnvName is the environment variable the new encryption key can be given with, the variable
+// suffixed with _FILE gives the path of a file containing it instead.
+const storageNewEncryptionKeyEnvName = "AUTHELIA_STORAGE_NEW_ENCRYPTION_KEY"
+
+func init() {
+	StorageCmd.PersistentFlags().StringP("config", "c", "", "Configuration file")
+
+	err := StorageCmd.MarkPersistentFlagRequired("config")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	StorageMigrateCmd.Flags().Int("target", int(storage.SchemaLatestVersion), "the version to upgrade or downgrade the schema to")
+	StorageMigrateCmd.Flags().Bool("dry-run", false, "print the migrations and their statements without applying them")
+	StorageUserTOTPDeleteCmd.Flags().String("id", "", "the identifier of the device to delete, all the devices of the user are deleted when omitted")
+	StorageUserTOTPGenerateCmd.Flags().String("description", storageTOTPDefaultDeviceDescription, "the name of the device")
+	StorageUserU2FDeleteCmd.Flags().String("id", "", "the identifier of the security key to delete, all the security keys of the user are deleted when omitted")
+	StorageUserWebauthnDeleteCmd.Flags().String("id", "", "the identifier of the Webauthn device to delete, all the Webauthn devices of the user are deleted when omitted")
+	StorageEncryptionChangeKeyCmd.Flags().String("new-encryption-key", "", fmt.Sprintf("the new key to encrypt the data with, at least 20 characters long, it can also be given with the %s environment variable", storageNewEncryptionKeyEnvName))
+	StorageEncryptionChangeKeyCmd.Flags().String("new-encryption-key-file", "", fmt.Sprintf("the path of a file containing the new key, it can also be given with the %s_FILE environment variable", storageNewEncryptionKeyEnvName))
+
+	StorageUserPreferencesSetCmd.Flags().String("method", "", fmt.Sprintf("the preferred second factor method, one of %s", strings.Join(authentication.PossibleMethods, ", ")))
+
+	err = StorageUserPreferencesSetCmd.MarkFlagRequired("method")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	StorageUserTOTPCmd.AddCommand(StorageUserTOTPDeleteCmd, StorageUserTOTPGenerateCmd)
+	StorageUserU2FCmd.AddCommand(StorageUserU2FDeleteCmd)
+	StorageUserWebauthnCmd.AddCommand(StorageUserWebauthnDeleteCmd)
+	StorageUserPreferencesCmd.AddCommand(StorageUserPreferencesSetCmd)
+	StorageUserCmd.AddCommand(StorageUserTOTPCmd, StorageUserU2FCmd, StorageUserWebauthnCmd, StorageUserPreferencesCmd)
+	StorageEncryptionCmd.AddCommand(StorageEncryptionCheckCmd, StorageEncryptionChangeKeyCmd)
+	StorageCmd.AddCommand(StorageSchemaInfoCmd, StorageMigrateCmd, StorageEncryptionCmd, StorageUserCmd)
+}
+
+// StorageCmd administrates the storage backend of a configuration.
+var StorageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "Administrate the storage backend offline.",
+}
+
+// StorageSchemaInfoCmd shows the version of the schema and the tables of the database.
+var StorageSchemaInfoCmd = &cobra.Command{
+	Use:   "schema-info",
+	Short: "Show the version of the schema and the tables of the database.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		_, migrator, _ := loadStorageProvider(cobraCmd)
+
+		version, tables, err := migrator.SchemaInfo()
+		if err != nil {
+			log.Fatalf("Unable to retrieve the schema information: %s", err)
+		}
+
+		upgrade := "no"
+
+		switch {
+		case version < storage.SchemaLatestVersion:
+			upgrade = fmt.Sprintf("yes, to v%d", storage.SchemaLatestVersion)
+		case version > storage.SchemaLatestVersion:
+			upgrade = fmt.Sprintf("no, the schema is newer than v%d supported by this version", storage.SchemaLatestVersion)
+		}
+
+		fmt.Printf("Schema Version: %d\nSchema Upgrade Available: %s\nTables: %s\n", version, upgrade, strings.Join(tables, ", "))
+	},
+	Args: cobra.NoArgs,
+}
+
+// StorageMigrateCmd upgrades or downgrades the schema of the database.
+var StorageMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade the schema of the database to the latest version, or to the version given by --target.",
+	Long: "Upgrade the schema of the database to the latest version, or upgrade or downgrade it to the version given by " +
+		"--target. Downgrading the schema deletes the data the older versions cannot store.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		target, _ := cobraCmd.Flags().GetInt("target")
+		dryRun, _ := cobraCmd.Flags().GetBool("dry-run")
+
+		_, migrator, _ := loadStorageProvider(cobraCmd)
+
+		migrations, err := migrator.MigrateTo(storage.SchemaVersion(target), dryRun)
+		if err != nil {
+			log.Fatalf("Unable to migrate the schema: %s", err)
+		}
+
+		for _, migration := range migrations {
+			direction := "Upgrade to"
+			if !migration.Up {
+				direction = "Downgrade from"
+			}
+
+			fmt.Printf("%s v%d: %s\n", direction, migration.Version, migration.Name)
+
+			if !dryRun {
+				continue
+			}
+
+			// The data is converted after the statements of the upgrades and before the ones of the downgrades.
+			if migration.Data != "" && !migration.Up {
+				fmt.Printf("    -- %s\n", migration.Data)
+			}
+
+			for _, statement := range migration.Statements {
+				fmt.Printf("    %s;\n", statement)
+			}
+
+			if migration.Data != "" && migration.Up {
+				fmt.Printf("    -- %s\n", migration.Data)
+			}
+		}
+
+		switch {
+		case dryRun && len(migrations) == 0:
+			fmt.Printf("Schema is already at v%d, there is nothing to migrate\n", target)
+		case dryRun:
+			fmt.Printf("Dry run, the schema has not been migrated\n")
+		default:
+			fmt.Printf("Schema is up to date at v%d\n", target)
+		}
+	},
+	Args: cobra.NoArgs,
+}
+
+// StorageEncryptionCmd groups the commands managing the key the sensitive data of the database is encrypted with.
+var StorageEncryptionCmd = &cobra.Command{
+	Use:   "encryption",
+	Short: "Manage the key the sensitive data of the database is encrypted with.",
+}
+
+// StorageEncryptionCheckCmd checks that the data of the database has been encrypted with the configured key.
+var StorageEncryptionCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check the data of the database has been encrypted with the configured key.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		_, migrator, _ := loadStorageProvider(cobraCmd)
+
+		if err := migrator.CheckEncryptionKey(); err != nil {
+			log.Fatalf("Unable to check the encryption key: %s", err)
+		}
+
+		fmt.Println("The encryption key is valid")
+	},
+	Args: cobra.NoArgs,
+}
+
+// StorageEncryptionChangeKeyCmd re-encrypts the sensitive data of the database with a new key.
+var StorageEncryptionChangeKeyCmd = &cobra.Command{
+	Use:   "change-key",
+	Short: "Re-encrypt the sensitive data of the database with a new key.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		newKey, err := loadNewEncryptionKey(cobraCmd)
+		if err != nil {
+			log.Fatalf("Unable to load the new encryption key: %s", err)
+		}
+
+		if len(newKey) < storageEncryptionKeyMinLength {
+			log.Fatalf("The new encryption key must be at least %d characters long", storageEncryptionKeyMinLength)
+		}
+
+		_, migrator, _ := loadStorageProvider(cobraCmd)
+
+		if err := migrator.ChangeEncryptionKey(newKey); err != nil {
+			log.Fatalf("Unable to change the encryption key: %s", err)
+		}
+
+		fmt.Println("The data has been re-encrypted, set the new key as the storage encryption_key of the configuration")
+	},
+	Args: cobra.NoArgs,
+}
+
+// StorageUserCmd groups the commands administrating the data of a user.
+var StorageUserCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Administrate the second factors and preferences of the users.",
+}
+
+// StorageUserTOTPCmd groups the commands administrating the TOTP devices of a user.
+var StorageUserTOTPCmd = &cobra.Command{
+	Use:   "totp",
+	Short: "Administrate the TOTP devices of the users.",
+}
+
+// StorageUserTOTPDeleteCmd deletes one or all the TOTP devices of a user.
+var StorageUserTOTPDeleteCmd = &cobra.Command{
+	Use:   "delete [username]",
+	Short: "Delete a TOTP device of a user.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		provider, _ := loadCheckedStorageProvider(cobraCmd)
+		id, _ := cobraCmd.Flags().GetString("id")
+
+		var err error
+
+		if id == "" {
+			err = provider.DeleteTOTPDevicesByUsername(args[0])
+		} else {
+			err = provider.DeleteTOTPDevice(args[0], id)
+		}
+
+		switch {
+		case errors.Is(err, storage.ErrNoTOTPSecret):
+			log.Fatalf("User %s has no TOTP device with identifier %s", args[0], id)
+		case err != nil:
+			log.Fatalf("Unable to delete the TOTP devices of user %s: %s", args[0], err)
+		}
+
+		fmt.Printf("Deleted the TOTP devices of user %s\n", args[0])
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+// StorageUserTOTPGenerateCmd registers a TOTP device for a user and prints its otpauth URI.
+var StorageUserTOTPGenerateCmd = &cobra.Command{
+	Use:   "generate [username]",
+	Short: "Register a TOTP device for a user and print its otpauth URI.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		provider, config := loadCheckedStorageProvider(cobraCmd)
+		description, _ := cobraCmd.Flags().GetString("description")
+
+		key, err := totp.Generate(totp.GenerateOpts{
+			Issuer:      config.TOTP.Issuer,
+			AccountName: args[0],
+			SecretSize:  32,
+			Period:      uint(config.TOTP.Period),
+			Digits:      otp.Digits(config.TOTP.Digits),
+			Algorithm:   utils.OTPAlgorithm(config.TOTP.Algorithm),
+		})
+		if err != nil {
+			log.Fatalf("Unable to generate the TOTP key: %s", err)
+		}
+
+		err = provider.SaveTOTPDevice(models.TOTPDevice{
+			Username:    args[0],
+			ID:          utils.RandomString(storageTOTPDeviceIDLength, utils.AlphaNumericCharacters),
+			Description: description,
+			Secret:      key.Secret(),
+			Algorithm:   config.TOTP.Algorithm,
+			Digits:      uint(config.TOTP.Digits),
+			Period:      uint(config.TOTP.Period),
+			CreatedAt:   time.Now(),
+		})
+		if err != nil {
+			log.Fatalf("Unable to save the TOTP device of user %s: %s", args[0], err)
+		}
+
+		fmt.Println(key.URL())
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+// StorageUserU2FCmd groups the commands administrating the security keys of a user.
+var StorageUserU2FCmd = &cobra.Command{
+	Use:   "u2f",
+	Short: "Administrate the security keys of the users.",
+}
+
+// StorageUserU2FDeleteCmd deletes one or all the security keys of a user.
+var StorageUserU2FDeleteCmd = &cobra.Command{
+	Use:   "delete [username]",
+	Short: "Delete a security key of a user.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		provider, _ := loadCheckedStorageProvider(cobraCmd)
+		id, _ := cobraCmd.Flags().GetString("id")
+
+		var keyHandles [][]byte
+
+		if id == "" {
+			devices, err := provider.LoadU2FDevicesByUsername(args[0])
+			if err != nil && !errors.Is(err, storage.ErrNoU2FDeviceHandle) {
+				log.Fatalf("Unable to load the security keys of user %s: %s", args[0], err)
+			}
+
+			for _, device := range devices {
+				keyHandles = append(keyHandles, device.KeyHandle)
+			}
+		} else {
+			keyHandle, err := utils.DecodeKeyHandle(id)
+			if err != nil {
+				log.Fatalf("Malformed security key identifier %s: %s", id, err)
+			}
+
+			keyHandles = append(keyHandles, keyHandle)
+		}
+
+		for _, keyHandle := range keyHandles {
+			err := provider.DeleteU2FDevice(args[0], keyHandle)
+
+			switch {
+			case errors.Is(err, storage.ErrNoU2FDeviceHandle):
+				log.Fatalf("User %s has no security key with identifier %s", args[0], id)
+			case err != nil:
+				log.Fatalf("Unable to delete the security keys of user %s: %s", args[0], err)
+			}
+		}
+
+		fmt.Printf("Deleted %d security key(s) of user %s\n", len(keyHandles), args[0])
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+// StorageUserWebauthnCmd groups the commands administrating the Webauthn devices of a user.
+var StorageUserWebauthnCmd = &cobra.Command{
+	Use:   "webauthn",
+	Short: "Administrate the Webauthn devices of the users.",
+}
+
+// StorageUserWebauthnDeleteCmd deletes one or all the Webauthn devices of a user.
+var StorageUserWebauthnDeleteCmd = &cobra.Command{
+	Use:   "delete [username]",
+	Short: "Delete a Webauthn device of a user.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		provider, _ := loadCheckedStorageProvider(cobraCmd)
+		id, _ := cobraCmd.Flags().GetString("id")
+
+		var kids [][]byte
+
+		if id == "" {
+			devices, err := provider.LoadWebauthnDevicesByUsername(args[0])
+			if err != nil && !errors.Is(err, storage.ErrNoWebauthnDevice) {
+				log.Fatalf("Unable to load the Webauthn devices of user %s: %s", args[0], err)
+			}
+
+			for _, device := range devices {
+				kids = append(kids, device.KID)
+			}
+		} else {
+			kid, err := utils.DecodeKeyHandle(id)
+			if err != nil {
+				log.Fatalf("Malformed Webauthn device identifier %s: %s", id, err)
+			}
+
+			kids = append(kids, kid)
+		}
+
+		for _, kid := range kids {
+			err := provider.DeleteWebauthnDevice(args[0], kid)
+
+			switch {
+			case errors.Is(err, storage.ErrNoWebauthnDevice):
+				log.Fatalf("User %s has no Webauthn device with identifier %s", args[0], id)
+			case err != nil:
+				log.Fatalf("Unable to delete the Webauthn devices of user %s: %s", args[0], err)
+			}
+		}
+
+		fmt.Printf("Deleted %d Webauthn device(s) of user %s\n", len(kids), args[0])
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+// StorageUserPreferencesCmd groups the commands administrating the preferences of a user.
+var StorageUserPreferencesCmd = &cobra.Command{
+	Use:   "preferences",
+	Short: "Administrate the preferences of the users.",
+}
+
+// StorageUserPreferencesSetCmd sets the preferred second factor method of a user.
+var StorageUserPreferencesSetCmd = &cobra.Command{
+	Use:   "set [username]",
+	Short: "Set the preferred second factor method of a user.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		method, _ := cobraCmd.Flags().GetString("method")
+
+		if !utils.IsStringInSlice(method, authentication.PossibleMethods) {
+			log.Fatalf("Unknown method '%s', it should be one of %s", method, strings.Join(authentication.PossibleMethods, ", "))
+		}
+
+		provider, _ := loadCheckedStorageProvider(cobraCmd)
+
+		if err := provider.SavePreferred2FAMethod(args[0], method); err != nil {
+			log.Fatalf("Unable to save the preferred method of user %s: %s", args[0], err)
+		}
+
+		fmt.Printf("Preferred method of user %s set to %s\n", args[0], method)
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+// loadConfiguration reads the configuration given to the command and exits when it is invalid.
+func loadConfiguration(cobraCmd *cobra.Command) *schema.Configuration {
+	configPath, _ := cobraCmd.Flags().GetString("config")
+
+	config, errs := configuration.Read(configPath)
+	if len(errs) != 0 {
+		messages := ""
+		for _, err := range errs {
+			messages += fmt.Sprintf("\t%s\n", err.Error())
+		}
+
+		log.Fatalf("Error(s) occurred parsing configuration:\n%s", messages)
+	}
+
+	return config
+}
+
+// loadStorageProvider reads the configuration given to the storage command and connects to its storage backend.
+func loadStorageProvider(cobraCmd *cobra.Command) (storage.Provider, storage.Migrator, *schema.Configuration) {
+	config := loadConfiguration(cobraCmd)
+
+	switch {
+	case config.Storage.PostgreSQL != nil:
+		provider := storage.NewPostgreSQLProvider(*config.Storage.PostgreSQL, config.Storage.EncryptionKey)
+		return provider, provider, config
+	case config.Storage.MySQL != nil:
+		provider := storage.NewMySQLProvider(*config.Storage.MySQL, config.Storage.EncryptionKey)
+		return provider, provider, config
+	case config.Storage.Local != nil:
+		provider := storage.NewSQLiteProvider(config.Storage.Local.Path, config.Storage.EncryptionKey)
+		return provider, provider, config
+	default:
+		log.Fatalf("Unrecognized storage backend")
+	}
+
+	return nil, nil, nil
+}
+
+// loadCheckedStorageProvider connects to the storage backend like loadStorageProvider and exits unless the schema is
+// the one supported by this version and the data is encrypted with the configured key, so that the commands never
+// write data the server would not be able to read.
+func loadCheckedStorageProvider(cobraCmd *cobra.Command) (storage.Provider, *schema.Configuration) {
+	provider, migrator, config := loadStorageProvider(cobraCmd)
+
+	version, _, err := migrator.SchemaInfo()
+	if err != nil {
+		log.Fatalf("Unable to retrieve the schema information: %s", err)
+	}
+
+	switch {
+	case version < storage.SchemaLatestVersion:
+		log.Fatalf("Schema is v%d but v%d is required, upgrade it with 'authelia storage migrate'", version, storage.SchemaLatestVersion)
+	case version > storage.SchemaLatestVersion:
+		log.Fatalf("Schema is v%d which is newer than v%d supported by this version of Authelia", version, storage.SchemaLatestVersion)
+	}
+
+	if err := migrator.CheckEncryptionKey(); err != nil {
+		log.Fatalf("Unable to check the encryption key: %s", err)
+	}
+
+	return provider, config
+}
+
+// loadNewEncryptionKey returns the new encryption key given with exactly one of the flags or environment variables,
+// the keys read from files have their line breaks removed like the secrets of the configuration.
+func loadNewEncryptionKey(cobraCmd *cobra.Command) (string, error) {
+	key, _ := cobraCmd.Flags().GetString("new-encryption-key")
+	keyFile, _ := cobraCmd.Flags().GetString("new-encryption-key-file")
+	envKey := os.Getenv(storageNewEncryptionKeyEnvName)
+	envKeyFile := os.Getenv(storageNewEncryptionKeyEnvName + "_FILE")
+
+	given := 0
+
+	for _, value := range []string{key, keyFile, envKey, envKeyFile} {
+		if value != "" {
+			given++
+		}
+	}
+
+	switch {
+	case given == 0:
+		return "", fmt.Errorf("it must be given with --new-encryption-key, --new-encryption-key-file or the %[1]s or %[1]s_FILE environment variables", storageNewEncryptionKeyEnvName)
+	case given > 1:
+		return "", fmt.Errorf("it must be given only once")
+	case key != "":
+		return key, nil
+	case envKey != "":
+		return envKey, nil
+	case envKeyFile != "":
+		keyFile = envKeyFile
+	}
+
+	content, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ReplaceAll(string(content), "\n", ""), nil
+}
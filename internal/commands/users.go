@@ -0,0 +1,286 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+func init() {
+	UsersCmd.PersistentFlags().StringP("config", "c", "", "Configuration file")
+
+	err := UsersCmd.MarkPersistentFlagRequired("config")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	UsersCreateCmd.Flags().String("display-name", "", "the display name of the user")
+	UsersCreateCmd.Flags().String("email", "", "the email address of the user")
+	UsersCreateCmd.Flags().StringSlice("group", nil, "a group of the user, can be repeated")
+	UsersCreateCmd.Flags().String("password", "", "the password of the user, read from the standard input when omitted")
+
+	err = UsersCreateCmd.MarkFlagRequired("display-name")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	UsersSetPasswordCmd.Flags().String("password", "", "the new password of the user, read from the standard input when omitted")
+
+	UsersGroupsCmd.AddCommand(UsersGroupsAddCmd, UsersGroupsRemoveCmd)
+	UsersCmd.AddCommand(UsersListCmd, UsersShowCmd, UsersCreateCmd, UsersDeleteCmd, UsersSetPasswordCmd,
+		UsersSetDisplayNameCmd, UsersGroupsCmd)
+}
+
+// UsersCmd administrates the users of the file authentication backend.
+var UsersCmd = &cobra.Command{
+	Use:   "users",
+	Short: "Administrate the users of the file authentication backend.",
+}
+
+// UsersListCmd lists the users of the database.
+var UsersListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the users of the database.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		_, database, _ := loadUsersDatabase(cobraCmd)
+
+		usernames := make([]string, 0, len(database.Users))
+		for username := range database.Users {
+			usernames = append(usernames, username)
+		}
+
+		sort.Strings(usernames)
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "USERNAME\tDISPLAY NAME\tEMAIL\tGROUPS")
+
+		for _, username := range usernames {
+			details := database.Users[username]
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", username, details.DisplayName, details.Email, strings.Join(details.Groups, ", "))
+		}
+
+		_ = writer.Flush()
+	},
+	Args: cobra.NoArgs,
+}
+
+// UsersShowCmd shows the details of a user.
+var UsersShowCmd = &cobra.Command{
+	Use:   "show [username]",
+	Short: "Show the details of a user.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		_, database, _ := loadUsersDatabase(cobraCmd)
+		details := getUserDetails(database, args[0])
+
+		expiresAt := "never"
+		if details.ExpiresAt != nil {
+			expiresAt = details.ExpiresAt.Format(time.RFC3339)
+		}
+
+		fmt.Printf("Username: %s\nDisplay Name: %s\nEmail: %s\nGroups: %s\nDisabled: %t\nExpires At: %s\n",
+			args[0], details.DisplayName, details.Email, strings.Join(details.Groups, ", "), details.IsDisabled(time.Now()), expiresAt)
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+// UsersCreateCmd adds a user to the database.
+var UsersCreateCmd = &cobra.Command{
+	Use:   "create [username]",
+	Short: "Add a user to the database.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		backend, database, config := loadUsersDatabase(cobraCmd)
+
+		if _, ok := database.Users[args[0]]; ok {
+			log.Fatalf("User %s already exists", args[0])
+		}
+
+		displayName, _ := cobraCmd.Flags().GetString("display-name")
+		email, _ := cobraCmd.Flags().GetString("email")
+		groups, _ := cobraCmd.Flags().GetStringSlice("group")
+
+		database.Users[args[0]] = authentication.UserDetailsModel{
+			HashedPassword: hashUserPassword(cobraCmd, config, args[0]),
+			DisplayName:    displayName,
+			Email:          email,
+			Groups:         groups,
+		}
+
+		saveUsersDatabase(backend.Path, database)
+		fmt.Printf("Created user %s\n", args[0])
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+// UsersDeleteCmd removes a user from the database.
+var UsersDeleteCmd = &cobra.Command{
+	Use:   "delete [username]",
+	Short: "Remove a user from the database.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		backend, database, _ := loadUsersDatabase(cobraCmd)
+		getUserDetails(database, args[0])
+
+		delete(database.Users, args[0])
+
+		saveUsersDatabase(backend.Path, database)
+		fmt.Printf("Deleted user %s\n", args[0])
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+// UsersSetPasswordCmd changes the password of a user.
+var UsersSetPasswordCmd = &cobra.Command{
+	Use:   "set-password [username]",
+	Short: "Change the password of a user.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		backend, database, config := loadUsersDatabase(cobraCmd)
+		details := getUserDetails(database, args[0])
+
+		details.HashedPassword = hashUserPassword(cobraCmd, config, args[0])
+		database.Users[args[0]] = details
+
+		saveUsersDatabase(backend.Path, database)
+		fmt.Printf("Changed the password of user %s\n", args[0])
+	},
+	Args: cobra.ExactArgs(1),
+}
+
+// UsersSetDisplayNameCmd changes the display name of a user.
+var UsersSetDisplayNameCmd = &cobra.Command{
+	Use:   "set-display-name [username] [display name]",
+	Short: "Change the display name of a user.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		backend, database, _ := loadUsersDatabase(cobraCmd)
+		details := getUserDetails(database, args[0])
+
+		details.DisplayName = args[1]
+		database.Users[args[0]] = details
+
+		saveUsersDatabase(backend.Path, database)
+		fmt.Printf("Changed the display name of user %s\n", args[0])
+	},
+	Args: cobra.ExactArgs(2),
+}
+
+// UsersGroupsCmd administrates the groups of the users.
+var UsersGroupsCmd = &cobra.Command{
+	Use:   "groups",
+	Short: "Administrate the groups of the users.",
+}
+
+// UsersGroupsAddCmd adds a user to groups.
+var UsersGroupsAddCmd = &cobra.Command{
+	Use:   "add [username] [group]...",
+	Short: "Add a user to groups.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		backend, database, _ := loadUsersDatabase(cobraCmd)
+		details := getUserDetails(database, args[0])
+
+		for _, group := range args[1:] {
+			if !utils.IsStringInSlice(group, details.Groups) {
+				details.Groups = append(details.Groups, group)
+			}
+		}
+
+		database.Users[args[0]] = details
+
+		saveUsersDatabase(backend.Path, database)
+		fmt.Printf("Groups of user %s: %s\n", args[0], strings.Join(details.Groups, ", "))
+	},
+	Args: cobra.MinimumNArgs(2),
+}
+
+// UsersGroupsRemoveCmd removes a user from groups.
+var UsersGroupsRemoveCmd = &cobra.Command{
+	Use:   "remove [username] [group]...",
+	Short: "Remove a user from groups.",
+	Run: func(cobraCmd *cobra.Command, args []string) {
+		backend, database, _ := loadUsersDatabase(cobraCmd)
+		details := getUserDetails(database, args[0])
+
+		groups := make([]string, 0, len(details.Groups))
+
+		for _, group := range details.Groups {
+			if !utils.IsStringInSlice(group, args[1:]) {
+				groups = append(groups, group)
+			}
+		}
+
+		details.Groups = groups
+		database.Users[args[0]] = details
+
+		saveUsersDatabase(backend.Path, database)
+		fmt.Printf("Groups of user %s: %s\n", args[0], strings.Join(details.Groups, ", "))
+	},
+	Args: cobra.MinimumNArgs(2),
+}
+
+func loadUsersDatabase(cobraCmd *cobra.Command) (*schema.FileAuthenticationBackendConfiguration, *authentication.DatabaseModel, *schema.Configuration) {
+	config := loadConfiguration(cobraCmd)
+
+	if config.AuthenticationBackend.File == nil {
+		log.Fatalf("The users can only be administrated with the file authentication backend")
+	}
+
+	database, err := authentication.LoadDatabase(config.AuthenticationBackend.File.Path)
+	if err != nil {
+		log.Fatalf("Unable to load the users database: %s", err)
+	}
+
+	return config.AuthenticationBackend.File, database, config
+}
+
+func saveUsersDatabase(path string, database *authentication.DatabaseModel) {
+	if err := authentication.WriteDatabase(path, database); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func getUserDetails(database *authentication.DatabaseModel, username string) authentication.UserDetailsModel {
+	details, ok := database.Users[username]
+	if !ok {
+		log.Fatalf("User %s does not exist", username)
+	}
+
+	return details
+}
+
+// hashUserPassword hashes the password given by the --password flag, or the first line of the standard input, once
+// checked against the password policy.
+func hashUserPassword(cobraCmd *cobra.Command, config *schema.Configuration, username string) string {
+	password, _ := cobraCmd.Flags().GetString("password")
+
+	if password == "" {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			log.Fatalf("Unable to read the password from the standard input: %s", err)
+		}
+
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	if password == "" {
+		log.Fatalf("The password of user %s must not be empty", username)
+	}
+
+	if err := authentication.NewPasswordPolicy(config.PasswordPolicy).Check(password, username); err != nil {
+		log.Fatalf("Unable to set the password of user %s: %s", username, err)
+	}
+
+	hash, err := authentication.HashPasswordWithConfiguration(password, config.AuthenticationBackend.File.Password)
+	if err != nil {
+		log.Fatalf("Unable to hash the password of user %s: %s", username, err)
+	}
+
+	return hash
+}
@@ -31,6 +31,7 @@ func resetEnv() {
 	_ = os.Unsetenv("AUTHELIA_SESSION_REDIS_HIGH_AVAILABILITY_SENTINEL_PASSWORD_FILE")
 	_ = os.Unsetenv("AUTHELIA_STORAGE_MYSQL_PASSWORD_FILE")
 	_ = os.Unsetenv("AUTHELIA_STORAGE_POSTGRES_PASSWORD_FILE")
+	_ = os.Unsetenv("AUTHELIA_STORAGE_ENCRYPTION_KEY_FILE")
 }
 
 func setupEnv(t *testing.T) string {
@@ -0,0 +1,7 @@
+package schema
+
+// AuditLogConfiguration represents the configuration of the security audit log.
+type AuditLogConfiguration struct {
+	Enabled  bool   `mapstructure:"enabled"`
+	FilePath string `mapstructure:"file_path"`
+}
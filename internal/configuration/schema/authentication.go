@@ -2,28 +2,38 @@ package schema
 
 // LDAPAuthenticationBackendConfiguration represents the configuration related to LDAP server.
 type LDAPAuthenticationBackendConfiguration struct {
-	Implementation       string     `mapstructure:"implementation"`
-	URL                  string     `mapstructure:"url"`
-	BaseDN               string     `mapstructure:"base_dn"`
-	AdditionalUsersDN    string     `mapstructure:"additional_users_dn"`
-	UsersFilter          string     `mapstructure:"users_filter"`
-	AdditionalGroupsDN   string     `mapstructure:"additional_groups_dn"`
-	GroupsFilter         string     `mapstructure:"groups_filter"`
-	GroupNameAttribute   string     `mapstructure:"group_name_attribute"`
-	UsernameAttribute    string     `mapstructure:"username_attribute"`
-	MailAttribute        string     `mapstructure:"mail_attribute"`
-	DisplayNameAttribute string     `mapstructure:"display_name_attribute"`
-	User                 string     `mapstructure:"user"`
-	Password             string     `mapstructure:"password"`
-	StartTLS             bool       `mapstructure:"start_tls"`
-	TLS                  *TLSConfig `mapstructure:"tls"`
-	SkipVerify           *bool      `mapstructure:"skip_verify"`         // Deprecated: Replaced with LDAPAuthenticationBackendConfiguration.TLS.SkipVerify. TODO: Remove in 4.28.
-	MinimumTLSVersion    string     `mapstructure:"minimum_tls_version"` // Deprecated: Replaced with LDAPAuthenticationBackendConfiguration.TLS.MinimumVersion. TODO: Remove in 4.28.
+	Implementation       string                 `mapstructure:"implementation"`
+	URL                  string                 `mapstructure:"url"`
+	BaseDN               string                 `mapstructure:"base_dn"`
+	AdditionalUsersDN    string                 `mapstructure:"additional_users_dn"`
+	UsersFilter          string                 `mapstructure:"users_filter"`
+	AdditionalGroupsDN   string                 `mapstructure:"additional_groups_dn"`
+	GroupsFilter         string                 `mapstructure:"groups_filter"`
+	GroupNameAttribute   string                 `mapstructure:"group_name_attribute"`
+	UsernameAttribute    string                 `mapstructure:"username_attribute"`
+	MailAttribute        string                 `mapstructure:"mail_attribute"`
+	DisplayNameAttribute string                 `mapstructure:"display_name_attribute"`
+	User                 string                 `mapstructure:"user"`
+	Password             string                 `mapstructure:"password"`
+	Timeout              string                 `mapstructure:"timeout"`
+	StartTLS             bool                   `mapstructure:"start_tls"`
+	TLS                  *TLSConfig             `mapstructure:"tls"`
+	Pool                 *LDAPPoolConfiguration `mapstructure:"pool"`
+	SkipVerify           *bool                  `mapstructure:"skip_verify"`         // Deprecated: Replaced with LDAPAuthenticationBackendConfiguration.TLS.SkipVerify. TODO: Remove in 4.28.
+	MinimumTLSVersion    string                 `mapstructure:"minimum_tls_version"` // Deprecated: Replaced with LDAPAuthenticationBackendConfiguration.TLS.MinimumVersion. TODO: Remove in 4.28.
+}
+
+// LDAPPoolConfiguration represents the configuration of the pool of connections bound as the LDAP service user.
+type LDAPPoolConfiguration struct {
+	MaxConnections      int    `mapstructure:"max_connections"`
+	IdleTimeout         string `mapstructure:"idle_timeout"`
+	HealthCheckInterval string `mapstructure:"health_check_interval"`
 }
 
 // FileAuthenticationBackendConfiguration represents the configuration related to file-based backend.
 type FileAuthenticationBackendConfiguration struct {
 	Path     string                 `mapstructure:"path"`
+	Watch    bool                   `mapstructure:"watch"`
 	Password *PasswordConfiguration `mapstructure:"password"`
 }
 
@@ -79,9 +89,15 @@ var DefaultLDAPAuthenticationBackendConfiguration = LDAPAuthenticationBackendCon
 	MailAttribute:        "mail",
 	DisplayNameAttribute: "displayname",
 	GroupNameAttribute:   "cn",
+	Timeout:              "5s",
 	TLS: &TLSConfig{
 		MinimumVersion: "TLS1.2",
 	},
+	Pool: &LDAPPoolConfiguration{
+		MaxConnections:      10,
+		IdleTimeout:         "5m",
+		HealthCheckInterval: "1m",
+	},
 }
 
 // DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration represents the default LDAP config for the MSAD Implementation.
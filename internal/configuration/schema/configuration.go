@@ -17,10 +17,14 @@ type Configuration struct {
 	AuthenticationBackend AuthenticationBackendConfiguration `mapstructure:"authentication_backend"`
 	Session               SessionConfiguration               `mapstructure:"session"`
 	TOTP                  *TOTPConfiguration                 `mapstructure:"totp"`
+	Webauthn              *WebauthnConfiguration             `mapstructure:"webauthn"`
 	DuoAPI                *DuoAPIConfiguration               `mapstructure:"duo_api"`
 	AccessControl         AccessControlConfiguration         `mapstructure:"access_control"`
 	Regulation            *RegulationConfiguration           `mapstructure:"regulation"`
+	PasswordPolicy        PasswordPolicyConfiguration        `mapstructure:"password_policy"`
 	Storage               StorageConfiguration               `mapstructure:"storage"`
 	Notifier              *NotifierConfiguration             `mapstructure:"notifier"`
 	Server                ServerConfiguration                `mapstructure:"server"`
+	AuditLog              AuditLogConfiguration              `mapstructure:"audit_log"`
+	IdentityProviders     IdentityProvidersConfiguration     `mapstructure:"identity_providers"`
 }
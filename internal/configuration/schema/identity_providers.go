@@ -0,0 +1,45 @@
+package schema
+
+// IdentityProvidersConfiguration represents the configuration of the identity providers Authelia acts as.
+type IdentityProvidersConfiguration struct {
+	OIDC *OpenIDConnectConfiguration `mapstructure:"oidc"`
+}
+
+// OpenIDConnectConfiguration represents the configuration of the OpenID Connect provider.
+type OpenIDConnectConfiguration struct {
+	IssuerPrivateKey      string                             `mapstructure:"issuer_private_key"`
+	AccessTokenLifespan   string                             `mapstructure:"access_token_lifespan"`
+	AuthorizeCodeLifespan string                             `mapstructure:"authorize_code_lifespan"`
+	IDTokenLifespan       string                             `mapstructure:"id_token_lifespan"`
+	RefreshTokenLifespan  string                             `mapstructure:"refresh_token_lifespan"`
+	Clients               []OpenIDConnectClientConfiguration `mapstructure:"clients"`
+}
+
+// OpenIDConnectClientConfiguration represents the configuration of a client of the OpenID Connect provider.
+type OpenIDConnectClientConfiguration struct {
+	ID            string   `mapstructure:"id"`
+	Description   string   `mapstructure:"description"`
+	Secret        string   `mapstructure:"secret"`
+	Public        bool     `mapstructure:"public"`
+	Policy        string   `mapstructure:"authorization_policy"`
+	RedirectURIs  []string `mapstructure:"redirect_uris"`
+	Scopes        []string `mapstructure:"scopes"`
+	GrantTypes    []string `mapstructure:"grant_types"`
+	ResponseTypes []string `mapstructure:"response_types"`
+}
+
+// DefaultOpenIDConnectConfiguration contains defaults for the OpenID Connect provider.
+var DefaultOpenIDConnectConfiguration = OpenIDConnectConfiguration{
+	AccessTokenLifespan:   "1h",
+	AuthorizeCodeLifespan: "1m",
+	IDTokenLifespan:       "1h",
+	RefreshTokenLifespan:  "90m",
+}
+
+// DefaultOpenIDConnectClientConfiguration contains defaults for the clients of the OpenID Connect provider.
+var DefaultOpenIDConnectClientConfiguration = OpenIDConnectClientConfiguration{
+	Policy:        "two_factor",
+	Scopes:        []string{"openid", "groups", "profile", "email"},
+	GrantTypes:    []string{"refresh_token", "authorization_code"},
+	ResponseTypes: []string{"code"},
+}
@@ -0,0 +1,17 @@
+package schema
+
+// PasswordPolicyConfiguration represents the rules the passwords chosen by the users must follow.
+type PasswordPolicyConfiguration struct {
+	MinLength        int  `mapstructure:"min_length"`
+	MaxLength        int  `mapstructure:"max_length"`
+	RequireUppercase bool `mapstructure:"require_uppercase"`
+	RequireLowercase bool `mapstructure:"require_lowercase"`
+	RequireNumber    bool `mapstructure:"require_number"`
+	RequireSpecial   bool `mapstructure:"require_special"`
+
+	// MinScore is the minimum zxcvbn strength score, from 0 to 4, of the passwords.
+	MinScore int `mapstructure:"min_score"`
+}
+
+// DefaultPasswordPolicyConfiguration represents the default password policy, which accepts any password.
+var DefaultPasswordPolicyConfiguration = PasswordPolicyConfiguration{}
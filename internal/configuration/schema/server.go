@@ -5,6 +5,22 @@ type ServerConfiguration struct {
 	Path            string `mapstructure:"path"`
 	ReadBufferSize  int    `mapstructure:"read_buffer_size"`
 	WriteBufferSize int    `mapstructure:"write_buffer_size"`
+
+	Metrics ServerMetricsConfiguration `mapstructure:"metrics"`
+	Admin   ServerAdminConfiguration   `mapstructure:"admin"`
+}
+
+// ServerMetricsConfiguration represents the configuration of the metrics endpoint.
+type ServerMetricsConfiguration struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Address string `mapstructure:"address"`
+}
+
+// ServerAdminConfiguration represents the configuration of the admin API.
+type ServerAdminConfiguration struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Group   string `mapstructure:"group"`
+	Address string `mapstructure:"address"`
 }
 
 // DefaultServerConfiguration represents the default values of the ServerConfiguration.
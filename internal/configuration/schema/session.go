@@ -30,13 +30,24 @@ type RedisSessionConfiguration struct {
 
 // SessionConfiguration represents the configuration related to user sessions.
 type SessionConfiguration struct {
-	Name               string                     `mapstructure:"name"`
-	Secret             string                     `mapstructure:"secret"`
-	Expiration         string                     `mapstructure:"expiration"`
-	Inactivity         string                     `mapstructure:"inactivity"`
-	RememberMeDuration string                     `mapstructure:"remember_me_duration"`
-	Domain             string                     `mapstructure:"domain"`
-	Redis              *RedisSessionConfiguration `mapstructure:"redis"`
+	Name               string                       `mapstructure:"name"`
+	Secret             string                       `mapstructure:"secret"`
+	Expiration         string                       `mapstructure:"expiration"`
+	Inactivity         string                       `mapstructure:"inactivity"`
+	RememberMeDuration string                       `mapstructure:"remember_me_duration"`
+	Domain             string                       `mapstructure:"domain"`
+	PortalURL          string                       `mapstructure:"portal_url"`
+	Cookies            []SessionCookieConfiguration `mapstructure:"cookies"`
+	Redis              *RedisSessionConfiguration   `mapstructure:"redis"`
+}
+
+// SessionCookieConfiguration represents the configuration of an additional domain protected with its own session
+// cookie. The name and expiration default to the ones of the session.
+type SessionCookieConfiguration struct {
+	Domain     string `mapstructure:"domain"`
+	Name       string `mapstructure:"name"`
+	Expiration string `mapstructure:"expiration"`
+	PortalURL  string `mapstructure:"portal_url"`
 }
 
 // DefaultSessionConfiguration is the default session configuration.
@@ -27,6 +27,9 @@ type PostgreSQLStorageConfiguration struct {
 
 // StorageConfiguration represents the configuration of the storage backend.
 type StorageConfiguration struct {
+	DisableAutomaticMigration bool   `mapstructure:"disable_automatic_migration"`
+	EncryptionKey             string `mapstructure:"encryption_key"`
+
 	Local      *LocalStorageConfiguration      `mapstructure:"local"`
 	MySQL      *MySQLStorageConfiguration      `mapstructure:"mysql"`
 	PostgreSQL *PostgreSQLStorageConfiguration `mapstructure:"postgres"`
@@ -0,0 +1,18 @@
+package schema
+
+// WebauthnConfiguration represents the webauthn config.
+type WebauthnConfiguration struct {
+	DisplayName                     string `mapstructure:"display_name"`
+	Timeout                         string `mapstructure:"timeout"`
+	AttestationConveyancePreference string `mapstructure:"attestation_conveyance_preference"`
+	UserVerification                string `mapstructure:"user_verification"`
+	NotifyClonedDevices             bool   `mapstructure:"notify_cloned_devices"`
+}
+
+// DefaultWebauthnConfiguration describes the default values for the WebauthnConfiguration.
+var DefaultWebauthnConfiguration = WebauthnConfiguration{
+	DisplayName:                     "Authelia",
+	Timeout:                         "60s",
+	AttestationConveyancePreference: "indirect",
+	UserVerification:                "preferred",
+}
@@ -132,6 +132,14 @@ func validateLdapAuthenticationBackend(configuration *schema.LDAPAuthenticationB
 		validator.Push(fmt.Errorf("error occurred validating the LDAP minimum_tls_version key with value %s: %v", configuration.TLS.MinimumVersion, err))
 	}
 
+	if configuration.Timeout == "" {
+		configuration.Timeout = schema.DefaultLDAPAuthenticationBackendConfiguration.Timeout
+	} else if _, err := utils.ParseDurationString(configuration.Timeout); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing LDAP timeout string: %s", err))
+	}
+
+	validateLdapPool(configuration, validator)
+
 	switch configuration.Implementation {
 	case schema.LDAPImplementationCustom:
 		setDefaultImplementationCustomLdapAuthenticationBackend(configuration)
@@ -193,6 +201,35 @@ func validateLdapAuthenticationBackend(configuration *schema.LDAPAuthenticationB
 	}
 }
 
+func validateLdapPool(configuration *schema.LDAPAuthenticationBackendConfiguration, validator *schema.StructValidator) {
+	defaults := schema.DefaultLDAPAuthenticationBackendConfiguration.Pool
+
+	if configuration.Pool == nil {
+		pool := *defaults
+		configuration.Pool = &pool
+
+		return
+	}
+
+	if configuration.Pool.MaxConnections == 0 {
+		configuration.Pool.MaxConnections = defaults.MaxConnections
+	} else if configuration.Pool.MaxConnections < 0 {
+		validator.Push(fmt.Errorf("The LDAP pool max_connections must be a positive number but it is configured to %d", configuration.Pool.MaxConnections))
+	}
+
+	if configuration.Pool.IdleTimeout == "" {
+		configuration.Pool.IdleTimeout = defaults.IdleTimeout
+	} else if _, err := utils.ParseDurationString(configuration.Pool.IdleTimeout); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing LDAP pool idle_timeout string: %s", err))
+	}
+
+	if configuration.Pool.HealthCheckInterval == "" {
+		configuration.Pool.HealthCheckInterval = defaults.HealthCheckInterval
+	} else if _, err := utils.ParseDurationString(configuration.Pool.HealthCheckInterval); err != nil {
+		validator.Push(fmt.Errorf("Error occurred parsing LDAP pool health_check_interval string: %s", err))
+	}
+}
+
 func setDefaultImplementationActiveDirectoryLdapAuthenticationBackend(configuration *schema.LDAPAuthenticationBackendConfiguration) {
 	if configuration.UsersFilter == "" {
 		configuration.UsersFilter = schema.DefaultLDAPAuthenticationBackendImplementationActiveDirectoryConfiguration.UsersFilter
@@ -470,6 +470,63 @@ func (suite *LdapAuthenticationBackendSuite) TestShouldNotAllowInvalidTLSValue()
 	suite.Assert().EqualError(suite.validator.Errors()[0], "error occurred validating the LDAP minimum_tls_version key with value SSL2.0: supplied TLS version isn't supported")
 }
 
+func (suite *LdapAuthenticationBackendSuite) TestShouldSetDefaultPool() {
+	ValidateAuthenticationBackend(&suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+
+	suite.Require().NotNil(suite.configuration.Ldap.Pool)
+	suite.Assert().Equal(10, suite.configuration.Ldap.Pool.MaxConnections)
+	suite.Assert().Equal("5m", suite.configuration.Ldap.Pool.IdleTimeout)
+	suite.Assert().Equal("1m", suite.configuration.Ldap.Pool.HealthCheckInterval)
+	suite.Assert().Equal("5s", suite.configuration.Ldap.Timeout)
+}
+
+func (suite *LdapAuthenticationBackendSuite) TestShouldRaiseOnBadTimeout() {
+	suite.configuration.Ldap.Timeout = "5x"
+
+	ValidateAuthenticationBackend(&suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "Error occurred parsing LDAP timeout string: Could not convert the input string of 5x into a duration")
+}
+
+func (suite *LdapAuthenticationBackendSuite) TestShouldSetDefaultPoolValues() {
+	suite.configuration.Ldap.Pool = &schema.LDAPPoolConfiguration{
+		MaxConnections: 3,
+	}
+
+	ValidateAuthenticationBackend(&suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Assert().False(suite.validator.HasErrors())
+
+	suite.Assert().Equal(3, suite.configuration.Ldap.Pool.MaxConnections)
+	suite.Assert().Equal("5m", suite.configuration.Ldap.Pool.IdleTimeout)
+	suite.Assert().Equal("1m", suite.configuration.Ldap.Pool.HealthCheckInterval)
+	suite.Assert().Equal(10, schema.DefaultLDAPAuthenticationBackendConfiguration.Pool.MaxConnections)
+}
+
+func (suite *LdapAuthenticationBackendSuite) TestShouldRaiseOnBadPoolValues() {
+	suite.configuration.Ldap.Pool = &schema.LDAPPoolConfiguration{
+		MaxConnections:      -1,
+		IdleTimeout:         "blah",
+		HealthCheckInterval: "10x",
+	}
+
+	ValidateAuthenticationBackend(&suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 3)
+
+	suite.Assert().EqualError(suite.validator.Errors()[0], "The LDAP pool max_connections must be a positive number but it is configured to -1")
+	suite.Assert().EqualError(suite.validator.Errors()[1], "Error occurred parsing LDAP pool idle_timeout string: Could not convert the input string of blah into a duration")
+	suite.Assert().EqualError(suite.validator.Errors()[2], "Error occurred parsing LDAP pool health_check_interval string: Could not convert the input string of 10x into a duration")
+}
+
 // Deprecated: Temporary Test. TODO: Remove in 4.28 (Whole Test).
 func (suite *LdapAuthenticationBackendSuite) TestShouldReturnDeprecationWarningsAndNoMappingFor428() {
 	var skipVerify = true
@@ -64,6 +64,12 @@ func ValidateConfiguration(configuration *schema.Configuration, validator *schem
 
 	ValidateTOTP(configuration.TOTP, validator)
 
+	if configuration.Webauthn == nil {
+		configuration.Webauthn = &schema.DefaultWebauthnConfiguration
+	}
+
+	ValidateWebauthn(configuration.Webauthn, validator)
+
 	ValidateAuthenticationBackend(&configuration.AuthenticationBackend, validator)
 
 	if configuration.AccessControl.DefaultPolicy == "" {
@@ -84,10 +90,14 @@ func ValidateConfiguration(configuration *schema.Configuration, validator *schem
 
 	ValidateRegulation(configuration.Regulation, validator)
 
+	ValidatePasswordPolicy(&configuration.PasswordPolicy, validator)
+
 	ValidateServer(&configuration.Server, validator)
 
 	ValidateStorage(configuration.Storage, validator)
 
+	ValidateIdentityProviders(&configuration.IdentityProviders, validator)
+
 	if configuration.Notifier == nil {
 		validator.Push(fmt.Errorf("A notifier configuration must be provided"))
 	} else {
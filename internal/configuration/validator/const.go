@@ -10,16 +10,38 @@ const (
 	errFilePHashing = "config key incorrect: authentication_backend.file.password_hashing should be authentication_backend.file.password"
 	errFilePOptions = "config key incorrect: authentication_backend.file.password_options should be authentication_backend.file.password"
 
-	denyPolicy   = "deny"
-	bypassPolicy = "bypass"
+	errFmtOIDCNoPrivateKey                  = "OIDC: issuer_private_key must be provided"
+	errFmtOIDCInvalidPrivateKey             = "OIDC: issuer_private_key is invalid: %v"
+	errFmtOIDCInvalidLifespan               = "OIDC: %s is invalid: %v"
+	errFmtOIDCNoClientsConfigured           = "OIDC: at least one client must be configured"
+	errFmtOIDCClientsWithEmptyID            = "OIDC: one or more clients have been configured with an empty id"
+	errFmtOIDCClientsDuplicateID            = "OIDC: client with id '%s' has been configured more than once"
+	errFmtOIDCClientInvalidSecret           = "OIDC: client with id '%s' must have a secret unless it is public"
+	errFmtOIDCClientPublicInvalidSecret     = "OIDC: client with id '%s' is public and must not have a secret"
+	errFmtOIDCClientInvalidPolicy           = "OIDC: client with id '%s' has an invalid authorization_policy '%s', it must be either 'one_factor' or 'two_factor'"
+	errFmtOIDCClientInvalidListEntry        = "OIDC: client with id '%s' has an invalid %s entry '%s', it must be one of %v"
+	errFmtOIDCClientMissingOpenIDScope      = "OIDC: client with id '%s' must have the 'openid' scope"
+	errFmtOIDCClientNoRedirectURIs          = "OIDC: client with id '%s' must have at least one redirect_uris entry"
+	errFmtOIDCClientRedirectURICantBeParsed = "OIDC: client with id '%s' has a redirect uri '%s' which could not be parsed: %v"
+	errFmtOIDCClientRedirectURINotAbsolute  = "OIDC: client with id '%s' has a redirect uri '%s' which is not absolute"
+	errFmtOIDCClientRedirectURIFragment     = "OIDC: client with id '%s' has a redirect uri '%s' which must not contain a fragment"
+	errFmtOIDCClientRedirectURIInsecure     = "OIDC: client with id '%s' has a redirect uri '%s' which must use the https scheme (http is only allowed for loopback addresses of public clients)"
+
+	denyPolicy      = "deny"
+	bypassPolicy    = "bypass"
+	policyOneFactor = "one_factor"
+	policyTwoFactor = "two_factor"
 
 	argon2id = "argon2id"
 	sha512   = "sha512"
 
 	schemeLDAP  = "ldap"
 	schemeLDAPS = "ldaps"
+	schemeHTTP  = "http"
+	schemeHTTPS = "https"
 
 	testBadTimer      = "-1"
+	testEncryptionKey = "a_not_so_secure_encryption_key"
 	testInvalidPolicy = "invalid"
 	testJWTSecret     = "a_secret"
 	testLDAPBaseDN    = "base_dn"
@@ -35,8 +57,15 @@ const (
 		"https://www.authelia.com/docs/configuration/access-control.html#combining-subjects-and-the-bypass-policy"
 )
 
+// storageEncryptionKeyMinLength is the minimum length of the key the storage encrypts sensitive data with.
+const storageEncryptionKeyMinLength = 20
+
 var validRequestMethods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "TRACE", "CONNECT", "OPTIONS"}
 
+var validOIDCScopes = []string{"openid", "email", "profile", "groups", "offline_access"}
+var validOIDCGrantTypes = []string{"authorization_code", "refresh_token"}
+var validOIDCResponseTypes = []string{"code"}
+
 // SecretNames contains a map of secret names.
 var SecretNames = map[string]string{
 	"JWTSecret":             "jwt_secret",
@@ -48,6 +77,8 @@ var SecretNames = map[string]string{
 	"SMTPPassword":          "notifier.smtp.password",
 	"MySQLPassword":         "storage.mysql.password",
 	"PostgreSQLPassword":    "storage.postgres.password",
+	"StorageEncryptionKey":  "storage.encryption_key",
+	"OIDCIssuerPrivateKey":  "identity_providers.oidc.issuer_private_key",
 }
 
 // validKeys is a list of valid keys that are not secret names. For the sake of consistency please place any secret in
@@ -65,16 +96,34 @@ var validKeys = []string{
 	"tls_cert",
 	"certificates_directory",
 
+	// Audit Log Keys.
+	"audit_log.enabled",
+	"audit_log.file_path",
+
 	// Server Keys.
 	"server.read_buffer_size",
 	"server.write_buffer_size",
 	"server.path",
+	"server.metrics.enabled",
+	"server.metrics.address",
+	"server.admin.enabled",
+	"server.admin.group",
+	"server.admin.address",
 
 	// TOTP Keys.
 	"totp.issuer",
+	"totp.algorithm",
+	"totp.digits",
 	"totp.period",
 	"totp.skew",
 
+	// Webauthn Keys.
+	"webauthn.display_name",
+	"webauthn.timeout",
+	"webauthn.attestation_conveyance_preference",
+	"webauthn.user_verification",
+	"webauthn.notify_cloned_devices",
+
 	// Access Control Keys.
 	"access_control.rules",
 	"access_control.default_policy",
@@ -86,6 +135,8 @@ var validKeys = []string{
 	"session.inactivity",
 	"session.remember_me_duration",
 	"session.domain",
+	"session.portal_url",
+	"session.cookies",
 
 	// Redis Session Keys.
 	"session.redis.host",
@@ -107,6 +158,9 @@ var validKeys = []string{
 	"session.redis.timeouts.read",
 	"session.redis.timeouts.write",
 
+	// Storage Keys.
+	"storage.disable_automatic_migration",
+
 	// Local Storage Keys.
 	"storage.local.path",
 
@@ -148,6 +202,15 @@ var validKeys = []string{
 	"regulation.find_time",
 	"regulation.ban_time",
 
+	// Password Policy Keys.
+	"password_policy.min_length",
+	"password_policy.max_length",
+	"password_policy.require_uppercase",
+	"password_policy.require_lowercase",
+	"password_policy.require_number",
+	"password_policy.require_special",
+	"password_policy.min_score",
+
 	// DUO API Keys.
 	"duo_api.hostname",
 	"duo_api.integration_key",
@@ -170,14 +233,26 @@ var validKeys = []string{
 	"authentication_backend.ldap.display_name_attribute",
 	"authentication_backend.ldap.user",
 	"authentication_backend.ldap.start_tls",
+	"authentication_backend.ldap.timeout",
 	"authentication_backend.ldap.tls.minimum_version",
 	"authentication_backend.ldap.tls.skip_verify",
 	"authentication_backend.ldap.tls.server_name",
+	"authentication_backend.ldap.pool.max_connections",
+	"authentication_backend.ldap.pool.idle_timeout",
+	"authentication_backend.ldap.pool.health_check_interval",
 	"authentication_backend.ldap.skip_verify",         // TODO: Deprecated: Remove in 4.28.
 	"authentication_backend.ldap.minimum_tls_version", // TODO: Deprecated: Remove in 4.28.
 
+	// OpenID Connect Identity Provider Keys.
+	"identity_providers.oidc.access_token_lifespan",
+	"identity_providers.oidc.authorize_code_lifespan",
+	"identity_providers.oidc.id_token_lifespan",
+	"identity_providers.oidc.refresh_token_lifespan",
+	"identity_providers.oidc.clients",
+
 	// File Authentication Backend Keys.
 	"authentication_backend.file.path",
+	"authentication_backend.file.watch",
 	"authentication_backend.file.password.algorithm",
 	"authentication_backend.file.password.iterations",
 	"authentication_backend.file.password.key_length",
@@ -0,0 +1,174 @@
+package validator
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// ValidateIdentityProviders validates and update IdentityProviders configuration.
+func ValidateIdentityProviders(configuration *schema.IdentityProvidersConfiguration, validator *schema.StructValidator) {
+	if configuration.OIDC != nil {
+		validateOIDC(configuration.OIDC, validator)
+	}
+}
+
+func validateOIDC(configuration *schema.OpenIDConnectConfiguration, validator *schema.StructValidator) {
+	if configuration.IssuerPrivateKey == "" {
+		validator.Push(fmt.Errorf(errFmtOIDCNoPrivateKey))
+	} else if err := validateOIDCIssuerPrivateKey(configuration.IssuerPrivateKey); err != nil {
+		validator.Push(fmt.Errorf(errFmtOIDCInvalidPrivateKey, err))
+	}
+
+	if configuration.AccessTokenLifespan == "" {
+		configuration.AccessTokenLifespan = schema.DefaultOpenIDConnectConfiguration.AccessTokenLifespan
+	}
+
+	if configuration.AuthorizeCodeLifespan == "" {
+		configuration.AuthorizeCodeLifespan = schema.DefaultOpenIDConnectConfiguration.AuthorizeCodeLifespan
+	}
+
+	if configuration.IDTokenLifespan == "" {
+		configuration.IDTokenLifespan = schema.DefaultOpenIDConnectConfiguration.IDTokenLifespan
+	}
+
+	if configuration.RefreshTokenLifespan == "" {
+		configuration.RefreshTokenLifespan = schema.DefaultOpenIDConnectConfiguration.RefreshTokenLifespan
+	}
+
+	for name, value := range map[string]string{
+		"access_token_lifespan":   configuration.AccessTokenLifespan,
+		"authorize_code_lifespan": configuration.AuthorizeCodeLifespan,
+		"id_token_lifespan":       configuration.IDTokenLifespan,
+		"refresh_token_lifespan":  configuration.RefreshTokenLifespan,
+	} {
+		if _, err := utils.ParseDurationString(value); err != nil {
+			validator.Push(fmt.Errorf(errFmtOIDCInvalidLifespan, name, err))
+		}
+	}
+
+	validateOIDCClients(configuration, validator)
+
+	if len(configuration.Clients) == 0 {
+		validator.Push(fmt.Errorf(errFmtOIDCNoClientsConfigured))
+	}
+}
+
+func validateOIDCIssuerPrivateKey(key string) error {
+	block, _ := pem.Decode([]byte(key))
+	if block == nil {
+		return fmt.Errorf("the key is not PEM encoded")
+	}
+
+	if _, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return nil
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := privateKey.(*rsa.PrivateKey); !ok {
+		return fmt.Errorf("the key is not an RSA private key")
+	}
+
+	return nil
+}
+
+func validateOIDCClients(configuration *schema.OpenIDConnectConfiguration, validator *schema.StructValidator) {
+	var ids []string
+
+	for c, client := range configuration.Clients {
+		if client.ID == "" {
+			validator.Push(fmt.Errorf(errFmtOIDCClientsWithEmptyID))
+		} else if utils.IsStringInSlice(client.ID, ids) {
+			validator.Push(fmt.Errorf(errFmtOIDCClientsDuplicateID, client.ID))
+		} else {
+			ids = append(ids, client.ID)
+		}
+
+		if client.Public {
+			if client.Secret != "" {
+				validator.Push(fmt.Errorf(errFmtOIDCClientPublicInvalidSecret, client.ID))
+			}
+		} else if client.Secret == "" {
+			validator.Push(fmt.Errorf(errFmtOIDCClientInvalidSecret, client.ID))
+		}
+
+		if client.Description == "" {
+			configuration.Clients[c].Description = client.ID
+		}
+
+		if client.Policy == "" {
+			configuration.Clients[c].Policy = schema.DefaultOpenIDConnectClientConfiguration.Policy
+		} else if client.Policy != policyOneFactor && client.Policy != policyTwoFactor {
+			validator.Push(fmt.Errorf(errFmtOIDCClientInvalidPolicy, client.ID, client.Policy))
+		}
+
+		validateOIDCClientRedirectURIs(client, validator)
+
+		configuration.Clients[c].Scopes = validateOIDCClientList(client.ID, "scopes", client.Scopes,
+			schema.DefaultOpenIDConnectClientConfiguration.Scopes, validOIDCScopes, validator)
+		configuration.Clients[c].GrantTypes = validateOIDCClientList(client.ID, "grant_types", client.GrantTypes,
+			schema.DefaultOpenIDConnectClientConfiguration.GrantTypes, validOIDCGrantTypes, validator)
+		configuration.Clients[c].ResponseTypes = validateOIDCClientList(client.ID, "response_types", client.ResponseTypes,
+			schema.DefaultOpenIDConnectClientConfiguration.ResponseTypes, validOIDCResponseTypes, validator)
+
+		if !utils.IsStringInSlice("openid", configuration.Clients[c].Scopes) {
+			validator.Push(fmt.Errorf(errFmtOIDCClientMissingOpenIDScope, client.ID))
+		}
+	}
+}
+
+func validateOIDCClientList(id, name string, values, defaults, valid []string, validator *schema.StructValidator) []string {
+	if len(values) == 0 {
+		return append([]string{}, defaults...)
+	}
+
+	for _, value := range values {
+		if !utils.IsStringInSlice(value, valid) {
+			validator.Push(fmt.Errorf(errFmtOIDCClientInvalidListEntry, id, name, value, valid))
+		}
+	}
+
+	return values
+}
+
+func validateOIDCClientRedirectURIs(client schema.OpenIDConnectClientConfiguration, validator *schema.StructValidator) {
+	if len(client.RedirectURIs) == 0 {
+		validator.Push(fmt.Errorf(errFmtOIDCClientNoRedirectURIs, client.ID))
+		return
+	}
+
+	for _, redirectURI := range client.RedirectURIs {
+		parsedURI, err := url.Parse(redirectURI)
+		if err != nil {
+			validator.Push(fmt.Errorf(errFmtOIDCClientRedirectURICantBeParsed, client.ID, redirectURI, err))
+			continue
+		}
+
+		if !parsedURI.IsAbs() || parsedURI.Host == "" {
+			validator.Push(fmt.Errorf(errFmtOIDCClientRedirectURINotAbsolute, client.ID, redirectURI))
+			continue
+		}
+
+		if parsedURI.Fragment != "" {
+			validator.Push(fmt.Errorf(errFmtOIDCClientRedirectURIFragment, client.ID, redirectURI))
+			continue
+		}
+
+		if parsedURI.Scheme != schemeHTTPS && !(client.Public && parsedURI.Scheme == schemeHTTP && isLoopbackHost(parsedURI.Hostname())) {
+			validator.Push(fmt.Errorf(errFmtOIDCClientRedirectURIInsecure, client.ID, redirectURI))
+		}
+	}
+}
+
+func isLoopbackHost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
@@ -0,0 +1,174 @@
+package validator
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func newTestOIDCPrivateKey(t *testing.T) string {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+}
+
+func TestShouldNotRaiseErrorWhenOIDCNotConfigured(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.IdentityProvidersConfiguration{}
+
+	ValidateIdentityProviders(&config, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+}
+
+func TestShouldSetDefaultOIDCValues(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.IdentityProvidersConfiguration{
+		OIDC: &schema.OpenIDConnectConfiguration{
+			IssuerPrivateKey: newTestOIDCPrivateKey(t),
+			Clients: []schema.OpenIDConnectClientConfiguration{
+				{
+					ID:           "myapp",
+					Secret:       "secret",
+					RedirectURIs: []string{"https://app.example.com/oauth2/callback"},
+				},
+			},
+		},
+	}
+
+	ValidateIdentityProviders(&config, validator)
+
+	require.Len(t, validator.Errors(), 0)
+	assert.Equal(t, "1h", config.OIDC.AccessTokenLifespan)
+	assert.Equal(t, "1m", config.OIDC.AuthorizeCodeLifespan)
+	assert.Equal(t, "1h", config.OIDC.IDTokenLifespan)
+	assert.Equal(t, "90m", config.OIDC.RefreshTokenLifespan)
+
+	client := config.OIDC.Clients[0]
+	assert.Equal(t, "myapp", client.Description)
+	assert.Equal(t, "two_factor", client.Policy)
+	assert.Equal(t, []string{"openid", "groups", "profile", "email"}, client.Scopes)
+	assert.Equal(t, []string{"refresh_token", "authorization_code"}, client.GrantTypes)
+	assert.Equal(t, []string{"code"}, client.ResponseTypes)
+}
+
+func TestShouldRaiseErrorWhenOIDCIssuerPrivateKeyIsMissingOrInvalid(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.IdentityProvidersConfiguration{
+		OIDC: &schema.OpenIDConnectConfiguration{},
+	}
+
+	ValidateIdentityProviders(&config, validator)
+
+	require.Len(t, validator.Errors(), 2)
+	assert.EqualError(t, validator.Errors()[0], "OIDC: issuer_private_key must be provided")
+	assert.EqualError(t, validator.Errors()[1], "OIDC: at least one client must be configured")
+
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	ecdsaKeyBytes, err := x509.MarshalPKCS8PrivateKey(ecdsaKey)
+	require.NoError(t, err)
+
+	validator = schema.NewStructValidator()
+	config.OIDC.IssuerPrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecdsaKeyBytes}))
+
+	ValidateIdentityProviders(&config, validator)
+
+	require.Len(t, validator.Errors(), 2)
+	assert.EqualError(t, validator.Errors()[0], "OIDC: issuer_private_key is invalid: the key is not an RSA private key")
+
+	validator = schema.NewStructValidator()
+	config.OIDC.IssuerPrivateKey = "abc"
+
+	ValidateIdentityProviders(&config, validator)
+
+	require.Len(t, validator.Errors(), 2)
+	assert.EqualError(t, validator.Errors()[0], "OIDC: issuer_private_key is invalid: the key is not PEM encoded")
+}
+
+func TestShouldRaiseErrorWhenOIDCLifespanIsInvalid(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.IdentityProvidersConfiguration{
+		OIDC: &schema.OpenIDConnectConfiguration{
+			IssuerPrivateKey:    newTestOIDCPrivateKey(t),
+			AccessTokenLifespan: "one hour",
+			Clients: []schema.OpenIDConnectClientConfiguration{
+				{
+					ID:           "myapp",
+					Secret:       "secret",
+					RedirectURIs: []string{"https://app.example.com/oauth2/callback"},
+				},
+			},
+		},
+	}
+
+	ValidateIdentityProviders(&config, validator)
+
+	require.Len(t, validator.Errors(), 1)
+	assert.Contains(t, validator.Errors()[0].Error(), "OIDC: access_token_lifespan is invalid: ")
+}
+
+func TestShouldRaiseErrorWhenOIDCClientsAreInvalid(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.IdentityProvidersConfiguration{
+		OIDC: &schema.OpenIDConnectConfiguration{
+			IssuerPrivateKey: newTestOIDCPrivateKey(t),
+			Clients: []schema.OpenIDConnectClientConfiguration{
+				{
+					ID:           "",
+					Secret:       "secret",
+					RedirectURIs: []string{"https://app.example.com/oauth2/callback"},
+				},
+				{
+					ID:           "myapp",
+					Policy:       "deny",
+					RedirectURIs: []string{"http://app.example.com/oauth2/callback"},
+				},
+				{
+					ID:           "myapp",
+					Secret:       "secret",
+					Scopes:       []string{"groups", "unknown"},
+					RedirectURIs: []string{"/oauth2/callback"},
+				},
+				{
+					ID:           "public",
+					Public:       true,
+					Secret:       "secret",
+					GrantTypes:   []string{"implicit"},
+					RedirectURIs: []string{"http://localhost:8080/callback", "https://app.example.com/#fragment"},
+				},
+				{
+					ID:     "noredirect",
+					Secret: "secret",
+				},
+			},
+		},
+	}
+
+	ValidateIdentityProviders(&config, validator)
+
+	require.Len(t, validator.Errors(), 12)
+	assert.EqualError(t, validator.Errors()[0], "OIDC: one or more clients have been configured with an empty id")
+	assert.EqualError(t, validator.Errors()[1], "OIDC: client with id 'myapp' must have a secret unless it is public")
+	assert.EqualError(t, validator.Errors()[2], "OIDC: client with id 'myapp' has an invalid authorization_policy 'deny', it must be either 'one_factor' or 'two_factor'")
+	assert.EqualError(t, validator.Errors()[3], "OIDC: client with id 'myapp' has a redirect uri 'http://app.example.com/oauth2/callback' which must use the https scheme (http is only allowed for loopback addresses of public clients)")
+	assert.EqualError(t, validator.Errors()[4], "OIDC: client with id 'myapp' has been configured more than once")
+	assert.EqualError(t, validator.Errors()[5], "OIDC: client with id 'myapp' has a redirect uri '/oauth2/callback' which is not absolute")
+	assert.EqualError(t, validator.Errors()[6], "OIDC: client with id 'myapp' has an invalid scopes entry 'unknown', it must be one of [openid email profile groups offline_access]")
+	assert.EqualError(t, validator.Errors()[7], "OIDC: client with id 'myapp' must have the 'openid' scope")
+	assert.EqualError(t, validator.Errors()[8], "OIDC: client with id 'public' is public and must not have a secret")
+	assert.EqualError(t, validator.Errors()[9], "OIDC: client with id 'public' has a redirect uri 'https://app.example.com/#fragment' which must not contain a fragment")
+	assert.EqualError(t, validator.Errors()[10], "OIDC: client with id 'public' has an invalid grant_types entry 'implicit', it must be one of [authorization_code refresh_token]")
+	assert.EqualError(t, validator.Errors()[11], "OIDC: client with id 'noredirect' must have at least one redirect_uris entry")
+}
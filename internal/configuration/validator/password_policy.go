@@ -0,0 +1,24 @@
+package validator
+
+import (
+	"fmt"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+// ValidatePasswordPolicy validates the password policy configuration.
+func ValidatePasswordPolicy(configuration *schema.PasswordPolicyConfiguration, validator *schema.StructValidator) {
+	if configuration.MinLength < 0 {
+		validator.Push(fmt.Errorf("The password policy min_length must be 0 or more"))
+	}
+
+	if configuration.MaxLength < 0 {
+		validator.Push(fmt.Errorf("The password policy max_length must be 0 or more"))
+	} else if configuration.MaxLength != 0 && configuration.MaxLength < configuration.MinLength {
+		validator.Push(fmt.Errorf("The password policy max_length must be greater than or equal to min_length"))
+	}
+
+	if configuration.MinScore < 0 || configuration.MinScore > 4 {
+		validator.Push(fmt.Errorf("The password policy min_score must be between 0 and 4 but it is configured as %d", configuration.MinScore))
+	}
+}
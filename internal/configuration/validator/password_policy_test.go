@@ -0,0 +1,73 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldAcceptDefaultPasswordPolicy(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.DefaultPasswordPolicyConfiguration
+
+	ValidatePasswordPolicy(&config, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+}
+
+func TestShouldAcceptPasswordPolicy(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.PasswordPolicyConfiguration{
+		MinLength:        12,
+		MaxLength:        64,
+		RequireUppercase: true,
+		RequireNumber:    true,
+		MinScore:         3,
+	}
+
+	ValidatePasswordPolicy(&config, validator)
+
+	assert.Len(t, validator.Errors(), 0)
+}
+
+func TestShouldRaiseErrorWhenPasswordPolicyLengthsAreInvalid(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.PasswordPolicyConfiguration{
+		MinLength: -1,
+		MaxLength: -1,
+	}
+
+	ValidatePasswordPolicy(&config, validator)
+
+	require.Len(t, validator.Errors(), 2)
+	assert.EqualError(t, validator.Errors()[0], "The password policy min_length must be 0 or more")
+	assert.EqualError(t, validator.Errors()[1], "The password policy max_length must be 0 or more")
+}
+
+func TestShouldRaiseErrorWhenPasswordPolicyMaxLengthIsLowerThanMinLength(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.PasswordPolicyConfiguration{
+		MinLength: 12,
+		MaxLength: 8,
+	}
+
+	ValidatePasswordPolicy(&config, validator)
+
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The password policy max_length must be greater than or equal to min_length")
+}
+
+func TestShouldRaiseErrorWhenPasswordPolicyMinScoreIsInvalid(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.PasswordPolicyConfiguration{
+		MinScore: 5,
+	}
+
+	ValidatePasswordPolicy(&config, validator)
+
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "The password policy min_score must be between 0 and 4 but it is configured as 5")
+}
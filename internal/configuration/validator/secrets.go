@@ -51,6 +51,8 @@ func ValidateSecrets(configuration *schema.Configuration, validator *schema.Stru
 		configuration.Notifier.SMTP.Password = getSecretValue(SecretNames["SMTPPassword"], validator, viper)
 	}
 
+	configuration.Storage.EncryptionKey = getSecretValue(SecretNames["StorageEncryptionKey"], validator, viper)
+
 	if configuration.Storage.MySQL != nil {
 		configuration.Storage.MySQL.Password = getSecretValue(SecretNames["MySQLPassword"], validator, viper)
 	}
@@ -58,6 +60,10 @@ func ValidateSecrets(configuration *schema.Configuration, validator *schema.Stru
 	if configuration.Storage.PostgreSQL != nil {
 		configuration.Storage.PostgreSQL.Password = getSecretValue(SecretNames["PostgreSQLPassword"], validator, viper)
 	}
+
+	if configuration.IdentityProviders.OIDC != nil {
+		configuration.IdentityProviders.OIDC.IssuerPrivateKey = getSecretValue(SecretNames["OIDCIssuerPrivateKey"], validator, viper)
+	}
 }
 
 func getSecretValue(name string, validator *schema.StructValidator, viper *viper.Viper) string {
@@ -75,6 +81,11 @@ func getSecretValue(name string, validator *schema.StructValidator, viper *viper
 		if err != nil {
 			validator.Push(fmt.Errorf("error loading secret file (%s): %s", name, err))
 		} else {
+			// Multi-line secrets such as PEM encoded keys must keep their inner line breaks.
+			if strings.Contains(name, "private_key") {
+				return strings.TrimRight(string(content), "\n")
+			}
+
 			return strings.ReplaceAll(string(content), "\n", "")
 		}
 	}
@@ -2,6 +2,7 @@ package validator
 
 import (
 	"fmt"
+	"net"
 	"path"
 	"strings"
 
@@ -35,4 +36,20 @@ func ValidateServer(configuration *schema.ServerConfiguration, validator *schema
 	} else if configuration.WriteBufferSize < 0 {
 		validator.Push(fmt.Errorf("server write buffer size must be above 0"))
 	}
+
+	if configuration.Metrics.Address != "" {
+		if _, _, err := net.SplitHostPort(configuration.Metrics.Address); err != nil {
+			validator.Push(fmt.Errorf("server metrics address must be in the host:port format: %s", err))
+		}
+	}
+
+	if configuration.Admin.Enabled && configuration.Admin.Group == "" {
+		validator.Push(fmt.Errorf("server admin group must be provided when the admin API is enabled"))
+	}
+
+	if configuration.Admin.Address != "" {
+		if _, _, err := net.SplitHostPort(configuration.Admin.Address); err != nil {
+			validator.Push(fmt.Errorf("server admin address must be in the host:port format: %s", err))
+		}
+	}
 }
@@ -61,3 +61,42 @@ func TestShouldRaiseOnForwardSlashInPath(t *testing.T) {
 	assert.Len(t, validator.Errors(), 1)
 	assert.Error(t, validator.Errors()[0], "server path must not contain any forward slashes")
 }
+
+func TestShouldAcceptMetricsAddress(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		Metrics: schema.ServerMetricsConfiguration{
+			Enabled: true,
+			Address: "127.0.0.1:9959",
+		},
+	}
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 0)
+}
+
+func TestShouldRaiseOnInvalidMetricsAddress(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		Metrics: schema.ServerMetricsConfiguration{
+			Enabled: true,
+			Address: "127.0.0.1",
+		},
+	}
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 1)
+	assert.EqualError(t, validator.Errors()[0], "server metrics address must be in the host:port format: address 127.0.0.1: missing port in address")
+}
+
+func TestShouldRaiseWhenAdminAPIHasNoGroup(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.ServerConfiguration{
+		Admin: schema.ServerAdminConfiguration{
+			Enabled: true,
+			Address: "127.0.0.1",
+		},
+	}
+	ValidateServer(&config, validator)
+	require.Len(t, validator.Errors(), 2)
+	assert.EqualError(t, validator.Errors()[0], "server admin group must be provided when the admin API is enabled")
+	assert.EqualError(t, validator.Errors()[1], "server admin address must be in the host:port format: address 127.0.0.1: missing port in address")
+}
@@ -3,6 +3,7 @@ package validator
 import (
 	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
@@ -56,6 +57,52 @@ func validateSession(configuration *schema.SessionConfiguration, validator *sche
 	if strings.Contains(configuration.Domain, "*") {
 		validator.Push(errors.New("The domain of the session must be the root domain you're protecting instead of a wildcard domain"))
 	}
+
+	validateSessionPortalURL(configuration.PortalURL, configuration.Domain, validator)
+	validateSessionCookies(configuration, validator)
+}
+
+func validateSessionCookies(configuration *schema.SessionConfiguration, validator *schema.StructValidator) {
+	domains := []string{configuration.Domain}
+
+	for _, cookie := range configuration.Cookies {
+		switch {
+		case cookie.Domain == "":
+			validator.Push(errors.New("Set domain of each session cookie"))
+			continue
+		case strings.Contains(cookie.Domain, "*"):
+			validator.Push(fmt.Errorf("The domain %s of the session cookie must be the root domain you're protecting instead of a wildcard domain", cookie.Domain))
+		case utils.IsStringInSlice(cookie.Domain, domains):
+			validator.Push(fmt.Errorf("The domain %s is protected by several session cookies", cookie.Domain))
+		}
+
+		domains = append(domains, cookie.Domain)
+
+		if cookie.Expiration != "" {
+			if _, err := utils.ParseDurationString(cookie.Expiration); err != nil {
+				validator.Push(fmt.Errorf("Error occurred parsing expiration string of the session cookie of domain %s: %s", cookie.Domain, err))
+			}
+		}
+
+		validateSessionPortalURL(cookie.PortalURL, cookie.Domain, validator)
+	}
+}
+
+func validateSessionPortalURL(portalURL, domain string, validator *schema.StructValidator) {
+	if portalURL == "" {
+		return
+	}
+
+	parsedURL, err := url.Parse(portalURL)
+
+	switch {
+	case err != nil:
+		validator.Push(fmt.Errorf("Error occurred parsing the portal URL of domain %s: %s", domain, err))
+	case parsedURL.Scheme != "https":
+		validator.Push(fmt.Errorf("The portal URL %s of domain %s must use the https scheme", portalURL, domain))
+	case !strings.HasSuffix(parsedURL.Hostname(), domain):
+		validator.Push(fmt.Errorf("The portal URL %s must be under the domain %s", portalURL, domain))
+	}
 }
 
 func validateRedis(configuration *schema.SessionConfiguration, validator *schema.StructValidator) {
@@ -417,3 +417,54 @@ func TestShouldSetDefaultRememberMeDuration(t *testing.T) {
 	assert.False(t, validator.HasErrors())
 	assert.Equal(t, config.RememberMeDuration, schema.DefaultSessionConfiguration.RememberMeDuration)
 }
+
+func TestShouldValidateSessionCookies(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.PortalURL = "https://login.example.com"
+	config.Cookies = []schema.SessionCookieConfiguration{
+		{Domain: "example.org", Name: "authelia_org", Expiration: "2h", PortalURL: "https://login.example.org/"},
+		{Domain: "example.net"},
+	}
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	assert.False(t, validator.HasErrors())
+}
+
+func TestShouldRaiseErrorsWhenSessionCookiesAreInvalid(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.Cookies = []schema.SessionCookieConfiguration{
+		{Domain: ""},
+		{Domain: "*.example.org"},
+		{Domain: "example.com"},
+		{Domain: "example.net", Expiration: "1 year"},
+	}
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	require.Len(t, validator.Errors(), 4)
+	assert.EqualError(t, validator.Errors()[0], "Set domain of each session cookie")
+	assert.EqualError(t, validator.Errors()[1], "The domain *.example.org of the session cookie must be the root domain you're protecting instead of a wildcard domain")
+	assert.EqualError(t, validator.Errors()[2], "The domain example.com is protected by several session cookies")
+	assert.EqualError(t, validator.Errors()[3], "Error occurred parsing expiration string of the session cookie of domain example.net: Could not convert the input string of 1 year into a duration")
+}
+
+func TestShouldRaiseErrorsWhenSessionPortalURLIsInvalid(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := newDefaultSessionConfig()
+	config.PortalURL = "http://login.example.com"
+	config.Cookies = []schema.SessionCookieConfiguration{
+		{Domain: "example.org", PortalURL: "https://login.example.com"},
+	}
+
+	ValidateSession(&config, validator)
+
+	assert.False(t, validator.HasWarnings())
+	require.Len(t, validator.Errors(), 2)
+	assert.EqualError(t, validator.Errors()[0], "The portal URL http://login.example.com of domain example.com must use the https scheme")
+	assert.EqualError(t, validator.Errors()[1], "The portal URL https://login.example.com must be under the domain example.org")
+}
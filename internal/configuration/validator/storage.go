@@ -2,6 +2,7 @@ package validator
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 )
@@ -12,6 +13,13 @@ func ValidateStorage(configuration schema.StorageConfiguration, validator *schem
 		validator.Push(errors.New("A storage configuration must be provided. It could be 'local', 'mysql' or 'postgres'"))
 	}
 
+	switch {
+	case configuration.EncryptionKey == "":
+		validator.Push(errors.New("the storage encryption_key must be provided"))
+	case len(configuration.EncryptionKey) < storageEncryptionKeyMinLength:
+		validator.Push(fmt.Errorf("the storage encryption_key must be at least %d characters long", storageEncryptionKeyMinLength))
+	}
+
 	switch {
 	case configuration.MySQL != nil:
 		validateSQLConfiguration(&configuration.MySQL.SQLStorageConfiguration, validator)
@@ -16,6 +16,7 @@ type StorageSuite struct {
 
 func (suite *StorageSuite) SetupTest() {
 	suite.validator = schema.NewStructValidator()
+	suite.configuration.EncryptionKey = testEncryptionKey
 	suite.configuration.Local = &schema.LocalStorageConfiguration{
 		Path: "/this/is/a/path",
 	}
@@ -106,6 +107,26 @@ func (suite *StorageSuite) TestShouldValidatePostgresSSLModeMustBeValid() {
 	suite.Assert().EqualError(suite.validator.Errors()[0], "SSL mode must be 'disable', 'require', 'verify-ca', or 'verify-full'")
 }
 
+func (suite *StorageSuite) TestShouldValidateEncryptionKeyIsProvided() {
+	suite.configuration.EncryptionKey = ""
+
+	ValidateStorage(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+	suite.Assert().EqualError(suite.validator.Errors()[0], "the storage encryption_key must be provided")
+}
+
+func (suite *StorageSuite) TestShouldValidateEncryptionKeyIsLongEnough() {
+	suite.configuration.EncryptionKey = "too_short"
+
+	ValidateStorage(suite.configuration, suite.validator)
+
+	suite.Assert().False(suite.validator.HasWarnings())
+	suite.Require().Len(suite.validator.Errors(), 1)
+	suite.Assert().EqualError(suite.validator.Errors()[0], "the storage encryption_key must be at least 20 characters long")
+}
+
 func TestShouldRunStorageSuite(t *testing.T) {
 	suite.Run(t, new(StorageSuite))
 }
@@ -2,6 +2,7 @@ package validator
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
 )
@@ -12,6 +13,24 @@ func ValidateTOTP(configuration *schema.TOTPConfiguration, validator *schema.Str
 		configuration.Issuer = schema.DefaultTOTPConfiguration.Issuer
 	}
 
+	configuration.Algorithm = strings.ToUpper(configuration.Algorithm)
+
+	switch configuration.Algorithm {
+	case "":
+		configuration.Algorithm = schema.DefaultTOTPConfiguration.Algorithm
+	case "SHA1", "SHA256", "SHA512":
+	default:
+		validator.Push(fmt.Errorf("TOTP Algorithm must be one of 'SHA1', 'SHA256' or 'SHA512' but it is configured as '%s'", configuration.Algorithm))
+	}
+
+	switch configuration.Digits {
+	case 0:
+		configuration.Digits = schema.DefaultTOTPConfiguration.Digits
+	case 6, 8:
+	default:
+		validator.Push(fmt.Errorf("TOTP Digits must be 6 or 8 but it is configured as %d", configuration.Digits))
+	}
+
 	if configuration.Period == 0 {
 		configuration.Period = schema.DefaultTOTPConfiguration.Period
 	} else if configuration.Period < 0 {
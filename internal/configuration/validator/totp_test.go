@@ -17,6 +17,8 @@ func TestShouldSetDefaultTOTPValues(t *testing.T) {
 
 	require.Len(t, validator.Errors(), 0)
 	assert.Equal(t, "Authelia", config.Issuer)
+	assert.Equal(t, "SHA1", config.Algorithm)
+	assert.Equal(t, 6, config.Digits)
 	assert.Equal(t, *schema.DefaultTOTPConfiguration.Skew, *config.Skew)
 	assert.Equal(t, schema.DefaultTOTPConfiguration.Period, config.Period)
 }
@@ -34,3 +36,31 @@ func TestShouldRaiseErrorWhenInvalidTOTPMinimumValues(t *testing.T) {
 	assert.EqualError(t, validator.Errors()[0], "TOTP Period must be 1 or more")
 	assert.EqualError(t, validator.Errors()[1], "TOTP Skew must be 0 or more")
 }
+
+func TestShouldNormalizeTOTPAlgorithm(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.TOTPConfiguration{
+		Algorithm: "sha512",
+		Digits:    8,
+	}
+
+	ValidateTOTP(&config, validator)
+
+	require.Len(t, validator.Errors(), 0)
+	assert.Equal(t, "SHA512", config.Algorithm)
+	assert.Equal(t, 8, config.Digits)
+}
+
+func TestShouldRaiseErrorWhenInvalidTOTPAlgorithmAndDigits(t *testing.T) {
+	validator := schema.NewStructValidator()
+	config := schema.TOTPConfiguration{
+		Algorithm: "md5",
+		Digits:    7,
+	}
+
+	ValidateTOTP(&config, validator)
+
+	require.Len(t, validator.Errors(), 2)
+	assert.EqualError(t, validator.Errors()[0], "TOTP Algorithm must be one of 'SHA1', 'SHA256' or 'SHA512' but it is configured as 'MD5'")
+	assert.EqualError(t, validator.Errors()[1], "TOTP Digits must be 6 or 8 but it is configured as 7")
+}
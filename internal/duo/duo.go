@@ -17,21 +17,37 @@ func NewDuoAPI(duoAPI *duoapi.DuoApi) *APIImpl {
 	return api
 }
 
-// Call call to the DuoAPI.
-func (d *APIImpl) Call(values url.Values, ctx *middlewares.AutheliaCtx) (*Response, error) {
-	var response Response
+// PreAuthCall call the preauth endpoint of the DuoAPI to retrieve the devices of the user and the factors they support.
+func (d *APIImpl) PreAuthCall(values url.Values, ctx *middlewares.AutheliaCtx) (*PreAuthResponse, error) {
+	var response PreAuthResponse
 
-	_, responseBytes, err := d.DuoApi.SignedCall("POST", "/auth/v2/auth", values)
+	err := d.signedCall("/auth/v2/preauth", values, ctx, &response)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx.Logger.Tracef("Duo Push Auth Response Raw Data for %s from IP %s: %s", ctx.GetSession().Username, ctx.RemoteIP().String(), string(responseBytes))
+	return &response, nil
+}
+
+// AuthCall call the auth endpoint of the DuoAPI to authenticate the user with a factor.
+func (d *APIImpl) AuthCall(values url.Values, ctx *middlewares.AutheliaCtx) (*Response, error) {
+	var response Response
 
-	err = json.Unmarshal(responseBytes, &response)
+	err := d.signedCall("/auth/v2/auth", values, ctx, &response)
 	if err != nil {
 		return nil, err
 	}
 
 	return &response, nil
 }
+
+func (d *APIImpl) signedCall(path string, values url.Values, ctx *middlewares.AutheliaCtx, response interface{}) error {
+	_, responseBytes, err := d.DuoApi.SignedCall("POST", path, values)
+	if err != nil {
+		return err
+	}
+
+	ctx.Logger.Tracef("Duo %s Response Raw Data for %s from IP %s: %s", path, ctx.GetSession().Username, ctx.RemoteIP().String(), string(responseBytes))
+
+	return json.Unmarshal(responseBytes, response)
+}
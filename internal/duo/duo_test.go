@@ -0,0 +1,132 @@
+package duo_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	duoapi "github.com/duosecurity/duo_api_golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/duo"
+	"github.com/authelia/authelia/internal/mocks"
+)
+
+// newStubDuoAPI starts a server standing for the Duo API which answers the given bodies per path and records the
+// requests it receives.
+func newStubDuoAPI(t *testing.T, bodies map[string]string) (*duo.APIImpl, *[]*http.Request) {
+	var requests []*http.Request
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		requests = append(requests, r)
+
+		body, ok := bodies[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	host := strings.TrimPrefix(server.URL, "https://")
+
+	return duo.NewDuoAPI(duoapi.NewDuoApi("ABCDEFG", "SECRET", host, "", duoapi.SetInsecure())), &requests
+}
+
+func TestShouldCallPreAuthAndParseDevices(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	api, requests := newStubDuoAPI(t, map[string]string{
+		"/auth/v2/preauth": `{"stat": "OK", "response": {"result": "auth", "status_msg": "Account is active",
+			"devices": [{"device": "DPFZRS9FB0D46QFTM891", "display_name": "iOS (XXX-XXX-0100)", "name": "iOS",
+			"number": "XXX-XXX-0100", "type": "phone", "capabilities": ["auto", "push", "sms", "phone", "mobile_otp"]},
+			{"device": "DHEKH0JJIYC1LX3AZWO4", "display_name": "Token", "type": "token", "capabilities": []}]}}`,
+	})
+
+	values := url.Values{}
+	values.Set("username", "john")
+	values.Set("ipaddr", "127.0.0.1")
+
+	response, err := api.PreAuthCall(values, mock.Ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, "OK", response.Stat)
+	assert.Equal(t, "auth", response.Response.Result)
+	require.Len(t, response.Response.Devices, 2)
+	assert.Equal(t, duo.Device{
+		Device:       "DPFZRS9FB0D46QFTM891",
+		DisplayName:  "iOS (XXX-XXX-0100)",
+		Name:         "iOS",
+		Number:       "XXX-XXX-0100",
+		Type:         "phone",
+		Capabilities: []string{"auto", "push", "sms", "phone", "mobile_otp"},
+	}, response.Response.Devices[0])
+	assert.Equal(t, "token", response.Response.Devices[1].Type)
+
+	require.Len(t, *requests, 1)
+	assert.Equal(t, "POST", (*requests)[0].Method)
+	assert.Equal(t, "john", (*requests)[0].Form.Get("username"))
+	assert.NotEmpty(t, (*requests)[0].Header.Get("Authorization"))
+}
+
+func TestShouldCallAuthWithChosenFactor(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	api, requests := newStubDuoAPI(t, map[string]string{
+		"/auth/v2/auth": `{"stat": "OK", "response": {"result": "allow", "status": "allow", "status_msg": "Success. Logging you in..."}}`,
+	})
+
+	values := url.Values{}
+	values.Set("username", "john")
+	values.Set("factor", "passcode")
+	values.Set("passcode", "123456")
+
+	response, err := api.AuthCall(values, mock.Ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, "allow", response.Response.Result)
+	assert.Equal(t, "Success. Logging you in...", response.Response.StatusMessage)
+
+	require.Len(t, *requests, 1)
+	assert.Equal(t, "/auth/v2/auth", (*requests)[0].URL.Path)
+	assert.Equal(t, "passcode", (*requests)[0].Form.Get("factor"))
+	assert.Equal(t, "123456", (*requests)[0].Form.Get("passcode"))
+}
+
+func TestShouldParseDuoFailure(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	api, _ := newStubDuoAPI(t, map[string]string{
+		"/auth/v2/auth": `{"stat": "FAIL", "code": 40002, "message": "Invalid request parameters", "message_detail": "username"}`,
+	})
+
+	response, err := api.AuthCall(url.Values{}, mock.Ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, "FAIL", response.Stat)
+	assert.Equal(t, 40002, response.Code)
+	assert.Equal(t, "username", response.MessageDetail)
+}
+
+func TestShouldFailWhenResponseIsNotJSON(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	api, _ := newStubDuoAPI(t, map[string]string{
+		"/auth/v2/preauth": `not json`,
+	})
+
+	response, err := api.PreAuthCall(url.Values{}, mock.Ctx)
+	assert.Error(t, err)
+	assert.Nil(t, response)
+}
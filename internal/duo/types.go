@@ -10,7 +10,8 @@ import (
 
 // API interface wrapping duo api library for testing purpose.
 type API interface {
-	Call(values url.Values, ctx *middlewares.AutheliaCtx) (*Response, error)
+	PreAuthCall(values url.Values, ctx *middlewares.AutheliaCtx) (*PreAuthResponse, error)
+	AuthCall(values url.Values, ctx *middlewares.AutheliaCtx) (*Response, error)
 }
 
 // APIImpl implementation of DuoAPI interface.
@@ -18,6 +19,16 @@ type APIImpl struct {
 	*duoapi.DuoApi
 }
 
+// Device is a device enrolled by a user in Duo, as returned by the preauth endpoint.
+type Device struct {
+	Device       string   `json:"device"`
+	DisplayName  string   `json:"display_name"`
+	Name         string   `json:"name"`
+	Number       string   `json:"number"`
+	Type         string   `json:"type"`
+	Capabilities []string `json:"capabilities"`
+}
+
 // Response response coming from Duo API.
 type Response struct {
 	Response struct {
@@ -30,3 +41,17 @@ type Response struct {
 	MessageDetail string `json:"message_detail"`
 	Stat          string `json:"stat"`
 }
+
+// PreAuthResponse response coming from the preauth endpoint of Duo API.
+type PreAuthResponse struct {
+	Response struct {
+		Result          string   `json:"result"`
+		StatusMessage   string   `json:"status_msg"`
+		Devices         []Device `json:"devices"`
+		EnrollPortalURL string   `json:"enroll_portal_url"`
+	} `json:"response"`
+	Code          int    `json:"code"`
+	Message       string `json:"message"`
+	MessageDetail string `json:"message_detail"`
+	Stat          string `json:"stat"`
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"github.com/authelia/authelia/internal/audit"
+	"github.com/authelia/authelia/internal/metrics"
+	"github.com/authelia/authelia/internal/middlewares"
+)
+
+// recordAuthentication records the outcome of an authentication attempt in the metrics and the audit log.
+func recordAuthentication(ctx *middlewares.AutheliaCtx, factor, method, username, targetURL string, success bool) {
+	ctx.RecordAuthentication(factor, method, success)
+
+	outcome := audit.OutcomeFailure
+	if success {
+		outcome = audit.OutcomeSuccess
+	}
+
+	ctx.Audit(audit.Event{
+		Type:      authenticationEventType(factor),
+		Username:  username,
+		TargetURL: targetURL,
+		Method:    method,
+		Outcome:   outcome,
+	})
+}
+
+// recordBan records an authentication attempt refused by the regulation in the metrics and the audit log.
+func recordBan(ctx *middlewares.AutheliaCtx, factor, method, username, targetURL string) {
+	ctx.RecordBan()
+
+	ctx.Audit(audit.Event{
+		Type:      authenticationEventType(factor),
+		Username:  username,
+		TargetURL: targetURL,
+		Method:    method,
+		Outcome:   audit.OutcomeBanned,
+	})
+}
+
+func authenticationEventType(factor string) string {
+	if factor == metrics.FactorFirst {
+		return audit.EventFirstFactor
+	}
+
+	return audit.EventSecondFactor
+}
+
+// recordVerifyDecision records a decision of the verify endpoint in the metrics and, when the access is denied, in the
+// audit log.
+func recordVerifyDecision(ctx *middlewares.AutheliaCtx, decision, username, targetURL string, method []byte) {
+	ctx.RecordVerifyDecision(decision)
+
+	if decision == metrics.DecisionAuthorized {
+		return
+	}
+
+	outcome := audit.OutcomeUnauthorized
+	if decision == metrics.DecisionForbidden {
+		outcome = audit.OutcomeForbidden
+	}
+
+	ctx.Audit(audit.Event{
+		Type:      audit.EventAccessDenied,
+		Username:  username,
+		TargetURL: targetURL,
+		Method:    string(method),
+		Outcome:   outcome,
+	})
+}
+
+// auditSuccess writes the successful event of the user to the audit log.
+func auditSuccess(ctx *middlewares.AutheliaCtx, eventType, username, method string) {
+	ctx.Audit(audit.Event{
+		Type:     eventType,
+		Username: username,
+		Method:   method,
+		Outcome:  audit.OutcomeSuccess,
+	})
+}
+
+// auditAdminAction writes the action performed by the administrator on behalf of the given user to the audit log.
+func auditAdminAction(ctx *middlewares.AutheliaCtx, action, username string) {
+	ctx.Audit(audit.Event{
+		Type:     audit.EventAdminAction,
+		Username: ctx.GetSession().Username,
+		Method:   action,
+		Outcome:  audit.OutcomeSuccess,
+		Subject:  username,
+	})
+}
@@ -1,14 +1,33 @@
 package handlers
 
+import (
+	"time"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/oidc"
+)
+
 // TOTPRegistrationAction is the string representation of the action for which the token has been produced.
 const TOTPRegistrationAction = "RegisterTOTPDevice"
 
 // U2FRegistrationAction is the string representation of the action for which the token has been produced.
 const U2FRegistrationAction = "RegisterU2FDevice"
 
+// WebauthnRegistrationAction is the string representation of the action for which the token has been produced.
+const WebauthnRegistrationAction = "RegisterWebauthnDevice"
+
+// RecoveryCodesGenerationAction is the string representation of the action for which the token has been produced.
+const RecoveryCodesGenerationAction = "GenerateRecoveryCodes"
+
 // ResetPasswordAction is the string representation of the action for which the token has been produced.
 const ResetPasswordAction = "ResetPassword"
 
+// OIDCConsentAccept is the value sent by the portal when the user accepts an OpenID Connect consent request.
+const OIDCConsentAccept = "accept"
+
+// OIDCConsentReject is the value sent by the portal when the user rejects an OpenID Connect consent request.
+const OIDCConsentReject = "reject"
+
 const authPrefix = "Basic "
 
 // ProxyAuthorizationHeader is the basic-auth HTTP header Authelia utilises.
@@ -42,7 +61,104 @@ const userBannedMessage = "Please retry in a few minutes."
 const unableToRegisterOneTimePasswordMessage = "Unable to set up one-time passwords." //nolint:gosec
 const unableToRegisterSecurityKeyMessage = "Unable to register your security key."
 const unableToResetPasswordMessage = "Unable to reset your password."
+const unableToChangePasswordMessage = "Unable to change your password."
+const secondFactorRequiredMessage = "Please authenticate again with your second factor."
+const passwordPolicyViolationMessage = "Your supplied password does not meet the password policy requirements."
 const mfaValidationFailedMessage = "Authentication failed, please retry later."
+const unableToGenerateRecoveryCodesMessage = "Unable to generate recovery codes."
+const unableToSendEmailOTPMessage = "Unable to send the one-time passcode."
+const tooManyEmailOTPMessage = "Too many one-time passcodes have been sent, please try again later."
+
+// u2fDefaultDeviceDescription is the name given to a U2F device when the user does not name it.
+const u2fDefaultDeviceDescription = "Security key"
+const u2fDeviceDescriptionMaxLength = 64
+
+// totpDefaultDeviceDescription is the name given to a TOTP device when the user does not name it.
+const totpDefaultDeviceDescription = "Authenticator app"
+const totpDeviceDescriptionMaxLength = 64
+const totpDeviceIDLength = 16
+
+// recoveryCodesCount is the number of recovery codes generated at once.
+const recoveryCodesCount = 10
+
+// recoveryCodeLength is the number of random characters of a recovery code, recoveryCodeCharacters does not contain
+// characters which are easily mistaken for one another.
+const recoveryCodeLength = 10
+const recoveryCodeCharacters = "abcdefghjkmnpqrstuvwxyz23456789"
+const recoveryCodeSaltLength = 16
+
+// recoveryCodeLookupLength is the number of characters of a recovery code stored in clear to find the only hash the
+// code has to be checked against, the codes of a set never start with the same characters.
+const recoveryCodeLookupLength = 2
+
+// emailOTPDigits is the number of digits of the passcodes sent by email and emailOTPLifespan the time they are valid
+// for. The number of passcodes sent and attempts are limited by the regulator.
+const emailOTPDigits = 6
+const emailOTPLifespan = 5 * time.Minute
+
+const emailOTPNotificationTitle = "Your one-time passcode"
+const emailOTPNotificationBody = `
+Here is the one-time passcode to sign in to your account %s: %s
+
+It expires in %d minutes. If you did not try to sign in, your password might have been compromised and you should reset it.
+`
+
+// The Duo factors a user can choose from, the passcode being generated by the mobile app, a hardware token or
+// received by SMS.
+const (
+	duoFactorPush     = "push"
+	duoFactorPhone    = "phone"
+	duoFactorSMS      = "sms"
+	duoFactorPasscode = "passcode"
+)
+
+const duoDeviceAuto = "auto"
+
+// duoFactorMethods maps the Duo factors to the methods reported in the metrics and the audit log.
+var duoFactorMethods = map[string]string{
+	duoFactorPush:     authentication.Push,
+	duoFactorPhone:    "duo_phone",
+	duoFactorSMS:      "duo_sms",
+	duoFactorPasscode: "duo_passcode",
+}
+
+// duoCapabilitiesFactors maps the capabilities of the Duo devices to the factors they allow.
+var duoCapabilitiesFactors = map[string]string{
+	"push":       duoFactorPush,
+	"phone":      duoFactorPhone,
+	"sms":        duoFactorSMS,
+	"mobile_otp": duoFactorPasscode,
+}
+
+const clonedDeviceNotificationTitle = "A security key might have been cloned"
+const clonedDeviceNotificationBody = `
+%s of your account %s has just been used with a signature counter which did not increase.
+This usually means the key has been cloned, the sign in has therefore been refused.
+
+If you did not try to sign in, your credentials might have been compromised. You should reset your password, remove the key from your account and contact an administrator.
+`
+
+// passwordChangeSecondFactorMaxAge is how long after completing the second factor a user enrolled in one can change
+// their password.
+const passwordChangeSecondFactorMaxAge = 5 * time.Minute
+
+const passwordChangedNotificationTitle = "Your password has been changed"
+const passwordChangedNotificationBody = `
+The password of your account %s has just been changed.
+
+If you did not change it, your account might have been compromised. You should reset your password and contact an administrator.
+`
+
+const oidcInvalidRedirectURIMessage = "The redirect_uri is not registered for the client."
+const oidcServerErrorMessage = "The authorization server encountered an unexpected condition."
+
+var oidcScopeDescriptions = map[string]string{
+	oidc.ScopeOpenID:        "Use OpenID to verify your identity",
+	oidc.ScopeProfile:       "Access your display name and username",
+	oidc.ScopeEmail:         "Access your email address",
+	oidc.ScopeGroups:        "Access your group membership",
+	oidc.ScopeOfflineAccess: "Keep access to these permissions without your interaction",
+}
 
 const ldapPasswordComplexityCode = "0000052D."
 
@@ -57,3 +173,23 @@ const testUsername = "john"
 const movingAverageWindow = 10
 const msMinimumDelay1FA = float64(250)
 const msMaximumRandomDelay = int64(85)
+
+// The methods labelling the authentication metrics which are not second factor methods a user can prefer.
+const (
+	firstFactorMethod  = "password"
+	recoveryCodeMethod = "recovery_code"
+)
+
+// adminAttemptsDefaultPeriod is the period the authentication attempts are returned for by the admin API when no
+// date is provided.
+const adminAttemptsDefaultPeriod = 24 * time.Hour
+
+// The operations of the admin API written to the audit log.
+const (
+	adminActionDeleteTOTPDevice     = "delete_totp_device"
+	adminActionDeleteU2FDevice      = "delete_u2f_device"
+	adminActionDeleteWebauthnDevice = "delete_webauthn_device"
+	adminActionSetPreferredMethod   = "set_preferred_method"
+	adminActionClearBan             = "clear_ban"
+	adminActionRevokeSessions       = "revoke_sessions"
+)
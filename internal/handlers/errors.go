@@ -11,3 +11,5 @@ const UnauthorizedError = "You're not authorized."
 
 var errMissingXForwardedHost = errors.New("Missing header X-Forwarded-Host")
 var errMissingXForwardedProto = errors.New("Missing header X-Forwarded-Proto")
+var errOIDCInvalidScope = errors.New("requested scope exceeds the granted scope")
+var errU2FCounterNotIncreasing = errors.New("the signature counter of the U2F device did not increase")
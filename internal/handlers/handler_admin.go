@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/regulation"
+	"github.com/authelia/authelia/internal/storage"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// AdminUserMethodsGet lists the second factor methods registered by a user.
+func AdminUserMethodsGet(ctx *middlewares.AutheliaCtx) {
+	username := adminTargetUsername(ctx)
+
+	method, err := ctx.Providers.StorageProvider.LoadPreferred2FAMethod(username)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to load preferred 2FA method of user %s: %s", username, err), operationFailedMessage)
+		return
+	}
+
+	totpDevices, err := ctx.Providers.StorageProvider.LoadTOTPDevicesByUsername(username)
+	if err != nil && err != storage.ErrNoTOTPSecret {
+		ctx.Error(fmt.Errorf("Unable to load TOTP devices of user %s: %s", username, err), operationFailedMessage)
+		return
+	}
+
+	u2fDevices, err := ctx.Providers.StorageProvider.LoadU2FDevicesByUsername(username)
+	if err != nil && err != storage.ErrNoU2FDeviceHandle {
+		ctx.Error(fmt.Errorf("Unable to load U2F devices of user %s: %s", username, err), operationFailedMessage)
+		return
+	}
+
+	webauthnDevices, err := ctx.Providers.StorageProvider.LoadWebauthnDevicesByUsername(username)
+	if err != nil && err != storage.ErrNoWebauthnDevice {
+		ctx.Error(fmt.Errorf("Unable to load Webauthn devices of user %s: %s", username, err), operationFailedMessage)
+		return
+	}
+
+	recoveryCodes, err := ctx.Providers.StorageProvider.LoadRecoveryCodesByUsername(username)
+	if err != nil && err != storage.ErrNoRecoveryCode {
+		ctx.Error(fmt.Errorf("Unable to load recovery codes of user %s: %s", username, err), operationFailedMessage)
+		return
+	}
+
+	response := adminUserMethodsResponse{
+		Method:          method,
+		TOTPDevices:     newTOTPDevicesResponse(totpDevices),
+		U2FDevices:      newU2FDevicesResponse(u2fDevices),
+		WebauthnDevices: newWebauthnDevicesResponse(webauthnDevices),
+		RecoveryCodes:   len(recoveryCodes),
+	}
+
+	if err = ctx.SetJSONBody(response); err != nil {
+		ctx.Logger.Errorf("Unable to set methods of user %s in body: %s", username, err)
+	}
+}
+
+// AdminTOTPDeviceDelete deletes one of the TOTP devices registered by a user.
+func AdminTOTPDeviceDelete(ctx *middlewares.AutheliaCtx) {
+	username := adminTargetUsername(ctx)
+	id, _ := ctx.UserValue("id").(string)
+
+	err := ctx.Providers.StorageProvider.DeleteTOTPDevice(username, id)
+	if err != nil {
+		if err == storage.ErrNoTOTPSecret {
+			ctx.SetStatusCode(fasthttp.StatusNotFound)
+		}
+
+		ctx.Error(fmt.Errorf("Unable to delete TOTP device %s of user %s: %s", id, username, err), operationFailedMessage)
+
+		return
+	}
+
+	ctx.Logger.Infof("TOTP device %s of user %s has been deleted by %s", id, username, ctx.GetSession().Username)
+	auditAdminAction(ctx, adminActionDeleteTOTPDevice, username)
+	ctx.ReplyOK()
+}
+
+// AdminU2FDeviceDelete deletes one of the U2F devices registered by a user.
+func AdminU2FDeviceDelete(ctx *middlewares.AutheliaCtx) {
+	username := adminTargetUsername(ctx)
+	id, _ := ctx.UserValue("id").(string)
+
+	keyHandle, err := utils.DecodeKeyHandle(id)
+	if err != nil || len(keyHandle) == 0 {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		ctx.Error(fmt.Errorf("Invalid U2F device id %s", id), operationFailedMessage)
+
+		return
+	}
+
+	err = ctx.Providers.StorageProvider.DeleteU2FDevice(username, keyHandle)
+	if err != nil {
+		if err == storage.ErrNoU2FDeviceHandle {
+			ctx.SetStatusCode(fasthttp.StatusNotFound)
+		}
+
+		ctx.Error(fmt.Errorf("Unable to delete U2F device %s of user %s: %s", id, username, err), operationFailedMessage)
+
+		return
+	}
+
+	ctx.Logger.Infof("U2F device %s of user %s has been deleted by %s", id, username, ctx.GetSession().Username)
+	auditAdminAction(ctx, adminActionDeleteU2FDevice, username)
+	ctx.ReplyOK()
+}
+
+// AdminWebauthnDeviceDelete deletes one of the Webauthn devices registered by a user.
+func AdminWebauthnDeviceDelete(ctx *middlewares.AutheliaCtx) {
+	username := adminTargetUsername(ctx)
+	id, _ := ctx.UserValue("id").(string)
+
+	kid, err := utils.DecodeKeyHandle(id)
+	if err != nil || len(kid) == 0 {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		ctx.Error(fmt.Errorf("Invalid Webauthn device id %s", id), operationFailedMessage)
+
+		return
+	}
+
+	err = ctx.Providers.StorageProvider.DeleteWebauthnDevice(username, kid)
+	if err != nil {
+		if err == storage.ErrNoWebauthnDevice {
+			ctx.SetStatusCode(fasthttp.StatusNotFound)
+		}
+
+		ctx.Error(fmt.Errorf("Unable to delete Webauthn device %s of user %s: %s", id, username, err), operationFailedMessage)
+
+		return
+	}
+
+	ctx.Logger.Infof("Webauthn device %s of user %s has been deleted by %s", id, username, ctx.GetSession().Username)
+	auditAdminAction(ctx, adminActionDeleteWebauthnDevice, username)
+	ctx.ReplyOK()
+}
+
+// AdminPreferredMethodPut changes the preferred second factor method of a user.
+func AdminPreferredMethodPut(ctx *middlewares.AutheliaCtx) {
+	username := adminTargetUsername(ctx)
+	bodyJSON := MethodBody{}
+
+	err := ctx.ParseBody(&bodyJSON)
+	if err != nil {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		ctx.Error(err, operationFailedMessage)
+
+		return
+	}
+
+	if !utils.IsStringInSlice(bodyJSON.Method, authentication.PossibleMethods) {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		ctx.Error(fmt.Errorf("Unknown method '%s', it should be one of %s", bodyJSON.Method, strings.Join(authentication.PossibleMethods, ", ")), operationFailedMessage)
+
+		return
+	}
+
+	err = ctx.Providers.StorageProvider.SavePreferred2FAMethod(username, bodyJSON.Method)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to save new preferred 2FA method of user %s: %s", username, err), operationFailedMessage)
+		return
+	}
+
+	ctx.Logger.Infof("Preferred 2FA method of user %s has been set to %s by %s", username, bodyJSON.Method, ctx.GetSession().Username)
+	auditAdminAction(ctx, adminActionSetPreferredMethod, username)
+	ctx.ReplyOK()
+}
+
+// AdminAuthenticationAttemptsGet lists the recent authentication attempts of a user and the time until when the user
+// is banned. The attempts are returned since the unix timestamp of the since query argument, the last 24 hours by
+// default.
+func AdminAuthenticationAttemptsGet(ctx *middlewares.AutheliaCtx) {
+	username := adminTargetUsername(ctx)
+	since := ctx.Clock.Now().Add(-adminAttemptsDefaultPeriod)
+
+	if arg := ctx.QueryArgs().Peek("since"); len(arg) != 0 {
+		timestamp, err := strconv.ParseInt(string(arg), 10, 64)
+		if err != nil {
+			ctx.SetStatusCode(fasthttp.StatusBadRequest)
+			ctx.Error(fmt.Errorf("Unable to parse since argument %s: %s", arg, err), operationFailedMessage)
+
+			return
+		}
+
+		since = time.Unix(timestamp, 0)
+	}
+
+	attempts, err := ctx.Providers.StorageProvider.LoadLatestAuthenticationLogs(username, since)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to load authentication attempts of user %s: %s", username, err), operationFailedMessage)
+		return
+	}
+
+	response := adminAuthenticationAttemptsResponse{
+		Attempts: make([]adminAuthenticationAttempt, len(attempts)),
+	}
+
+	for i, attempt := range attempts {
+		response.Attempts[i] = adminAuthenticationAttempt{
+			Successful: attempt.Successful,
+			Time:       attempt.Time.Unix(),
+		}
+	}
+
+	bannedUntil, err := ctx.Providers.Regulator.Regulate(username)
+	if err == regulation.ErrUserIsBanned {
+		response.BannedUntil = bannedUntil.Unix()
+	}
+
+	if err = ctx.SetJSONBody(response); err != nil {
+		ctx.Logger.Errorf("Unable to set authentication attempts of user %s in body: %s", username, err)
+	}
+}
+
+// AdminBanDelete lifts the ban of a user.
+func AdminBanDelete(ctx *middlewares.AutheliaCtx) {
+	username := adminTargetUsername(ctx)
+
+	if err := ctx.Providers.Regulator.Unban(username); err != nil {
+		ctx.Error(fmt.Errorf("Unable to lift the ban of user %s: %s", username, err), operationFailedMessage)
+		return
+	}
+
+	ctx.Logger.Infof("Ban of user %s has been lifted by %s", username, ctx.GetSession().Username)
+	auditAdminAction(ctx, adminActionClearBan, username)
+	ctx.ReplyOK()
+}
+
+// AdminSessionsDelete destroys all the sessions of a user.
+func AdminSessionsDelete(ctx *middlewares.AutheliaCtx) {
+	username := adminTargetUsername(ctx)
+
+	entries, err := ctx.Providers.SessionProvider.ListSessions(username)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to list sessions of user %s: %s", username, err), operationFailedMessage)
+		return
+	}
+
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.ID
+	}
+
+	if err = ctx.Providers.SessionProvider.DestroyUserSessions(username, ids...); err != nil {
+		ctx.Error(fmt.Errorf("Unable to destroy sessions of user %s: %s", username, err), operationFailedMessage)
+		return
+	}
+
+	ctx.Logger.Infof("%d session(s) of user %s have been destroyed by %s", len(ids), username, ctx.GetSession().Username)
+	auditAdminAction(ctx, adminActionRevokeSessions, username)
+	ctx.ReplyOK()
+}
+
+func adminTargetUsername(ctx *middlewares.AutheliaCtx) string {
+	username, _ := ctx.UserValue("username").(string)
+	return username
+}
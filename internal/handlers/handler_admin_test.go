@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/audit"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/regulation"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+type HandlerAdminSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *HandlerAdminSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Clock.Set(time.Unix(1600000000, 0))
+	s.mock.Ctx.Clock = &s.mock.Clock
+	s.mock.Ctx.SetUserValue("username", "harry")
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = testUsername
+	err := s.mock.Ctx.SaveSession(userSession)
+	s.Require().NoError(err)
+}
+
+func (s *HandlerAdminSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *HandlerAdminSuite) TestShouldListMethodsOfUser() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadPreferred2FAMethod(gomock.Eq("harry")).
+		Return("u2f", nil)
+	s.mock.StorageProviderMock.EXPECT().
+		LoadTOTPDevicesByUsername(gomock.Eq("harry")).
+		Return([]models.TOTPDevice{{Username: "harry", ID: "primary", Description: "Phone"}}, nil)
+	s.mock.StorageProviderMock.EXPECT().
+		LoadU2FDevicesByUsername(gomock.Eq("harry")).
+		Return(nil, storage.ErrNoU2FDeviceHandle)
+	s.mock.StorageProviderMock.EXPECT().
+		LoadWebauthnDevicesByUsername(gomock.Eq("harry")).
+		Return([]models.WebauthnDevice{{Username: "harry", KID: []byte("primary"), AttestationType: "none", CreatedAt: time.Unix(1600000000, 0)}}, nil)
+	s.mock.StorageProviderMock.EXPECT().
+		LoadRecoveryCodesByUsername(gomock.Eq("harry")).
+		Return([]models.RecoveryCode{{Username: "harry"}, {Username: "harry"}}, nil)
+
+	AdminUserMethodsGet(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), adminUserMethodsResponse{
+		Method:          "u2f",
+		TOTPDevices:     []totpDeviceResponse{{ID: "primary", Description: "Phone"}},
+		U2FDevices:      []u2fDeviceResponse{},
+		WebauthnDevices: []webauthnDeviceResponse{{ID: "cHJpbWFyeQ", AttestationType: "none", CreatedAt: 1600000000}},
+		RecoveryCodes:   2,
+	})
+}
+
+func (s *HandlerAdminSuite) TestShouldFailListingMethodsWhenStorageFails() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadPreferred2FAMethod(gomock.Eq("harry")).
+		Return("", fmt.Errorf("failure"))
+
+	AdminUserMethodsGet(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), "Operation failed.")
+	assert.Equal(s.T(), "Unable to load preferred 2FA method of user harry: failure", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerAdminSuite) TestShouldDeleteTOTPDeviceOfUserAndAudit() {
+	buffer := new(bytes.Buffer)
+	s.mock.Ctx.Providers.Audit = audit.NewJSONLogger(buffer)
+	s.mock.Ctx.SetUserValue("id", "primary")
+
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteTOTPDevice(gomock.Eq("harry"), gomock.Eq("primary")).
+		Return(nil)
+
+	AdminTOTPDeviceDelete(s.mock.Ctx)
+
+	assert.Equal(s.T(), 200, s.mock.Ctx.Response.StatusCode())
+
+	var event audit.Event
+
+	s.Require().NoError(json.Unmarshal(buffer.Bytes(), &event))
+	assert.Equal(s.T(), audit.EventAdminAction, event.Type)
+	assert.Equal(s.T(), testUsername, event.Username)
+	assert.Equal(s.T(), "harry", event.Subject)
+	assert.Equal(s.T(), adminActionDeleteTOTPDevice, event.Method)
+}
+
+func (s *HandlerAdminSuite) TestShouldReturnNotFoundWhenU2FDeviceDoesNotExist() {
+	s.mock.Ctx.SetUserValue("id", "cHJpbWFyeQ")
+
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteU2FDevice(gomock.Eq("harry"), gomock.Eq([]byte("primary"))).
+		Return(storage.ErrNoU2FDeviceHandle)
+
+	AdminU2FDeviceDelete(s.mock.Ctx)
+
+	assert.Equal(s.T(), 404, s.mock.Ctx.Response.StatusCode())
+}
+
+func (s *HandlerAdminSuite) TestShouldDeleteWebauthnDeviceOfUserAndAudit() {
+	buffer := new(bytes.Buffer)
+	s.mock.Ctx.Providers.Audit = audit.NewJSONLogger(buffer)
+	s.mock.Ctx.SetUserValue("id", "cHJpbWFyeQ")
+
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteWebauthnDevice(gomock.Eq("harry"), gomock.Eq([]byte("primary"))).
+		Return(nil)
+
+	AdminWebauthnDeviceDelete(s.mock.Ctx)
+
+	assert.Equal(s.T(), 200, s.mock.Ctx.Response.StatusCode())
+
+	var event audit.Event
+
+	s.Require().NoError(json.Unmarshal(buffer.Bytes(), &event))
+	assert.Equal(s.T(), "harry", event.Subject)
+	assert.Equal(s.T(), adminActionDeleteWebauthnDevice, event.Method)
+}
+
+func (s *HandlerAdminSuite) TestShouldReturnNotFoundWhenWebauthnDeviceDoesNotExist() {
+	s.mock.Ctx.SetUserValue("id", "cHJpbWFyeQ")
+
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteWebauthnDevice(gomock.Eq("harry"), gomock.Eq([]byte("primary"))).
+		Return(storage.ErrNoWebauthnDevice)
+
+	AdminWebauthnDeviceDelete(s.mock.Ctx)
+
+	assert.Equal(s.T(), 404, s.mock.Ctx.Response.StatusCode())
+}
+
+func (s *HandlerAdminSuite) TestShouldSetPreferredMethodOfUser() {
+	s.mock.Ctx.Request.SetBodyString(`{"method":"totp"}`)
+
+	s.mock.StorageProviderMock.EXPECT().
+		SavePreferred2FAMethod(gomock.Eq("harry"), gomock.Eq("totp")).
+		Return(nil)
+
+	AdminPreferredMethodPut(s.mock.Ctx)
+
+	assert.Equal(s.T(), 200, s.mock.Ctx.Response.StatusCode())
+}
+
+func (s *HandlerAdminSuite) TestShouldRefuseUnknownPreferredMethod() {
+	s.mock.Ctx.Request.SetBodyString(`{"method":"sms"}`)
+
+	AdminPreferredMethodPut(s.mock.Ctx)
+
+	assert.Equal(s.T(), 400, s.mock.Ctx.Response.StatusCode())
+	assert.Equal(s.T(), "Unknown method 'sms', it should be one of totp, u2f, mobile_push, email_otp", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerAdminSuite) TestShouldListAuthenticationAttemptsAndBan() {
+	s.mock.Ctx.Providers.Regulator = regulation.NewRegulator(&schema.RegulationConfiguration{
+		MaxRetries: 2,
+		FindTime:   "2m",
+		BanTime:    "5m",
+	}, s.mock.StorageProviderMock, &s.mock.Clock)
+	s.mock.Ctx.QueryArgs().Add("since", "1599990000")
+
+	attempts := []models.AuthenticationAttempt{
+		{Username: "harry", Successful: false, Time: time.Unix(1599999990, 0)},
+		{Username: "harry", Successful: false, Time: time.Unix(1599999980, 0)},
+	}
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Eq("harry"), gomock.Eq(time.Unix(1599990000, 0))).
+		Return(attempts, nil)
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Eq("harry"), gomock.Eq(time.Unix(1599999700, 0))).
+		Return(attempts, nil)
+
+	AdminAuthenticationAttemptsGet(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), adminAuthenticationAttemptsResponse{
+		BannedUntil: 1600000290,
+		Attempts: []adminAuthenticationAttempt{
+			{Successful: false, Time: 1599999990},
+			{Successful: false, Time: 1599999980},
+		},
+	})
+}
+
+func (s *HandlerAdminSuite) TestShouldRefuseMalformedSinceArgument() {
+	s.mock.Ctx.QueryArgs().Add("since", "yesterday")
+
+	AdminAuthenticationAttemptsGet(s.mock.Ctx)
+
+	assert.Equal(s.T(), 400, s.mock.Ctx.Response.StatusCode())
+}
+
+func (s *HandlerAdminSuite) TestShouldLiftBanOfUser() {
+	s.mock.Ctx.Providers.Regulator = regulation.NewRegulator(&schema.RegulationConfiguration{
+		MaxRetries: 2,
+		FindTime:   "2m",
+		BanTime:    "5m",
+	}, s.mock.StorageProviderMock, &s.mock.Clock)
+
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteFailedAuthenticationLogs(gomock.Eq("harry"), gomock.Eq(time.Unix(1599999700, 0))).
+		Return(nil)
+
+	AdminBanDelete(s.mock.Ctx)
+
+	assert.Equal(s.T(), 200, s.mock.Ctx.Response.StatusCode())
+	assert.Equal(s.T(), "Ban of user harry has been lifted by john", s.mock.Hook.LastEntry().Message)
+}
+
+func TestRunHandlerAdminSuite(t *testing.T) {
+	suite.Run(t, new(HandlerAdminSuite))
+}
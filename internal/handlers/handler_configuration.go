@@ -22,6 +22,11 @@ func ConfigurationGet(ctx *middlewares.AutheliaCtx) {
 		body.AvailableMethods = append(body.AvailableMethods, authentication.Push)
 	}
 
+	// The passcodes are only sent by email when a real mail server is configured.
+	if ctx.Configuration.Notifier != nil && ctx.Configuration.Notifier.SMTP != nil {
+		body.AvailableMethods = append(body.AvailableMethods, authentication.EmailOTP)
+	}
+
 	body.SecondFactorEnabled = ctx.Providers.Authorizer.IsSecondFactorEnabled()
 	ctx.Logger.Tracef("Second factor enabled: %v", body.SecondFactorEnabled)
 
@@ -60,6 +60,44 @@ func (s *SecondFactorAvailableMethodsFixture) TestShouldServeDefaultMethodsAndMo
 	s.mock.Assert200OK(s.T(), expectedBody)
 }
 
+func (s *SecondFactorAvailableMethodsFixture) TestShouldServeEmailOTPWhenNotifierIsSMTP() {
+	s.mock.Ctx.Configuration = schema.Configuration{
+		Notifier: &schema.NotifierConfiguration{
+			SMTP: &schema.SMTPNotifierConfiguration{},
+		},
+		TOTP: &schema.TOTPConfiguration{
+			Period: schema.DefaultTOTPConfiguration.Period,
+		},
+	}
+	expectedBody := ConfigurationBody{
+		AvailableMethods:    []string{"totp", "u2f", "email_otp"},
+		SecondFactorEnabled: false,
+		TOTPPeriod:          schema.DefaultTOTPConfiguration.Period,
+	}
+
+	ConfigurationGet(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), expectedBody)
+}
+
+func (s *SecondFactorAvailableMethodsFixture) TestShouldNotServeEmailOTPWhenNotifierIsFileSystem() {
+	s.mock.Ctx.Configuration = schema.Configuration{
+		Notifier: &schema.NotifierConfiguration{
+			FileSystem: &schema.FileSystemNotifierConfiguration{},
+		},
+		TOTP: &schema.TOTPConfiguration{
+			Period: schema.DefaultTOTPConfiguration.Period,
+		},
+	}
+	expectedBody := ConfigurationBody{
+		AvailableMethods:    []string{"totp", "u2f"},
+		SecondFactorEnabled: false,
+		TOTPPeriod:          schema.DefaultTOTPConfiguration.Period,
+	}
+
+	ConfigurationGet(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), expectedBody)
+}
+
 func (s *SecondFactorAvailableMethodsFixture) TestShouldCheckSecondFactorIsDisabledWhenNoRuleIsSetToTwoFactor() {
 	s.mock.Ctx.Configuration = schema.Configuration{
 		TOTP: &schema.TOTPConfiguration{
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/metrics"
 	"github.com/authelia/authelia/internal/middlewares"
 	"github.com/authelia/authelia/internal/regulation"
 	"github.com/authelia/authelia/internal/session"
@@ -82,6 +83,7 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 
 		if err != nil {
 			if err == regulation.ErrUserIsBanned {
+				recordBan(ctx, metrics.FactorFirst, firstFactorMethod, bodyJSON.Username, bodyJSON.TargetURL)
 				handleAuthenticationUnauthorized(ctx, fmt.Errorf("User %s is banned until %s", bodyJSON.Username, bannedUntil), userBannedMessage)
 				return
 			}
@@ -100,6 +102,8 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 				ctx.Logger.Errorf("Unable to mark authentication: %s", err.Error())
 			}
 
+			recordAuthentication(ctx, metrics.FactorFirst, firstFactorMethod, bodyJSON.Username, bodyJSON.TargetURL, false)
+
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Error while checking password for user %s: %s", bodyJSON.Username, err.Error()), authenticationFailedMessage)
 
 			return
@@ -112,6 +116,8 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 				ctx.Logger.Errorf("Unable to mark authentication: %s", err.Error())
 			}
 
+			recordAuthentication(ctx, metrics.FactorFirst, firstFactorMethod, bodyJSON.Username, bodyJSON.TargetURL, false)
+
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Credentials are wrong for user %s", bodyJSON.Username), authenticationFailedMessage)
 
 			return
@@ -119,6 +125,7 @@ func FirstFactorPost(msInitialDelay time.Duration, delayEnabled bool) middleware
 
 		ctx.Logger.Debugf("Mark authentication attempt made by user %s", bodyJSON.Username)
 		err = ctx.Providers.Regulator.Mark(bodyJSON.Username, true)
+		recordAuthentication(ctx, metrics.FactorFirst, firstFactorMethod, bodyJSON.Username, bodyJSON.TargetURL, true)
 
 		if err != nil {
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to mark authentication: %s", err.Error()), authenticationFailedMessage)
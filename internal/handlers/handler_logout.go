@@ -3,11 +3,14 @@ package handlers
 import (
 	"fmt"
 
+	"github.com/authelia/authelia/internal/audit"
 	"github.com/authelia/authelia/internal/middlewares"
 )
 
 // LogoutPost is the handler logging out the user attached to the given cookie.
 func LogoutPost(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+
 	ctx.Logger.Tracef("Destroy session")
 	err := ctx.Providers.SessionProvider.DestroySession(ctx.RequestCtx)
 
@@ -15,5 +18,9 @@ func LogoutPost(ctx *middlewares.AutheliaCtx) {
 		ctx.Error(fmt.Errorf("Unable to destroy session during logout: %s", err), operationFailedMessage)
 	}
 
+	if userSession.Username != "" {
+		auditSuccess(ctx, audit.EventLogout, userSession.Username, "")
+	}
+
 	ctx.ReplyOK()
 }
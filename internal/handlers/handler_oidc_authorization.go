@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/oidc"
+	"github.com/authelia/authelia/internal/session"
+	"github.com/authelia/authelia/internal/storage"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// OpenIDConnectAuthorizationGet handles the authorization requests of the OpenID Connect clients (authorization code flow).
+// The user is sent to the portal to authenticate and give consent if required and then redirected to the client with a code.
+func OpenIDConnectAuthorizationGet(ctx *middlewares.AutheliaCtx) {
+	issuer, err := ctx.ExternalRootURL()
+	if err != nil {
+		oidcRespondError(ctx, fasthttp.StatusBadRequest, oidc.ErrorInvalidRequest, oidcServerErrorMessage, err)
+		return
+	}
+
+	args := ctx.QueryArgs()
+
+	client, err := ctx.Providers.OpenIDConnect.GetClient(string(args.Peek("client_id")))
+	if err != nil {
+		oidcRespondError(ctx, fasthttp.StatusBadRequest, oidc.ErrorInvalidClient, "The client is not registered.",
+			fmt.Errorf("Unable to find client %s: %s", args.Peek("client_id"), err))
+
+		return
+	}
+
+	redirectURI := string(args.Peek("redirect_uri"))
+
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+
+	// Never redirect the user agent to an URI which is not registered for the client.
+	if !client.IsRedirectURIAllowed(redirectURI) {
+		oidcRespondError(ctx, fasthttp.StatusBadRequest, oidc.ErrorInvalidRequest, oidcInvalidRedirectURIMessage,
+			fmt.Errorf("Redirect URI %s is not registered for client %s", redirectURI, client.ID))
+
+		return
+	}
+
+	state := string(args.Peek("state"))
+	scopes := strings.Fields(string(args.Peek("scope")))
+
+	if errorCode, err := validateOIDCAuthorizationRequest(client, args, scopes); err != nil {
+		oidcRedirectError(ctx, redirectURI, state, errorCode, err.Error(),
+			fmt.Errorf("Invalid authorization request from client %s: %s", client.ID, err))
+
+		return
+	}
+
+	prompt := string(args.Peek("prompt"))
+	authURI := fmt.Sprintf("%s%s?%s", issuer, oidc.AuthorizationPath, args.QueryString())
+	userSession := ctx.GetSession()
+
+	if !client.IsAuthenticationLevelSufficient(userSession.AuthenticationLevel) {
+		if prompt == "none" {
+			oidcRedirectError(ctx, redirectURI, state, oidc.ErrorLoginRequired, "The user must authenticate.",
+				fmt.Errorf("Client %s requires the user to authenticate", client.ID))
+
+			return
+		}
+
+		ctx.Logger.Debugf("Client %s requires the user to authenticate, redirecting to the portal", client.ID)
+		ctx.Redirect(fmt.Sprintf("%s/?rd=%s", issuer, url.QueryEscape(authURI)), fasthttp.StatusFound)
+
+		return
+	}
+
+	consent, err := ctx.Providers.StorageProvider.LoadOAuth2Consent(userSession.Username, client.ID)
+	if err != nil && err != storage.ErrNoOAuth2Consent {
+		oidcRedirectError(ctx, redirectURI, state, oidc.ErrorServerError, oidcServerErrorMessage,
+			fmt.Errorf("Unable to load consent of user %s for client %s: %s", userSession.Username, client.ID, err))
+
+		return
+	}
+
+	if consent == nil || !oidc.IsScopeSubset(scopes, consent.Scopes) {
+		if prompt == "none" {
+			oidcRedirectError(ctx, redirectURI, state, oidc.ErrorConsentRequired, "The user must give consent.",
+				fmt.Errorf("Client %s requires the consent of user %s", client.ID, userSession.Username))
+
+			return
+		}
+
+		userSession.OIDCWorkflowSession = &session.OIDCWorkflowSession{
+			ClientID:                   client.ID,
+			RequestedScopes:            scopes,
+			AuthURI:                    authURI,
+			RequiredAuthorizationLevel: client.RequiredAuthenticationLevel(),
+			CreatedTimestamp:           ctx.Clock.Now().Unix(),
+		}
+
+		if err := ctx.SaveSession(userSession); err != nil {
+			oidcRedirectError(ctx, redirectURI, state, oidc.ErrorServerError, oidcServerErrorMessage,
+				fmt.Errorf("Unable to save session of user %s: %s", userSession.Username, err))
+
+			return
+		}
+
+		ctx.Logger.Debugf("Client %s requires the consent of user %s, redirecting to the portal", client.ID, userSession.Username)
+		ctx.Redirect(fmt.Sprintf("%s/consent", issuer), fasthttp.StatusFound)
+
+		return
+	}
+
+	issueOIDCAuthorizationCode(ctx, client, userSession, scopes, redirectURI)
+}
+
+// issueOIDCAuthorizationCode saves a new authorization code for the user and redirects the user agent to the client with it.
+func issueOIDCAuthorizationCode(ctx *middlewares.AutheliaCtx, client *oidc.Client, userSession session.UserSession, scopes []string, redirectURI string) {
+	args := ctx.QueryArgs()
+	state := string(args.Peek("state"))
+
+	code, err := oidc.GenerateToken()
+	if err != nil {
+		oidcRedirectError(ctx, redirectURI, state, oidc.ErrorServerError, oidcServerErrorMessage, err)
+		return
+	}
+
+	err = ctx.Providers.StorageProvider.SaveOAuth2AuthorizationCode(models.OAuth2AuthorizationCode{
+		Signature:           oidc.HashToken(code),
+		ClientID:            client.ID,
+		Username:            userSession.Username,
+		Scopes:              scopes,
+		RedirectURI:         string(args.Peek("redirect_uri")),
+		Nonce:               string(args.Peek("nonce")),
+		CodeChallenge:       string(args.Peek("code_challenge")),
+		CodeChallengeMethod: string(args.Peek("code_challenge_method")),
+		AMR:                 oidc.AuthenticationMethodReferences(userSession.AuthenticationLevel),
+		ExpiresAt:           ctx.Clock.Now().Add(ctx.Providers.OpenIDConnect.AuthorizeCodeLifespan),
+	})
+	if err != nil {
+		oidcRedirectError(ctx, redirectURI, state, oidc.ErrorServerError, oidcServerErrorMessage,
+			fmt.Errorf("Unable to save authorization code for client %s: %s", client.ID, err))
+
+		return
+	}
+
+	if userSession.OIDCWorkflowSession != nil {
+		userSession.OIDCWorkflowSession = nil
+
+		if err := ctx.SaveSession(userSession); err != nil {
+			ctx.Logger.Errorf("Unable to save session of user %s: %s", userSession.Username, err)
+		}
+	}
+
+	uri, err := oidcRedirectURI(redirectURI, map[string]string{"code": code, "state": state})
+	if err != nil {
+		oidcRespondError(ctx, fasthttp.StatusBadRequest, oidc.ErrorInvalidRequest, oidcInvalidRedirectURIMessage, err)
+		return
+	}
+
+	ctx.Logger.Debugf("Authorization code issued to client %s for user %s", client.ID, userSession.Username)
+	ctx.Redirect(uri, fasthttp.StatusFound)
+}
+
+// validateOIDCAuthorizationRequest checks the response type, the scopes and the PKCE parameters of an authorization
+// request and returns the error code to send back to the client if they are invalid.
+func validateOIDCAuthorizationRequest(client *oidc.Client, args *fasthttp.Args, scopes []string) (string, error) {
+	responseType := string(args.Peek("response_type"))
+	if responseType != oidc.ResponseTypeCode || !client.IsResponseTypeAllowed(responseType) {
+		return oidc.ErrorUnsupportedResponseType, fmt.Errorf("The response type %s is not supported.", responseType)
+	}
+
+	if !utils.IsStringInSlice(oidc.ScopeOpenID, scopes) || !client.IsScopeAllowed(scopes) {
+		return oidc.ErrorInvalidScope, fmt.Errorf("The requested scope is invalid.")
+	}
+
+	if err := validateOIDCCodeChallenge(client, string(args.Peek("code_challenge")), string(args.Peek("code_challenge_method"))); err != nil {
+		return oidc.ErrorInvalidRequest, err
+	}
+
+	return "", nil
+}
+
+func validateOIDCCodeChallenge(client *oidc.Client, codeChallenge, codeChallengeMethod string) error {
+	switch codeChallengeMethod {
+	case "", oidc.PKCEMethodPlain, oidc.PKCEMethodS256:
+	default:
+		return fmt.Errorf("The code_challenge_method %s is not supported.", codeChallengeMethod)
+	}
+
+	if codeChallenge == "" {
+		if codeChallengeMethod != "" {
+			return fmt.Errorf("The code_challenge is required when the code_challenge_method is provided.")
+		}
+
+		if client.Public {
+			return fmt.Errorf("Public clients must use PKCE.")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/oidc"
+	"github.com/authelia/authelia/internal/session"
+)
+
+// OpenIDConnectConsentGet returns the client and the scopes the user is asked to consent to.
+func OpenIDConnectConsentGet(ctx *middlewares.AutheliaCtx) {
+	userSession, client, err := getOIDCConsentWorkflow(ctx)
+	if err != nil {
+		ctx.Logger.Errorf("Unable to get the consent workflow: %s", err)
+		ctx.ReplyForbidden()
+
+		return
+	}
+
+	body := OIDCConsentGetResponseBody{
+		ClientID:          client.ID,
+		ClientDescription: client.Description,
+		Scopes:            make([]OIDCConsentScope, 0, len(userSession.OIDCWorkflowSession.RequestedScopes)),
+	}
+
+	for _, scope := range userSession.OIDCWorkflowSession.RequestedScopes {
+		body.Scopes = append(body.Scopes, OIDCConsentScope{Name: scope, Description: oidcScopeDescriptions[scope]})
+	}
+
+	if err := ctx.SetJSONBody(body); err != nil {
+		ctx.Error(fmt.Errorf("Unable to set consent in body: %s", err), operationFailedMessage)
+	}
+}
+
+// OpenIDConnectConsentPost records the decision of the user and returns the URL the user agent must be redirected to.
+func OpenIDConnectConsentPost(ctx *middlewares.AutheliaCtx) {
+	var body oidcConsentPostRequestBody
+
+	if err := ctx.ParseBody(&body); err != nil {
+		ctx.Error(err, operationFailedMessage)
+		return
+	}
+
+	userSession, client, err := getOIDCConsentWorkflow(ctx)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to get the consent workflow: %s", err), operationFailedMessage)
+		return
+	}
+
+	if body.ClientID != client.ID {
+		ctx.Error(fmt.Errorf("Consent was given for client %s but the workflow is for client %s", body.ClientID, client.ID), operationFailedMessage)
+		return
+	}
+
+	workflow := userSession.OIDCWorkflowSession
+	redirect := workflow.AuthURI
+
+	switch body.AcceptOrReject {
+	case OIDCConsentAccept:
+		err = ctx.Providers.StorageProvider.SaveOAuth2Consent(models.OAuth2Consent{
+			Username:  userSession.Username,
+			ClientID:  client.ID,
+			Scopes:    workflow.RequestedScopes,
+			GrantedAt: ctx.Clock.Now(),
+		})
+		if err != nil {
+			ctx.Error(fmt.Errorf("Unable to save consent of user %s for client %s: %s", userSession.Username, client.ID, err), operationFailedMessage)
+			return
+		}
+	case OIDCConsentReject:
+		redirect, err = oidcConsentRejectedRedirectURI(client, workflow.AuthURI)
+		if err != nil {
+			ctx.Error(err, operationFailedMessage)
+			return
+		}
+	default:
+		ctx.Error(fmt.Errorf("Invalid consent decision %s", body.AcceptOrReject), operationFailedMessage)
+		return
+	}
+
+	userSession.OIDCWorkflowSession = nil
+
+	if err := ctx.SaveSession(userSession); err != nil {
+		ctx.Error(fmt.Errorf("Unable to save session of user %s: %s", userSession.Username, err), operationFailedMessage)
+		return
+	}
+
+	if err := ctx.SetJSONBody(redirectResponse{Redirect: redirect}); err != nil {
+		ctx.Logger.Errorf("Unable to set redirection URL in body: %s", err)
+	}
+}
+
+func getOIDCConsentWorkflow(ctx *middlewares.AutheliaCtx) (session.UserSession, *oidc.Client, error) {
+	userSession := ctx.GetSession()
+
+	if userSession.OIDCWorkflowSession == nil {
+		return userSession, nil, fmt.Errorf("No consent workflow in session of user %s", userSession.Username)
+	}
+
+	client, err := ctx.Providers.OpenIDConnect.GetClient(userSession.OIDCWorkflowSession.ClientID)
+	if err != nil {
+		return userSession, nil, fmt.Errorf("Unable to find client %s: %s", userSession.OIDCWorkflowSession.ClientID, err)
+	}
+
+	if !client.IsAuthenticationLevelSufficient(userSession.AuthenticationLevel) {
+		return userSession, nil, fmt.Errorf("User %s is not sufficiently authenticated for client %s", userSession.Username, client.ID)
+	}
+
+	return userSession, client, nil
+}
+
+// oidcConsentRejectedRedirectURI builds the URI redirecting the user agent to the client with an access_denied error
+// from the original authorization request.
+func oidcConsentRejectedRedirectURI(client *oidc.Client, authURI string) (string, error) {
+	uri, err := url.Parse(authURI)
+	if err != nil {
+		return "", fmt.Errorf("Unable to parse authorization request URI %s: %s", authURI, err)
+	}
+
+	query := uri.Query()
+	redirectURI := query.Get("redirect_uri")
+
+	if redirectURI == "" && len(client.RedirectURIs) == 1 {
+		redirectURI = client.RedirectURIs[0]
+	}
+
+	if !client.IsRedirectURIAllowed(redirectURI) {
+		return "", fmt.Errorf("Redirect URI %s is not registered for client %s", redirectURI, client.ID)
+	}
+
+	return oidcErrorRedirectURI(redirectURI, query.Get("state"), oidc.ErrorAccessDenied, "The user rejected the request.")
+}
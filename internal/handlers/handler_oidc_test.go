@@ -0,0 +1,606 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/url"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/oidc"
+	"github.com/authelia/authelia/internal/session"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+const (
+	testOIDCIssuer       = "https://auth.example.com"
+	testOIDCClientID     = "myapp"
+	testOIDCClientSecret = "my$ecret"
+	testOIDCRedirectURI  = "https://app.example.com/callback"
+	testOIDCState        = "af0ifjsldkj"
+	testOIDCNonce        = "n-0S6_WzA2Mj"
+	testOIDCCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+type HandlerOIDCSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+	key  *rsa.PrivateKey
+}
+
+func (s *HandlerOIDCSuite) SetupTest() {
+	var err error
+
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Clock = &s.mock.Clock
+
+	if s.key == nil {
+		s.key, err = rsa.GenerateKey(rand.Reader, 1024)
+		s.Require().NoError(err)
+	}
+
+	s.mock.Ctx.Providers.OpenIDConnect, err = oidc.NewOpenIDConnectProvider(&schema.OpenIDConnectConfiguration{
+		IssuerPrivateKey:      string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(s.key)})),
+		AccessTokenLifespan:   "1h",
+		AuthorizeCodeLifespan: "1m",
+		IDTokenLifespan:       "1h",
+		RefreshTokenLifespan:  "90m",
+		Clients: []schema.OpenIDConnectClientConfiguration{
+			{
+				ID:            testOIDCClientID,
+				Description:   "My Application",
+				Secret:        testOIDCClientSecret,
+				Policy:        "two_factor",
+				RedirectURIs:  []string{testOIDCRedirectURI},
+				Scopes:        []string{"openid", "profile", "email", "groups", "offline_access"},
+				GrantTypes:    []string{"authorization_code", "refresh_token"},
+				ResponseTypes: []string{"code"},
+			},
+		},
+	})
+	s.Require().NoError(err)
+
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-Proto", "https")
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-Host", "auth.example.com")
+}
+
+func (s *HandlerOIDCSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *HandlerOIDCSuite) setAuthenticationLevel(level authentication.Level) {
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = testUsername
+	userSession.AuthenticationLevel = level
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+}
+
+func (s *HandlerOIDCSuite) setAuthorizationRequest(parameters map[string]string) {
+	query := url.Values{
+		"client_id":     []string{testOIDCClientID},
+		"redirect_uri":  []string{testOIDCRedirectURI},
+		"response_type": []string{"code"},
+		"scope":         []string{"openid profile"},
+		"state":         []string{testOIDCState},
+		"nonce":         []string{testOIDCNonce},
+	}
+
+	for key, value := range parameters {
+		if value == "" {
+			query.Del(key)
+		} else {
+			query.Set(key, value)
+		}
+	}
+
+	s.mock.Ctx.Request.SetRequestURI(oidc.AuthorizationPath + "?" + query.Encode())
+}
+
+func (s *HandlerOIDCSuite) assertRedirect() *url.URL {
+	s.Require().Equal(fasthttp.StatusFound, s.mock.Ctx.Response.StatusCode())
+
+	location, err := url.Parse(string(s.mock.Ctx.Response.Header.Peek("Location")))
+	s.Require().NoError(err)
+
+	return location
+}
+
+func (s *HandlerOIDCSuite) assertErrorResponse(statusCode int, errorCode string) {
+	s.Assert().Equal(statusCode, s.mock.Ctx.Response.StatusCode())
+
+	var response oidc.ErrorResponse
+
+	s.Require().NoError(json.Unmarshal(s.mock.Ctx.Response.Body(), &response))
+	s.Assert().Equal(errorCode, response.Error)
+}
+
+func (s *HandlerOIDCSuite) TestShouldServeWellKnownConfiguration() {
+	OpenIDConnectWellKnownConfigurationGet(s.mock.Ctx)
+
+	var wellKnown oidc.WellKnownConfiguration
+
+	s.Assert().Equal(fasthttp.StatusOK, s.mock.Ctx.Response.StatusCode())
+	s.Require().NoError(json.Unmarshal(s.mock.Ctx.Response.Body(), &wellKnown))
+	s.Assert().Equal(testOIDCIssuer, wellKnown.Issuer)
+	s.Assert().Equal("https://auth.example.com/api/oidc/token", wellKnown.TokenEndpoint)
+}
+
+func (s *HandlerOIDCSuite) TestShouldFailWellKnownConfigurationWithoutForwardedHeaders() {
+	s.mock.Ctx.Request.Header.Del("X-Forwarded-Host")
+
+	OpenIDConnectWellKnownConfigurationGet(s.mock.Ctx)
+
+	s.assertErrorResponse(fasthttp.StatusBadRequest, oidc.ErrorInvalidRequest)
+}
+
+func (s *HandlerOIDCSuite) TestShouldServeKeySet() {
+	OpenIDConnectJWKsGet(s.mock.Ctx)
+
+	var keySet oidc.JSONWebKeySet
+
+	s.Assert().Equal(fasthttp.StatusOK, s.mock.Ctx.Response.StatusCode())
+	s.Require().NoError(json.Unmarshal(s.mock.Ctx.Response.Body(), &keySet))
+	s.Assert().Len(keySet.Keys, 1)
+}
+
+func (s *HandlerOIDCSuite) TestShouldRejectAuthorizationOfUnknownClient() {
+	s.setAuthorizationRequest(map[string]string{"client_id": "unknown"})
+
+	OpenIDConnectAuthorizationGet(s.mock.Ctx)
+
+	s.assertErrorResponse(fasthttp.StatusBadRequest, oidc.ErrorInvalidClient)
+}
+
+func (s *HandlerOIDCSuite) TestShouldNotRedirectToUnregisteredRedirectURI() {
+	s.setAuthorizationRequest(map[string]string{"redirect_uri": "https://evil.example.com/callback"})
+
+	OpenIDConnectAuthorizationGet(s.mock.Ctx)
+
+	s.assertErrorResponse(fasthttp.StatusBadRequest, oidc.ErrorInvalidRequest)
+	s.Assert().Empty(s.mock.Ctx.Response.Header.Peek("Location"))
+}
+
+func (s *HandlerOIDCSuite) TestShouldRedirectErrorWhenScopeIsNotAllowed() {
+	s.setAuthorizationRequest(map[string]string{"scope": "openid admin"})
+
+	OpenIDConnectAuthorizationGet(s.mock.Ctx)
+
+	location := s.assertRedirect()
+	s.Assert().Equal("app.example.com", location.Host)
+	s.Assert().Equal(oidc.ErrorInvalidScope, location.Query().Get("error"))
+	s.Assert().Equal(testOIDCState, location.Query().Get("state"))
+}
+
+func (s *HandlerOIDCSuite) TestShouldRedirectErrorWhenCodeChallengeMethodIsNotSupported() {
+	s.setAuthorizationRequest(map[string]string{"code_challenge": "abc", "code_challenge_method": "S512"})
+
+	OpenIDConnectAuthorizationGet(s.mock.Ctx)
+
+	location := s.assertRedirect()
+	s.Assert().Equal(oidc.ErrorInvalidRequest, location.Query().Get("error"))
+}
+
+func (s *HandlerOIDCSuite) TestShouldRedirectUnauthenticatedUserToPortal() {
+	s.setAuthenticationLevel(authentication.OneFactor)
+	s.setAuthorizationRequest(nil)
+
+	OpenIDConnectAuthorizationGet(s.mock.Ctx)
+
+	location := s.assertRedirect()
+	s.Assert().Equal("auth.example.com", location.Host)
+	s.Assert().Equal("/", location.Path)
+
+	authURI, err := url.Parse(location.Query().Get("rd"))
+	s.Require().NoError(err)
+	s.Assert().Equal(oidc.AuthorizationPath, authURI.Path)
+	s.Assert().Equal(testOIDCClientID, authURI.Query().Get("client_id"))
+}
+
+func (s *HandlerOIDCSuite) TestShouldRedirectLoginRequiredWithPromptNone() {
+	s.setAuthorizationRequest(map[string]string{"prompt": "none"})
+
+	OpenIDConnectAuthorizationGet(s.mock.Ctx)
+
+	location := s.assertRedirect()
+	s.Assert().Equal("app.example.com", location.Host)
+	s.Assert().Equal(oidc.ErrorLoginRequired, location.Query().Get("error"))
+}
+
+func (s *HandlerOIDCSuite) TestShouldRedirectToConsentWhenNotGranted() {
+	s.setAuthenticationLevel(authentication.TwoFactor)
+	s.setAuthorizationRequest(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadOAuth2Consent(gomock.Eq(testUsername), gomock.Eq(testOIDCClientID)).
+		Return(nil, storage.ErrNoOAuth2Consent)
+
+	OpenIDConnectAuthorizationGet(s.mock.Ctx)
+
+	location := s.assertRedirect()
+	s.Assert().Equal("https://auth.example.com/consent", location.String())
+
+	workflow := s.mock.Ctx.GetSession().OIDCWorkflowSession
+	s.Require().NotNil(workflow)
+	s.Assert().Equal(testOIDCClientID, workflow.ClientID)
+	s.Assert().Equal([]string{"openid", "profile"}, workflow.RequestedScopes)
+	s.Assert().Equal(authentication.TwoFactor, workflow.RequiredAuthorizationLevel)
+}
+
+func (s *HandlerOIDCSuite) TestShouldRedirectConsentRequiredWithPromptNone() {
+	s.setAuthenticationLevel(authentication.TwoFactor)
+	s.setAuthorizationRequest(map[string]string{"prompt": "none", "scope": "openid email"})
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadOAuth2Consent(gomock.Eq(testUsername), gomock.Eq(testOIDCClientID)).
+		Return(&models.OAuth2Consent{Scopes: []string{"openid", "profile"}}, nil)
+
+	OpenIDConnectAuthorizationGet(s.mock.Ctx)
+
+	location := s.assertRedirect()
+	s.Assert().Equal(oidc.ErrorConsentRequired, location.Query().Get("error"))
+}
+
+func (s *HandlerOIDCSuite) TestShouldIssueAuthorizationCode() {
+	s.setAuthenticationLevel(authentication.TwoFactor)
+	s.setAuthorizationRequest(map[string]string{"code_challenge": s.codeChallenge(), "code_challenge_method": "S256"})
+
+	var saved models.OAuth2AuthorizationCode
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadOAuth2Consent(gomock.Eq(testUsername), gomock.Eq(testOIDCClientID)).
+		Return(&models.OAuth2Consent{Scopes: []string{"openid", "profile", "email"}}, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveOAuth2AuthorizationCode(gomock.Any()).
+		DoAndReturn(func(code models.OAuth2AuthorizationCode) error {
+			saved = code
+			return nil
+		})
+
+	OpenIDConnectAuthorizationGet(s.mock.Ctx)
+
+	location := s.assertRedirect()
+	s.Assert().Equal("app.example.com", location.Host)
+	s.Assert().Equal(testOIDCState, location.Query().Get("state"))
+
+	code := location.Query().Get("code")
+	s.Require().NotEmpty(code)
+
+	s.Assert().Equal(oidc.HashToken(code), saved.Signature)
+	s.Assert().Equal(testUsername, saved.Username)
+	s.Assert().Equal(testOIDCNonce, saved.Nonce)
+	s.Assert().Equal(testOIDCRedirectURI, saved.RedirectURI)
+	s.Assert().Equal([]string{"pwd", "mfa"}, saved.AMR)
+	s.Assert().Equal(s.mock.Clock.Now().Add(time.Minute), saved.ExpiresAt)
+}
+
+func (s *HandlerOIDCSuite) TestShouldReturnConsentWorkflow() {
+	s.setConsentWorkflow()
+
+	OpenIDConnectConsentGet(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), OIDCConsentGetResponseBody{
+		ClientID:          testOIDCClientID,
+		ClientDescription: "My Application",
+		Scopes: []OIDCConsentScope{
+			{Name: "openid", Description: oidcScopeDescriptions["openid"]},
+			{Name: "groups", Description: oidcScopeDescriptions["groups"]},
+		},
+	})
+}
+
+func (s *HandlerOIDCSuite) TestShouldForbidConsentWithoutWorkflow() {
+	s.setAuthenticationLevel(authentication.TwoFactor)
+
+	OpenIDConnectConsentGet(s.mock.Ctx)
+
+	s.Assert().Equal(fasthttp.StatusForbidden, s.mock.Ctx.Response.StatusCode())
+}
+
+func (s *HandlerOIDCSuite) TestShouldSaveAcceptedConsent() {
+	authURI := s.setConsentWorkflow()
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveOAuth2Consent(gomock.Eq(models.OAuth2Consent{
+			Username:  testUsername,
+			ClientID:  testOIDCClientID,
+			Scopes:    []string{"openid", "groups"},
+			GrantedAt: s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
+	s.setConsentDecision(OIDCConsentAccept)
+	OpenIDConnectConsentPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), redirectResponse{Redirect: authURI})
+	s.Assert().Nil(s.mock.Ctx.GetSession().OIDCWorkflowSession)
+}
+
+func (s *HandlerOIDCSuite) TestShouldRedirectRejectedConsentToClient() {
+	s.setConsentWorkflow()
+	s.setConsentDecision(OIDCConsentReject)
+
+	OpenIDConnectConsentPost(s.mock.Ctx)
+
+	var response redirectResponse
+
+	s.mock.GetResponseData(s.T(), &response)
+
+	location, err := url.Parse(response.Redirect)
+	s.Require().NoError(err)
+	s.Assert().Equal("app.example.com", location.Host)
+	s.Assert().Equal(oidc.ErrorAccessDenied, location.Query().Get("error"))
+	s.Assert().Equal(testOIDCState, location.Query().Get("state"))
+	s.Assert().Nil(s.mock.Ctx.GetSession().OIDCWorkflowSession)
+}
+
+func (s *HandlerOIDCSuite) TestShouldRedeemAuthorizationCode() {
+	code := "mycode"
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadOAuth2AuthorizationCode(gomock.Eq(oidc.HashToken(code))).
+		Return(&models.OAuth2AuthorizationCode{
+			ClientID:            testOIDCClientID,
+			Username:            testUsername,
+			Scopes:              []string{"openid", "profile", "offline_access"},
+			RedirectURI:         testOIDCRedirectURI,
+			Nonce:               testOIDCNonce,
+			CodeChallenge:       s.codeChallenge(),
+			CodeChallengeMethod: "S256",
+			AMR:                 []string{"pwd", "mfa"},
+			ExpiresAt:           s.mock.Clock.Now().Add(time.Minute),
+		}, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteOAuth2AuthorizationCode(gomock.Eq(oidc.HashToken(code))).
+		Return(nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveOAuth2RefreshToken(gomock.Any()).
+		Return(nil)
+
+	s.mock.UserProviderMock.EXPECT().
+		GetDetails(gomock.Eq(testUsername)).
+		Return(&authentication.UserDetails{Username: testUsername, DisplayName: "John Doe"}, nil)
+
+	s.mock.Ctx.Request.Header.Set(AuthorizationHeader, "Basic "+base64.StdEncoding.EncodeToString(
+		[]byte(testOIDCClientID+":"+url.QueryEscape(testOIDCClientSecret))))
+	s.setTokenRequest(url.Values{
+		"grant_type":    []string{"authorization_code"},
+		"code":          []string{code},
+		"redirect_uri":  []string{testOIDCRedirectURI},
+		"code_verifier": []string{testOIDCCodeVerifier},
+	})
+
+	OpenIDConnectTokenPost(s.mock.Ctx)
+
+	s.Require().Equal(fasthttp.StatusOK, s.mock.Ctx.Response.StatusCode())
+	s.Assert().Equal("no-store", string(s.mock.Ctx.Response.Header.Peek("Cache-Control")))
+
+	var response oidc.TokenResponse
+
+	s.Require().NoError(json.Unmarshal(s.mock.Ctx.Response.Body(), &response))
+	s.Assert().Equal("bearer", response.TokenType)
+	s.Assert().Equal(int64(3600), response.ExpiresIn)
+	s.Assert().NotEmpty(response.RefreshToken)
+
+	var claims oidc.IDTokenClaims
+
+	// The mock clock is in the past so the expiration is not verified here.
+	_, _, err := new(jwt.Parser).ParseUnverified(response.IDToken, &claims)
+	s.Require().NoError(err)
+	s.Assert().Equal(testUsername, claims.Subject)
+	s.Assert().Equal(testOIDCClientID, claims.Audience)
+	s.Assert().Equal(testOIDCNonce, claims.Nonce)
+	s.Assert().Equal("John Doe", claims.Name)
+	s.Assert().Equal(oidc.AccessTokenHash(response.AccessToken), claims.AccessTokenHash)
+}
+
+func (s *HandlerOIDCSuite) TestShouldRejectTokenRequestWithInvalidClientSecret() {
+	s.setTokenRequest(url.Values{
+		"grant_type":    []string{"authorization_code"},
+		"client_id":     []string{testOIDCClientID},
+		"client_secret": []string{"bad"},
+	})
+
+	OpenIDConnectTokenPost(s.mock.Ctx)
+
+	s.assertErrorResponse(fasthttp.StatusUnauthorized, oidc.ErrorInvalidClient)
+}
+
+func (s *HandlerOIDCSuite) TestShouldRejectUnsupportedGrantType() {
+	s.setTokenRequest(url.Values{
+		"grant_type":    []string{"password"},
+		"client_id":     []string{testOIDCClientID},
+		"client_secret": []string{testOIDCClientSecret},
+	})
+
+	OpenIDConnectTokenPost(s.mock.Ctx)
+
+	s.assertErrorResponse(fasthttp.StatusBadRequest, oidc.ErrorUnsupportedGrantType)
+}
+
+func (s *HandlerOIDCSuite) TestShouldRejectAuthorizationCodeWithWrongVerifier() {
+	code := "mycode"
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadOAuth2AuthorizationCode(gomock.Eq(oidc.HashToken(code))).
+		Return(&models.OAuth2AuthorizationCode{
+			ClientID:            testOIDCClientID,
+			Username:            testUsername,
+			Scopes:              []string{"openid"},
+			RedirectURI:         testOIDCRedirectURI,
+			CodeChallenge:       s.codeChallenge(),
+			CodeChallengeMethod: "S256",
+			ExpiresAt:           s.mock.Clock.Now().Add(time.Minute),
+		}, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteOAuth2AuthorizationCode(gomock.Eq(oidc.HashToken(code))).
+		Return(nil)
+
+	s.setTokenRequest(url.Values{
+		"grant_type":    []string{"authorization_code"},
+		"client_id":     []string{testOIDCClientID},
+		"client_secret": []string{testOIDCClientSecret},
+		"code":          []string{code},
+		"redirect_uri":  []string{testOIDCRedirectURI},
+		"code_verifier": []string{testOIDCCodeVerifier[1:] + "a"},
+	})
+
+	OpenIDConnectTokenPost(s.mock.Ctx)
+
+	s.assertErrorResponse(fasthttp.StatusBadRequest, oidc.ErrorInvalidGrant)
+}
+
+func (s *HandlerOIDCSuite) TestShouldRejectAlreadyRedeemedAuthorizationCode() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadOAuth2AuthorizationCode(gomock.Any()).
+		Return(nil, storage.ErrNoOAuth2AuthorizationCode)
+
+	s.setTokenRequest(url.Values{
+		"grant_type":    []string{"authorization_code"},
+		"client_id":     []string{testOIDCClientID},
+		"client_secret": []string{testOIDCClientSecret},
+		"code":          []string{"mycode"},
+	})
+
+	OpenIDConnectTokenPost(s.mock.Ctx)
+
+	s.assertErrorResponse(fasthttp.StatusBadRequest, oidc.ErrorInvalidGrant)
+}
+
+func (s *HandlerOIDCSuite) TestShouldRejectRefreshTokenWithBroaderScope() {
+	token := "mytoken"
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadOAuth2RefreshToken(gomock.Eq(oidc.HashToken(token))).
+		Return(&models.OAuth2RefreshToken{
+			ClientID:  testOIDCClientID,
+			Username:  testUsername,
+			Scopes:    []string{"openid", "offline_access"},
+			ExpiresAt: s.mock.Clock.Now().Add(time.Hour),
+		}, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteOAuth2RefreshToken(gomock.Eq(oidc.HashToken(token))).
+		Return(nil)
+
+	s.setTokenRequest(url.Values{
+		"grant_type":    []string{"refresh_token"},
+		"client_id":     []string{testOIDCClientID},
+		"client_secret": []string{testOIDCClientSecret},
+		"refresh_token": []string{token},
+		"scope":         []string{"openid email"},
+	})
+
+	OpenIDConnectTokenPost(s.mock.Ctx)
+
+	s.assertErrorResponse(fasthttp.StatusBadRequest, oidc.ErrorInvalidScope)
+}
+
+func (s *HandlerOIDCSuite) TestShouldReturnUserinfo() {
+	token, err := s.mock.Ctx.Providers.OpenIDConnect.SignToken(oidc.AccessTokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    testOIDCIssuer,
+			Subject:   testUsername,
+			ExpiresAt: time.Now().Add(time.Minute).Unix(),
+		},
+		ClientID: testOIDCClientID,
+		Scope:    "openid email",
+	})
+	s.Require().NoError(err)
+
+	s.mock.UserProviderMock.EXPECT().
+		GetDetails(gomock.Eq(testUsername)).
+		Return(&authentication.UserDetails{Username: testUsername, DisplayName: "John Doe", Emails: []string{"john@example.com"}}, nil)
+
+	s.mock.Ctx.Request.Header.Set(AuthorizationHeader, "Bearer "+token)
+
+	OpenIDConnectUserinfo(s.mock.Ctx)
+
+	var response oidc.UserinfoResponse
+
+	s.Require().Equal(fasthttp.StatusOK, s.mock.Ctx.Response.StatusCode())
+	s.Require().NoError(json.Unmarshal(s.mock.Ctx.Response.Body(), &response))
+	s.Assert().Equal(testUsername, response.Subject)
+	s.Assert().Equal("john@example.com", response.Email)
+	s.Assert().Empty(response.Name)
+}
+
+func (s *HandlerOIDCSuite) TestShouldRejectUserinfoWithoutBearerToken() {
+	OpenIDConnectUserinfo(s.mock.Ctx)
+
+	s.assertErrorResponse(fasthttp.StatusUnauthorized, oidc.ErrorInvalidToken)
+	s.Assert().Equal(`Bearer error="invalid_token"`, string(s.mock.Ctx.Response.Header.Peek("WWW-Authenticate")))
+}
+
+func (s *HandlerOIDCSuite) TestShouldNotRedirectAfterFirstFactorWhenClientRequiresTwoFactor() {
+	s.mock.Ctx.Configuration.Session.Domain = "example.com"
+
+	Handle1FAResponse(s.mock.Ctx, "https://bypass.example.com"+oidc.AuthorizationPath+"?client_id="+testOIDCClientID,
+		"GET", testUsername, nil)
+
+	s.mock.Assert200OK(s.T(), nil)
+}
+
+func (s *HandlerOIDCSuite) codeChallenge() string {
+	sum := sha256.Sum256([]byte(testOIDCCodeVerifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (s *HandlerOIDCSuite) setConsentWorkflow() string {
+	authURI := testOIDCIssuer + oidc.AuthorizationPath + "?" + url.Values{
+		"client_id":    []string{testOIDCClientID},
+		"redirect_uri": []string{testOIDCRedirectURI},
+		"state":        []string{testOIDCState},
+	}.Encode()
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = testUsername
+	userSession.AuthenticationLevel = authentication.TwoFactor
+	userSession.OIDCWorkflowSession = &session.OIDCWorkflowSession{
+		ClientID:                   testOIDCClientID,
+		RequestedScopes:            []string{"openid", "groups"},
+		AuthURI:                    authURI,
+		RequiredAuthorizationLevel: authentication.TwoFactor,
+	}
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	return authURI
+}
+
+func (s *HandlerOIDCSuite) setConsentDecision(decision string) {
+	bodyBytes, err := json.Marshal(oidcConsentPostRequestBody{
+		ClientID:       testOIDCClientID,
+		AcceptOrReject: decision,
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+}
+
+func (s *HandlerOIDCSuite) setTokenRequest(values url.Values) {
+	s.mock.Ctx.Request.Header.SetMethod("POST")
+	s.mock.Ctx.Request.Header.SetContentType("application/x-www-form-urlencoded")
+	s.mock.Ctx.Request.SetBodyString(values.Encode())
+}
+
+func TestRunHandlerOIDCSuite(t *testing.T) {
+	suite.Run(t, new(HandlerOIDCSuite))
+}
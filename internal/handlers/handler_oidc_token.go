@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/oidc"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// oidcGrant is what is granted to a client once an authorization code or a refresh token has been redeemed.
+type oidcGrant struct {
+	Username string
+	Scopes   []string
+	AMR      []string
+	Nonce    string
+}
+
+// OpenIDConnectTokenPost exchanges authorization codes and refresh tokens for access, ID and refresh tokens.
+func OpenIDConnectTokenPost(ctx *middlewares.AutheliaCtx) {
+	ctx.Response.Header.Set("Cache-Control", "no-store")
+	ctx.Response.Header.Set("Pragma", "no-cache")
+
+	issuer, err := ctx.ExternalRootURL()
+	if err != nil {
+		oidcRespondError(ctx, fasthttp.StatusBadRequest, oidc.ErrorInvalidRequest, oidcServerErrorMessage, err)
+		return
+	}
+
+	client, err := authenticateOIDCClient(ctx)
+	if err != nil {
+		ctx.Response.Header.Set("WWW-Authenticate", "Basic")
+		oidcRespondError(ctx, fasthttp.StatusUnauthorized, oidc.ErrorInvalidClient, "Client authentication failed.", err)
+
+		return
+	}
+
+	grantType := string(ctx.PostArgs().Peek("grant_type"))
+
+	switch grantType {
+	case oidc.GrantTypeAuthorizationCode, oidc.GrantTypeRefreshToken:
+	default:
+		oidcRespondError(ctx, fasthttp.StatusBadRequest, oidc.ErrorUnsupportedGrantType, "The grant type is not supported.",
+			fmt.Errorf("Client %s used grant type %s", client.ID, grantType))
+
+		return
+	}
+
+	if !client.IsGrantTypeAllowed(grantType) {
+		oidcRespondError(ctx, fasthttp.StatusBadRequest, oidc.ErrorUnauthorizedClient, "The client is not allowed to use this grant type.",
+			fmt.Errorf("Client %s is not allowed to use grant type %s", client.ID, grantType))
+
+		return
+	}
+
+	var grant *oidcGrant
+
+	if grantType == oidc.GrantTypeAuthorizationCode {
+		grant, err = redeemOIDCAuthorizationCode(ctx, client)
+	} else {
+		grant, err = redeemOIDCRefreshToken(ctx, client)
+	}
+
+	if err != nil {
+		errorCode := oidc.ErrorInvalidGrant
+		if err == errOIDCInvalidScope {
+			errorCode = oidc.ErrorInvalidScope
+		}
+
+		oidcRespondError(ctx, fasthttp.StatusBadRequest, errorCode, "The grant is invalid.",
+			fmt.Errorf("Client %s failed to redeem %s grant: %s", client.ID, grantType, err))
+
+		return
+	}
+
+	response, err := issueOIDCTokens(ctx, issuer, client, grant)
+	if err != nil {
+		oidcRespondError(ctx, fasthttp.StatusInternalServerError, oidc.ErrorServerError, oidcServerErrorMessage,
+			fmt.Errorf("Unable to issue tokens to client %s for user %s: %s", client.ID, grant.Username, err))
+
+		return
+	}
+
+	ctx.Logger.Debugf("Tokens issued to client %s for user %s with %s grant", client.ID, grant.Username, grantType)
+	oidcRespondJSON(ctx, fasthttp.StatusOK, response)
+}
+
+// authenticateOIDCClient authenticates the client with either the client_secret_basic or the client_secret_post method.
+// Public clients only have to provide their client_id.
+func authenticateOIDCClient(ctx *middlewares.AutheliaCtx) (*oidc.Client, error) {
+	var clientID, clientSecret string
+
+	if header := ctx.Request.Header.Peek(AuthorizationHeader); header != nil {
+		id, secret, err := parseBasicAuth(AuthorizationHeader, string(header))
+		if err != nil {
+			return nil, err
+		}
+
+		// Credentials are form-urlencoded before being placed in the header as described in RFC6749 section 2.3.1.
+		if clientID, err = url.QueryUnescape(id); err != nil {
+			return nil, err
+		}
+
+		if clientSecret, err = url.QueryUnescape(secret); err != nil {
+			return nil, err
+		}
+	} else {
+		clientID = string(ctx.PostArgs().Peek("client_id"))
+		clientSecret = string(ctx.PostArgs().Peek("client_secret"))
+	}
+
+	client, err := ctx.Providers.OpenIDConnect.GetClient(clientID)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to find client %s: %s", clientID, err)
+	}
+
+	if !client.ValidateSecret(clientSecret) {
+		return nil, fmt.Errorf("Invalid credentials for client %s", clientID)
+	}
+
+	return client, nil
+}
+
+func redeemOIDCAuthorizationCode(ctx *middlewares.AutheliaCtx, client *oidc.Client) (*oidcGrant, error) {
+	args := ctx.PostArgs()
+	signature := oidc.HashToken(string(args.Peek("code")))
+
+	code, err := ctx.Providers.StorageProvider.LoadOAuth2AuthorizationCode(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	// The code is burnt before being checked so that it can never be redeemed twice, even concurrently.
+	if err := ctx.Providers.StorageProvider.DeleteOAuth2AuthorizationCode(signature); err != nil {
+		return nil, err
+	}
+
+	if code.ClientID != client.ID {
+		return nil, fmt.Errorf("authorization code was issued to client %s", code.ClientID)
+	}
+
+	if ctx.Clock.Now().After(code.ExpiresAt) {
+		return nil, fmt.Errorf("authorization code expired at %s", code.ExpiresAt)
+	}
+
+	if code.RedirectURI != string(args.Peek("redirect_uri")) {
+		return nil, fmt.Errorf("redirect_uri does not match the one of the authorization request")
+	}
+
+	if !oidc.VerifyCodeChallenge(code.CodeChallenge, code.CodeChallengeMethod, string(args.Peek("code_verifier"))) {
+		return nil, fmt.Errorf("code_verifier does not match the code_challenge")
+	}
+
+	return &oidcGrant{Username: code.Username, Scopes: code.Scopes, AMR: code.AMR, Nonce: code.Nonce}, nil
+}
+
+func redeemOIDCRefreshToken(ctx *middlewares.AutheliaCtx, client *oidc.Client) (*oidcGrant, error) {
+	args := ctx.PostArgs()
+	signature := oidc.HashToken(string(args.Peek("refresh_token")))
+
+	token, err := ctx.Providers.StorageProvider.LoadOAuth2RefreshToken(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	// Refresh tokens are rotated, a new one is issued with the tokens.
+	if err := ctx.Providers.StorageProvider.DeleteOAuth2RefreshToken(signature); err != nil {
+		return nil, err
+	}
+
+	if token.ClientID != client.ID {
+		return nil, fmt.Errorf("refresh token was issued to client %s", token.ClientID)
+	}
+
+	if ctx.Clock.Now().After(token.ExpiresAt) {
+		return nil, fmt.Errorf("refresh token expired at %s", token.ExpiresAt)
+	}
+
+	scopes := token.Scopes
+
+	if scope := string(args.Peek("scope")); scope != "" {
+		scopes = strings.Fields(scope)
+
+		if !oidc.IsScopeSubset(scopes, token.Scopes) {
+			return nil, errOIDCInvalidScope
+		}
+	}
+
+	return &oidcGrant{Username: token.Username, Scopes: scopes, AMR: token.AMR}, nil
+}
+
+func issueOIDCTokens(ctx *middlewares.AutheliaCtx, issuer string, client *oidc.Client, grant *oidcGrant) (*oidc.TokenResponse, error) {
+	provider := ctx.Providers.OpenIDConnect
+	now := ctx.Clock.Now()
+
+	details, err := ctx.Providers.UserProvider.GetDetails(grant.Username)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := strings.Join(grant.Scopes, " ")
+
+	accessToken, err := provider.SignToken(oidc.AccessTokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    issuer,
+			Subject:   grant.Username,
+			Audience:  client.ID,
+			ExpiresAt: now.Add(provider.AccessTokenLifespan).Unix(),
+			IssuedAt:  now.Unix(),
+		},
+		ClientID: client.ID,
+		Scope:    scope,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &oidc.TokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "bearer",
+		ExpiresIn:   int64(provider.AccessTokenLifespan.Seconds()),
+		Scope:       scope,
+	}
+
+	if utils.IsStringInSlice(oidc.ScopeOpenID, grant.Scopes) {
+		response.IDToken, err = provider.SignToken(oidc.IDTokenClaims{
+			StandardClaims: jwt.StandardClaims{
+				Issuer:    issuer,
+				Subject:   grant.Username,
+				Audience:  client.ID,
+				ExpiresAt: now.Add(provider.IDTokenLifespan).Unix(),
+				IssuedAt:  now.Unix(),
+			},
+			UserinfoClaims:  oidc.NewUserinfoClaims(details, grant.Scopes),
+			Nonce:           grant.Nonce,
+			AMR:             grant.AMR,
+			AccessTokenHash: oidc.AccessTokenHash(accessToken),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if utils.IsStringInSlice(oidc.ScopeOfflineAccess, grant.Scopes) && client.IsGrantTypeAllowed(oidc.GrantTypeRefreshToken) {
+		refreshToken, err := oidc.GenerateToken()
+		if err != nil {
+			return nil, err
+		}
+
+		err = ctx.Providers.StorageProvider.SaveOAuth2RefreshToken(models.OAuth2RefreshToken{
+			Signature: oidc.HashToken(refreshToken),
+			ClientID:  client.ID,
+			Username:  grant.Username,
+			Scopes:    grant.Scopes,
+			AMR:       grant.AMR,
+			ExpiresAt: now.Add(provider.RefreshTokenLifespan),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		response.RefreshToken = refreshToken
+	}
+
+	return response, nil
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/oidc"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+const bearerPrefix = "Bearer "
+
+// OpenIDConnectUserinfo returns the claims about the user the access token was issued for.
+func OpenIDConnectUserinfo(ctx *middlewares.AutheliaCtx) {
+	issuer, err := ctx.ExternalRootURL()
+	if err != nil {
+		oidcRespondError(ctx, fasthttp.StatusBadRequest, oidc.ErrorInvalidRequest, oidcServerErrorMessage, err)
+		return
+	}
+
+	header := string(ctx.Request.Header.Peek(AuthorizationHeader))
+	if !strings.HasPrefix(header, bearerPrefix) {
+		oidcRespondBearerError(ctx, fasthttp.StatusUnauthorized, oidc.ErrorInvalidToken,
+			fmt.Errorf("%s prefix not found in %s header", strings.Trim(bearerPrefix, " "), AuthorizationHeader))
+
+		return
+	}
+
+	claims, err := ctx.Providers.OpenIDConnect.ParseAccessToken(header[len(bearerPrefix):], issuer)
+	if err != nil {
+		oidcRespondBearerError(ctx, fasthttp.StatusUnauthorized, oidc.ErrorInvalidToken, err)
+		return
+	}
+
+	scopes := strings.Fields(claims.Scope)
+	if !utils.IsStringInSlice(oidc.ScopeOpenID, scopes) {
+		oidcRespondBearerError(ctx, fasthttp.StatusForbidden, oidc.ErrorInsufficientScope,
+			fmt.Errorf("Access token of client %s was not granted the openid scope", claims.ClientID))
+
+		return
+	}
+
+	details, err := ctx.Providers.UserProvider.GetDetails(claims.Subject)
+	if err != nil {
+		oidcRespondBearerError(ctx, fasthttp.StatusUnauthorized, oidc.ErrorInvalidToken,
+			fmt.Errorf("Unable to retrieve details of user %s: %s", claims.Subject, err))
+
+		return
+	}
+
+	oidcRespondJSON(ctx, fasthttp.StatusOK, oidc.UserinfoResponse{
+		Subject:        claims.Subject,
+		UserinfoClaims: oidc.NewUserinfoClaims(details, scopes),
+	})
+}
+
+// oidcRespondBearerError replies to a request authenticated with a bearer token as described in RFC6750 section 3.
+func oidcRespondBearerError(ctx *middlewares.AutheliaCtx, statusCode int, errorCode string, err error) {
+	ctx.Response.Header.Set("WWW-Authenticate", fmt.Sprintf("Bearer error=%q", errorCode))
+	oidcRespondError(ctx, statusCode, errorCode, "", err)
+}
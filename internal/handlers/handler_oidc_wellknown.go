@@ -0,0 +1,24 @@
+package handlers
+
+import (
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/oidc"
+)
+
+// OpenIDConnectWellKnownConfigurationGet serves the OpenID Connect discovery document.
+func OpenIDConnectWellKnownConfigurationGet(ctx *middlewares.AutheliaCtx) {
+	issuer, err := ctx.ExternalRootURL()
+	if err != nil {
+		oidcRespondError(ctx, fasthttp.StatusBadRequest, oidc.ErrorInvalidRequest, oidcServerErrorMessage, err)
+		return
+	}
+
+	oidcRespondJSON(ctx, fasthttp.StatusOK, ctx.Providers.OpenIDConnect.WellKnownConfiguration(issuer))
+}
+
+// OpenIDConnectJWKsGet serves the keys used to verify the tokens issued by the OpenID Connect provider.
+func OpenIDConnectJWKsGet(ctx *middlewares.AutheliaCtx) {
+	oidcRespondJSON(ctx, fasthttp.StatusOK, ctx.Providers.OpenIDConnect.KeySet())
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/middlewares"
+)
+
+// PasswordPolicyBody the content returned by the password policy endpoint.
+type PasswordPolicyBody struct {
+	MinLength        int  `json:"min_length"`
+	MaxLength        int  `json:"max_length"`
+	RequireUppercase bool `json:"require_uppercase"`
+	RequireLowercase bool `json:"require_lowercase"`
+	RequireNumber    bool `json:"require_number"`
+	RequireSpecial   bool `json:"require_special"`
+	MinScore         int  `json:"min_score"`
+}
+
+// PasswordPolicyViolationsBody the data of the error returned when a password does not follow the password policy.
+type PasswordPolicyViolationsBody struct {
+	Violations []authentication.PasswordPolicyViolation `json:"violations"`
+}
+
+// PasswordPolicyGet get the rules the passwords chosen by the users must follow.
+func PasswordPolicyGet(ctx *middlewares.AutheliaCtx) {
+	policy := ctx.Providers.PasswordPolicy.Configuration()
+
+	err := ctx.SetJSONBody(PasswordPolicyBody{
+		MinLength:        policy.MinLength,
+		MaxLength:        policy.MaxLength,
+		RequireUppercase: policy.RequireUppercase,
+		RequireLowercase: policy.RequireLowercase,
+		RequireNumber:    policy.RequireNumber,
+		RequireSpecial:   policy.RequireSpecial,
+		MinScore:         policy.MinScore,
+	})
+	if err != nil {
+		ctx.Logger.Errorf("Unable to set password policy response in body: %s", err)
+	}
+}
+
+// checkPasswordPolicy checks the password chosen by a user against the password policy and replies with the rules it
+// does not follow, if any. It returns whether the password follows the policy.
+func checkPasswordPolicy(ctx *middlewares.AutheliaCtx, username, password string) bool {
+	err := ctx.Providers.PasswordPolicy.Check(password, username)
+	if err == nil {
+		return true
+	}
+
+	var policyErr *authentication.PasswordPolicyError
+
+	if errors.As(err, &policyErr) {
+		ctx.ErrorWithData(err, passwordPolicyViolationMessage, PasswordPolicyViolationsBody{Violations: policyErr.Violations})
+	} else {
+		ctx.Error(err, operationFailedMessage)
+	}
+
+	return false
+}
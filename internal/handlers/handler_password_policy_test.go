@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+)
+
+type HandlerPasswordPolicySuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *HandlerPasswordPolicySuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Providers.PasswordPolicy = authentication.NewPasswordPolicy(schema.PasswordPolicyConfiguration{
+		MinLength:        8,
+		MaxLength:        64,
+		RequireUppercase: true,
+		RequireNumber:    true,
+		MinScore:         2,
+	})
+
+	username := testUsername
+	userSession := s.mock.Ctx.GetSession()
+	userSession.PasswordResetUsername = &username
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+}
+
+func (s *HandlerPasswordPolicySuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *HandlerPasswordPolicySuite) TestShouldServePasswordPolicy() {
+	PasswordPolicyGet(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), PasswordPolicyBody{
+		MinLength:        8,
+		MaxLength:        64,
+		RequireUppercase: true,
+		RequireNumber:    true,
+		MinScore:         2,
+	})
+}
+
+func (s *HandlerPasswordPolicySuite) TestShouldRefuseToResetPasswordNotFollowingPolicy() {
+	s.mock.Ctx.Request.SetBodyString(`{"password":"abc"}`)
+
+	ResetPasswordPost(s.mock.Ctx)
+
+	var response struct {
+		Status  string                       `json:"status"`
+		Message string                       `json:"message"`
+		Data    PasswordPolicyViolationsBody `json:"data"`
+	}
+
+	s.Require().NoError(json.Unmarshal(s.mock.Ctx.Response.Body(), &response))
+	s.Assert().Equal("KO", response.Status)
+	s.Assert().Equal(passwordPolicyViolationMessage, response.Message)
+	s.Assert().Equal([]authentication.PasswordPolicyViolation{
+		{Rule: authentication.PasswordPolicyRuleMinLength, Message: "The password must be at least 8 characters long"},
+		{Rule: authentication.PasswordPolicyRuleUppercase, Message: "The password must contain an uppercase letter"},
+		{Rule: authentication.PasswordPolicyRuleNumber, Message: "The password must contain a number"},
+		{Rule: authentication.PasswordPolicyRuleMinScore, Message: "The password is too easy to guess"},
+	}, response.Data.Violations)
+
+	s.Assert().Equal(testUsername, *s.mock.Ctx.GetSession().PasswordResetUsername)
+}
+
+func (s *HandlerPasswordPolicySuite) TestShouldResetPasswordFollowingPolicy() {
+	s.mock.Ctx.Request.SetBodyString(`{"password":"Correct horse battery staple 4"}`)
+
+	s.mock.UserProviderMock.EXPECT().
+		UpdatePassword(testUsername, "Correct horse battery staple 4").
+		Return(nil)
+
+	ResetPasswordPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Nil(s.mock.Ctx.GetSession().PasswordResetUsername)
+}
+
+func TestRunHandlerPasswordPolicySuite(t *testing.T) {
+	suite.Run(t, new(HandlerPasswordPolicySuite))
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/authelia/authelia/internal/audit"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+)
+
+// SecondFactorRecoveryCodesIdentityStart the handler for initiating the identity validation.
+var SecondFactorRecoveryCodesIdentityStart = middlewares.IdentityVerificationStart(middlewares.IdentityVerificationStartArgs{
+	MailTitle:             "Generate your recovery codes",
+	MailButtonContent:     "Generate",
+	TargetEndpoint:        "/recovery-codes/generate",
+	ActionClaim:           RecoveryCodesGenerationAction,
+	IdentityRetrieverFunc: identityRetrieverFromSession,
+})
+
+func secondFactorRecoveryCodesIdentityFinish(ctx *middlewares.AutheliaCtx, username string) {
+	codes, err := generateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to generate recovery codes: %s", err), unableToGenerateRecoveryCodesMessage)
+		return
+	}
+
+	now := ctx.Clock.Now()
+	hashed := make([]models.RecoveryCode, 0, len(codes))
+
+	for _, code := range codes {
+		hash, err := hashRecoveryCode(code)
+		if err != nil {
+			ctx.Error(fmt.Errorf("Unable to hash recovery code: %s", err), unableToGenerateRecoveryCodesMessage)
+			return
+		}
+
+		hashed = append(hashed, models.RecoveryCode{Username: username, Lookup: recoveryCodeLookup(code), Hash: hash, CreatedAt: now})
+	}
+
+	ctx.Logger.Debugf("Generate recovery codes for user %s", username)
+
+	// The codes generated previously are replaced so that only the last set displayed to the user is valid.
+	err = ctx.Providers.StorageProvider.SaveRecoveryCodes(username, hashed)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to save recovery codes in DB: %s", err), unableToGenerateRecoveryCodesMessage)
+		return
+	}
+
+	auditSuccess(ctx, audit.EventDeviceRegistration, username, recoveryCodeMethod)
+
+	err = ctx.SetJSONBody(recoveryCodesResponse{Codes: codes})
+	if err != nil {
+		ctx.Logger.Errorf("Unable to set recovery codes response in body: %s", err)
+	}
+}
+
+// SecondFactorRecoveryCodesIdentityFinish the handler for finishing the identity validation.
+var SecondFactorRecoveryCodesIdentityFinish = middlewares.IdentityVerificationFinish(
+	middlewares.IdentityVerificationFinishArgs{
+		ActionClaim:          RecoveryCodesGenerationAction,
+		IsTokenUserValidFunc: isTokenUserValidFor2FARegistration,
+	}, secondFactorRecoveryCodesIdentityFinish)
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+)
+
+type HandlerRegisterRecoveryCodesSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *HandlerRegisterRecoveryCodesSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Clock = &s.mock.Clock
+}
+
+func (s *HandlerRegisterRecoveryCodesSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *HandlerRegisterRecoveryCodesSuite) TestShouldGenerateAndStoreHashedCodes() {
+	var saved []models.RecoveryCode
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveRecoveryCodes(gomock.Eq(testUsername), gomock.Any()).
+		DoAndReturn(func(_ string, codes []models.RecoveryCode) error {
+			saved = codes
+			return nil
+		})
+
+	secondFactorRecoveryCodesIdentityFinish(s.mock.Ctx, testUsername)
+
+	response := recoveryCodesResponse{}
+	s.mock.GetResponseData(s.T(), &response)
+
+	s.Require().Len(response.Codes, recoveryCodesCount)
+	s.Require().Len(saved, recoveryCodesCount)
+
+	format := regexp.MustCompile("^[a-z2-9]{5}-[a-z2-9]{5}$")
+	lookups := map[string]bool{}
+
+	for i, code := range response.Codes {
+		assert.Regexp(s.T(), format, code)
+		assert.Equal(s.T(), testUsername, saved[i].Username)
+		assert.Equal(s.T(), code[:recoveryCodeLookupLength], saved[i].Lookup)
+		assert.False(s.T(), lookups[saved[i].Lookup], "lookup %s is not unique", saved[i].Lookup)
+		lookups[saved[i].Lookup] = true
+		assert.Equal(s.T(), s.mock.Clock.Now(), saved[i].CreatedAt)
+		assert.NotContains(s.T(), saved[i].Hash, code)
+
+		valid, err := checkRecoveryCode(code, saved[i].Hash)
+		s.Require().NoError(err)
+		assert.True(s.T(), valid)
+	}
+}
+
+func (s *HandlerRegisterRecoveryCodesSuite) TestShouldFailWhenCodesCannotBeSaved() {
+	s.mock.StorageProviderMock.EXPECT().
+		SaveRecoveryCodes(gomock.Eq(testUsername), gomock.Any()).
+		Return(fmt.Errorf("failure"))
+
+	secondFactorRecoveryCodesIdentityFinish(s.mock.Ctx, testUsername)
+
+	s.mock.Assert200KO(s.T(), unableToGenerateRecoveryCodesMessage)
+	assert.Equal(s.T(), "Unable to save recovery codes in DB: failure", s.mock.Hook.LastEntry().Message)
+}
+
+func TestRunHandlerRegisterRecoveryCodesSuite(t *testing.T) {
+	suite.Run(t, new(HandlerRegisterRecoveryCodesSuite))
+}
@@ -1,12 +1,18 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
 
+	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
 
+	"github.com/authelia/authelia/internal/audit"
+	"github.com/authelia/authelia/internal/authentication"
 	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
 	"github.com/authelia/authelia/internal/session"
+	"github.com/authelia/authelia/internal/utils"
 )
 
 // identityRetrieverFromSession retriever computing the identity from the cookie session.
@@ -37,11 +43,31 @@ var SecondFactorTOTPIdentityStart = middlewares.IdentityVerificationStart(middle
 })
 
 func secondFactorTOTPIdentityFinish(ctx *middlewares.AutheliaCtx, username string) {
+	var requestBody registerTOTPRequestBody
+
+	// The body has already been parsed by the identity verification middleware, the description is optional.
+	if err := json.Unmarshal(ctx.PostBody(), &requestBody); err != nil {
+		ctx.Error(fmt.Errorf("Unable to parse TOTP registration request: %s", err), unableToRegisterOneTimePasswordMessage)
+		return
+	}
+
+	description := requestBody.Description
+	if description == "" {
+		description = totpDefaultDeviceDescription
+	}
+
+	if len(description) > totpDeviceDescriptionMaxLength {
+		ctx.Error(fmt.Errorf("TOTP device description is longer than %d characters", totpDeviceDescriptionMaxLength), unableToRegisterOneTimePasswordMessage)
+		return
+	}
+
 	key, err := totp.Generate(totp.GenerateOpts{
 		Issuer:      ctx.Configuration.TOTP.Issuer,
 		AccountName: username,
 		SecretSize:  32,
 		Period:      uint(ctx.Configuration.TOTP.Period),
+		Digits:      otp.Digits(ctx.Configuration.TOTP.Digits),
+		Algorithm:   utils.OTPAlgorithm(ctx.Configuration.TOTP.Algorithm),
 	})
 
 	if err != nil {
@@ -49,12 +75,25 @@ func secondFactorTOTPIdentityFinish(ctx *middlewares.AutheliaCtx, username strin
 		return
 	}
 
-	err = ctx.Providers.StorageProvider.SaveTOTPSecret(username, key.Secret())
+	ctx.Logger.Debugf("Register TOTP device %s for user %s", description, username)
+
+	err = ctx.Providers.StorageProvider.SaveTOTPDevice(models.TOTPDevice{
+		Username:    username,
+		ID:          utils.RandomString(totpDeviceIDLength, utils.AlphaNumericCharacters),
+		Description: description,
+		Secret:      key.Secret(),
+		Algorithm:   ctx.Configuration.TOTP.Algorithm,
+		Digits:      uint(ctx.Configuration.TOTP.Digits),
+		Period:      uint(ctx.Configuration.TOTP.Period),
+		CreatedAt:   ctx.Clock.Now(),
+	})
 	if err != nil {
 		ctx.Error(fmt.Errorf("Unable to save TOTP secret in DB: %s", err), unableToRegisterOneTimePasswordMessage)
 		return
 	}
 
+	auditSuccess(ctx, audit.EventDeviceRegistration, username, authentication.TOTP)
+
 	response := TOTPKeyResponse{
 		OTPAuthURL:   key.URL(),
 		Base32Secret: key.Secret(),
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+)
+
+type HandlerRegisterTOTPSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *HandlerRegisterTOTPSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Clock = &s.mock.Clock
+	s.mock.Ctx.Configuration.TOTP = &schema.TOTPConfiguration{Issuer: "Authelia", Algorithm: "SHA256", Digits: 8, Period: 60}
+}
+
+func (s *HandlerRegisterTOTPSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *HandlerRegisterTOTPSuite) TestShouldRegisterNamedDevice() {
+	var saved models.TOTPDevice
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveTOTPDevice(gomock.Any()).
+		DoAndReturn(func(device models.TOTPDevice) error {
+			saved = device
+			return nil
+		})
+
+	s.mock.Ctx.Request.SetBodyString(`{"token":"abc","description":"Tablet"}`)
+	secondFactorTOTPIdentityFinish(s.mock.Ctx, testUsername)
+
+	response := TOTPKeyResponse{}
+	s.mock.GetResponseData(s.T(), &response)
+
+	assert.Equal(s.T(), testUsername, saved.Username)
+	assert.Equal(s.T(), "Tablet", saved.Description)
+	assert.Len(s.T(), saved.ID, totpDeviceIDLength)
+	assert.Equal(s.T(), response.Base32Secret, saved.Secret)
+	assert.Equal(s.T(), "SHA256", saved.Algorithm)
+	assert.Equal(s.T(), uint(8), saved.Digits)
+	assert.Equal(s.T(), uint(60), saved.Period)
+	assert.Equal(s.T(), s.mock.Clock.Now(), saved.CreatedAt)
+	assert.True(s.T(), saved.LastUsedAt.IsZero())
+
+	otpauthURL, err := url.Parse(response.OTPAuthURL)
+	s.Require().NoError(err)
+	assert.Equal(s.T(), "SHA256", otpauthURL.Query().Get("algorithm"))
+	assert.Equal(s.T(), "8", otpauthURL.Query().Get("digits"))
+	assert.Equal(s.T(), "60", otpauthURL.Query().Get("period"))
+}
+
+func (s *HandlerRegisterTOTPSuite) TestShouldGiveDefaultDescription() {
+	s.mock.StorageProviderMock.EXPECT().
+		SaveTOTPDevice(gomock.Any()).
+		DoAndReturn(func(device models.TOTPDevice) error {
+			assert.Equal(s.T(), totpDefaultDeviceDescription, device.Description)
+			return nil
+		})
+
+	s.mock.Ctx.Request.SetBodyString(`{"token":"abc"}`)
+	secondFactorTOTPIdentityFinish(s.mock.Ctx, testUsername)
+}
+
+func (s *HandlerRegisterTOTPSuite) TestShouldFailWhenDescriptionIsTooLong() {
+	s.mock.Ctx.Request.SetBodyString(`{"token":"abc","description":"` + strings.Repeat("a", 65) + `"}`)
+	secondFactorTOTPIdentityFinish(s.mock.Ctx, testUsername)
+
+	s.mock.Assert200KO(s.T(), unableToRegisterOneTimePasswordMessage)
+	assert.Equal(s.T(), "TOTP device description is longer than 64 characters", s.mock.Hook.LastEntry().Message)
+}
+
+func TestRunHandlerRegisterTOTPSuite(t *testing.T) {
+	suite.Run(t, new(HandlerRegisterTOTPSuite))
+}
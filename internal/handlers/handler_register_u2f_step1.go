@@ -6,6 +6,7 @@ import (
 	"github.com/tstranex/u2f"
 
 	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/storage"
 )
 
 var u2fConfig = &u2f.Config{
@@ -56,7 +57,14 @@ func secondFactorU2FIdentityFinish(ctx *middlewares.AutheliaCtx, username string
 		return
 	}
 
-	err = ctx.SetJSONBody(u2f.NewWebRegisterRequest(challenge, []u2f.Registration{}))
+	devices, err := ctx.Providers.StorageProvider.LoadU2FDevicesByUsername(username)
+	if err != nil && err != storage.ErrNoU2FDeviceHandle {
+		ctx.Error(fmt.Errorf("Unable to load U2F devices of user %s: %s", username, err), operationFailedMessage)
+		return
+	}
+
+	// The devices already registered are sent so that the same device is not registered twice.
+	err = ctx.SetJSONBody(u2f.NewWebRegisterRequest(challenge, newU2FRegistrations(devices)))
 	if err != nil {
 		ctx.Logger.Errorf("Unable to create request to enrol new token: %s", err)
 	}
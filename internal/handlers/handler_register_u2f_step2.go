@@ -6,17 +6,31 @@ import (
 
 	"github.com/tstranex/u2f"
 
+	"github.com/authelia/authelia/internal/audit"
+	"github.com/authelia/authelia/internal/authentication"
 	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
 )
 
 // SecondFactorU2FRegister handler validating the client has successfully validated the challenge
 // to complete the U2F registration.
 func SecondFactorU2FRegister(ctx *middlewares.AutheliaCtx) {
-	responseBody := u2f.RegisterResponse{}
-	err := ctx.ParseBody(&responseBody)
+	requestBody := registerU2FRequestBody{}
+	err := ctx.ParseBody(&requestBody)
 
 	if err != nil {
 		ctx.Error(fmt.Errorf("Unable to parse response body: %v", err), unableToRegisterSecurityKeyMessage)
+		return
+	}
+
+	description := requestBody.Description
+	if description == "" {
+		description = u2fDefaultDeviceDescription
+	}
+
+	if len(description) > u2fDeviceDescriptionMaxLength {
+		ctx.Error(fmt.Errorf("U2F device description is longer than %d characters", u2fDeviceDescriptionMaxLength), unableToRegisterSecurityKeyMessage)
+		return
 	}
 
 	userSession := ctx.GetSession()
@@ -35,22 +49,29 @@ func SecondFactorU2FRegister(ctx *middlewares.AutheliaCtx) {
 		}
 	}()
 
-	registration, err := u2f.Register(responseBody, *userSession.U2FChallenge, u2fConfig)
+	registration, err := u2f.Register(requestBody.RegisterResponse, *userSession.U2FChallenge, u2fConfig)
 
 	if err != nil {
 		ctx.Error(fmt.Errorf("Unable to verify U2F registration: %v", err), unableToRegisterSecurityKeyMessage)
 		return
 	}
 
-	ctx.Logger.Debugf("Register U2F device for user %s", userSession.Username)
+	ctx.Logger.Debugf("Register U2F device %s for user %s", description, userSession.Username)
 
-	publicKey := elliptic.Marshal(elliptic.P256(), registration.PubKey.X, registration.PubKey.Y)
-	err = ctx.Providers.StorageProvider.SaveU2FDeviceHandle(userSession.Username, registration.KeyHandle, publicKey)
+	err = ctx.Providers.StorageProvider.SaveU2FDevice(models.U2FDevice{
+		Username:    userSession.Username,
+		Description: description,
+		KeyHandle:   registration.KeyHandle,
+		PublicKey:   elliptic.Marshal(elliptic.P256(), registration.PubKey.X, registration.PubKey.Y),
+		CreatedAt:   ctx.Clock.Now(),
+	})
 
 	if err != nil {
 		ctx.Error(fmt.Errorf("Unable to register U2F device for user %s: %v", userSession.Username, err), unableToRegisterSecurityKeyMessage)
 		return
 	}
 
+	auditSuccess(ctx, audit.EventDeviceRegistration, userSession.Username, authentication.U2F)
+
 	ctx.ReplyOK()
 }
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/mocks"
+)
+
+type HandlerRegisterU2FStep2Suite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *HandlerRegisterU2FStep2Suite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = testUsername
+	err := s.mock.Ctx.SaveSession(userSession)
+	s.Require().NoError(err)
+}
+
+func (s *HandlerRegisterU2FStep2Suite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *HandlerRegisterU2FStep2Suite) TestShouldFailWhenDescriptionIsTooLong() {
+	s.mock.Ctx.Request.SetBodyString(`{"description":"` + strings.Repeat("a", 65) + `"}`)
+
+	SecondFactorU2FRegister(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), unableToRegisterSecurityKeyMessage)
+	assert.Equal(s.T(), "U2F device description is longer than 64 characters", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerRegisterU2FStep2Suite) TestShouldFailWhenRegistrationHasNotBeenInitiated() {
+	s.mock.Ctx.Request.SetBodyString(`{"description":"Backup"}`)
+
+	SecondFactorU2FRegister(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), unableToRegisterSecurityKeyMessage)
+	assert.Equal(s.T(), "U2F registration has not been initiated yet", s.mock.Hook.LastEntry().Message)
+}
+
+func TestRunHandlerRegisterU2FStep2Suite(t *testing.T) {
+	suite.Run(t, new(HandlerRegisterU2FStep2Suite))
+}
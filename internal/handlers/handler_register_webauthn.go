@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/duo-labs/webauthn/protocol"
+	"github.com/duo-labs/webauthn/webauthn"
+
+	"github.com/authelia/authelia/internal/audit"
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+)
+
+// SecondFactorWebauthnIdentityStart the handler for initiating the identity validation.
+var SecondFactorWebauthnIdentityStart = middlewares.IdentityVerificationStart(middlewares.IdentityVerificationStartArgs{
+	MailTitle:             "Register your key",
+	MailButtonContent:     "Register",
+	TargetEndpoint:        "/webauthn/register",
+	ActionClaim:           WebauthnRegistrationAction,
+	IdentityRetrieverFunc: identityRetrieverFromSession,
+})
+
+func secondFactorWebauthnIdentityFinish(ctx *middlewares.AutheliaCtx, username string) {
+	w, err := newWebauthn(ctx)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to configure Webauthn: %s", err), operationFailedMessage)
+		return
+	}
+
+	userSession := ctx.GetSession()
+
+	user, err := getWebauthnUser(ctx, userSession)
+	if err != nil {
+		ctx.Error(err, operationFailedMessage)
+		return
+	}
+
+	// The devices already registered are excluded so that the same authenticator is not registered twice.
+	creation, data, err := w.BeginRegistration(user,
+		webauthn.WithExclusions(user.CredentialDescriptors()),
+		webauthn.WithAuthenticatorSelection(w.Config.AuthenticatorSelection))
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to generate Webauthn challenge for registration: %s", err), operationFailedMessage)
+		return
+	}
+
+	userSession.Webauthn = data
+
+	if err = ctx.SaveSession(userSession); err != nil {
+		ctx.Error(fmt.Errorf("Unable to save Webauthn challenge in session: %s", err), operationFailedMessage)
+		return
+	}
+
+	if err = ctx.SetJSONBody(creation); err != nil {
+		ctx.Logger.Errorf("Unable to create request to enrol new Webauthn device: %s", err)
+	}
+}
+
+// SecondFactorWebauthnIdentityFinish the handler for finishing the identity validation.
+var SecondFactorWebauthnIdentityFinish = middlewares.IdentityVerificationFinish(
+	middlewares.IdentityVerificationFinishArgs{
+		ActionClaim:          WebauthnRegistrationAction,
+		IsTokenUserValidFunc: isTokenUserValidFor2FARegistration,
+	}, secondFactorWebauthnIdentityFinish)
+
+// SecondFactorWebauthnAttestationPost handler validating the attestation produced by the authenticator
+// to complete the Webauthn registration.
+func SecondFactorWebauthnAttestationPost(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+
+	if userSession.Webauthn == nil {
+		ctx.Error(fmt.Errorf("Webauthn registration has not been initiated yet"), unableToRegisterSecurityKeyMessage)
+		return
+	}
+	// Ensure the challenge is cleared if anything goes wrong.
+	defer func() {
+		userSession.Webauthn = nil
+
+		err := ctx.SaveSession(userSession)
+		if err != nil {
+			ctx.Logger.Errorf("Unable to clear Webauthn challenge in session for user %s: %s", userSession.Username, err)
+		}
+	}()
+
+	response, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(ctx.PostBody()))
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to parse Webauthn attestation: %s", err), unableToRegisterSecurityKeyMessage)
+		return
+	}
+
+	w, err := newWebauthn(ctx)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to configure Webauthn: %s", err), unableToRegisterSecurityKeyMessage)
+		return
+	}
+
+	user, err := getWebauthnUser(ctx, userSession)
+	if err != nil {
+		ctx.Error(err, unableToRegisterSecurityKeyMessage)
+		return
+	}
+
+	credential, err := w.CreateCredential(user, *userSession.Webauthn, response)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to verify Webauthn attestation: %s", err), unableToRegisterSecurityKeyMessage)
+		return
+	}
+
+	ctx.Logger.Debugf("Register Webauthn device for user %s", userSession.Username)
+
+	err = ctx.Providers.StorageProvider.SaveWebauthnDevice(models.WebauthnDevice{
+		Username:        userSession.Username,
+		KID:             credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		CreatedAt:       ctx.Clock.Now(),
+	})
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to register Webauthn device for user %s: %s", userSession.Username, err), unableToRegisterSecurityKeyMessage)
+		return
+	}
+
+	auditSuccess(ctx, audit.EventDeviceRegistration, userSession.Username, authentication.U2F)
+
+	ctx.ReplyOK()
+}
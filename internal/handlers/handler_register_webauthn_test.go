@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/duo-labs/webauthn/protocol"
+	"github.com/duo-labs/webauthn/protocol/webauthncose"
+	"github.com/duo-labs/webauthn/webauthn"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+type HandlerRegisterWebauthnSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *HandlerRegisterWebauthnSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Clock = &s.mock.Clock
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-Proto", "https")
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-Host", testWebauthnHost)
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = testUsername
+	userSession.DisplayName = "John Smith"
+	err := s.mock.Ctx.SaveSession(userSession)
+	s.Require().NoError(err)
+}
+
+func (s *HandlerRegisterWebauthnSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *HandlerRegisterWebauthnSuite) TestShouldExcludeRegisteredDevices() {
+	registered := newTestWebauthnAuthenticator(s.T())
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadWebauthnDevicesByUsername(gomock.Eq(testUsername)).
+		Return([]models.WebauthnDevice{registered.Device(s.T(), 0)}, nil)
+	s.mock.StorageProviderMock.EXPECT().
+		LoadU2FDevicesByUsername(gomock.Eq(testUsername)).
+		Return(nil, storage.ErrNoU2FDeviceHandle)
+
+	secondFactorWebauthnIdentityFinish(s.mock.Ctx, testUsername)
+
+	creation := protocol.CredentialCreation{}
+	s.mock.GetResponseData(s.T(), &creation)
+
+	assert.Equal(s.T(), testWebauthnHost, creation.Response.RelyingParty.ID)
+	assert.Equal(s.T(), "Authelia", creation.Response.RelyingParty.Name)
+	assert.Equal(s.T(), []byte(testUsername), []byte(creation.Response.User.ID))
+	assert.Equal(s.T(), "John Smith", creation.Response.User.DisplayName)
+	assert.Equal(s.T(), protocol.PreferIndirectAttestation, creation.Response.Attestation)
+	assert.Equal(s.T(), 60000, creation.Response.Timeout)
+	assert.Equal(s.T(), protocol.AuthenticatorAttachment(""), creation.Response.AuthenticatorSelection.AuthenticatorAttachment)
+	s.Require().Len(creation.Response.CredentialExcludeList, 1)
+	assert.Equal(s.T(), registered.kid, []byte(creation.Response.CredentialExcludeList[0].CredentialID))
+	assert.NotNil(s.T(), s.mock.Ctx.GetSession().Webauthn)
+}
+
+func (s *HandlerRegisterWebauthnSuite) TestShouldRegisterWebauthnDevice() {
+	authenticator := newTestWebauthnAuthenticator(s.T())
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadWebauthnDevicesByUsername(gomock.Eq(testUsername)).
+		Return(nil, storage.ErrNoWebauthnDevice).
+		Times(2)
+	s.mock.StorageProviderMock.EXPECT().
+		LoadU2FDevicesByUsername(gomock.Eq(testUsername)).
+		Return(nil, storage.ErrNoU2FDeviceHandle).
+		Times(2)
+
+	var saved models.WebauthnDevice
+
+	s.mock.StorageProviderMock.EXPECT().
+		SaveWebauthnDevice(gomock.Any()).
+		DoAndReturn(func(device models.WebauthnDevice) error {
+			saved = device
+			return nil
+		})
+
+	secondFactorWebauthnIdentityFinish(s.mock.Ctx, testUsername)
+
+	s.mock.Ctx.Request.SetBody(authenticator.Attest(s.T(), s.mock.Ctx.GetSession().Webauthn.Challenge))
+	SecondFactorWebauthnAttestationPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+	assert.Equal(s.T(), testUsername, saved.Username)
+	assert.Equal(s.T(), authenticator.kid, saved.KID)
+
+	key, err := webauthncose.ParsePublicKey(saved.PublicKey)
+	s.Require().NoError(err)
+	assert.Equal(s.T(), authenticator.key.X.FillBytes(make([]byte, 32)), key.(webauthncose.EC2PublicKeyData).XCoord)
+	assert.Equal(s.T(), authenticator.key.Y.FillBytes(make([]byte, 32)), key.(webauthncose.EC2PublicKeyData).YCoord)
+
+	assert.Equal(s.T(), "none", saved.AttestationType)
+	assert.Equal(s.T(), make([]byte, 16), saved.AAGUID)
+	assert.Equal(s.T(), s.mock.Clock.Now(), saved.CreatedAt)
+	assert.Nil(s.T(), s.mock.Ctx.GetSession().Webauthn)
+}
+
+func (s *HandlerRegisterWebauthnSuite) TestShouldFailWhenChallengeDoesNotMatch() {
+	authenticator := newTestWebauthnAuthenticator(s.T())
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadWebauthnDevicesByUsername(gomock.Eq(testUsername)).
+		Return(nil, storage.ErrNoWebauthnDevice)
+	s.mock.StorageProviderMock.EXPECT().
+		LoadU2FDevicesByUsername(gomock.Eq(testUsername)).
+		Return(nil, storage.ErrNoU2FDeviceHandle)
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Webauthn = &webauthn.SessionData{Challenge: "challenge", UserID: []byte(testUsername)}
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	s.mock.Ctx.Request.SetBody(authenticator.Attest(s.T(), "another_challenge"))
+	SecondFactorWebauthnAttestationPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), unableToRegisterSecurityKeyMessage)
+	assert.Nil(s.T(), s.mock.Ctx.GetSession().Webauthn)
+}
+
+func (s *HandlerRegisterWebauthnSuite) TestShouldFailWhenRegistrationHasNotBeenInitiated() {
+	SecondFactorWebauthnAttestationPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), unableToRegisterSecurityKeyMessage)
+	assert.Equal(s.T(), "Webauthn registration has not been initiated yet", s.mock.Hook.LastEntry().Message)
+}
+
+func TestShouldRunHandlerRegisterWebauthnSuite(t *testing.T) {
+	suite.Run(t, new(HandlerRegisterWebauthnSuite))
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"fmt"
 
+	"github.com/authelia/authelia/internal/audit"
 	"github.com/authelia/authelia/internal/middlewares"
 	"github.com/authelia/authelia/internal/utils"
 )
@@ -27,22 +28,32 @@ func ResetPasswordPost(ctx *middlewares.AutheliaCtx) {
 		return
 	}
 
+	if !checkPasswordPolicy(ctx, *userSession.PasswordResetUsername, requestBody.Password) {
+		ctx.Audit(audit.Event{
+			Type:     audit.EventPasswordReset,
+			Username: *userSession.PasswordResetUsername,
+			Outcome:  audit.OutcomeFailure,
+		})
+
+		return
+	}
+
 	err = ctx.Providers.UserProvider.UpdatePassword(*userSession.PasswordResetUsername, requestBody.Password)
 
 	if err != nil {
-		switch {
-		case utils.IsStringInSliceContains(err.Error(), ldapPasswordComplexityCodes):
-			ctx.Error(fmt.Errorf("%s", err), ldapPasswordComplexityCode)
-		case utils.IsStringInSliceContains(err.Error(), ldapPasswordComplexityErrors):
-			ctx.Error(fmt.Errorf("%s", err), ldapPasswordComplexityCode)
-		default:
-			ctx.Error(fmt.Errorf("%s", err), unableToResetPasswordMessage)
-		}
+		ctx.Audit(audit.Event{
+			Type:     audit.EventPasswordReset,
+			Username: *userSession.PasswordResetUsername,
+			Outcome:  audit.OutcomeFailure,
+		})
+
+		replyUpdatePasswordError(ctx, err, unableToResetPasswordMessage)
 
 		return
 	}
 
 	ctx.Logger.Debugf("Password of user %s has been reset", *userSession.PasswordResetUsername)
+	auditSuccess(ctx, audit.EventPasswordReset, *userSession.PasswordResetUsername, "")
 
 	// Reset the request.
 	userSession.PasswordResetUsername = nil
@@ -55,3 +66,16 @@ func ResetPasswordPost(ctx *middlewares.AutheliaCtx) {
 
 	ctx.ReplyOK()
 }
+
+// replyUpdatePasswordError replies with the error returned by the user provider while updating a password, the LDAP
+// password complexity errors are replied with a code the portal understands.
+func replyUpdatePasswordError(ctx *middlewares.AutheliaCtx, err error, message string) {
+	switch {
+	case utils.IsStringInSliceContains(err.Error(), ldapPasswordComplexityCodes):
+		ctx.Error(fmt.Errorf("%s", err), ldapPasswordComplexityCode)
+	case utils.IsStringInSliceContains(err.Error(), ldapPasswordComplexityErrors):
+		ctx.Error(fmt.Errorf("%s", err), ldapPasswordComplexityCode)
+	default:
+		ctx.Error(fmt.Errorf("%s", err), message)
+	}
+}
@@ -6,10 +6,59 @@ import (
 
 	"github.com/authelia/authelia/internal/authentication"
 	"github.com/authelia/authelia/internal/duo"
+	"github.com/authelia/authelia/internal/metrics"
 	"github.com/authelia/authelia/internal/middlewares"
 )
 
-// SecondFactorDuoPost handler for sending a push notification via duo api.
+// SecondFactorDuoDevicesGet handler listing the Duo devices of the user and the factors they support.
+func SecondFactorDuoDevicesGet(duoAPI duo.API) middlewares.RequestHandler {
+	return func(ctx *middlewares.AutheliaCtx) {
+		userSession := ctx.GetSession()
+		remoteIP := ctx.RemoteIP().String()
+
+		values := url.Values{}
+		values.Set("username", userSession.Username)
+		values.Set("ipaddr", remoteIP)
+
+		preAuthResponse, err := duoAPI.PreAuthCall(values, ctx)
+		if err != nil {
+			ctx.Error(fmt.Errorf("Duo PreAuth API errored: %s", err), operationFailedMessage)
+			return
+		}
+
+		if preAuthResponse.Stat == "FAIL" {
+			ctx.Error(fmt.Errorf("Duo PreAuth failed for %s from %s: %s (%s), error code %d", userSession.Username, remoteIP,
+				preAuthResponse.Message, preAuthResponse.MessageDetail, preAuthResponse.Code), operationFailedMessage)
+
+			return
+		}
+
+		response := duoDevicesResponse{
+			Result:    preAuthResponse.Response.Result,
+			EnrollURL: preAuthResponse.Response.EnrollPortalURL,
+		}
+
+		for _, device := range preAuthResponse.Response.Devices {
+			factors := duoDeviceFactors(device)
+			if len(factors) == 0 {
+				continue
+			}
+
+			response.Devices = append(response.Devices, duoDevice{
+				Device:      device.Device,
+				DisplayName: device.DisplayName,
+				Factors:     factors,
+			})
+		}
+
+		err = ctx.SetJSONBody(response)
+		if err != nil {
+			ctx.Logger.Errorf("Unable to set Duo devices response in body: %s", err)
+		}
+	}
+}
+
+// SecondFactorDuoPost handler for authenticating the user with the factor they chose via duo api.
 func SecondFactorDuoPost(duoAPI duo.API) middlewares.RequestHandler {
 	return func(ctx *middlewares.AutheliaCtx) {
 		var requestBody signDuoRequestBody
@@ -23,20 +72,45 @@ func SecondFactorDuoPost(duoAPI duo.API) middlewares.RequestHandler {
 		userSession := ctx.GetSession()
 		remoteIP := ctx.RemoteIP().String()
 
-		ctx.Logger.Debugf("Starting Duo Push Auth Attempt for %s from IP %s", userSession.Username, remoteIP)
+		factor := requestBody.Factor
+		if factor == "" {
+			factor = duoFactorPush
+		}
+
+		device := requestBody.Device
+		if device == "" {
+			device = duoDeviceAuto
+		}
+
+		ctx.Logger.Debugf("Starting Duo %s Auth Attempt for %s from IP %s", factor, userSession.Username, remoteIP)
 
 		values := url.Values{}
-		// { username, ipaddr: clientIP, factor: "push", device: "auto", pushinfo: `target%20url=${targetURL}`}
 		values.Set("username", userSession.Username)
 		values.Set("ipaddr", remoteIP)
-		values.Set("factor", "push")
-		values.Set("device", "auto")
+		values.Set("factor", factor)
+
+		switch factor {
+		case duoFactorPush:
+			values.Set("device", device)
 
-		if requestBody.TargetURL != "" {
-			values.Set("pushinfo", fmt.Sprintf("target%%20url=%s", requestBody.TargetURL))
+			if requestBody.TargetURL != "" {
+				values.Set("pushinfo", fmt.Sprintf("target%%20url=%s", requestBody.TargetURL))
+			}
+		case duoFactorPhone, duoFactorSMS:
+			values.Set("device", device)
+		case duoFactorPasscode:
+			if requestBody.Passcode == "" {
+				handleAuthenticationUnauthorized(ctx, fmt.Errorf("No Duo passcode provided by user %s", userSession.Username), mfaValidationFailedMessage)
+				return
+			}
+
+			values.Set("passcode", requestBody.Passcode)
+		default:
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unknown Duo factor '%s' requested by user %s", factor, userSession.Username), mfaValidationFailedMessage)
+			return
 		}
 
-		duoResponse, err := duoAPI.Call(values, ctx)
+		duoResponse, err := duoAPI.AuthCall(values, ctx)
 		if err != nil {
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Duo API errored: %s", err), mfaValidationFailedMessage)
 			return
@@ -53,11 +127,23 @@ func SecondFactorDuoPost(duoAPI duo.API) middlewares.RequestHandler {
 			}
 		}
 
+		// Duo denies the SMS factor once the passcodes have been sent, the user then authenticates with one of them.
+		if factor == duoFactorSMS && duoResponse.Stat != "FAIL" {
+			ctx.Logger.Debugf("Duo SMS passcodes sent to %s: %s", userSession.Username, duoResponse.Response.StatusMessage)
+			ctx.ReplyOK()
+
+			return
+		}
+
 		if duoResponse.Response.Result != testResultAllow {
+			recordAuthentication(ctx, metrics.FactorSecond, duoFactorMethods[factor], userSession.Username, requestBody.TargetURL, false)
 			ctx.ReplyUnauthorized()
+
 			return
 		}
 
+		recordAuthentication(ctx, metrics.FactorSecond, duoFactorMethods[factor], userSession.Username, requestBody.TargetURL, true)
+
 		err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx)
 
 		if err != nil {
@@ -66,6 +152,7 @@ func SecondFactorDuoPost(duoAPI duo.API) middlewares.RequestHandler {
 		}
 
 		userSession.AuthenticationLevel = authentication.TwoFactor
+		userSession.SecondFactorAt = ctx.Clock.Now().Unix()
 		err = ctx.SaveSession(userSession)
 
 		if err != nil {
@@ -76,3 +163,20 @@ func SecondFactorDuoPost(duoAPI duo.API) middlewares.RequestHandler {
 		Handle2FAResponse(ctx, requestBody.TargetURL)
 	}
 }
+
+// duoDeviceFactors returns the factors supported by a Duo device, hardware tokens only generating passcodes.
+func duoDeviceFactors(device duo.Device) []string {
+	if device.Type == "token" {
+		return []string{duoFactorPasscode}
+	}
+
+	factors := make([]string, 0, len(device.Capabilities))
+
+	for _, capability := range device.Capabilities {
+		if factor, ok := duoCapabilitiesFactors[capability]; ok {
+			factors = append(factors, factor)
+		}
+	}
+
+	return factors
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -12,6 +13,8 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/authelia/authelia/internal/audit"
+	"github.com/authelia/authelia/internal/authentication"
 	"github.com/authelia/authelia/internal/duo"
 	"github.com/authelia/authelia/internal/mocks"
 )
@@ -47,7 +50,7 @@ func (s *SecondFactorDuoPostSuite) TestShouldCallDuoAPIAndAllowAccess() {
 	response := duo.Response{}
 	response.Response.Result = testResultAllow
 
-	duoMock.EXPECT().Call(gomock.Eq(values), s.mock.Ctx).Return(&response, nil)
+	duoMock.EXPECT().AuthCall(gomock.Eq(values), s.mock.Ctx).Return(&response, nil)
 
 	s.mock.Ctx.Request.SetBodyString("{\"targetURL\": \"https://target.example.com\"}")
 
@@ -69,7 +72,7 @@ func (s *SecondFactorDuoPostSuite) TestShouldCallDuoAPIAndDenyAccess() {
 	response := duo.Response{}
 	response.Response.Result = "deny"
 
-	duoMock.EXPECT().Call(gomock.Eq(values), s.mock.Ctx).Return(&response, nil)
+	duoMock.EXPECT().AuthCall(gomock.Eq(values), s.mock.Ctx).Return(&response, nil)
 
 	s.mock.Ctx.Request.SetBodyString("{\"targetURL\": \"https://target.example.com\"}")
 
@@ -88,7 +91,7 @@ func (s *SecondFactorDuoPostSuite) TestShouldCallDuoAPIAndFail() {
 	values.Set("device", "auto")
 	values.Set("pushinfo", "target%20url=https://target.example.com")
 
-	duoMock.EXPECT().Call(gomock.Eq(values), s.mock.Ctx).Return(nil, fmt.Errorf("Connnection error"))
+	duoMock.EXPECT().AuthCall(gomock.Eq(values), s.mock.Ctx).Return(nil, fmt.Errorf("Connnection error"))
 
 	s.mock.Ctx.Request.SetBodyString("{\"targetURL\": \"https://target.example.com\"}")
 
@@ -103,7 +106,7 @@ func (s *SecondFactorDuoPostSuite) TestShouldRedirectUserToDefaultURL() {
 	response := duo.Response{}
 	response.Response.Result = testResultAllow
 
-	duoMock.EXPECT().Call(gomock.Any(), s.mock.Ctx).Return(&response, nil)
+	duoMock.EXPECT().AuthCall(gomock.Any(), s.mock.Ctx).Return(&response, nil)
 
 	s.mock.Ctx.Configuration.DefaultRedirectionURL = testRedirectionURL
 
@@ -123,7 +126,7 @@ func (s *SecondFactorDuoPostSuite) TestShouldNotReturnRedirectURL() {
 	response := duo.Response{}
 	response.Response.Result = testResultAllow
 
-	duoMock.EXPECT().Call(gomock.Any(), s.mock.Ctx).Return(&response, nil)
+	duoMock.EXPECT().AuthCall(gomock.Any(), s.mock.Ctx).Return(&response, nil)
 
 	bodyBytes, err := json.Marshal(signDuoRequestBody{})
 	s.Require().NoError(err)
@@ -139,7 +142,7 @@ func (s *SecondFactorDuoPostSuite) TestShouldRedirectUserToSafeTargetURL() {
 	response := duo.Response{}
 	response.Response.Result = testResultAllow
 
-	duoMock.EXPECT().Call(gomock.Any(), s.mock.Ctx).Return(&response, nil)
+	duoMock.EXPECT().AuthCall(gomock.Any(), s.mock.Ctx).Return(&response, nil)
 
 	bodyBytes, err := json.Marshal(signDuoRequestBody{
 		TargetURL: "https://mydomain.local",
@@ -159,7 +162,7 @@ func (s *SecondFactorDuoPostSuite) TestShouldNotRedirectToUnsafeURL() {
 	response := duo.Response{}
 	response.Response.Result = testResultAllow
 
-	duoMock.EXPECT().Call(gomock.Any(), s.mock.Ctx).Return(&response, nil)
+	duoMock.EXPECT().AuthCall(gomock.Any(), s.mock.Ctx).Return(&response, nil)
 
 	bodyBytes, err := json.Marshal(signDuoRequestBody{
 		TargetURL: "http://mydomain.local",
@@ -177,7 +180,7 @@ func (s *SecondFactorDuoPostSuite) TestShouldRegenerateSessionForPreventingSessi
 	response := duo.Response{}
 	response.Response.Result = testResultAllow
 
-	duoMock.EXPECT().Call(gomock.Any(), s.mock.Ctx).Return(&response, nil)
+	duoMock.EXPECT().AuthCall(gomock.Any(), s.mock.Ctx).Return(&response, nil)
 
 	bodyBytes, err := json.Marshal(signDuoRequestBody{
 		TargetURL: "http://mydomain.local",
@@ -196,6 +199,166 @@ func (s *SecondFactorDuoPostSuite) TestShouldRegenerateSessionForPreventingSessi
 		string(s.mock.Ctx.Request.Header.Cookie("authelia_session")))
 }
 
+func (s *SecondFactorDuoPostSuite) TestShouldAuthenticateWithChosenDeviceAndFactor() {
+	duoMock := mocks.NewMockAPI(s.mock.Ctrl)
+
+	values := url.Values{}
+	values.Set("username", "john")
+	values.Set("ipaddr", s.mock.Ctx.RemoteIP().String())
+	values.Set("factor", "phone")
+	values.Set("device", "DPFZRS9FB0D46QFTM891")
+
+	response := duo.Response{}
+	response.Response.Result = testResultAllow
+
+	duoMock.EXPECT().AuthCall(gomock.Eq(values), s.mock.Ctx).Return(&response, nil)
+
+	s.mock.Ctx.Request.SetBodyString(`{"device": "DPFZRS9FB0D46QFTM891", "factor": "phone"}`)
+
+	SecondFactorDuoPost(duoMock)(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Equal(authentication.TwoFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *SecondFactorDuoPostSuite) TestShouldAuditChosenFactor() {
+	buffer := new(bytes.Buffer)
+	s.mock.Ctx.Providers.Audit = audit.NewJSONLogger(buffer)
+
+	duoMock := mocks.NewMockAPI(s.mock.Ctrl)
+
+	response := duo.Response{}
+	response.Response.Result = "deny"
+
+	duoMock.EXPECT().AuthCall(gomock.Any(), s.mock.Ctx).Return(&response, nil)
+
+	s.mock.Ctx.Request.SetBodyString(`{"factor": "phone"}`)
+
+	SecondFactorDuoPost(duoMock)(s.mock.Ctx)
+
+	var event audit.Event
+
+	s.Require().NoError(json.Unmarshal(buffer.Bytes(), &event))
+	s.Assert().Equal(audit.EventSecondFactor, event.Type)
+	s.Assert().Equal("duo_phone", event.Method)
+	s.Assert().Equal(audit.OutcomeFailure, event.Outcome)
+}
+
+func (s *SecondFactorDuoPostSuite) TestShouldAuthenticateWithPasscode() {
+	duoMock := mocks.NewMockAPI(s.mock.Ctrl)
+
+	values := url.Values{}
+	values.Set("username", "john")
+	values.Set("ipaddr", s.mock.Ctx.RemoteIP().String())
+	values.Set("factor", "passcode")
+	values.Set("passcode", "123456")
+
+	response := duo.Response{}
+	response.Response.Result = testResultAllow
+
+	duoMock.EXPECT().AuthCall(gomock.Eq(values), s.mock.Ctx).Return(&response, nil)
+
+	s.mock.Ctx.Request.SetBodyString(`{"factor": "passcode", "passcode": "123456"}`)
+
+	SecondFactorDuoPost(duoMock)(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Equal(authentication.TwoFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *SecondFactorDuoPostSuite) TestShouldSendSMSPasscodesWithoutAuthenticating() {
+	duoMock := mocks.NewMockAPI(s.mock.Ctrl)
+
+	values := url.Values{}
+	values.Set("username", "john")
+	values.Set("ipaddr", s.mock.Ctx.RemoteIP().String())
+	values.Set("factor", "sms")
+	values.Set("device", "DPFZRS9FB0D46QFTM891")
+
+	response := duo.Response{Stat: "OK"}
+	response.Response.Result = "deny"
+	response.Response.StatusMessage = "New SMS passcodes sent"
+
+	duoMock.EXPECT().AuthCall(gomock.Eq(values), s.mock.Ctx).Return(&response, nil)
+
+	s.mock.Ctx.Request.SetBodyString(`{"device": "DPFZRS9FB0D46QFTM891", "factor": "sms"}`)
+
+	SecondFactorDuoPost(duoMock)(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Equal(authentication.NotAuthenticated, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *SecondFactorDuoPostSuite) TestShouldFailWhenPasscodeIsMissing() {
+	duoMock := mocks.NewMockAPI(s.mock.Ctrl)
+
+	s.mock.Ctx.Request.SetBodyString(`{"factor": "passcode"}`)
+
+	SecondFactorDuoPost(duoMock)(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("No Duo passcode provided by user john", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *SecondFactorDuoPostSuite) TestShouldFailWhenFactorIsUnknown() {
+	duoMock := mocks.NewMockAPI(s.mock.Ctrl)
+
+	s.mock.Ctx.Request.SetBodyString(`{"factor": "carrier_pigeon"}`)
+
+	SecondFactorDuoPost(duoMock)(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("Unknown Duo factor 'carrier_pigeon' requested by user john", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *SecondFactorDuoPostSuite) TestShouldListDevicesAndTheirFactors() {
+	duoMock := mocks.NewMockAPI(s.mock.Ctrl)
+
+	values := url.Values{}
+	values.Set("username", "john")
+	values.Set("ipaddr", s.mock.Ctx.RemoteIP().String())
+
+	response := duo.PreAuthResponse{Stat: "OK"}
+	response.Response.Result = "auth"
+	response.Response.Devices = []duo.Device{
+		{Device: "DPFZRS9FB0D46QFTM891", DisplayName: "iOS (XXX-XXX-0100)", Type: "phone", Capabilities: []string{"auto", "push", "sms", "phone", "mobile_otp"}},
+		{Device: "DHEKH0JJIYC1LX3AZWO4", DisplayName: "Token", Type: "token"},
+		{Device: "DRE4A1IKOTMFHBG3DDGI", DisplayName: "Landline", Type: "phone"},
+	}
+
+	duoMock.EXPECT().PreAuthCall(gomock.Eq(values), s.mock.Ctx).Return(&response, nil)
+
+	SecondFactorDuoDevicesGet(duoMock)(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), duoDevicesResponse{
+		Result: "auth",
+		Devices: []duoDevice{
+			{Device: "DPFZRS9FB0D46QFTM891", DisplayName: "iOS (XXX-XXX-0100)", Factors: []string{"push", "sms", "phone", "passcode"}},
+			{Device: "DHEKH0JJIYC1LX3AZWO4", DisplayName: "Token", Factors: []string{"passcode"}},
+		},
+	})
+}
+
+func (s *SecondFactorDuoPostSuite) TestShouldReturnEnrollURLWhenUserIsNotEnrolled() {
+	duoMock := mocks.NewMockAPI(s.mock.Ctrl)
+
+	response := duo.PreAuthResponse{Stat: "OK"}
+	response.Response.Result = "enroll"
+	response.Response.EnrollPortalURL = "https://api-example.duosecurity.com/portal?code=48bac5d9393fb2c2"
+
+	duoMock.EXPECT().PreAuthCall(gomock.Any(), s.mock.Ctx).Return(&response, nil)
+
+	SecondFactorDuoDevicesGet(duoMock)(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), duoDevicesResponse{
+		Result:    "enroll",
+		EnrollURL: "https://api-example.duosecurity.com/portal?code=48bac5d9393fb2c2",
+	})
+}
+
+func (s *SecondFactorDuoPostSuite) TestShouldFailToListDevicesWhenDuoFails() {
+	duoMock := mocks.NewMockAPI(s.mock.Ctrl)
+
+	duoMock.EXPECT().PreAuthCall(gomock.Any(), s.mock.Ctx).Return(nil, fmt.Errorf("Connnection error"))
+
+	SecondFactorDuoDevicesGet(duoMock)(s.mock.Ctx)
+	s.mock.Assert200KO(s.T(), operationFailedMessage)
+	s.Assert().Equal("Duo PreAuth API errored: Connnection error", s.mock.Hook.LastEntry().Message)
+}
+
 func TestRunSecondFactorDuoPostSuite(t *testing.T) {
 	s := new(SecondFactorDuoPostSuite)
 	suite.Run(t, s)
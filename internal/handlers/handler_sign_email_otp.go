@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/metrics"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/regulation"
+	"github.com/authelia/authelia/internal/session"
+)
+
+// SecondFactorEmailOTPStart send a one-time passcode to the email address of the user.
+func SecondFactorEmailOTPStart(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+
+	if len(userSession.Emails) == 0 {
+		ctx.Error(fmt.Errorf("User %s does not have any email address", userSession.Username), unableToSendEmailOTPMessage)
+		return
+	}
+
+	if !regulateSecondFactor(ctx, authentication.EmailOTP, userSession.Username) {
+		return
+	}
+
+	// The emails sent are limited separately from the regulation so that asking for a new passcode does not count as
+	// a failed authentication attempt.
+	err := ctx.Providers.Regulator.RegulateEmailOTPSend(userSession.Username)
+	if err == regulation.ErrTooManyEmailOTPSent {
+		ctx.Error(fmt.Errorf("User %s requested too many email one-time passcodes", userSession.Username), tooManyEmailOTPMessage)
+		return
+	}
+
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to regulate email one-time passcodes of user %s: %s", userSession.Username, err), unableToSendEmailOTPMessage)
+		return
+	}
+
+	passcode, err := generateEmailOTP()
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to generate email one-time passcode: %s", err), unableToSendEmailOTPMessage)
+		return
+	}
+
+	userSession.EmailOTP = &session.EmailOTPChallenge{
+		Digest:    emailOTPDigest(passcode),
+		ExpiresAt: ctx.Clock.Now().Add(emailOTPLifespan).Unix(),
+	}
+
+	err = ctx.SaveSession(userSession)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to save email one-time passcode in session: %s", err), unableToSendEmailOTPMessage)
+		return
+	}
+
+	// The passcode is recorded before it is sent so that it counts against the limit even if sending it fails.
+	err = ctx.Providers.Regulator.MarkEmailOTP(userSession.Username, regulation.EmailOTPEventSent)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to record email one-time passcode sent to user %s: %s", userSession.Username, err), unableToSendEmailOTPMessage)
+		return
+	}
+
+	body := fmt.Sprintf(emailOTPNotificationBody, userSession.Username, passcode, int(emailOTPLifespan.Minutes()))
+
+	err = ctx.Providers.Notifier.Send(userSession.Emails[0], emailOTPNotificationTitle, body, "")
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to send email one-time passcode to user %s: %s", userSession.Username, err), unableToSendEmailOTPMessage)
+		return
+	}
+
+	ctx.Logger.Debugf("Email one-time passcode sent to user %s", userSession.Username)
+	ctx.ReplyOK()
+}
+
+// SecondFactorEmailOTPPost validate the one-time passcode sent by email to the user.
+func SecondFactorEmailOTPPost(ctx *middlewares.AutheliaCtx) {
+	bodyJSON := signEmailOTPRequestBody{}
+
+	err := ctx.ParseBody(&bodyJSON)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, err, mfaValidationFailedMessage)
+		return
+	}
+
+	userSession := ctx.GetSession()
+
+	if !regulateSecondFactor(ctx, authentication.EmailOTP, userSession.Username) {
+		return
+	}
+
+	challenge := userSession.EmailOTP
+	if challenge == nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Email one-time passcode has not been requested by user %s", userSession.Username), mfaValidationFailedMessage)
+		return
+	}
+
+	if ctx.Clock.Now().Unix() > challenge.ExpiresAt {
+		userSession.EmailOTP = nil
+		saveSessionAfterEmailOTPFailure(ctx, userSession)
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Email one-time passcode of user %s has expired", userSession.Username), mfaValidationFailedMessage)
+
+		return
+	}
+
+	// The attempts are limited per user rather than per session so that the passcode cannot be brute forced from
+	// several sessions when the regulation is disabled.
+	err = ctx.Providers.Regulator.RegulateEmailOTPAttempt(userSession.Username)
+	if err == regulation.ErrTooManyEmailOTPAttempts {
+		userSession.EmailOTP = nil
+		saveSessionAfterEmailOTPFailure(ctx, userSession)
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Email one-time passcode of user %s has been tried too many times", userSession.Username), mfaValidationFailedMessage)
+
+		return
+	}
+
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to regulate email one-time passcode attempts: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	if subtle.ConstantTimeCompare([]byte(emailOTPDigest(bodyJSON.Token)), []byte(challenge.Digest)) != 1 {
+		if err := ctx.Providers.Regulator.MarkEmailOTP(userSession.Username, regulation.EmailOTPEventFailed); err != nil {
+			ctx.Logger.Errorf("Unable to record failed email one-time passcode attempt: %s", err)
+		}
+
+		if err := ctx.Providers.Regulator.Mark(userSession.Username, false); err != nil {
+			ctx.Logger.Errorf("Unable to mark authentication: %s", err)
+		}
+
+		recordAuthentication(ctx, metrics.FactorSecond, authentication.EmailOTP, userSession.Username, bodyJSON.TargetURL, false)
+
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Wrong email one-time passcode for user %s", userSession.Username), mfaValidationFailedMessage)
+
+		return
+	}
+
+	userSession.EmailOTP = nil
+
+	err = ctx.Providers.Regulator.Mark(userSession.Username, true)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to mark authentication: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	recordAuthentication(ctx, metrics.FactorSecond, authentication.EmailOTP, userSession.Username, bodyJSON.TargetURL, true)
+
+	err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to regenerate session for user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+		return
+	}
+
+	userSession.AuthenticationLevel = authentication.TwoFactor
+	userSession.SecondFactorAt = ctx.Clock.Now().Unix()
+
+	err = ctx.SaveSession(userSession)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to update the authentication level with email one-time passcode: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	Handle2FAResponse(ctx, bodyJSON.TargetURL)
+}
+
+func saveSessionAfterEmailOTPFailure(ctx *middlewares.AutheliaCtx, userSession session.UserSession) {
+	if err := ctx.SaveSession(userSession); err != nil {
+		ctx.Logger.Errorf("Unable to save session of user %s: %s", userSession.Username, err)
+	}
+}
+
+// generateEmailOTP generates a random numeric passcode of emailOTPDigits digits.
+func generateEmailOTP() (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(emailOTPDigits), nil)
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", emailOTPDigits, n), nil
+}
+
+// emailOTPDigest returns the digest of a passcode kept in session.
+func emailOTPDigest(passcode string) string {
+	sum := sha256.Sum256([]byte(passcode))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/regulation"
+	"github.com/authelia/authelia/internal/session"
+)
+
+type HandlerSignEmailOTPSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+
+	// The email one-time passcode log of the user, the latest entry first.
+	emailOTPLogs []models.EmailOTPLog
+}
+
+func (s *HandlerSignEmailOTPSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Clock = &s.mock.Clock
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = testUsername
+	userSession.Emails = []string{"john@example.com"}
+	userSession.AuthenticationLevel = authentication.OneFactor
+	err := s.mock.Ctx.SaveSession(userSession)
+	require.NoError(s.T(), err)
+
+	s.emailOTPLogs = nil
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendEmailOTPLog(gomock.Any()).
+		DoAndReturn(func(log models.EmailOTPLog) error {
+			s.emailOTPLogs = append([]models.EmailOTPLog{log}, s.emailOTPLogs...)
+			return nil
+		}).
+		AnyTimes()
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLatestEmailOTPLogs(gomock.Eq(testUsername), gomock.Any()).
+		DoAndReturn(func(_ string, fromDate time.Time) ([]models.EmailOTPLog, error) {
+			logs := make([]models.EmailOTPLog, 0, len(s.emailOTPLogs))
+
+			for _, log := range s.emailOTPLogs {
+				if log.Time.After(fromDate) {
+					logs = append(logs, log)
+				}
+			}
+
+			return logs, nil
+		}).
+		AnyTimes()
+}
+
+func (s *HandlerSignEmailOTPSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *HandlerSignEmailOTPSuite) setChallenge(passcode string, expiresAt time.Time) {
+	userSession := s.mock.Ctx.GetSession()
+	userSession.EmailOTP = &session.EmailOTPChallenge{
+		Digest:    emailOTPDigest(passcode),
+		ExpiresAt: expiresAt.Unix(),
+	}
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+}
+
+func (s *HandlerSignEmailOTPSuite) logEmailOTP(event string, at time.Time) {
+	s.emailOTPLogs = append([]models.EmailOTPLog{{Username: testUsername, Event: event, Time: at}}, s.emailOTPLogs...)
+}
+
+func (s *HandlerSignEmailOTPSuite) setBody(token string) {
+	bodyBytes, err := json.Marshal(signEmailOTPRequestBody{
+		Token: token,
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+}
+
+func (s *HandlerSignEmailOTPSuite) TestShouldSendPasscode() {
+	var body string
+
+	s.mock.NotifierMock.EXPECT().
+		Send(gomock.Eq("john@example.com"), gomock.Eq(emailOTPNotificationTitle), gomock.Any(), gomock.Eq("")).
+		DoAndReturn(func(_, _, b, _ string) error {
+			body = b
+			return nil
+		})
+
+	SecondFactorEmailOTPStart(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), nil)
+
+	passcode := regexp.MustCompile(`: (\d{6})\n`).FindStringSubmatch(body)
+	s.Require().Len(passcode, 2)
+
+	challenge := s.mock.Ctx.GetSession().EmailOTP
+	s.Require().NotNil(challenge)
+	s.Assert().Equal(emailOTPDigest(passcode[1]), challenge.Digest)
+	s.Assert().Equal(s.mock.Clock.Now().Add(emailOTPLifespan).Unix(), challenge.ExpiresAt)
+	s.Assert().Equal([]models.EmailOTPLog{
+		{Username: testUsername, Event: regulation.EmailOTPEventSent, Time: s.mock.Clock.Now()},
+	}, s.emailOTPLogs)
+}
+
+func (s *HandlerSignEmailOTPSuite) TestShouldLimitPasscodesSent() {
+	s.mock.NotifierMock.EXPECT().
+		Send(gomock.Eq("john@example.com"), gomock.Eq(emailOTPNotificationTitle), gomock.Any(), gomock.Eq("")).
+		Return(nil).
+		Times(regulation.EmailOTPMaxSends + 1)
+
+	for i := 0; i < regulation.EmailOTPMaxSends; i++ {
+		s.mock.Ctx.Response.Reset()
+		SecondFactorEmailOTPStart(s.mock.Ctx)
+		s.mock.Assert200OK(s.T(), nil)
+	}
+
+	s.mock.Ctx.Response.Reset()
+	SecondFactorEmailOTPStart(s.mock.Ctx)
+	s.mock.Assert200KO(s.T(), tooManyEmailOTPMessage)
+	s.Assert().Equal("User john requested too many email one-time passcodes", s.mock.Hook.LastEntry().Message)
+
+	// The passcodes can be sent again once the oldest ones are out of the window.
+	s.mock.Clock.Set(s.mock.Clock.Now().Add(regulation.EmailOTPSendWindow))
+
+	s.mock.Ctx.Response.Reset()
+	SecondFactorEmailOTPStart(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Len(s.emailOTPLogs, regulation.EmailOTPMaxSends+1)
+}
+
+func (s *HandlerSignEmailOTPSuite) TestShouldLimitPasscodesSentFromOtherSessions() {
+	for i := 0; i < regulation.EmailOTPMaxSends; i++ {
+		s.logEmailOTP(regulation.EmailOTPEventSent, s.mock.Clock.Now().Add(-time.Minute))
+	}
+
+	SecondFactorEmailOTPStart(s.mock.Ctx)
+	s.mock.Assert200KO(s.T(), tooManyEmailOTPMessage)
+	s.Assert().Equal("User john requested too many email one-time passcodes", s.mock.Hook.LastEntry().Message)
+	s.Assert().Nil(s.mock.Ctx.GetSession().EmailOTP)
+}
+
+func (s *HandlerSignEmailOTPSuite) TestShouldNotSendPasscodeWhenUserIsBanned() {
+	s.mock.Ctx.Providers.Regulator = regulation.NewRegulator(&schema.RegulationConfiguration{
+		MaxRetries: 1,
+		FindTime:   "2m",
+		BanTime:    "5m",
+	}, s.mock.StorageProviderMock, &s.mock.Clock)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Eq(testUsername), gomock.Any()).
+		Return([]models.AuthenticationAttempt{{Username: testUsername, Successful: false, Time: s.mock.Clock.Now()}}, nil)
+
+	SecondFactorEmailOTPStart(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), userBannedMessage)
+	s.Assert().Nil(s.mock.Ctx.GetSession().EmailOTP)
+}
+
+func (s *HandlerSignEmailOTPSuite) TestShouldFailWhenUserHasNoEmail() {
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Emails = nil
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	SecondFactorEmailOTPStart(s.mock.Ctx)
+	s.mock.Assert200KO(s.T(), unableToSendEmailOTPMessage)
+	s.Assert().Equal("User john does not have any email address", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerSignEmailOTPSuite) TestShouldFailWhenEmailCannotBeSent() {
+	s.mock.NotifierMock.EXPECT().
+		Send(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(fmt.Errorf("connection refused"))
+
+	SecondFactorEmailOTPStart(s.mock.Ctx)
+	s.mock.Assert200KO(s.T(), unableToSendEmailOTPMessage)
+	s.Assert().Equal("Unable to send email one-time passcode to user john: connection refused", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerSignEmailOTPSuite) TestShouldAuthenticateWithPasscode() {
+	s.setChallenge("123456", s.mock.Clock.Now().Add(time.Minute))
+	s.setBody("123456")
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: true,
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
+	SecondFactorEmailOTPPost(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Equal(authentication.TwoFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+	s.Assert().Nil(s.mock.Ctx.GetSession().EmailOTP)
+}
+
+func (s *HandlerSignEmailOTPSuite) TestShouldFailWhenPasscodeIsWrong() {
+	s.setChallenge("123456", s.mock.Clock.Now().Add(time.Minute))
+	s.setBody("654321")
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: false,
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
+
+	SecondFactorEmailOTPPost(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("Wrong email one-time passcode for user john", s.mock.Hook.LastEntry().Message)
+	s.Assert().Equal(authentication.OneFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+	s.Assert().NotNil(s.mock.Ctx.GetSession().EmailOTP)
+	s.Assert().Equal([]models.EmailOTPLog{
+		{Username: testUsername, Event: regulation.EmailOTPEventFailed, Time: s.mock.Clock.Now()},
+	}, s.emailOTPLogs)
+}
+
+func (s *HandlerSignEmailOTPSuite) TestShouldDropPasscodeAfterTooManyAttempts() {
+	s.logEmailOTP(regulation.EmailOTPEventSent, s.mock.Clock.Now().Add(-2*time.Minute))
+
+	for i := 0; i < regulation.EmailOTPMaxAttempts; i++ {
+		s.logEmailOTP(regulation.EmailOTPEventFailed, s.mock.Clock.Now().Add(-time.Minute))
+	}
+
+	s.setChallenge("123456", s.mock.Clock.Now().Add(time.Minute))
+	s.setBody("123456")
+
+	SecondFactorEmailOTPPost(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("Email one-time passcode of user john has been tried too many times", s.mock.Hook.LastEntry().Message)
+	s.Assert().Equal(authentication.OneFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+	s.Assert().Nil(s.mock.Ctx.GetSession().EmailOTP)
+}
+
+func (s *HandlerSignEmailOTPSuite) TestShouldOnlyCountAttemptsOfLatestPasscode() {
+	s.logEmailOTP(regulation.EmailOTPEventSent, s.mock.Clock.Now().Add(-3*time.Minute))
+
+	for i := 0; i < regulation.EmailOTPMaxAttempts; i++ {
+		s.logEmailOTP(regulation.EmailOTPEventFailed, s.mock.Clock.Now().Add(-2*time.Minute))
+	}
+
+	s.logEmailOTP(regulation.EmailOTPEventSent, s.mock.Clock.Now().Add(-time.Minute))
+
+	s.setChallenge("123456", s.mock.Clock.Now().Add(time.Minute))
+	s.setBody("123456")
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
+
+	SecondFactorEmailOTPPost(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Equal(authentication.TwoFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignEmailOTPSuite) TestShouldFailWhenPasscodeHasExpired() {
+	s.setChallenge("123456", s.mock.Clock.Now().Add(-time.Second))
+	s.setBody("123456")
+
+	SecondFactorEmailOTPPost(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("Email one-time passcode of user john has expired", s.mock.Hook.LastEntry().Message)
+	s.Assert().Equal(authentication.OneFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+	s.Assert().Nil(s.mock.Ctx.GetSession().EmailOTP)
+}
+
+func (s *HandlerSignEmailOTPSuite) TestShouldFailWhenPasscodeHasNotBeenRequested() {
+	s.setBody("123456")
+
+	SecondFactorEmailOTPPost(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("Email one-time passcode has not been requested by user john", s.mock.Hook.LastEntry().Message)
+}
+
+func TestRunHandlerSignEmailOTPSuite(t *testing.T) {
+	suite.Run(t, new(HandlerSignEmailOTPSuite))
+}
+
+func TestShouldGenerateNumericEmailOTP(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		passcode, err := generateEmailOTP()
+		require.NoError(t, err)
+		require.Regexp(t, "^[0-9]{6}$", passcode)
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/metrics"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+// SecondFactorRecoveryCodePost validate a recovery code provided by the user and burn it.
+func SecondFactorRecoveryCodePost(ctx *middlewares.AutheliaCtx) {
+	bodyJSON := signRecoveryCodeRequestBody{}
+
+	err := ctx.ParseBody(&bodyJSON)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, err, mfaValidationFailedMessage)
+		return
+	}
+
+	userSession := ctx.GetSession()
+
+	// The regulation applies before any code is hashed so that a banned user cannot make the server spend time
+	// hashing.
+	if !regulateSecondFactor(ctx, recoveryCodeMethod, userSession.Username) {
+		return
+	}
+
+	codes, err := ctx.Providers.StorageProvider.LoadRecoveryCodesByUsername(userSession.Username)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to load recovery codes of user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+		return
+	}
+
+	code, err := findRecoveryCode(codes, bodyJSON.Code)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Error occurred during recovery code validation for user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+		return
+	}
+
+	if code == nil {
+		markRecoveryCodeFailure(ctx, userSession.Username)
+		recordAuthentication(ctx, metrics.FactorSecond, recoveryCodeMethod, userSession.Username, bodyJSON.TargetURL, false)
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Wrong recovery code for user %s", userSession.Username), mfaValidationFailedMessage)
+		return
+	}
+
+	// The code is burnt before the user is authenticated, if the deletion does not affect any row the code has been
+	// used concurrently.
+	err = ctx.Providers.StorageProvider.DeleteRecoveryCode(userSession.Username, code.Hash)
+	if errors.Is(err, storage.ErrNoRecoveryCode) {
+		markRecoveryCodeFailure(ctx, userSession.Username)
+		recordAuthentication(ctx, metrics.FactorSecond, recoveryCodeMethod, userSession.Username, bodyJSON.TargetURL, false)
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Recovery code of user %s has already been used", userSession.Username), mfaValidationFailedMessage)
+		return
+	}
+
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to burn recovery code of user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+		return
+	}
+
+	err = ctx.Providers.Regulator.Mark(userSession.Username, true)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to mark authentication: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	recordAuthentication(ctx, metrics.FactorSecond, recoveryCodeMethod, userSession.Username, bodyJSON.TargetURL, true)
+	ctx.Logger.Debugf("User %s used a recovery code, %d remaining", userSession.Username, len(codes)-1)
+
+	err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to regenerate session for user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+		return
+	}
+
+	userSession.AuthenticationLevel = authentication.TwoFactor
+	userSession.SecondFactorAt = ctx.Clock.Now().Unix()
+
+	err = ctx.SaveSession(userSession)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to update the authentication level with recovery code: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	Handle2FAResponse(ctx, bodyJSON.TargetURL)
+}
+
+// markRecoveryCodeFailure marks a failed authentication attempt with a recovery code for the regulation.
+func markRecoveryCodeFailure(ctx *middlewares.AutheliaCtx, username string) {
+	if err := ctx.Providers.Regulator.Mark(username, false); err != nil {
+		ctx.Logger.Errorf("Unable to mark authentication: %s", err)
+	}
+}
+
+// findRecoveryCode finds the recovery code matching the one typed by the user, nil if none does. Only the code with
+// the lookup of the typed one is hashed, along with the codes generated before the lookups were stored.
+func findRecoveryCode(codes []models.RecoveryCode, typed string) (*models.RecoveryCode, error) {
+	lookup := recoveryCodeLookup(typed)
+
+	for i, code := range codes {
+		if code.Lookup != "" && code.Lookup != lookup {
+			continue
+		}
+
+		valid, err := checkRecoveryCode(typed, code.Hash)
+		if err != nil {
+			return nil, err
+		}
+
+		if valid {
+			return &codes[i], nil
+		}
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/regulation"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+type HandlerSignRecoveryCodeSuite struct {
+	suite.Suite
+
+	mock  *mocks.MockAutheliaCtx
+	codes []models.RecoveryCode
+}
+
+func (s *HandlerSignRecoveryCodeSuite) SetupSuite() {
+	for _, code := range []string{"abcde-fghjk", "mnpqr-stuvw"} {
+		hash, err := hashRecoveryCode(code)
+		require.NoError(s.T(), err)
+
+		s.codes = append(s.codes, models.RecoveryCode{Username: testUsername, Lookup: recoveryCodeLookup(code), Hash: hash})
+	}
+}
+
+func (s *HandlerSignRecoveryCodeSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = testUsername
+	userSession.AuthenticationLevel = authentication.OneFactor
+	err := s.mock.Ctx.SaveSession(userSession)
+	require.NoError(s.T(), err)
+}
+
+func (s *HandlerSignRecoveryCodeSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *HandlerSignRecoveryCodeSuite) setBody(code string) {
+	bodyBytes, err := json.Marshal(signRecoveryCodeRequestBody{
+		Code: code,
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+}
+
+func (s *HandlerSignRecoveryCodeSuite) expectMark(successful bool) {
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Eq(models.AuthenticationAttempt{
+			Username:   testUsername,
+			Successful: successful,
+			Time:       s.mock.Clock.Now(),
+		})).
+		Return(nil)
+}
+
+func (s *HandlerSignRecoveryCodeSuite) TestShouldAuthenticateAndBurnCode() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadRecoveryCodesByUsername(gomock.Eq(testUsername)).
+		Return(s.codes, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteRecoveryCode(gomock.Eq(testUsername), gomock.Eq(s.codes[1].Hash)).
+		Return(nil)
+
+	s.expectMark(true)
+
+	// The code is accepted regardless of the case and the separators it is typed with.
+	s.setBody("MNPQR STUVW")
+
+	SecondFactorRecoveryCodePost(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Equal(authentication.TwoFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignRecoveryCodeSuite) TestShouldFailWhenCodeIsWrong() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadRecoveryCodesByUsername(gomock.Eq(testUsername)).
+		Return(s.codes, nil)
+
+	s.expectMark(false)
+
+	s.setBody("abcde-fghjm")
+
+	SecondFactorRecoveryCodePost(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("Wrong recovery code for user john", s.mock.Hook.LastEntry().Message)
+	s.Assert().Equal(authentication.OneFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignRecoveryCodeSuite) TestShouldFailWhenCodeHasAlreadyBeenBurnt() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadRecoveryCodesByUsername(gomock.Eq(testUsername)).
+		Return(s.codes, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteRecoveryCode(gomock.Eq(testUsername), gomock.Eq(s.codes[0].Hash)).
+		Return(storage.ErrNoRecoveryCode)
+
+	s.expectMark(false)
+
+	s.setBody("abcde-fghjk")
+
+	SecondFactorRecoveryCodePost(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("Recovery code of user john has already been used", s.mock.Hook.LastEntry().Message)
+	s.Assert().Equal(authentication.OneFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignRecoveryCodeSuite) TestShouldFailWhenUserHasNoCode() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadRecoveryCodesByUsername(gomock.Eq(testUsername)).
+		Return(nil, storage.ErrNoRecoveryCode)
+
+	s.setBody("abcde-fghjk")
+
+	SecondFactorRecoveryCodePost(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("Unable to load recovery codes of user john: No recovery code found", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerSignRecoveryCodeSuite) TestShouldFailWhenCodeCannotBeBurnt() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadRecoveryCodesByUsername(gomock.Eq(testUsername)).
+		Return(s.codes, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteRecoveryCode(gomock.Eq(testUsername), gomock.Eq(s.codes[0].Hash)).
+		Return(fmt.Errorf("failure"))
+
+	s.setBody("abcde-fghjk")
+
+	SecondFactorRecoveryCodePost(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("Unable to burn recovery code of user john: failure", s.mock.Hook.LastEntry().Message)
+	s.Assert().Equal(authentication.OneFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignRecoveryCodeSuite) TestShouldNotCheckCodeWhenUserIsBanned() {
+	s.mock.Ctx.Providers.Regulator = regulation.NewRegulator(&schema.RegulationConfiguration{
+		MaxRetries: 1,
+		FindTime:   "2m",
+		BanTime:    "5m",
+	}, s.mock.StorageProviderMock, &s.mock.Clock)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadLatestAuthenticationLogs(gomock.Eq(testUsername), gomock.Any()).
+		Return([]models.AuthenticationAttempt{{Username: testUsername, Successful: false, Time: s.mock.Clock.Now()}}, nil)
+
+	s.setBody("abcde-fghjk")
+
+	SecondFactorRecoveryCodePost(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), userBannedMessage)
+	s.Assert().Equal(authentication.OneFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignRecoveryCodeSuite) TestShouldOnlyCheckCodeWithSameLookup() {
+	// The hash of the other code is not valid, checking it would fail the request.
+	codes := []models.RecoveryCode{
+		{Username: testUsername, Lookup: "zz", Hash: "invalid"},
+		s.codes[1],
+	}
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadRecoveryCodesByUsername(gomock.Eq(testUsername)).
+		Return(codes, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteRecoveryCode(gomock.Eq(testUsername), gomock.Eq(s.codes[1].Hash)).
+		Return(nil)
+
+	s.expectMark(true)
+
+	s.setBody("mnpqr-stuvw")
+
+	SecondFactorRecoveryCodePost(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Equal(authentication.TwoFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignRecoveryCodeSuite) TestShouldCheckCodeGeneratedWithoutLookup() {
+	code := s.codes[0]
+	code.Lookup = ""
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadRecoveryCodesByUsername(gomock.Eq(testUsername)).
+		Return([]models.RecoveryCode{code}, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteRecoveryCode(gomock.Eq(testUsername), gomock.Eq(code.Hash)).
+		Return(nil)
+
+	s.expectMark(true)
+
+	s.setBody("abcde-fghjk")
+
+	SecondFactorRecoveryCodePost(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), nil)
+}
+
+func TestRunHandlerSignRecoveryCodeSuite(t *testing.T) {
+	suite.Run(t, new(HandlerSignRecoveryCodeSuite))
+}
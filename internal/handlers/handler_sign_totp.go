@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/metrics"
 	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/storage"
 )
 
 // SecondFactorTOTPPost validate the TOTP passcode provided by the user.
@@ -20,23 +25,46 @@ func SecondFactorTOTPPost(totpVerifier TOTPVerifier) middlewares.RequestHandler
 
 		userSession := ctx.GetSession()
 
-		secret, err := ctx.Providers.StorageProvider.LoadTOTPSecret(userSession.Username)
+		devices, err := ctx.Providers.StorageProvider.LoadTOTPDevicesByUsername(userSession.Username)
 		if err != nil {
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to load TOTP secret: %s", err), mfaValidationFailedMessage)
 			return
 		}
 
-		isValid, err := totpVerifier.Verify(bodyJSON.Token, secret)
+		device, step, err := findTOTPDevice(totpVerifier, devices, bodyJSON.Token, ctx.Clock.Now())
 		if err != nil {
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Error occurred during OTP validation for user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
 			return
 		}
 
-		if !isValid {
+		if device == nil {
+			recordAuthentication(ctx, metrics.FactorSecond, authentication.TOTP, userSession.Username, bodyJSON.TargetURL, false)
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Wrong passcode during TOTP validation for user %s", userSession.Username), mfaValidationFailedMessage)
 			return
 		}
 
+		if step <= device.LastStep {
+			recordAuthentication(ctx, metrics.FactorSecond, authentication.TOTP, userSession.Username, bodyJSON.TargetURL, false)
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("TOTP passcode of user %s has already been used", userSession.Username), mfaValidationFailedMessage)
+			return
+		}
+
+		// The step is only recorded if it is greater than the stored one so that two replicas sharing the
+		// database cannot both accept the same passcode.
+		err = ctx.Providers.StorageProvider.UpdateTOTPDeviceLastStep(userSession.Username, device.ID, step, ctx.Clock.Now())
+		if errors.Is(err, storage.ErrTOTPStepAlreadyUsed) {
+			recordAuthentication(ctx, metrics.FactorSecond, authentication.TOTP, userSession.Username, bodyJSON.TargetURL, false)
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("TOTP passcode of user %s has already been used", userSession.Username), mfaValidationFailedMessage)
+			return
+		}
+
+		if err != nil {
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to record the time step of TOTP device of user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+			return
+		}
+
+		recordAuthentication(ctx, metrics.FactorSecond, authentication.TOTP, userSession.Username, bodyJSON.TargetURL, true)
+
 		err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx)
 
 		if err != nil {
@@ -45,6 +73,7 @@ func SecondFactorTOTPPost(totpVerifier TOTPVerifier) middlewares.RequestHandler
 		}
 
 		userSession.AuthenticationLevel = authentication.TwoFactor
+		userSession.SecondFactorAt = ctx.Clock.Now().Unix()
 		err = ctx.SaveSession(userSession)
 
 		if err != nil {
@@ -55,3 +84,20 @@ func SecondFactorTOTPPost(totpVerifier TOTPVerifier) middlewares.RequestHandler
 		Handle2FAResponse(ctx, bodyJSON.TargetURL)
 	}
 }
+
+// findTOTPDevice finds the device which generated the passcode amongst the devices of the user, nil if none did.
+// The time step the passcode has been generated for is returned alongside the device.
+func findTOTPDevice(totpVerifier TOTPVerifier, devices []models.TOTPDevice, token string, now time.Time) (*models.TOTPDevice, uint64, error) {
+	for i, device := range devices {
+		step, isValid, err := totpVerifier.Verify(token, device, now)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if isValid {
+			return &devices[i], step, nil
+		}
+	}
+
+	return nil, 0, nil
+}
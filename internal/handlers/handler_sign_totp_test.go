@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
 	"regexp"
 	"testing"
@@ -10,8 +11,12 @@ import (
 	"github.com/stretchr/testify/suite"
 	"github.com/tstranex/u2f"
 
+	"github.com/authelia/authelia/internal/audit"
+	"github.com/authelia/authelia/internal/authentication"
 	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
 	"github.com/authelia/authelia/internal/session"
+	"github.com/authelia/authelia/internal/storage"
 )
 
 type HandlerSignTOTPSuite struct {
@@ -22,10 +27,11 @@ type HandlerSignTOTPSuite struct {
 
 func (s *HandlerSignTOTPSuite) SetupTest() {
 	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Clock = &s.mock.Clock
 	userSession := s.mock.Ctx.GetSession()
 	userSession.Username = testUsername
 	userSession.U2FChallenge = &u2f.Challenge{}
-	userSession.U2FRegistration = &session.U2FRegistration{}
+	userSession.U2FRegistrations = []session.U2FRegistration{{}}
 	err := s.mock.Ctx.SaveSession(userSession)
 	require.NoError(s.T(), err)
 }
@@ -38,12 +44,16 @@ func (s *HandlerSignTOTPSuite) TestShouldRedirectUserToDefaultURL() {
 	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
 
 	s.mock.StorageProviderMock.EXPECT().
-		LoadTOTPSecret(gomock.Any()).
-		Return("secret", nil)
+		LoadTOTPDevicesByUsername(gomock.Eq(testUsername)).
+		Return([]models.TOTPDevice{{Username: testUsername, ID: "primary", Secret: "secret"}}, nil)
 
 	verifier.EXPECT().
-		Verify(gomock.Eq("abc"), gomock.Eq("secret")).
-		Return(true, nil)
+		Verify(gomock.Eq("abc"), gomock.Eq(models.TOTPDevice{Username: testUsername, ID: "primary", Secret: "secret"}), gomock.Eq(s.mock.Clock.Now())).
+		Return(uint64(53333336), true, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateTOTPDeviceLastStep(gomock.Eq(testUsername), gomock.Eq("primary"), gomock.Eq(uint64(53333336)), gomock.Any()).
+		Return(nil)
 
 	s.mock.Ctx.Configuration.DefaultRedirectionURL = testRedirectionURL
 
@@ -63,12 +73,16 @@ func (s *HandlerSignTOTPSuite) TestShouldNotReturnRedirectURL() {
 	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
 
 	s.mock.StorageProviderMock.EXPECT().
-		LoadTOTPSecret(gomock.Any()).
-		Return("secret", nil)
+		LoadTOTPDevicesByUsername(gomock.Eq(testUsername)).
+		Return([]models.TOTPDevice{{Username: testUsername, ID: "primary", Secret: "secret"}}, nil)
 
 	verifier.EXPECT().
-		Verify(gomock.Eq("abc"), gomock.Eq("secret")).
-		Return(true, nil)
+		Verify(gomock.Eq("abc"), gomock.Eq(models.TOTPDevice{Username: testUsername, ID: "primary", Secret: "secret"}), gomock.Eq(s.mock.Clock.Now())).
+		Return(uint64(53333336), true, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateTOTPDeviceLastStep(gomock.Eq(testUsername), gomock.Eq("primary"), gomock.Eq(uint64(53333336)), gomock.Any()).
+		Return(nil)
 
 	bodyBytes, err := json.Marshal(signTOTPRequestBody{
 		Token: "abc",
@@ -84,12 +98,16 @@ func (s *HandlerSignTOTPSuite) TestShouldRedirectUserToSafeTargetURL() {
 	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
 
 	s.mock.StorageProviderMock.EXPECT().
-		LoadTOTPSecret(gomock.Any()).
-		Return("secret", nil)
+		LoadTOTPDevicesByUsername(gomock.Eq(testUsername)).
+		Return([]models.TOTPDevice{{Username: testUsername, ID: "primary", Secret: "secret"}}, nil)
 
 	verifier.EXPECT().
-		Verify(gomock.Eq("abc"), gomock.Eq("secret")).
-		Return(true, nil)
+		Verify(gomock.Eq("abc"), gomock.Eq(models.TOTPDevice{Username: testUsername, ID: "primary", Secret: "secret"}), gomock.Eq(s.mock.Clock.Now())).
+		Return(uint64(53333336), true, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateTOTPDeviceLastStep(gomock.Eq(testUsername), gomock.Eq("primary"), gomock.Eq(uint64(53333336)), gomock.Any()).
+		Return(nil)
 
 	bodyBytes, err := json.Marshal(signTOTPRequestBody{
 		Token:     "abc",
@@ -108,12 +126,16 @@ func (s *HandlerSignTOTPSuite) TestShouldNotRedirectToUnsafeURL() {
 	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
 
 	s.mock.StorageProviderMock.EXPECT().
-		LoadTOTPSecret(gomock.Any()).
-		Return("secret", nil)
+		LoadTOTPDevicesByUsername(gomock.Eq(testUsername)).
+		Return([]models.TOTPDevice{{Username: testUsername, ID: "primary", Secret: "secret"}}, nil)
 
 	verifier.EXPECT().
-		Verify(gomock.Eq("abc"), gomock.Eq("secret")).
-		Return(true, nil)
+		Verify(gomock.Eq("abc"), gomock.Eq(models.TOTPDevice{Username: testUsername, ID: "primary", Secret: "secret"}), gomock.Eq(s.mock.Clock.Now())).
+		Return(uint64(53333336), true, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateTOTPDeviceLastStep(gomock.Eq(testUsername), gomock.Eq("primary"), gomock.Eq(uint64(53333336)), gomock.Any()).
+		Return(nil)
 
 	bodyBytes, err := json.Marshal(signTOTPRequestBody{
 		Token:     "abc",
@@ -130,12 +152,16 @@ func (s *HandlerSignTOTPSuite) TestShouldRegenerateSessionForPreventingSessionFi
 	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
 
 	s.mock.StorageProviderMock.EXPECT().
-		LoadTOTPSecret(gomock.Any()).
-		Return("secret", nil)
+		LoadTOTPDevicesByUsername(gomock.Eq(testUsername)).
+		Return([]models.TOTPDevice{{Username: testUsername, ID: "primary", Secret: "secret"}}, nil)
 
 	verifier.EXPECT().
-		Verify(gomock.Eq("abc"), gomock.Eq("secret")).
-		Return(true, nil)
+		Verify(gomock.Eq("abc"), gomock.Eq(models.TOTPDevice{Username: testUsername, ID: "primary", Secret: "secret"}), gomock.Eq(s.mock.Clock.Now())).
+		Return(uint64(53333336), true, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateTOTPDeviceLastStep(gomock.Eq(testUsername), gomock.Eq("primary"), gomock.Eq(uint64(53333336)), gomock.Any()).
+		Return(nil)
 
 	bodyBytes, err := json.Marshal(signTOTPRequestBody{
 		Token: "abc",
@@ -154,6 +180,152 @@ func (s *HandlerSignTOTPSuite) TestShouldRegenerateSessionForPreventingSessionFi
 		string(s.mock.Ctx.Request.Header.Cookie("authelia_session")))
 }
 
+func (s *HandlerSignTOTPSuite) TestShouldAcceptPasscodeOfAnyDevice() {
+	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadTOTPDevicesByUsername(gomock.Eq(testUsername)).
+		Return([]models.TOTPDevice{
+			{Username: testUsername, ID: "primary", Secret: "secret"},
+			{Username: testUsername, ID: "k3b9Qz", Secret: "tablet_secret"},
+		}, nil)
+
+	gomock.InOrder(
+		verifier.EXPECT().
+			Verify(gomock.Eq("abc"), gomock.Eq(models.TOTPDevice{Username: testUsername, ID: "primary", Secret: "secret"}), gomock.Eq(s.mock.Clock.Now())).
+			Return(uint64(0), false, nil),
+		verifier.EXPECT().
+			Verify(gomock.Eq("abc"), gomock.Eq(models.TOTPDevice{Username: testUsername, ID: "k3b9Qz", Secret: "tablet_secret"}), gomock.Eq(s.mock.Clock.Now())).
+			Return(uint64(53333336), true, nil),
+	)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateTOTPDeviceLastStep(gomock.Eq(testUsername), gomock.Eq("k3b9Qz"), gomock.Eq(uint64(53333336)), gomock.Eq(s.mock.Clock.Now())).
+		Return(nil)
+
+	bodyBytes, err := json.Marshal(signTOTPRequestBody{
+		Token: "abc",
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorTOTPPost(verifier)(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Equal(authentication.TwoFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignTOTPSuite) TestShouldFailWhenNoDeviceGeneratedPasscode() {
+	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadTOTPDevicesByUsername(gomock.Eq(testUsername)).
+		Return([]models.TOTPDevice{
+			{Username: testUsername, ID: "primary", Secret: "secret"},
+			{Username: testUsername, ID: "k3b9Qz", Secret: "tablet_secret"},
+		}, nil)
+
+	verifier.EXPECT().
+		Verify(gomock.Eq("abc"), gomock.Any(), gomock.Eq(s.mock.Clock.Now())).
+		Return(uint64(0), false, nil).
+		Times(2)
+
+	bodyBytes, err := json.Marshal(signTOTPRequestBody{
+		Token: "abc",
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorTOTPPost(verifier)(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("Wrong passcode during TOTP validation for user john", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerSignTOTPSuite) TestShouldRefuseReplayedPasscode() {
+	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
+	device := models.TOTPDevice{Username: testUsername, ID: "primary", Secret: "secret", LastStep: 53333336}
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadTOTPDevicesByUsername(gomock.Eq(testUsername)).
+		Return([]models.TOTPDevice{device}, nil)
+
+	verifier.EXPECT().
+		Verify(gomock.Eq("abc"), gomock.Eq(device), gomock.Eq(s.mock.Clock.Now())).
+		Return(uint64(53333336), true, nil)
+
+	bodyBytes, err := json.Marshal(signTOTPRequestBody{
+		Token: "abc",
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorTOTPPost(verifier)(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("TOTP passcode of user john has already been used", s.mock.Hook.LastEntry().Message)
+	s.Assert().Equal(authentication.NotAuthenticated, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignTOTPSuite) TestShouldRefusePasscodeAcceptedConcurrentlyByAnotherInstance() {
+	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadTOTPDevicesByUsername(gomock.Eq(testUsername)).
+		Return([]models.TOTPDevice{{Username: testUsername, ID: "primary", Secret: "secret", LastStep: 53333335}}, nil)
+
+	verifier.EXPECT().
+		Verify(gomock.Eq("abc"), gomock.Any(), gomock.Eq(s.mock.Clock.Now())).
+		Return(uint64(53333336), true, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateTOTPDeviceLastStep(gomock.Eq(testUsername), gomock.Eq("primary"), gomock.Eq(uint64(53333336)), gomock.Any()).
+		Return(storage.ErrTOTPStepAlreadyUsed)
+
+	bodyBytes, err := json.Marshal(signTOTPRequestBody{
+		Token: "abc",
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorTOTPPost(verifier)(s.mock.Ctx)
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("TOTP passcode of user john has already been used", s.mock.Hook.LastEntry().Message)
+	s.Assert().Equal(authentication.NotAuthenticated, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignTOTPSuite) TestShouldAuditWrongPasscode() {
+	buffer := new(bytes.Buffer)
+	s.mock.Ctx.Providers.Audit = audit.NewJSONLogger(buffer)
+	s.mock.Ctx.Request.Header.SetUserAgent("curl/7.68.0")
+
+	verifier := NewMockTOTPVerifier(s.mock.Ctrl)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadTOTPDevicesByUsername(gomock.Eq(testUsername)).
+		Return([]models.TOTPDevice{{Username: testUsername, ID: "primary", Secret: "secret"}}, nil)
+
+	verifier.EXPECT().
+		Verify(gomock.Eq("abc"), gomock.Any(), gomock.Eq(s.mock.Clock.Now())).
+		Return(uint64(0), false, nil)
+
+	bodyBytes, err := json.Marshal(signTOTPRequestBody{
+		Token:     "abc",
+		TargetURL: "https://mydomain.local",
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorTOTPPost(verifier)(s.mock.Ctx)
+
+	var event audit.Event
+
+	s.Require().NoError(json.Unmarshal(buffer.Bytes(), &event))
+	s.Assert().Equal(audit.EventSecondFactor, event.Type)
+	s.Assert().Equal(testUsername, event.Username)
+	s.Assert().Equal("curl/7.68.0", event.UserAgent)
+	s.Assert().Equal("https://mydomain.local", event.TargetURL)
+	s.Assert().Equal(authentication.TOTP, event.Method)
+	s.Assert().Equal(audit.OutcomeFailure, event.Outcome)
+}
+
 func TestRunHandlerSignTOTPSuite(t *testing.T) {
 	suite.Run(t, new(HandlerSignTOTPSuite))
 }
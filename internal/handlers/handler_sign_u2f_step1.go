@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"crypto/elliptic"
 	"fmt"
 
 	"github.com/tstranex/u2f"
@@ -34,7 +33,7 @@ func SecondFactorU2FSignGet(ctx *middlewares.AutheliaCtx) {
 	}
 
 	userSession := ctx.GetSession()
-	keyHandleBytes, publicKeyBytes, err := ctx.Providers.StorageProvider.LoadU2FDeviceHandle(userSession.Username)
+	devices, err := ctx.Providers.StorageProvider.LoadU2FDevicesByUsername(userSession.Username)
 
 	if err != nil {
 		if err == storage.ErrNoU2FDeviceHandle {
@@ -47,18 +46,17 @@ func SecondFactorU2FSignGet(ctx *middlewares.AutheliaCtx) {
 		return
 	}
 
-	var registration u2f.Registration
-	registration.KeyHandle = keyHandleBytes
-	x, y := elliptic.Unmarshal(elliptic.P256(), publicKeyBytes)
-	registration.PubKey.Curve = elliptic.P256()
-	registration.PubKey.X = x
-	registration.PubKey.Y = y
-
-	// Save the challenge and registration for use in next request
-	userSession.U2FRegistration = &session.U2FRegistration{
-		KeyHandle: keyHandleBytes,
-		PublicKey: publicKeyBytes,
+	// Save the challenge and registrations for use in next request
+	userSession.U2FRegistrations = make([]session.U2FRegistration, len(devices))
+	for i, device := range devices {
+		userSession.U2FRegistrations[i] = session.U2FRegistration{
+			Description: device.Description,
+			KeyHandle:   device.KeyHandle,
+			PublicKey:   device.PublicKey,
+			Counter:     device.Counter,
+		}
 	}
+
 	userSession.U2FChallenge = challenge
 	err = ctx.SaveSession(userSession)
 
@@ -67,7 +65,8 @@ func SecondFactorU2FSignGet(ctx *middlewares.AutheliaCtx) {
 		return
 	}
 
-	signRequest := challenge.SignRequest([]u2f.Registration{registration})
+	// The user can sign with any of the registered devices.
+	signRequest := challenge.SignRequest(newU2FRegistrations(devices))
 	err = ctx.SetJSONBody(signRequest)
 
 	if err != nil {
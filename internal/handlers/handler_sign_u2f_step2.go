@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"bytes"
 	"fmt"
 
 	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/metrics"
 	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/session"
+	"github.com/authelia/authelia/internal/storage"
+	"github.com/authelia/authelia/internal/utils"
 )
 
 // SecondFactorU2FSignPost handler for completing a signing request.
@@ -24,22 +29,69 @@ func SecondFactorU2FSignPost(u2fVerifier U2FVerifier) middlewares.RequestHandler
 			return
 		}
 
-		if userSession.U2FRegistration == nil {
+		if len(userSession.U2FRegistrations) == 0 {
 			handleAuthenticationUnauthorized(ctx, fmt.Errorf("U2F signing has not been initiated yet (no registration)"), mfaValidationFailedMessage)
 			return
 		}
 
-		err = u2fVerifier.Verify(
-			userSession.U2FRegistration.KeyHandle,
-			userSession.U2FRegistration.PublicKey,
+		challenge := *userSession.U2FChallenge
+		registrations := userSession.U2FRegistrations
+
+		// The challenge can only be used once, it is cleared from the session before the signature is verified so that
+		// it cannot be replayed after a failure.
+		userSession.U2FChallenge = nil
+		userSession.U2FRegistrations = nil
+
+		if err = ctx.SaveSession(userSession); err != nil {
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to clear U2F challenge in session for user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+			return
+		}
+
+		registration, err := findU2FRegistration(registrations, requestBody.SignResponse.KeyHandle)
+		if err != nil {
+			recordAuthentication(ctx, metrics.FactorSecond, authentication.U2F, userSession.Username, requestBody.TargetURL, false)
+			handleAuthenticationUnauthorized(ctx, err, mfaValidationFailedMessage)
+			return
+		}
+
+		counter, err := u2fVerifier.Verify(
+			registration.KeyHandle,
+			registration.PublicKey,
+			registration.Counter,
 			requestBody.SignResponse,
-			*userSession.U2FChallenge)
+			challenge)
+
+		if err == nil {
+			// The counter is only saved when it is greater than the stored one, another sign in might have happened
+			// since the sign request.
+			err = ctx.Providers.StorageProvider.UpdateU2FDeviceCounter(userSession.Username, registration.KeyHandle, counter, ctx.Clock.Now())
+
+			switch {
+			case err == storage.ErrSignCountNotIncreasing:
+				err = errU2FCounterNotIncreasing
+			case err != nil:
+				handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to save the signature counter of the U2F device of user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+				return
+			}
+		}
+
+		if err == errU2FCounterNotIncreasing {
+			recordAuthentication(ctx, metrics.FactorSecond, authentication.U2F, userSession.Username, requestBody.TargetURL, false)
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("The signature counter of the U2F device %s of user %s did not increase, "+
+				"the device might have been cloned", registration.Description, userSession.Username), mfaValidationFailedMessage)
+			notifyClonedDevice(ctx, userSession, registration.Description)
+
+			return
+		}
 
 		if err != nil {
+			recordAuthentication(ctx, metrics.FactorSecond, authentication.U2F, userSession.Username, requestBody.TargetURL, false)
 			ctx.Error(err, mfaValidationFailedMessage)
 			return
 		}
 
+		recordAuthentication(ctx, metrics.FactorSecond, authentication.U2F, userSession.Username, requestBody.TargetURL, true)
+
 		err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx)
 
 		if err != nil {
@@ -48,6 +100,7 @@ func SecondFactorU2FSignPost(u2fVerifier U2FVerifier) middlewares.RequestHandler
 		}
 
 		userSession.AuthenticationLevel = authentication.TwoFactor
+		userSession.SecondFactorAt = ctx.Clock.Now().Unix()
 		err = ctx.SaveSession(userSession)
 
 		if err != nil {
@@ -58,3 +111,19 @@ func SecondFactorU2FSignPost(u2fVerifier U2FVerifier) middlewares.RequestHandler
 		Handle2FAResponse(ctx, requestBody.TargetURL)
 	}
 }
+
+// findU2FRegistration finds the registration of the device which signed the challenge.
+func findU2FRegistration(registrations []session.U2FRegistration, keyHandle string) (*session.U2FRegistration, error) {
+	keyHandleBytes, err := utils.DecodeKeyHandle(keyHandle)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to decode U2F key handle: %s", err)
+	}
+
+	for i, registration := range registrations {
+		if bytes.Equal(registration.KeyHandle, keyHandleBytes) {
+			return &registrations[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("U2F key handle %s does not belong to the user", keyHandle)
+}
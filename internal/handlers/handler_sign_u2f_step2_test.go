@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"regexp"
 	"testing"
 
@@ -10,8 +11,11 @@ import (
 	"github.com/stretchr/testify/suite"
 	"github.com/tstranex/u2f"
 
+	"github.com/authelia/authelia/internal/authentication"
 	"github.com/authelia/authelia/internal/mocks"
 	"github.com/authelia/authelia/internal/session"
+	"github.com/authelia/authelia/internal/storage"
+	"github.com/authelia/authelia/internal/utils"
 )
 
 type HandlerSignU2FStep2Suite struct {
@@ -25,7 +29,10 @@ func (s *HandlerSignU2FStep2Suite) SetupTest() {
 	userSession := s.mock.Ctx.GetSession()
 	userSession.Username = testUsername
 	userSession.U2FChallenge = &u2f.Challenge{}
-	userSession.U2FRegistration = &session.U2FRegistration{}
+	userSession.U2FRegistrations = []session.U2FRegistration{
+		{KeyHandle: []byte("primary"), PublicKey: []byte("primary_key")},
+		{Description: "Backup", KeyHandle: []byte("backup"), PublicKey: []byte("backup_key"), Counter: 41},
+	}
 	err := s.mock.Ctx.SaveSession(userSession)
 	require.NoError(s.T(), err)
 }
@@ -38,13 +45,17 @@ func (s *HandlerSignU2FStep2Suite) TestShouldRedirectUserToDefaultURL() {
 	u2fVerifier := NewMockU2FVerifier(s.mock.Ctrl)
 
 	u2fVerifier.EXPECT().
-		Verify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Verify(gomock.Eq([]byte("backup")), gomock.Eq([]byte("backup_key")), gomock.Eq(uint32(41)), gomock.Any(), gomock.Any()).
+		Return(uint32(42), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateU2FDeviceCounter(gomock.Eq(testUsername), gomock.Eq([]byte("backup")), gomock.Eq(uint32(42)), gomock.Any()).
 		Return(nil)
 
 	s.mock.Ctx.Configuration.DefaultRedirectionURL = testRedirectionURL
 
 	bodyBytes, err := json.Marshal(signU2FRequestBody{
-		SignResponse: u2f.SignResponse{},
+		SignResponse: u2f.SignResponse{KeyHandle: utils.EncodeKeyHandle([]byte("backup"))},
 	})
 	s.Require().NoError(err)
 	s.mock.Ctx.Request.SetBody(bodyBytes)
@@ -59,11 +70,15 @@ func (s *HandlerSignU2FStep2Suite) TestShouldNotReturnRedirectURL() {
 	u2fVerifier := NewMockU2FVerifier(s.mock.Ctrl)
 
 	u2fVerifier.EXPECT().
-		Verify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Verify(gomock.Eq([]byte("backup")), gomock.Eq([]byte("backup_key")), gomock.Eq(uint32(41)), gomock.Any(), gomock.Any()).
+		Return(uint32(42), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateU2FDeviceCounter(gomock.Eq(testUsername), gomock.Eq([]byte("backup")), gomock.Eq(uint32(42)), gomock.Any()).
 		Return(nil)
 
 	bodyBytes, err := json.Marshal(signU2FRequestBody{
-		SignResponse: u2f.SignResponse{},
+		SignResponse: u2f.SignResponse{KeyHandle: utils.EncodeKeyHandle([]byte("backup"))},
 	})
 	s.Require().NoError(err)
 	s.mock.Ctx.Request.SetBody(bodyBytes)
@@ -76,11 +91,15 @@ func (s *HandlerSignU2FStep2Suite) TestShouldRedirectUserToSafeTargetURL() {
 	u2fVerifier := NewMockU2FVerifier(s.mock.Ctrl)
 
 	u2fVerifier.EXPECT().
-		Verify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Verify(gomock.Eq([]byte("backup")), gomock.Eq([]byte("backup_key")), gomock.Eq(uint32(41)), gomock.Any(), gomock.Any()).
+		Return(uint32(42), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateU2FDeviceCounter(gomock.Eq(testUsername), gomock.Eq([]byte("backup")), gomock.Eq(uint32(42)), gomock.Any()).
 		Return(nil)
 
 	bodyBytes, err := json.Marshal(signU2FRequestBody{
-		SignResponse: u2f.SignResponse{},
+		SignResponse: u2f.SignResponse{KeyHandle: utils.EncodeKeyHandle([]byte("backup"))},
 		TargetURL:    "https://mydomain.local",
 	})
 	s.Require().NoError(err)
@@ -96,11 +115,15 @@ func (s *HandlerSignU2FStep2Suite) TestShouldNotRedirectToUnsafeURL() {
 	u2fVerifier := NewMockU2FVerifier(s.mock.Ctrl)
 
 	u2fVerifier.EXPECT().
-		Verify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Verify(gomock.Eq([]byte("backup")), gomock.Eq([]byte("backup_key")), gomock.Eq(uint32(41)), gomock.Any(), gomock.Any()).
+		Return(uint32(42), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateU2FDeviceCounter(gomock.Eq(testUsername), gomock.Eq([]byte("backup")), gomock.Eq(uint32(42)), gomock.Any()).
 		Return(nil)
 
 	bodyBytes, err := json.Marshal(signU2FRequestBody{
-		SignResponse: u2f.SignResponse{},
+		SignResponse: u2f.SignResponse{KeyHandle: utils.EncodeKeyHandle([]byte("backup"))},
 		TargetURL:    "http://mydomain.local",
 	})
 	s.Require().NoError(err)
@@ -114,11 +137,15 @@ func (s *HandlerSignU2FStep2Suite) TestShouldRegenerateSessionForPreventingSessi
 	u2fVerifier := NewMockU2FVerifier(s.mock.Ctrl)
 
 	u2fVerifier.EXPECT().
-		Verify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Verify(gomock.Eq([]byte("backup")), gomock.Eq([]byte("backup_key")), gomock.Eq(uint32(41)), gomock.Any(), gomock.Any()).
+		Return(uint32(42), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateU2FDeviceCounter(gomock.Eq(testUsername), gomock.Eq([]byte("backup")), gomock.Eq(uint32(42)), gomock.Any()).
 		Return(nil)
 
 	bodyBytes, err := json.Marshal(signU2FRequestBody{
-		SignResponse: u2f.SignResponse{},
+		SignResponse: u2f.SignResponse{KeyHandle: utils.EncodeKeyHandle([]byte("backup"))},
 	})
 	s.Require().NoError(err)
 	s.mock.Ctx.Request.SetBody(bodyBytes)
@@ -134,6 +161,131 @@ func (s *HandlerSignU2FStep2Suite) TestShouldRegenerateSessionForPreventingSessi
 		string(s.mock.Ctx.Request.Header.Cookie("authelia_session")))
 }
 
+func (s *HandlerSignU2FStep2Suite) TestShouldFailWhenKeyHandleIsNotRegistered() {
+	u2fVerifier := NewMockU2FVerifier(s.mock.Ctrl)
+
+	bodyBytes, err := json.Marshal(signU2FRequestBody{
+		SignResponse: u2f.SignResponse{KeyHandle: utils.EncodeKeyHandle([]byte("unknown"))},
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorU2FSignPost(u2fVerifier)(s.mock.Ctx)
+
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("U2F key handle dW5rbm93bg does not belong to the user", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerSignU2FStep2Suite) TestShouldFailAndNotifyUserWhenCounterDoesNotIncrease() {
+	u2fVerifier := NewMockU2FVerifier(s.mock.Ctrl)
+
+	s.mock.Ctx.Configuration.Webauthn.NotifyClonedDevices = true
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Emails = []string{"john@example.com"}
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	u2fVerifier.EXPECT().
+		Verify(gomock.Eq([]byte("backup")), gomock.Eq([]byte("backup_key")), gomock.Eq(uint32(41)), gomock.Any(), gomock.Any()).
+		Return(uint32(0), errU2FCounterNotIncreasing)
+
+	s.mock.NotifierMock.EXPECT().
+		Send(gomock.Eq("john@example.com"), gomock.Eq(clonedDeviceNotificationTitle), gomock.Any(), gomock.Eq("")).
+		DoAndReturn(func(_, _, body, _ string) error {
+			s.Assert().Contains(body, `The security key "Backup" of your account john`)
+			return nil
+		})
+
+	bodyBytes, err := json.Marshal(signU2FRequestBody{
+		SignResponse: u2f.SignResponse{KeyHandle: utils.EncodeKeyHandle([]byte("backup"))},
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorU2FSignPost(u2fVerifier)(s.mock.Ctx)
+
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("The signature counter of the U2F device Backup of user john did not increase, the device might have been cloned",
+		s.mock.Hook.LastEntry().Message)
+	s.Assert().Equal(authentication.NotAuthenticated, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignU2FStep2Suite) TestShouldFailWhenCounterCannotBeSaved() {
+	u2fVerifier := NewMockU2FVerifier(s.mock.Ctrl)
+
+	u2fVerifier.EXPECT().
+		Verify(gomock.Eq([]byte("backup")), gomock.Eq([]byte("backup_key")), gomock.Eq(uint32(41)), gomock.Any(), gomock.Any()).
+		Return(uint32(42), nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateU2FDeviceCounter(gomock.Eq(testUsername), gomock.Eq([]byte("backup")), gomock.Eq(uint32(42)), gomock.Any()).
+		Return(fmt.Errorf("failure"))
+
+	bodyBytes, err := json.Marshal(signU2FRequestBody{
+		SignResponse: u2f.SignResponse{KeyHandle: utils.EncodeKeyHandle([]byte("backup"))},
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorU2FSignPost(u2fVerifier)(s.mock.Ctx)
+
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("Unable to save the signature counter of the U2F device of user john: failure", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerSignU2FStep2Suite) TestShouldFailWhenCounterIncreasedConcurrently() {
+	u2fVerifier := NewMockU2FVerifier(s.mock.Ctrl)
+
+	u2fVerifier.EXPECT().
+		Verify(gomock.Eq([]byte("backup")), gomock.Eq([]byte("backup_key")), gomock.Eq(uint32(41)), gomock.Any(), gomock.Any()).
+		Return(uint32(42), nil)
+
+	// Another sign in recorded a greater counter since the sign request.
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateU2FDeviceCounter(gomock.Eq(testUsername), gomock.Eq([]byte("backup")), gomock.Eq(uint32(42)), gomock.Any()).
+		Return(storage.ErrSignCountNotIncreasing)
+
+	bodyBytes, err := json.Marshal(signU2FRequestBody{
+		SignResponse: u2f.SignResponse{KeyHandle: utils.EncodeKeyHandle([]byte("backup"))},
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorU2FSignPost(u2fVerifier)(s.mock.Ctx)
+
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("The signature counter of the U2F device Backup of user john did not increase, the device might have been cloned",
+		s.mock.Hook.LastEntry().Message)
+	s.Assert().Equal(authentication.NotAuthenticated, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignU2FStep2Suite) TestShouldNotReplayChallenge() {
+	u2fVerifier := NewMockU2FVerifier(s.mock.Ctrl)
+
+	u2fVerifier.EXPECT().
+		Verify(gomock.Eq([]byte("backup")), gomock.Eq([]byte("backup_key")), gomock.Eq(uint32(41)), gomock.Any(), gomock.Any()).
+		Return(uint32(0), fmt.Errorf("invalid signature"))
+
+	bodyBytes, err := json.Marshal(signU2FRequestBody{
+		SignResponse: u2f.SignResponse{KeyHandle: utils.EncodeKeyHandle([]byte("backup"))},
+	})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(bodyBytes)
+
+	SecondFactorU2FSignPost(u2fVerifier)(s.mock.Ctx)
+
+	userSession := s.mock.Ctx.GetSession()
+	s.Assert().Nil(userSession.U2FChallenge)
+	s.Assert().Empty(userSession.U2FRegistrations)
+
+	s.mock.Ctx.Response.Reset()
+
+	SecondFactorU2FSignPost(u2fVerifier)(s.mock.Ctx)
+
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Equal("U2F signing has not been initiated yet (no challenge)", s.mock.Hook.LastEntry().Message)
+}
+
 func TestRunHandlerSignU2FStep2Suite(t *testing.T) {
 	suite.Run(t, new(HandlerSignU2FStep2Suite))
 }
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/duo-labs/webauthn/protocol"
+	"github.com/duo-labs/webauthn/webauthn"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/metrics"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+// SecondFactorWebauthnAssertionGet handler for initiating a Webauthn signing request.
+func SecondFactorWebauthnAssertionGet(ctx *middlewares.AutheliaCtx) {
+	w, err := newWebauthn(ctx)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to configure Webauthn: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	userSession := ctx.GetSession()
+
+	user, err := getWebauthnUser(ctx, userSession)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, err, mfaValidationFailedMessage)
+		return
+	}
+
+	if len(user.Credentials) == 0 {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("No Webauthn device found for user %s", userSession.Username), mfaValidationFailedMessage)
+		return
+	}
+
+	var options []webauthn.LoginOption
+
+	// The appid extension lets the browser assert the devices registered with the U2F API.
+	if user.HasLegacyCredentials() {
+		appID, err := getWebauthnAppID(ctx)
+		if err != nil {
+			handleAuthenticationUnauthorized(ctx, err, mfaValidationFailedMessage)
+			return
+		}
+
+		options = append(options, webauthn.WithAssertionExtensions(protocol.AuthenticationExtensions{"appid": appID}))
+	}
+
+	assertion, data, err := w.BeginLogin(user, options...)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to create Webauthn challenge: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	userSession.Webauthn = data
+
+	if err = ctx.SaveSession(userSession); err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to save Webauthn challenge in session: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	if err = ctx.SetJSONBody(assertion); err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to set sign request in body: %s", err), mfaValidationFailedMessage)
+		return
+	}
+}
+
+// SecondFactorWebauthnAssertionPost handler for completing a Webauthn signing request.
+func SecondFactorWebauthnAssertionPost(ctx *middlewares.AutheliaCtx) {
+	var requestBody signWebauthnRequestBody
+
+	if err := ctx.ParseBody(&requestBody); err != nil {
+		ctx.Error(err, mfaValidationFailedMessage)
+		return
+	}
+
+	userSession := ctx.GetSession()
+	if userSession.Webauthn == nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Webauthn signing has not been initiated yet (no challenge)"), mfaValidationFailedMessage)
+		return
+	}
+
+	data := *userSession.Webauthn
+
+	// The challenge can only be used once, it is cleared from the session before the assertion is validated so that it
+	// cannot be replayed after a failure.
+	userSession.Webauthn = nil
+
+	if err := ctx.SaveSession(userSession); err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to clear Webauthn challenge in session for user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+		return
+	}
+
+	response, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(requestBody.Credential))
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to parse Webauthn assertion: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	user, err := getWebauthnUser(ctx, userSession)
+	if err != nil {
+		handleAuthenticationUnauthorized(ctx, err, mfaValidationFailedMessage)
+		return
+	}
+
+	credential, err := validateWebauthnAssertion(ctx, user, data, response)
+	if err != nil {
+		recordAuthentication(ctx, metrics.FactorSecond, authentication.U2F, userSession.Username, requestBody.TargetURL, false)
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to validate Webauthn assertion of user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+		return
+	}
+
+	if !credential.Authenticator.CloneWarning {
+		// The counter is only saved when it is greater than the stored one, another assertion might have happened
+		// since the challenge has been generated.
+		if user.IsLegacyCredential(credential.ID) {
+			err = ctx.Providers.StorageProvider.UpdateU2FDeviceCounter(userSession.Username, credential.ID, credential.Authenticator.SignCount, ctx.Clock.Now())
+		} else {
+			err = ctx.Providers.StorageProvider.UpdateWebauthnDeviceSignCount(userSession.Username, credential.ID, credential.Authenticator.SignCount)
+		}
+
+		if err != nil && err != storage.ErrSignCountNotIncreasing {
+			handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to save the signature counter of the Webauthn device of user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+			return
+		}
+	}
+
+	if credential.Authenticator.CloneWarning || err == storage.ErrSignCountNotIncreasing {
+		recordAuthentication(ctx, metrics.FactorSecond, authentication.U2F, userSession.Username, requestBody.TargetURL, false)
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("The signature counter of the Webauthn device of user %s did not increase, "+
+			"the device might have been cloned", userSession.Username), mfaValidationFailedMessage)
+		notifyClonedDevice(ctx, userSession, "")
+
+		return
+	}
+
+	recordAuthentication(ctx, metrics.FactorSecond, authentication.U2F, userSession.Username, requestBody.TargetURL, true)
+
+	if err = ctx.Providers.SessionProvider.RegenerateSession(ctx.RequestCtx); err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to regenerate session for user %s: %s", userSession.Username, err), mfaValidationFailedMessage)
+		return
+	}
+
+	userSession.AuthenticationLevel = authentication.TwoFactor
+	userSession.SecondFactorAt = ctx.Clock.Now().Unix()
+
+	if err = ctx.SaveSession(userSession); err != nil {
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to update authentication level with Webauthn: %s", err), mfaValidationFailedMessage)
+		return
+	}
+
+	Handle2FAResponse(ctx, requestBody.TargetURL)
+}
+
+// validateWebauthnAssertion validates the assertion against the credentials of the user. The devices registered
+// with the U2F API and asserted thanks to the appid extension sign the hash of the appID instead of the RP ID.
+func validateWebauthnAssertion(ctx *middlewares.AutheliaCtx, user *webauthnUser, data webauthn.SessionData,
+	response *protocol.ParsedCredentialAssertionData) (*webauthn.Credential, error) {
+	w, err := newWebauthn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsLegacyCredential(response.RawID) {
+		appID, err := getWebauthnAppID(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		appIDHash := sha256.Sum256([]byte(appID))
+
+		if bytes.Equal(response.Response.AuthenticatorData.RPIDHash, appIDHash[:]) {
+			w.Config.RPID = appID
+		}
+	}
+
+	return w.ValidateLogin(user, data, response)
+}
@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/duo-labs/webauthn/protocol"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+const testWebauthnHost = "login.example.com"
+const testWebauthnOrigin = "https://login.example.com"
+
+// testWebauthnAuthenticator is a software authenticator producing attestations and assertions.
+type testWebauthnAuthenticator struct {
+	key     *ecdsa.PrivateKey
+	kid     []byte
+	counter uint32
+}
+
+func newTestWebauthnAuthenticator(t *testing.T) *testWebauthnAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	kid := make([]byte, 16)
+	_, err = rand.Read(kid)
+	require.NoError(t, err)
+
+	return &testWebauthnAuthenticator{key: key, kid: kid}
+}
+
+// U2FPublicKey returns the public key in the format stored for the devices registered with the U2F API.
+func (a *testWebauthnAuthenticator) U2FPublicKey() []byte {
+	return elliptic.Marshal(elliptic.P256(), a.key.X, a.key.Y)
+}
+
+func (a *testWebauthnAuthenticator) Device(t *testing.T, signCount uint32) models.WebauthnDevice {
+	credential, err := webauthnCredentialFromU2FDevice(a.kid, a.U2FPublicKey())
+	require.NoError(t, err)
+
+	return models.WebauthnDevice{
+		Username:        testUsername,
+		KID:             a.kid,
+		PublicKey:       credential.PublicKey,
+		AttestationType: "none",
+		SignCount:       signCount,
+	}
+}
+
+func (a *testWebauthnAuthenticator) authenticatorData(rpID string, flags byte, attestedCredentialData []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+
+	data := append([]byte{}, rpIDHash[:]...)
+	data = append(data, flags)
+	data = append(data, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[33:], a.counter)
+
+	return append(data, attestedCredentialData...)
+}
+
+func (a *testWebauthnAuthenticator) clientData(t *testing.T, ceremony, challenge string) []byte {
+	clientData, err := json.Marshal(map[string]string{
+		"type":      ceremony,
+		"challenge": challenge,
+		"origin":    testWebauthnOrigin,
+	})
+	require.NoError(t, err)
+
+	return clientData
+}
+
+// Attest produces the response of navigator.credentials.create() with the none attestation format.
+func (a *testWebauthnAuthenticator) Attest(t *testing.T, challenge string) []byte {
+	credential, err := webauthnCredentialFromU2FDevice(a.kid, a.U2FPublicKey())
+	require.NoError(t, err)
+
+	attestedCredentialData := make([]byte, 16)
+	attestedCredentialData = append(attestedCredentialData, byte(len(a.kid)>>8), byte(len(a.kid)))
+	attestedCredentialData = append(attestedCredentialData, a.kid...)
+	attestedCredentialData = append(attestedCredentialData, credential.PublicKey...)
+
+	attestationObject, err := cbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authenticatorData(testWebauthnHost, 0x41, attestedCredentialData),
+	})
+	require.NoError(t, err)
+
+	body, err := json.Marshal(map[string]interface{}{
+		"id":    base64.RawURLEncoding.EncodeToString(a.kid),
+		"rawId": base64.RawURLEncoding.EncodeToString(a.kid),
+		"type":  "public-key",
+		"response": map[string]string{
+			"attestationObject": base64.RawURLEncoding.EncodeToString(attestationObject),
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(a.clientData(t, "webauthn.create", challenge)),
+		},
+	})
+	require.NoError(t, err)
+
+	return body
+}
+
+// Assert produces the response of navigator.credentials.get() signed for the given RP ID.
+func (a *testWebauthnAuthenticator) Assert(t *testing.T, rpID, challenge string) []byte {
+	a.counter++
+
+	authenticatorData := a.authenticatorData(rpID, 0x01, nil)
+	clientData := a.clientData(t, "webauthn.get", challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authenticatorData...), clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(t, err)
+
+	body, err := json.Marshal(map[string]interface{}{
+		"id":    base64.RawURLEncoding.EncodeToString(a.kid),
+		"rawId": base64.RawURLEncoding.EncodeToString(a.kid),
+		"type":  "public-key",
+		"response": map[string]string{
+			"authenticatorData": base64.RawURLEncoding.EncodeToString(authenticatorData),
+			"clientDataJSON":    base64.RawURLEncoding.EncodeToString(clientData),
+			"signature":         base64.RawURLEncoding.EncodeToString(signature),
+		},
+	})
+	require.NoError(t, err)
+
+	return body
+}
+
+type HandlerSignWebauthnSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *HandlerSignWebauthnSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-Proto", "https")
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-Host", testWebauthnHost)
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = testUsername
+	userSession.AuthenticationLevel = authentication.OneFactor
+	err := s.mock.Ctx.SaveSession(userSession)
+	s.Require().NoError(err)
+}
+
+func (s *HandlerSignWebauthnSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *HandlerSignWebauthnSuite) expectDevices(times int, devices []models.WebauthnDevice, u2fDevice *testWebauthnAuthenticator) {
+	if len(devices) == 0 {
+		s.mock.StorageProviderMock.EXPECT().
+			LoadWebauthnDevicesByUsername(gomock.Eq(testUsername)).
+			Return(nil, storage.ErrNoWebauthnDevice).
+			Times(times)
+	} else {
+		s.mock.StorageProviderMock.EXPECT().
+			LoadWebauthnDevicesByUsername(gomock.Eq(testUsername)).
+			Return(devices, nil).
+			Times(times)
+	}
+
+	if u2fDevice == nil {
+		s.mock.StorageProviderMock.EXPECT().
+			LoadU2FDevicesByUsername(gomock.Eq(testUsername)).
+			Return(nil, storage.ErrNoU2FDeviceHandle).
+			Times(times)
+	} else {
+		s.mock.StorageProviderMock.EXPECT().
+			LoadU2FDevicesByUsername(gomock.Eq(testUsername)).
+			Return([]models.U2FDevice{{Username: testUsername, KeyHandle: u2fDevice.kid, PublicKey: u2fDevice.U2FPublicKey()}}, nil).
+			Times(times)
+	}
+}
+
+func (s *HandlerSignWebauthnSuite) postAssertion(credential []byte) {
+	body, err := json.Marshal(signWebauthnRequestBody{Credential: credential})
+	s.Require().NoError(err)
+	s.mock.Ctx.Request.SetBody(body)
+
+	SecondFactorWebauthnAssertionPost(s.mock.Ctx)
+}
+
+func (s *HandlerSignWebauthnSuite) TestShouldRaiseWhenXForwardedProtoIsMissing() {
+	s.mock.Ctx.Request.Header.Del("X-Forwarded-Proto")
+
+	SecondFactorWebauthnAssertionGet(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), mfaValidationFailedMessage)
+	assert.Equal(s.T(), "Unable to configure Webauthn: Missing header X-Forwarded-Proto", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerSignWebauthnSuite) TestShouldFailWhenNoDeviceIsRegistered() {
+	s.expectDevices(1, nil, nil)
+
+	SecondFactorWebauthnAssertionGet(s.mock.Ctx)
+
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	assert.Equal(s.T(), "No Webauthn device found for user john", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerSignWebauthnSuite) TestShouldAskAssertionOfAllDevices() {
+	authenticator := newTestWebauthnAuthenticator(s.T())
+	s.expectDevices(1, []models.WebauthnDevice{authenticator.Device(s.T(), 0)}, nil)
+
+	SecondFactorWebauthnAssertionGet(s.mock.Ctx)
+
+	assertion := protocol.CredentialAssertion{}
+	s.mock.GetResponseData(s.T(), &assertion)
+
+	s.Require().Len(assertion.Response.AllowedCredentials, 1)
+	assert.Equal(s.T(), authenticator.kid, []byte(assertion.Response.AllowedCredentials[0].CredentialID))
+	assert.Equal(s.T(), testWebauthnHost, assertion.Response.RelyingPartyID)
+	assert.Equal(s.T(), protocol.VerificationPreferred, assertion.Response.UserVerification)
+	assert.Nil(s.T(), assertion.Response.Extensions)
+	assert.NotNil(s.T(), s.mock.Ctx.GetSession().Webauthn)
+}
+
+func (s *HandlerSignWebauthnSuite) TestShouldAddAppIDExtensionWhenU2FDeviceIsRegistered() {
+	s.expectDevices(1, nil, newTestWebauthnAuthenticator(s.T()))
+
+	SecondFactorWebauthnAssertionGet(s.mock.Ctx)
+
+	assertion := protocol.CredentialAssertion{}
+	s.mock.GetResponseData(s.T(), &assertion)
+
+	assert.Len(s.T(), assertion.Response.AllowedCredentials, 1)
+	assert.Equal(s.T(), testWebauthnOrigin, assertion.Response.Extensions["appid"])
+}
+
+func (s *HandlerSignWebauthnSuite) TestShouldAuthenticateWithWebauthnDevice() {
+	authenticator := newTestWebauthnAuthenticator(s.T())
+	s.expectDevices(2, []models.WebauthnDevice{authenticator.Device(s.T(), 0)}, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateWebauthnDeviceSignCount(gomock.Eq(testUsername), gomock.Eq(authenticator.kid), gomock.Eq(uint32(1))).
+		Return(nil)
+
+	SecondFactorWebauthnAssertionGet(s.mock.Ctx)
+	s.postAssertion(authenticator.Assert(s.T(), testWebauthnHost, s.mock.Ctx.GetSession().Webauthn.Challenge))
+
+	s.mock.Assert200OK(s.T(), nil)
+
+	userSession := s.mock.Ctx.GetSession()
+	assert.Equal(s.T(), authentication.TwoFactor, userSession.AuthenticationLevel)
+	assert.Nil(s.T(), userSession.Webauthn)
+}
+
+func (s *HandlerSignWebauthnSuite) TestShouldAuthenticateWithU2FDeviceThroughAppIDExtension() {
+	authenticator := newTestWebauthnAuthenticator(s.T())
+	s.expectDevices(2, nil, authenticator)
+
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateU2FDeviceCounter(gomock.Eq(testUsername), gomock.Eq(authenticator.kid), gomock.Eq(uint32(1)), gomock.Any()).
+		Return(nil)
+
+	s.mock.Ctx.Configuration.DefaultRedirectionURL = testRedirectionURL
+
+	SecondFactorWebauthnAssertionGet(s.mock.Ctx)
+	s.postAssertion(authenticator.Assert(s.T(), testWebauthnOrigin, s.mock.Ctx.GetSession().Webauthn.Challenge))
+
+	s.mock.Assert200OK(s.T(), redirectResponse{Redirect: testRedirectionURL})
+	assert.Equal(s.T(), authentication.TwoFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignWebauthnSuite) TestShouldFailWhenWebauthnDeviceSignsForAppID() {
+	authenticator := newTestWebauthnAuthenticator(s.T())
+	s.expectDevices(2, []models.WebauthnDevice{authenticator.Device(s.T(), 0)}, nil)
+
+	SecondFactorWebauthnAssertionGet(s.mock.Ctx)
+	s.postAssertion(authenticator.Assert(s.T(), testWebauthnOrigin, s.mock.Ctx.GetSession().Webauthn.Challenge))
+
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	assert.Equal(s.T(), authentication.OneFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignWebauthnSuite) TestShouldFailWhenSignatureCounterDoesNotIncrease() {
+	authenticator := newTestWebauthnAuthenticator(s.T())
+	s.expectDevices(2, []models.WebauthnDevice{authenticator.Device(s.T(), 5)}, nil)
+
+	SecondFactorWebauthnAssertionGet(s.mock.Ctx)
+	s.postAssertion(authenticator.Assert(s.T(), testWebauthnHost, s.mock.Ctx.GetSession().Webauthn.Challenge))
+
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	assert.Equal(s.T(), "The signature counter of the Webauthn device of user john did not increase, "+
+		"the device might have been cloned", s.mock.Hook.LastEntry().Message)
+	assert.Equal(s.T(), authentication.OneFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignWebauthnSuite) TestShouldFailAndNotifyUserWhenU2FDeviceCounterDoesNotIncrease() {
+	authenticator := newTestWebauthnAuthenticator(s.T())
+
+	s.mock.Ctx.Configuration.Webauthn.NotifyClonedDevices = true
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Emails = []string{"john@example.com"}
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadWebauthnDevicesByUsername(gomock.Eq(testUsername)).
+		Return(nil, storage.ErrNoWebauthnDevice).
+		Times(2)
+	s.mock.StorageProviderMock.EXPECT().
+		LoadU2FDevicesByUsername(gomock.Eq(testUsername)).
+		Return([]models.U2FDevice{{Username: testUsername, KeyHandle: authenticator.kid, PublicKey: authenticator.U2FPublicKey(), Counter: 5}}, nil).
+		Times(2)
+	s.mock.NotifierMock.EXPECT().
+		Send(gomock.Eq("john@example.com"), gomock.Eq(clonedDeviceNotificationTitle), gomock.Any(), gomock.Eq("")).
+		Return(nil)
+
+	SecondFactorWebauthnAssertionGet(s.mock.Ctx)
+	s.postAssertion(authenticator.Assert(s.T(), testWebauthnOrigin, s.mock.Ctx.GetSession().Webauthn.Challenge))
+
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	assert.Equal(s.T(), authentication.OneFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignWebauthnSuite) TestShouldFailWhenSignatureCounterIncreasedConcurrently() {
+	authenticator := newTestWebauthnAuthenticator(s.T())
+	s.expectDevices(2, []models.WebauthnDevice{authenticator.Device(s.T(), 0)}, nil)
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Emails = []string{"john@example.com"}
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	// Another assertion recorded a greater counter since the challenge has been generated.
+	s.mock.StorageProviderMock.EXPECT().
+		UpdateWebauthnDeviceSignCount(gomock.Eq(testUsername), gomock.Eq(authenticator.kid), gomock.Eq(uint32(1))).
+		Return(storage.ErrSignCountNotIncreasing)
+
+	SecondFactorWebauthnAssertionGet(s.mock.Ctx)
+	s.postAssertion(authenticator.Assert(s.T(), testWebauthnHost, s.mock.Ctx.GetSession().Webauthn.Challenge))
+
+	// The notification is disabled by default.
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	assert.Equal(s.T(), "The signature counter of the Webauthn device of user john did not increase, "+
+		"the device might have been cloned", s.mock.Hook.LastEntry().Message)
+	assert.Equal(s.T(), authentication.OneFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignWebauthnSuite) TestShouldNotReplayChallengeAfterFailedAssertion() {
+	authenticator := newTestWebauthnAuthenticator(s.T())
+	s.expectDevices(2, []models.WebauthnDevice{authenticator.Device(s.T(), 0)}, nil)
+
+	SecondFactorWebauthnAssertionGet(s.mock.Ctx)
+	challenge := s.mock.Ctx.GetSession().Webauthn.Challenge
+
+	// The first assertion is signed for the wrong RP ID and fails.
+	s.postAssertion(authenticator.Assert(s.T(), testWebauthnOrigin, challenge))
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	s.Assert().Nil(s.mock.Ctx.GetSession().Webauthn)
+
+	s.mock.Ctx.Response.Reset()
+	s.postAssertion(authenticator.Assert(s.T(), testWebauthnHost, challenge))
+
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	assert.Equal(s.T(), "Webauthn signing has not been initiated yet (no challenge)", s.mock.Hook.LastEntry().Message)
+	assert.Equal(s.T(), authentication.OneFactor, s.mock.Ctx.GetSession().AuthenticationLevel)
+}
+
+func (s *HandlerSignWebauthnSuite) TestShouldFailWhenSigningHasNotBeenInitiated() {
+	authenticator := newTestWebauthnAuthenticator(s.T())
+
+	s.postAssertion(authenticator.Assert(s.T(), testWebauthnHost, "challenge"))
+
+	s.mock.Assert401KO(s.T(), mfaValidationFailedMessage)
+	assert.Equal(s.T(), "Webauthn signing has not been initiated yet (no challenge)", s.mock.Hook.LastEntry().Message)
+}
+
+func TestShouldRunHandlerSignWebauthnSuite(t *testing.T) {
+	suite.Run(t, new(HandlerSignWebauthnSuite))
+}
+
+func TestShouldConvertU2FPublicKeyToCOSEKey(t *testing.T) {
+	authenticator := newTestWebauthnAuthenticator(t)
+
+	credential, err := webauthnCredentialFromU2FDevice(authenticator.kid, authenticator.U2FPublicKey())
+	require.NoError(t, err)
+
+	assert.Equal(t, authenticator.kid, credential.ID)
+	assert.Equal(t, "fido-u2f", credential.AttestationType)
+
+	_, err = webauthnCredentialFromU2FDevice(authenticator.kid, []byte("abc"))
+	assert.EqualError(t, err, "Unable to parse the public key of U2F device")
+}
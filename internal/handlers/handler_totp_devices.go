@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/audit"
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+// SecondFactorTOTPDevicesGet lists the TOTP devices registered by the user.
+func SecondFactorTOTPDevicesGet(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+
+	devices, err := ctx.Providers.StorageProvider.LoadTOTPDevicesByUsername(userSession.Username)
+	if err != nil && err != storage.ErrNoTOTPSecret {
+		ctx.Error(fmt.Errorf("Unable to load TOTP devices of user %s: %s", userSession.Username, err), operationFailedMessage)
+		return
+	}
+
+	if err = ctx.SetJSONBody(newTOTPDevicesResponse(devices)); err != nil {
+		ctx.Logger.Errorf("Unable to set TOTP devices response in body: %s", err)
+	}
+}
+
+// SecondFactorTOTPDeviceDelete deletes one of the TOTP devices registered by the user.
+func SecondFactorTOTPDeviceDelete(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+
+	id, _ := ctx.UserValue("id").(string)
+
+	err := ctx.Providers.StorageProvider.DeleteTOTPDevice(userSession.Username, id)
+	if err != nil {
+		if err == storage.ErrNoTOTPSecret {
+			ctx.SetStatusCode(fasthttp.StatusNotFound)
+		}
+
+		ctx.Error(fmt.Errorf("Unable to delete TOTP device %s of user %s: %s", id, userSession.Username, err), operationFailedMessage)
+
+		return
+	}
+
+	ctx.Logger.Debugf("TOTP device %s of user %s has been deleted", id, userSession.Username)
+	auditSuccess(ctx, audit.EventDeviceRemoval, userSession.Username, authentication.TOTP)
+	ctx.ReplyOK()
+}
+
+func newTOTPDevicesResponse(devices []models.TOTPDevice) []totpDeviceResponse {
+	response := make([]totpDeviceResponse, len(devices))
+
+	for i, device := range devices {
+		response[i] = totpDeviceResponse{
+			ID:          device.ID,
+			Description: device.Description,
+			CreatedAt:   unixTimestampOrZero(device.CreatedAt),
+			LastUsedAt:  unixTimestampOrZero(device.LastUsedAt),
+		}
+	}
+
+	return response
+}
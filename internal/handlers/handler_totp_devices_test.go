@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+type HandlerTOTPDevicesSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *HandlerTOTPDevicesSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = testUsername
+	err := s.mock.Ctx.SaveSession(userSession)
+	s.Require().NoError(err)
+}
+
+func (s *HandlerTOTPDevicesSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *HandlerTOTPDevicesSuite) TestShouldListDevices() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadTOTPDevicesByUsername(gomock.Eq(testUsername)).
+		Return([]models.TOTPDevice{
+			{Username: testUsername, ID: "primary", Description: "Primary", Secret: "secret"},
+			{Username: testUsername, ID: "k3b9Qz", Description: "Tablet", Secret: "tablet_secret",
+				CreatedAt: time.Unix(1600000000, 0), LastUsedAt: time.Unix(1600000100, 0)},
+		}, nil)
+
+	SecondFactorTOTPDevicesGet(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), []totpDeviceResponse{
+		{ID: "primary", Description: "Primary"},
+		{ID: "k3b9Qz", Description: "Tablet", CreatedAt: 1600000000, LastUsedAt: 1600000100},
+	})
+}
+
+func (s *HandlerTOTPDevicesSuite) TestShouldListNoDevice() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadTOTPDevicesByUsername(gomock.Eq(testUsername)).
+		Return(nil, storage.ErrNoTOTPSecret)
+
+	SecondFactorTOTPDevicesGet(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), []totpDeviceResponse{})
+}
+
+func (s *HandlerTOTPDevicesSuite) TestShouldFailToListDevicesWhenStorageFails() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadTOTPDevicesByUsername(gomock.Eq(testUsername)).
+		Return(nil, fmt.Errorf("failure"))
+
+	SecondFactorTOTPDevicesGet(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), operationFailedMessage)
+	assert.Equal(s.T(), "Unable to load TOTP devices of user john: failure", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerTOTPDevicesSuite) TestShouldDeleteDevice() {
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteTOTPDevice(gomock.Eq(testUsername), gomock.Eq("k3b9Qz")).
+		Return(nil)
+
+	s.mock.Ctx.SetUserValue("id", "k3b9Qz")
+	SecondFactorTOTPDeviceDelete(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+}
+
+func (s *HandlerTOTPDevicesSuite) TestShouldReturnNotFoundWhenDeletingUnknownDevice() {
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteTOTPDevice(gomock.Eq(testUsername), gomock.Eq("k3b9Qz")).
+		Return(storage.ErrNoTOTPSecret)
+
+	s.mock.Ctx.SetUserValue("id", "k3b9Qz")
+	SecondFactorTOTPDeviceDelete(s.mock.Ctx)
+
+	assert.Equal(s.T(), 404, s.mock.Ctx.Response.StatusCode())
+	assert.Equal(s.T(), "Unable to delete TOTP device k3b9Qz of user john: No TOTP secret registered", s.mock.Hook.LastEntry().Message)
+}
+
+func TestRunHandlerTOTPDevicesSuite(t *testing.T) {
+	suite.Run(t, new(HandlerTOTPDevicesSuite))
+}
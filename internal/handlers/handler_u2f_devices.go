@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/audit"
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/storage"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// SecondFactorU2FDevicesGet lists the U2F devices registered by the user.
+func SecondFactorU2FDevicesGet(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+
+	devices, err := ctx.Providers.StorageProvider.LoadU2FDevicesByUsername(userSession.Username)
+	if err != nil && err != storage.ErrNoU2FDeviceHandle {
+		ctx.Error(fmt.Errorf("Unable to load U2F devices of user %s: %s", userSession.Username, err), operationFailedMessage)
+		return
+	}
+
+	if err = ctx.SetJSONBody(newU2FDevicesResponse(devices)); err != nil {
+		ctx.Logger.Errorf("Unable to set U2F devices response in body: %s", err)
+	}
+}
+
+// SecondFactorU2FDeviceDelete deletes one of the U2F devices registered by the user.
+func SecondFactorU2FDeviceDelete(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+
+	id, _ := ctx.UserValue("id").(string)
+
+	keyHandle, err := utils.DecodeKeyHandle(id)
+	if err != nil || len(keyHandle) == 0 {
+		ctx.SetStatusCode(fasthttp.StatusBadRequest)
+		ctx.Error(fmt.Errorf("Invalid U2F device id %s", id), operationFailedMessage)
+
+		return
+	}
+
+	err = ctx.Providers.StorageProvider.DeleteU2FDevice(userSession.Username, keyHandle)
+	if err != nil {
+		if err == storage.ErrNoU2FDeviceHandle {
+			ctx.SetStatusCode(fasthttp.StatusNotFound)
+		}
+
+		ctx.Error(fmt.Errorf("Unable to delete U2F device %s of user %s: %s", id, userSession.Username, err), operationFailedMessage)
+
+		return
+	}
+
+	ctx.Logger.Debugf("U2F device %s of user %s has been deleted", id, userSession.Username)
+	auditSuccess(ctx, audit.EventDeviceRemoval, userSession.Username, authentication.U2F)
+	ctx.ReplyOK()
+}
+
+func newU2FDevicesResponse(devices []models.U2FDevice) []u2fDeviceResponse {
+	response := make([]u2fDeviceResponse, len(devices))
+
+	for i, device := range devices {
+		response[i] = u2fDeviceResponse{
+			ID:          utils.EncodeKeyHandle(device.KeyHandle),
+			Description: device.Description,
+			CreatedAt:   unixTimestampOrZero(device.CreatedAt),
+			LastUsedAt:  unixTimestampOrZero(device.LastUsedAt),
+		}
+	}
+
+	return response
+}
+
+func newWebauthnDevicesResponse(devices []models.WebauthnDevice) []webauthnDeviceResponse {
+	response := make([]webauthnDeviceResponse, len(devices))
+
+	for i, device := range devices {
+		response[i] = webauthnDeviceResponse{
+			ID:              utils.EncodeKeyHandle(device.KID),
+			AttestationType: device.AttestationType,
+			CreatedAt:       unixTimestampOrZero(device.CreatedAt),
+		}
+	}
+
+	return response
+}
+
+// unixTimestampOrZero returns the unix timestamp of a time or 0 for the zero time.
+func unixTimestampOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
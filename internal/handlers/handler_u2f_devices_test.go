@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+type HandlerU2FDevicesSuite struct {
+	suite.Suite
+
+	mock *mocks.MockAutheliaCtx
+}
+
+func (s *HandlerU2FDevicesSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = testUsername
+	err := s.mock.Ctx.SaveSession(userSession)
+	s.Require().NoError(err)
+}
+
+func (s *HandlerU2FDevicesSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *HandlerU2FDevicesSuite) TestShouldListDevices() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadU2FDevicesByUsername(gomock.Eq(testUsername)).
+		Return([]models.U2FDevice{
+			{Username: testUsername, Description: "Primary", KeyHandle: []byte("primary")},
+			{Username: testUsername, Description: "Backup", KeyHandle: []byte("backup"),
+				CreatedAt: time.Unix(1600000000, 0), LastUsedAt: time.Unix(1600000100, 0)},
+		}, nil)
+
+	SecondFactorU2FDevicesGet(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), []u2fDeviceResponse{
+		{ID: "cHJpbWFyeQ", Description: "Primary"},
+		{ID: "YmFja3Vw", Description: "Backup", CreatedAt: 1600000000, LastUsedAt: 1600000100},
+	})
+}
+
+func (s *HandlerU2FDevicesSuite) TestShouldListNoDevice() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadU2FDevicesByUsername(gomock.Eq(testUsername)).
+		Return(nil, storage.ErrNoU2FDeviceHandle)
+
+	SecondFactorU2FDevicesGet(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), []u2fDeviceResponse{})
+}
+
+func (s *HandlerU2FDevicesSuite) TestShouldFailToListDevicesWhenStorageFails() {
+	s.mock.StorageProviderMock.EXPECT().
+		LoadU2FDevicesByUsername(gomock.Eq(testUsername)).
+		Return(nil, fmt.Errorf("failure"))
+
+	SecondFactorU2FDevicesGet(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), operationFailedMessage)
+	assert.Equal(s.T(), "Unable to load U2F devices of user john: failure", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerU2FDevicesSuite) TestShouldDeleteDevice() {
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteU2FDevice(gomock.Eq(testUsername), gomock.Eq([]byte("backup"))).
+		Return(nil)
+
+	s.mock.Ctx.SetUserValue("id", "YmFja3Vw")
+	SecondFactorU2FDeviceDelete(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+}
+
+func (s *HandlerU2FDevicesSuite) TestShouldReturnNotFoundWhenDeletingUnknownDevice() {
+	s.mock.StorageProviderMock.EXPECT().
+		DeleteU2FDevice(gomock.Eq(testUsername), gomock.Eq([]byte("backup"))).
+		Return(storage.ErrNoU2FDeviceHandle)
+
+	s.mock.Ctx.SetUserValue("id", "YmFja3Vw")
+	SecondFactorU2FDeviceDelete(s.mock.Ctx)
+
+	assert.Equal(s.T(), 404, s.mock.Ctx.Response.StatusCode())
+	assert.Equal(s.T(), "Unable to delete U2F device YmFja3Vw of user john: No U2F device handle found", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerU2FDevicesSuite) TestShouldReturnBadRequestWhenDeviceIDIsInvalid() {
+	s.mock.Ctx.SetUserValue("id", "not*base64")
+	SecondFactorU2FDeviceDelete(s.mock.Ctx)
+
+	assert.Equal(s.T(), 400, s.mock.Ctx.Response.StatusCode())
+	assert.Equal(s.T(), "Invalid U2F device id not*base64", s.mock.Hook.LastEntry().Message)
+}
+
+func TestRunHandlerU2FDevicesSuite(t *testing.T) {
+	suite.Run(t, new(HandlerU2FDevicesSuite))
+}
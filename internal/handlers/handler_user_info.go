@@ -41,9 +41,23 @@ func loadInfo(username string, storageProvider storage.Provider, userInfo *UserI
 	go func() {
 		defer wg.Done()
 
-		_, _, err := storageProvider.LoadU2FDeviceHandle(username)
+		_, err := storageProvider.LoadU2FDevicesByUsername(username)
+		if err == nil {
+			userInfo.HasU2F = true
+			return
+		}
+
+		if err != storage.ErrNoU2FDeviceHandle {
+			errors = append(errors, err)
+			logger.Error(err)
+
+			return
+		}
+
+		// Security keys registered with Webauthn are usable as U2F devices by the portal.
+		_, err = storageProvider.LoadWebauthnDevicesByUsername(username)
 		if err != nil {
-			if err == storage.ErrNoU2FDeviceHandle {
+			if err == storage.ErrNoWebauthnDevice {
 				return
 			}
 
@@ -59,7 +73,7 @@ func loadInfo(username string, storageProvider storage.Provider, userInfo *UserI
 	go func() {
 		defer wg.Done()
 
-		_, err := storageProvider.LoadTOTPSecret(username)
+		_, err := storageProvider.LoadTOTPDevicesByUsername(username)
 		if err != nil {
 			if err == storage.ErrNoTOTPSecret {
 				return
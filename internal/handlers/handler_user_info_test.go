@@ -11,6 +11,7 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
 	"github.com/authelia/authelia/internal/storage"
 )
 
@@ -43,26 +44,30 @@ func setPreferencesExpectations(preferences UserInfo, provider *storage.MockProv
 		u2fData := []byte("abc")
 		provider.
 			EXPECT().
-			LoadU2FDeviceHandle(gomock.Eq("john")).
-			Return(u2fData, u2fData, nil)
+			LoadU2FDevicesByUsername(gomock.Eq("john")).
+			Return([]models.U2FDevice{{Username: "john", KeyHandle: u2fData, PublicKey: u2fData}}, nil)
 	} else {
 		provider.
 			EXPECT().
-			LoadU2FDeviceHandle(gomock.Eq("john")).
-			Return(nil, nil, storage.ErrNoU2FDeviceHandle)
+			LoadU2FDevicesByUsername(gomock.Eq("john")).
+			Return(nil, storage.ErrNoU2FDeviceHandle)
+
+		provider.
+			EXPECT().
+			LoadWebauthnDevicesByUsername(gomock.Eq("john")).
+			Return(nil, storage.ErrNoWebauthnDevice)
 	}
 
 	if preferences.HasTOTP {
-		totpSecret := "secret"
 		provider.
 			EXPECT().
-			LoadTOTPSecret(gomock.Eq("john")).
-			Return(totpSecret, nil)
+			LoadTOTPDevicesByUsername(gomock.Eq("john")).
+			Return([]models.TOTPDevice{{Username: "john", ID: "primary", Secret: "secret"}}, nil)
 	} else {
 		provider.
 			EXPECT().
-			LoadTOTPSecret(gomock.Eq("john")).
-			Return("", storage.ErrNoTOTPSecret)
+			LoadTOTPDevicesByUsername(gomock.Eq("john")).
+			Return(nil, storage.ErrNoTOTPSecret)
 	}
 }
 
@@ -126,18 +131,48 @@ func (s *FetchSuite) TestShouldGetDefaultPreferenceIfNotInDB() {
 
 	s.mock.StorageProviderMock.
 		EXPECT().
-		LoadU2FDeviceHandle(gomock.Eq("john")).
-		Return(nil, nil, storage.ErrNoU2FDeviceHandle)
+		LoadU2FDevicesByUsername(gomock.Eq("john")).
+		Return(nil, storage.ErrNoU2FDeviceHandle)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadWebauthnDevicesByUsername(gomock.Eq("john")).
+		Return(nil, storage.ErrNoWebauthnDevice)
 
 	s.mock.StorageProviderMock.
 		EXPECT().
-		LoadTOTPSecret(gomock.Eq("john")).
-		Return("", storage.ErrNoTOTPSecret)
+		LoadTOTPDevicesByUsername(gomock.Eq("john")).
+		Return(nil, storage.ErrNoTOTPSecret)
 
 	UserInfoGet(s.mock.Ctx)
 	s.mock.Assert200OK(s.T(), UserInfo{Method: "totp"})
 }
 
+func (s *FetchSuite) TestShouldReportSecurityKeyWhenWebauthnDeviceIsRegistered() {
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadPreferred2FAMethod(gomock.Eq("john")).
+		Return("u2f", nil)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadU2FDevicesByUsername(gomock.Eq("john")).
+		Return(nil, storage.ErrNoU2FDeviceHandle)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadWebauthnDevicesByUsername(gomock.Eq("john")).
+		Return([]models.WebauthnDevice{{Username: "john", KID: []byte("kid")}}, nil)
+
+	s.mock.StorageProviderMock.
+		EXPECT().
+		LoadTOTPDevicesByUsername(gomock.Eq("john")).
+		Return(nil, storage.ErrNoTOTPSecret)
+
+	UserInfoGet(s.mock.Ctx)
+	s.mock.Assert200OK(s.T(), UserInfo{Method: "u2f", HasU2F: true})
+}
+
 func (s *FetchSuite) TestShouldReturnError500WhenStorageFailsToLoad() {
 	s.mock.StorageProviderMock.EXPECT().
 		LoadPreferred2FAMethod(gomock.Eq("john")).
@@ -145,11 +180,11 @@ func (s *FetchSuite) TestShouldReturnError500WhenStorageFailsToLoad() {
 
 	s.mock.StorageProviderMock.
 		EXPECT().
-		LoadU2FDeviceHandle(gomock.Eq("john"))
+		LoadU2FDevicesByUsername(gomock.Eq("john"))
 
 	s.mock.StorageProviderMock.
 		EXPECT().
-		LoadTOTPSecret(gomock.Eq("john"))
+		LoadTOTPDevicesByUsername(gomock.Eq("john"))
 
 	UserInfoGet(s.mock.Ctx)
 
@@ -213,7 +248,7 @@ func (s *SaveSuite) TestShouldReturnError500WhenBadMethodProvided() {
 	MethodPreferencePost(s.mock.Ctx)
 
 	s.mock.Assert200KO(s.T(), "Operation failed.")
-	assert.Equal(s.T(), "Unknown method 'abc', it should be one of totp, u2f, mobile_push", s.mock.Hook.LastEntry().Message)
+	assert.Equal(s.T(), "Unknown method 'abc', it should be one of totp, u2f, mobile_push, email_otp", s.mock.Hook.LastEntry().Message)
 	assert.Equal(s.T(), logrus.ErrorLevel, s.mock.Hook.LastEntry().Level)
 }
 
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/authelia/authelia/internal/audit"
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/regulation"
+	"github.com/authelia/authelia/internal/session"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+// UserPasswordPost changes the password of the signed in user. The current password is required and, if the user is
+// enrolled in a second factor, it must have been completed recently.
+func UserPasswordPost(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+
+	var requestBody changePasswordRequestBody
+
+	if err := ctx.ParseBody(&requestBody); err != nil {
+		ctx.Error(err, unableToChangePasswordMessage)
+		return
+	}
+
+	fresh, err := hasRecentSecondFactor(ctx, userSession)
+	if err != nil {
+		ctx.Error(err, unableToChangePasswordMessage)
+		return
+	}
+
+	if !fresh {
+		auditPasswordChangeFailure(ctx, userSession.Username)
+		ctx.Error(fmt.Errorf("User %s must complete the second factor again to change their password", userSession.Username), secondFactorRequiredMessage)
+
+		return
+	}
+
+	if !checkCurrentPassword(ctx, userSession.Username, requestBody.CurrentPassword) {
+		auditPasswordChangeFailure(ctx, userSession.Username)
+		return
+	}
+
+	if !checkPasswordPolicy(ctx, userSession.Username, requestBody.NewPassword) {
+		auditPasswordChangeFailure(ctx, userSession.Username)
+		return
+	}
+
+	if err = ctx.Providers.UserProvider.UpdatePassword(userSession.Username, requestBody.NewPassword); err != nil {
+		auditPasswordChangeFailure(ctx, userSession.Username)
+		replyUpdatePasswordError(ctx, err, unableToChangePasswordMessage)
+
+		return
+	}
+
+	ctx.Logger.Debugf("Password of user %s has been changed", userSession.Username)
+	auditSuccess(ctx, audit.EventPasswordChange, userSession.Username, "")
+	notifyPasswordChanged(ctx, userSession)
+
+	if requestBody.RevokeOtherSessions {
+		destroyed, err := destroyOtherSessions(ctx, userSession.Username)
+		if err != nil {
+			ctx.Error(err, operationFailedMessage)
+			return
+		}
+
+		ctx.Logger.Debugf("%d other session(s) of user %s have been destroyed", destroyed, userSession.Username)
+
+		if destroyed != 0 {
+			auditSuccess(ctx, audit.EventSessionRevocation, userSession.Username, "")
+		}
+	}
+
+	ctx.ReplyOK()
+}
+
+// hasRecentSecondFactor returns whether the user completed the second factor recently enough to change their
+// password. It is not required from the users who are not enrolled in any second factor.
+func hasRecentSecondFactor(ctx *middlewares.AutheliaCtx, userSession session.UserSession) (bool, error) {
+	if userSession.AuthenticationLevel >= authentication.TwoFactor {
+		completedAt := time.Unix(userSession.SecondFactorAt, 0)
+
+		return ctx.Clock.Now().Sub(completedAt) <= passwordChangeSecondFactorMaxAge, nil
+	}
+
+	if isSecondFactorAvailableWithoutEnrollment(ctx, userSession) {
+		return false, nil
+	}
+
+	enrolled, err := isEnrolledInSecondFactor(ctx.Providers.StorageProvider, userSession.Username)
+	if err != nil {
+		return false, fmt.Errorf("Unable to check whether user %s is enrolled in a second factor: %s", userSession.Username, err)
+	}
+
+	return !enrolled, nil
+}
+
+// isSecondFactorAvailableWithoutEnrollment returns whether the user can complete a second factor without having
+// registered a device, that is with Duo or with a one-time passcode sent by email.
+func isSecondFactorAvailableWithoutEnrollment(ctx *middlewares.AutheliaCtx, userSession session.UserSession) bool {
+	if ctx.Configuration.DuoAPI != nil {
+		return true
+	}
+
+	return ctx.Configuration.Notifier != nil && ctx.Configuration.Notifier.SMTP != nil && len(userSession.Emails) != 0
+}
+
+// isEnrolledInSecondFactor returns whether the user registered a TOTP device or a security key.
+func isEnrolledInSecondFactor(storageProvider storage.Provider, username string) (bool, error) {
+	_, err := storageProvider.LoadTOTPDevicesByUsername(username)
+	if err != storage.ErrNoTOTPSecret {
+		return err == nil, err
+	}
+
+	_, err = storageProvider.LoadU2FDevicesByUsername(username)
+	if err != storage.ErrNoU2FDeviceHandle {
+		return err == nil, err
+	}
+
+	_, err = storageProvider.LoadWebauthnDevicesByUsername(username)
+	if err != storage.ErrNoWebauthnDevice {
+		return err == nil, err
+	}
+
+	return false, nil
+}
+
+// checkCurrentPassword checks the current password of the user under the regulation, the attempts being marked like
+// the sign ins so that the endpoint cannot be used to guess the password. It replies with an error when the password
+// is wrong.
+func checkCurrentPassword(ctx *middlewares.AutheliaCtx, username, password string) bool {
+	bannedUntil, err := ctx.Providers.Regulator.Regulate(username)
+	if err != nil {
+		if err == regulation.ErrUserIsBanned {
+			ctx.Error(fmt.Errorf("User %s is banned until %s", username, bannedUntil), userBannedMessage)
+			return false
+		}
+
+		ctx.Error(fmt.Errorf("Unable to regulate authentication: %s", err), unableToChangePasswordMessage)
+
+		return false
+	}
+
+	valid, err := ctx.Providers.UserProvider.CheckUserPassword(username, password)
+	if err != nil {
+		valid = false
+
+		ctx.Logger.Errorf("Error while checking password for user %s: %s", username, err)
+	}
+
+	ctx.Logger.Debugf("Mark authentication attempt made by user %s", username)
+
+	if err := ctx.Providers.Regulator.Mark(username, valid); err != nil {
+		ctx.Logger.Errorf("Unable to mark authentication: %s", err)
+	}
+
+	if !valid {
+		ctx.Error(fmt.Errorf("Current password of user %s is wrong", username), authenticationFailedMessage)
+	}
+
+	return valid
+}
+
+func auditPasswordChangeFailure(ctx *middlewares.AutheliaCtx, username string) {
+	ctx.Audit(audit.Event{
+		Type:     audit.EventPasswordChange,
+		Username: username,
+		Outcome:  audit.OutcomeFailure,
+	})
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/session"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+type HandlerUserPasswordSuite struct {
+	suite.Suite
+
+	mock  *mocks.MockAutheliaCtx
+	other *fasthttp.RequestCtx
+}
+
+func (s *HandlerUserPasswordSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Clock.Set(time.Unix(1600000000, 0))
+	s.mock.Ctx.Clock = &s.mock.Clock
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = testUsername
+	userSession.Emails = []string{"john@example.com"}
+	userSession.AuthenticationLevel = authentication.TwoFactor
+	userSession.SecondFactorAt = s.mock.Clock.Now().Add(-time.Minute).Unix()
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	provider := s.mock.Ctx.Providers.SessionProvider
+	s.Require().NoError(provider.IndexSession(s.mock.Ctx.RequestCtx, testUsername, session.IndexEntry{}))
+
+	// Another session of the same user, opened on another device.
+	s.other = &fasthttp.RequestCtx{}
+
+	otherSession, err := provider.GetSession(s.other)
+	s.Require().NoError(err)
+
+	otherSession.Username = testUsername
+	s.Require().NoError(provider.SaveSession(s.other, otherSession))
+	s.Require().NoError(provider.IndexSession(s.other, testUsername, session.IndexEntry{}))
+}
+
+func (s *HandlerUserPasswordSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *HandlerUserPasswordSuite) setAuthentication(level authentication.Level, secondFactorAt time.Time) {
+	userSession := s.mock.Ctx.GetSession()
+	userSession.AuthenticationLevel = level
+	userSession.SecondFactorAt = secondFactorAt.Unix()
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+}
+
+func (s *HandlerUserPasswordSuite) expectCurrentPassword(valid bool) {
+	s.mock.UserProviderMock.EXPECT().
+		CheckUserPassword(testUsername, "current").
+		Return(valid, nil)
+
+	s.mock.StorageProviderMock.EXPECT().
+		AppendAuthenticationLog(gomock.Any()).
+		Return(nil)
+}
+
+func (s *HandlerUserPasswordSuite) expectPasswordChange() {
+	s.mock.UserProviderMock.EXPECT().
+		UpdatePassword(testUsername, "new password").
+		Return(nil)
+
+	s.mock.NotifierMock.EXPECT().
+		Send("john@example.com", passwordChangedNotificationTitle, gomock.Any(), "").
+		Return(nil)
+}
+
+func (s *HandlerUserPasswordSuite) countSessions() int {
+	entries, err := s.mock.Ctx.Providers.SessionProvider.ListSessions(testUsername)
+	s.Require().NoError(err)
+
+	return len(entries)
+}
+
+func (s *HandlerUserPasswordSuite) TestShouldChangePassword() {
+	s.mock.Ctx.Request.SetBodyString(`{"current_password":"current","new_password":"new password"}`)
+	s.expectCurrentPassword(true)
+	s.expectPasswordChange()
+
+	UserPasswordPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Equal(2, s.countSessions())
+}
+
+func (s *HandlerUserPasswordSuite) TestShouldChangePasswordAndRevokeOtherSessions() {
+	s.mock.Ctx.Request.SetBodyString(`{"current_password":"current","new_password":"new password","revoke_other_sessions":true}`)
+	s.expectCurrentPassword(true)
+	s.expectPasswordChange()
+
+	UserPasswordPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+	s.Assert().Equal(1, s.countSessions())
+
+	otherSession, err := s.mock.Ctx.Providers.SessionProvider.GetSession(s.other)
+	s.Require().NoError(err)
+	s.Assert().Equal("", otherSession.Username)
+}
+
+func (s *HandlerUserPasswordSuite) TestShouldChangePasswordWithoutSecondFactorWhenNotEnrolled() {
+	s.setAuthentication(authentication.OneFactor, time.Unix(0, 0))
+	s.mock.Ctx.Request.SetBodyString(`{"current_password":"current","new_password":"new password"}`)
+
+	s.mock.StorageProviderMock.EXPECT().LoadTOTPDevicesByUsername(testUsername).Return(nil, storage.ErrNoTOTPSecret)
+	s.mock.StorageProviderMock.EXPECT().LoadU2FDevicesByUsername(testUsername).Return(nil, storage.ErrNoU2FDeviceHandle)
+	s.mock.StorageProviderMock.EXPECT().LoadWebauthnDevicesByUsername(testUsername).Return(nil, storage.ErrNoWebauthnDevice)
+	s.expectCurrentPassword(true)
+	s.expectPasswordChange()
+
+	UserPasswordPost(s.mock.Ctx)
+
+	s.mock.Assert200OK(s.T(), nil)
+}
+
+func (s *HandlerUserPasswordSuite) TestShouldRequireSecondFactorWhenEnrolled() {
+	s.setAuthentication(authentication.OneFactor, time.Unix(0, 0))
+	s.mock.Ctx.Request.SetBodyString(`{"current_password":"current","new_password":"new password"}`)
+
+	s.mock.StorageProviderMock.EXPECT().
+		LoadTOTPDevicesByUsername(testUsername).
+		Return([]models.TOTPDevice{{Username: testUsername}}, nil)
+
+	UserPasswordPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), secondFactorRequiredMessage)
+	s.Assert().Equal("User john must complete the second factor again to change their password", s.mock.Hook.LastEntry().Message)
+}
+
+func (s *HandlerUserPasswordSuite) TestShouldRequireSecondFactorWhenDuoIsConfigured() {
+	s.mock.Ctx.Configuration.DuoAPI = &schema.DuoAPIConfiguration{Hostname: "api-123.duosecurity.com"}
+	s.setAuthentication(authentication.OneFactor, time.Unix(0, 0))
+	s.mock.Ctx.Request.SetBodyString(`{"current_password":"current","new_password":"new password"}`)
+
+	UserPasswordPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), secondFactorRequiredMessage)
+}
+
+func (s *HandlerUserPasswordSuite) TestShouldRequireSecondFactorWhenPasscodesAreSentByEmail() {
+	s.mock.Ctx.Configuration.Notifier = &schema.NotifierConfiguration{SMTP: &schema.SMTPNotifierConfiguration{Host: "smtp.example.com"}}
+	s.setAuthentication(authentication.OneFactor, time.Unix(0, 0))
+	s.mock.Ctx.Request.SetBodyString(`{"current_password":"current","new_password":"new password"}`)
+
+	UserPasswordPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), secondFactorRequiredMessage)
+}
+
+func (s *HandlerUserPasswordSuite) TestShouldRequireRecentSecondFactor() {
+	s.setAuthentication(authentication.TwoFactor, s.mock.Clock.Now().Add(-10*time.Minute))
+	s.mock.Ctx.Request.SetBodyString(`{"current_password":"current","new_password":"new password"}`)
+
+	UserPasswordPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), secondFactorRequiredMessage)
+}
+
+func (s *HandlerUserPasswordSuite) TestShouldRefuseWrongCurrentPassword() {
+	s.mock.Ctx.Request.SetBodyString(`{"current_password":"current","new_password":"new password","revoke_other_sessions":true}`)
+	s.expectCurrentPassword(false)
+
+	UserPasswordPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), authenticationFailedMessage)
+	s.Assert().Equal(2, s.countSessions())
+}
+
+func (s *HandlerUserPasswordSuite) TestShouldRefusePasswordNotFollowingPolicy() {
+	s.mock.Ctx.Providers.PasswordPolicy = authentication.NewPasswordPolicy(schema.PasswordPolicyConfiguration{MinLength: 20})
+	s.mock.Ctx.Request.SetBodyString(`{"current_password":"current","new_password":"new password"}`)
+	s.expectCurrentPassword(true)
+
+	UserPasswordPost(s.mock.Ctx)
+
+	s.Assert().Equal(`{"status":"KO","message":"Your supplied password does not meet the password policy requirements.",`+
+		`"data":{"violations":[{"rule":"min_length","message":"The password must be at least 20 characters long"}]}}`,
+		string(s.mock.Ctx.Response.Body()))
+}
+
+func (s *HandlerUserPasswordSuite) TestShouldRefuseMissingCurrentPassword() {
+	s.mock.Ctx.Request.SetBodyString(`{"new_password":"new password"}`)
+
+	UserPasswordPost(s.mock.Ctx)
+
+	s.mock.Assert200KO(s.T(), unableToChangePasswordMessage)
+}
+
+func TestRunHandlerUserPasswordSuite(t *testing.T) {
+	suite.Run(t, new(HandlerUserPasswordSuite))
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/audit"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/session"
+)
+
+// UserSessionsGet lists the active sessions of the user.
+func UserSessionsGet(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+
+	entries, err := ctx.Providers.SessionProvider.ListSessions(userSession.Username)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to list sessions of user %s: %s", userSession.Username, err), operationFailedMessage)
+		return
+	}
+
+	currentID := ctx.Providers.SessionProvider.CurrentSessionID(ctx.RequestCtx)
+	response := make([]userSessionResponse, len(entries))
+
+	for i, entry := range entries {
+		response[i] = userSessionResponse{
+			ID:           entry.PublicID(),
+			CreatedAt:    entry.CreatedAt,
+			LastActivity: entry.LastActivity,
+			RemoteIP:     entry.RemoteIP,
+			UserAgent:    entry.UserAgent,
+			Current:      entry.ID == currentID,
+		}
+	}
+
+	if err = ctx.SetJSONBody(response); err != nil {
+		ctx.Logger.Errorf("Unable to set sessions response in body: %s", err)
+	}
+}
+
+// UserSessionDelete destroys one of the sessions of the user.
+func UserSessionDelete(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+	id, _ := ctx.UserValue("id").(string)
+
+	entries, err := ctx.Providers.SessionProvider.ListSessions(userSession.Username)
+	if err != nil {
+		ctx.Error(fmt.Errorf("Unable to list sessions of user %s: %s", userSession.Username, err), operationFailedMessage)
+		return
+	}
+
+	var entry *session.IndexEntry
+
+	for i := range entries {
+		if entries[i].PublicID() == id {
+			entry = &entries[i]
+			break
+		}
+	}
+
+	if entry == nil {
+		ctx.SetStatusCode(fasthttp.StatusNotFound)
+		ctx.Error(fmt.Errorf("User %s has no session %s", userSession.Username, id), operationFailedMessage)
+
+		return
+	}
+
+	if err = ctx.Providers.SessionProvider.DestroyUserSessions(userSession.Username, entry.ID); err != nil {
+		ctx.Error(fmt.Errorf("Unable to destroy session %s of user %s: %s", id, userSession.Username, err), operationFailedMessage)
+		return
+	}
+
+	ctx.Logger.Debugf("Session %s of user %s has been destroyed", id, userSession.Username)
+	auditSuccess(ctx, audit.EventSessionRevocation, userSession.Username, "")
+	ctx.ReplyOK()
+}
+
+// UserSessionsDelete destroys all the sessions of the user but the current one.
+func UserSessionsDelete(ctx *middlewares.AutheliaCtx) {
+	userSession := ctx.GetSession()
+
+	destroyed, err := destroyOtherSessions(ctx, userSession.Username)
+	if err != nil {
+		ctx.Error(err, operationFailedMessage)
+		return
+	}
+
+	ctx.Logger.Debugf("%d other session(s) of user %s have been destroyed", destroyed, userSession.Username)
+
+	if destroyed != 0 {
+		auditSuccess(ctx, audit.EventSessionRevocation, userSession.Username, "")
+	}
+
+	ctx.ReplyOK()
+}
+
+// destroyOtherSessions destroys all the sessions of the user but the current one and returns how many were destroyed.
+func destroyOtherSessions(ctx *middlewares.AutheliaCtx, username string) (int, error) {
+	entries, err := ctx.Providers.SessionProvider.ListSessions(username)
+	if err != nil {
+		return 0, fmt.Errorf("Unable to list sessions of user %s: %s", username, err)
+	}
+
+	currentID := ctx.Providers.SessionProvider.CurrentSessionID(ctx.RequestCtx)
+	ids := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.ID != currentID {
+			ids = append(ids, entry.ID)
+		}
+	}
+
+	if err = ctx.Providers.SessionProvider.DestroyUserSessions(username, ids...); err != nil {
+		return 0, fmt.Errorf("Unable to destroy sessions of user %s: %s", username, err)
+	}
+
+	return len(ids), nil
+}
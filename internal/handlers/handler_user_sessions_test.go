@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/session"
+)
+
+type HandlerUserSessionsSuite struct {
+	suite.Suite
+
+	mock  *mocks.MockAutheliaCtx
+	other *fasthttp.RequestCtx
+}
+
+func (s *HandlerUserSessionsSuite) SetupTest() {
+	s.mock = mocks.NewMockAutheliaCtx(s.T())
+	s.mock.Clock.Set(time.Unix(1600000000, 0))
+	s.mock.Ctx.Clock = &s.mock.Clock
+	s.mock.Ctx.Request.Header.Set("X-Forwarded-For", "10.0.0.1")
+	s.mock.Ctx.Request.Header.SetUserAgent("Firefox")
+
+	userSession := s.mock.Ctx.GetSession()
+	userSession.Username = testUsername
+	s.Require().NoError(s.mock.Ctx.SaveSession(userSession))
+
+	// Another session of the same user, opened on another device earlier.
+	provider := s.mock.Ctx.Providers.SessionProvider
+	s.other = &fasthttp.RequestCtx{}
+
+	otherSession, err := provider.GetSession(s.other)
+	s.Require().NoError(err)
+
+	otherSession.Username = testUsername
+	s.Require().NoError(provider.SaveSession(s.other, otherSession))
+	s.Require().NoError(provider.IndexSession(s.other, testUsername, session.IndexEntry{
+		LastActivity: 1500000000,
+		RemoteIP:     "10.0.0.2",
+		UserAgent:    "Chrome",
+	}))
+}
+
+func (s *HandlerUserSessionsSuite) TearDownTest() {
+	s.mock.Close()
+}
+
+func (s *HandlerUserSessionsSuite) otherSessionID() string {
+	return session.IndexEntry{ID: s.mock.Ctx.Providers.SessionProvider.CurrentSessionID(s.other)}.PublicID()
+}
+
+func (s *HandlerUserSessionsSuite) listSessions() []session.IndexEntry {
+	entries, err := s.mock.Ctx.Providers.SessionProvider.ListSessions(testUsername)
+	s.Require().NoError(err)
+
+	return entries
+}
+
+func (s *HandlerUserSessionsSuite) TestShouldListSessionsOfUser() {
+	UserSessionsGet(s.mock.Ctx)
+
+	currentID := session.IndexEntry{ID: s.mock.Ctx.Providers.SessionProvider.CurrentSessionID(s.mock.Ctx.RequestCtx)}.PublicID()
+
+	s.mock.Assert200OK(s.T(), []userSessionResponse{
+		{
+			ID:           s.otherSessionID(),
+			CreatedAt:    1500000000,
+			LastActivity: 1500000000,
+			RemoteIP:     "10.0.0.2",
+			UserAgent:    "Chrome",
+		},
+		{
+			ID:           currentID,
+			CreatedAt:    1600000000,
+			LastActivity: 1600000000,
+			RemoteIP:     "10.0.0.1",
+			UserAgent:    "Firefox",
+			Current:      true,
+		},
+	})
+}
+
+func (s *HandlerUserSessionsSuite) TestShouldDestroySessionOfUser() {
+	s.mock.Ctx.SetUserValue("id", s.otherSessionID())
+
+	UserSessionDelete(s.mock.Ctx)
+
+	assert.Equal(s.T(), 200, s.mock.Ctx.Response.StatusCode())
+	assert.Len(s.T(), s.listSessions(), 1)
+
+	otherSession, err := s.mock.Ctx.Providers.SessionProvider.GetSession(s.other)
+	s.Require().NoError(err)
+	assert.Equal(s.T(), "", otherSession.Username)
+}
+
+func (s *HandlerUserSessionsSuite) TestShouldReturnNotFoundWhenSessionIsUnknown() {
+	s.mock.Ctx.SetUserValue("id", "unknown")
+
+	UserSessionDelete(s.mock.Ctx)
+
+	assert.Equal(s.T(), 404, s.mock.Ctx.Response.StatusCode())
+	assert.Equal(s.T(), "User john has no session unknown", s.mock.Hook.LastEntry().Message)
+	assert.Len(s.T(), s.listSessions(), 2)
+}
+
+func (s *HandlerUserSessionsSuite) TestShouldDestroyOtherSessionsOfUser() {
+	UserSessionsDelete(s.mock.Ctx)
+
+	assert.Equal(s.T(), 200, s.mock.Ctx.Response.StatusCode())
+
+	entries := s.listSessions()
+	s.Require().Len(entries, 1)
+	assert.Equal(s.T(), s.mock.Ctx.Providers.SessionProvider.CurrentSessionID(s.mock.Ctx.RequestCtx), entries[0].ID)
+}
+
+func (s *HandlerUserSessionsSuite) TestShouldDestroyAllSessionsOfUserAsAdmin() {
+	s.mock.Ctx.SetUserValue("username", testUsername)
+
+	AdminSessionsDelete(s.mock.Ctx)
+
+	assert.Equal(s.T(), 200, s.mock.Ctx.Response.StatusCode())
+	assert.Len(s.T(), s.listSessions(), 0)
+}
+
+func TestRunHandlerUserSessionsSuite(t *testing.T) {
+	suite.Run(t, new(HandlerUserSessionsSuite))
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"net"
 	"net/url"
@@ -14,6 +15,7 @@ import (
 	"github.com/authelia/authelia/internal/authentication"
 	"github.com/authelia/authelia/internal/authorization"
 	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/metrics"
 	"github.com/authelia/authelia/internal/middlewares"
 	"github.com/authelia/authelia/internal/session"
 	"github.com/authelia/authelia/internal/utils"
@@ -23,6 +25,17 @@ func isURLUnderProtectedDomain(url *url.URL, domain string) bool {
 	return strings.HasSuffix(url.Hostname(), domain)
 }
 
+// getProtectedDomain returns the cookie domain the URL belongs to, the most specific one when several domains match.
+func getProtectedDomain(url *url.URL, domains []schema.SessionCookieConfiguration) (domain schema.SessionCookieConfiguration, found bool) {
+	for _, d := range domains {
+		if isURLUnderProtectedDomain(url, d.Domain) && (!found || len(d.Domain) > len(domain.Domain)) {
+			domain, found = d, true
+		}
+	}
+
+	return domain, found
+}
+
 func isSchemeHTTPS(url *url.URL) bool {
 	return url.Scheme == "https"
 }
@@ -220,7 +233,7 @@ func verifySessionCookie(ctx *middlewares.AutheliaCtx, targetURL *url.URL, userS
 
 	err = verifySessionHasUpToDateProfile(ctx, targetURL, userSession, refreshProfile, refreshProfileInterval)
 	if err != nil {
-		if err == authentication.ErrUserNotFound {
+		if errors.Is(err, authentication.ErrUserNotFound) {
 			err = ctx.Providers.SessionProvider.DestroySession(ctx.RequestCtx)
 			if err != nil {
 				ctx.Logger.Error(fmt.Errorf("Unable to destroy user session after provider refresh didn't find the user: %s", err))
@@ -229,13 +242,15 @@ func verifySessionCookie(ctx *middlewares.AutheliaCtx, targetURL *url.URL, userS
 			return userSession.Username, userSession.DisplayName, userSession.Groups, userSession.Emails, authentication.NotAuthenticated, err
 		}
 
-		ctx.Logger.Warnf("Error occurred while attempting to update user details from LDAP: %s", err)
+		ctx.Logger.Warnf("Error occurred while attempting to update user details from the authentication backend: %s", err)
 	}
 
 	return userSession.Username, userSession.DisplayName, userSession.Groups, userSession.Emails, userSession.AuthenticationLevel, nil
 }
 
-func handleUnauthorized(ctx *middlewares.AutheliaCtx, targetURL fmt.Stringer, isBasicAuth bool, username string, method []byte) {
+func handleUnauthorized(ctx *middlewares.AutheliaCtx, targetURL fmt.Stringer, isBasicAuth bool, username string, method []byte, portalURL string) {
+	recordVerifyDecision(ctx, metrics.DecisionUnauthorized, username, targetURL.String(), method)
+
 	friendlyUsername := "<anonymous>"
 	if username != "" {
 		friendlyUsername = username
@@ -251,8 +266,12 @@ func handleUnauthorized(ctx *middlewares.AutheliaCtx, targetURL fmt.Stringer, is
 
 	// Kubernetes ingress controller and Traefik use the rd parameter of the verify
 	// endpoint to provide the URL of the login portal. The target URL of the user
-	// is computed from X-Forwarded-* headers or X-Original-URL.
+	// is computed from X-Forwarded-* headers or X-Original-URL. Otherwise the users
+	// are sent to the portal of the cookie domain of the target URL if configured.
 	rd := string(ctx.QueryArgs().Peek("rd"))
+	if rd == "" {
+		rd = portalURL
+	}
 	rm := string(method)
 
 	friendlyMethod := "unknown"
@@ -402,23 +421,17 @@ func verifySessionHasUpToDateProfile(ctx *middlewares.AutheliaCtx, targetURL *ur
 }
 
 func getProfileRefreshSettings(cfg schema.AuthenticationBackendConfiguration) (refresh bool, refreshInterval time.Duration) {
-	if cfg.Ldap != nil {
-		if cfg.RefreshInterval == schema.ProfileRefreshDisabled {
-			refresh = false
-			refreshInterval = 0
-		} else {
-			refresh = true
+	switch cfg.RefreshInterval {
+	case schema.ProfileRefreshDisabled:
+		return false, 0
+	case schema.ProfileRefreshAlways:
+		return true, schema.RefreshIntervalAlways
+	default:
+		// Skip Error Check since validator checks it
+		refreshInterval, _ = utils.ParseDurationString(cfg.RefreshInterval)
 
-			if cfg.RefreshInterval != schema.ProfileRefreshAlways {
-				// Skip Error Check since validator checks it
-				refreshInterval, _ = utils.ParseDurationString(cfg.RefreshInterval)
-			} else {
-				refreshInterval = schema.RefreshIntervalAlways
-			}
-		}
+		return true, refreshInterval
 	}
-
-	return refresh, refreshInterval
 }
 
 func verifyAuth(ctx *middlewares.AutheliaCtx, targetURL *url.URL, refreshProfile bool, refreshProfileInterval time.Duration) (isBasicAuth bool, username, name string, groups, emails []string, authLevel authentication.Level, err error) {
@@ -478,19 +491,32 @@ func VerifyGet(cfg schema.AuthenticationBackendConfiguration) middlewares.Reques
 		if !isSchemeHTTPS(targetURL) && !isSchemeWSS(targetURL) {
 			ctx.Logger.Error(fmt.Errorf("Scheme of target URL %s must be secure since cookies are "+
 				"only transported over a secure connection for security reasons", targetURL.String()))
+			recordVerifyDecision(ctx, metrics.DecisionUnauthorized, "", targetURL.String(), ctx.XForwardedMethod())
 			ctx.ReplyUnauthorized()
 
 			return
 		}
 
-		if !isURLUnderProtectedDomain(targetURL, ctx.Configuration.Session.Domain) {
-			ctx.Logger.Error(fmt.Errorf("The target URL %s is not under the protected domain %s",
-				targetURL.String(), ctx.Configuration.Session.Domain))
+		domains := session.CookieDomains(ctx.Configuration.Session)
+
+		domain, found := getProtectedDomain(targetURL, domains)
+		if !found {
+			names := make([]string, len(domains))
+			for i, d := range domains {
+				names[i] = d.Domain
+			}
+
+			ctx.Logger.Error(fmt.Errorf("The target URL %s is not under any of the protected domains %s",
+				targetURL.String(), strings.Join(names, ", ")))
+			recordVerifyDecision(ctx, metrics.DecisionUnauthorized, "", targetURL.String(), ctx.XForwardedMethod())
 			ctx.ReplyUnauthorized()
 
 			return
 		}
 
+		// The session of the user is the one of the cookie domain of the target URL.
+		ctx.Providers.SessionProvider.SelectDomain(ctx.RequestCtx, domain.Domain)
+
 		isBasicAuth, username, name, groups, emails, authLevel, err := verifyAuth(ctx, targetURL, refreshProfile, refreshProfileInterval)
 
 		method := ctx.XForwardedMethod()
@@ -503,7 +529,7 @@ func VerifyGet(cfg schema.AuthenticationBackendConfiguration) middlewares.Reques
 				return
 			}
 
-			handleUnauthorized(ctx, targetURL, isBasicAuth, username, method)
+			handleUnauthorized(ctx, targetURL, isBasicAuth, username, method, domain.PortalURL)
 
 			return
 		}
@@ -514,10 +540,12 @@ func VerifyGet(cfg schema.AuthenticationBackendConfiguration) middlewares.Reques
 		switch authorized {
 		case Forbidden:
 			ctx.Logger.Infof("Access to %s is forbidden to user %s", targetURL.String(), username)
+			recordVerifyDecision(ctx, metrics.DecisionForbidden, username, targetURL.String(), method)
 			ctx.ReplyForbidden()
 		case NotAuthorized:
-			handleUnauthorized(ctx, targetURL, isBasicAuth, username, method)
+			handleUnauthorized(ctx, targetURL, isBasicAuth, username, method, domain.PortalURL)
 		case Authorized:
+			recordVerifyDecision(ctx, metrics.DecisionAuthorized, username, targetURL.String(), method)
 			setForwardedHeaders(&ctx.Response.Header, username, name, groups, emails)
 		}
 
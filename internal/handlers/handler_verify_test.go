@@ -12,10 +12,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"github.com/valyala/fasthttp"
 
 	"github.com/authelia/authelia/internal/authentication"
 	"github.com/authelia/authelia/internal/authorization"
 	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/metrics"
 	"github.com/authelia/authelia/internal/mocks"
 	"github.com/authelia/authelia/internal/session"
 	"github.com/authelia/authelia/internal/utils"
@@ -607,6 +609,36 @@ func TestShouldVerifyAuthorizationsUsingSessionCookie(t *testing.T) {
 	}
 }
 
+func TestShouldRecordVerifyDecisions(t *testing.T) {
+	recorder := metrics.NewPrometheus()
+
+	for _, targetURL := range []string{"https://one-factor.example.com", "https://bypass.example.com",
+		"https://two-factor.example.com", "https://deny.example.com"} {
+		mock := mocks.NewMockAutheliaCtx(t)
+		mock.Ctx.Providers.Metrics = recorder
+		mock.Clock.Set(time.Now())
+
+		userSession := mock.Ctx.GetSession()
+		userSession.Username = testUsername
+		userSession.AuthenticationLevel = authentication.OneFactor
+		userSession.RefreshTTL = mock.Clock.Now().Add(5 * time.Minute)
+		require.NoError(t, mock.Ctx.SaveSession(userSession))
+
+		mock.Ctx.Request.Header.Set("X-Original-URL", targetURL)
+
+		VerifyGet(verifyGetCfg)(mock.Ctx)
+		mock.Close()
+	}
+
+	ctx := &fasthttp.RequestCtx{}
+	recorder.Handler()(ctx)
+
+	body := string(ctx.Response.Body())
+	assert.Contains(t, body, `authelia_verify_decisions_total{decision="authorized"} 2`)
+	assert.Contains(t, body, `authelia_verify_decisions_total{decision="unauthorized"} 1`)
+	assert.Contains(t, body, `authelia_verify_decisions_total{decision="forbidden"} 1`)
+}
+
 func TestShouldDestroySessionWhenInactiveForTooLong(t *testing.T) {
 	mock := mocks.NewMockAutheliaCtx(t)
 	defer mock.Close()
@@ -1058,6 +1090,35 @@ func TestShouldDestroySessionWhenUserNotExist(t *testing.T) {
 	assert.Equal(t, authentication.NotAuthenticated, userSession.AuthenticationLevel)
 }
 
+func TestShouldDestroySessionWhenUserIsDisabled(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	mock.UserProviderMock.EXPECT().GetDetails("john").Return(nil, authentication.ErrUserDisabled).Times(1)
+
+	clock := mocks.TestingClock{}
+	clock.Set(time.Now())
+
+	userSession := mock.Ctx.GetSession()
+	userSession.Username = "john"
+	userSession.AuthenticationLevel = authentication.TwoFactor
+	userSession.LastActivity = clock.Now().Unix()
+	userSession.RefreshTTL = clock.Now().Add(-1 * time.Minute)
+	err := mock.Ctx.SaveSession(userSession)
+
+	require.NoError(t, err)
+
+	mock.Ctx.Request.Header.Set("X-Original-URL", "https://two-factor.example.com")
+
+	VerifyGet(verifyGetCfg)(mock.Ctx)
+
+	assert.Equal(t, 401, mock.Ctx.Response.StatusCode())
+
+	userSession = mock.Ctx.GetSession()
+	assert.Equal(t, "", userSession.Username)
+	assert.Equal(t, authentication.NotAuthenticated, userSession.AuthenticationLevel)
+}
+
 func TestShouldGetRemovedUserGroupsFromBackend(t *testing.T) {
 	mock := mocks.NewMockAutheliaCtx(t)
 	defer mock.Close()
@@ -1281,4 +1342,72 @@ func TestGetProfileRefreshSettings(t *testing.T) {
 
 	assert.Equal(t, true, refresh)
 	assert.Equal(t, time.Duration(0), interval)
+
+	cfg = schema.AuthenticationBackendConfiguration{
+		RefreshInterval: "10m",
+		File:            &schema.FileAuthenticationBackendConfiguration{},
+	}
+
+	refresh, interval = getProfileRefreshSettings(cfg)
+
+	assert.Equal(t, true, refresh)
+	assert.Equal(t, 10*time.Minute, interval)
+}
+
+func TestShouldRedirectToPortalOfCookieDomain(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	mock.Ctx.Configuration.Session.Domain = "example.com"
+	mock.Ctx.Configuration.Session.PortalURL = "https://login.example.com"
+	mock.Ctx.Configuration.Session.Cookies = []schema.SessionCookieConfiguration{{
+		Domain:    "example.org",
+		Name:      "authelia_session_org",
+		PortalURL: "https://login.example.org",
+	}}
+	mock.Ctx.Configuration.AccessControl.Rules = append(mock.Ctx.Configuration.AccessControl.Rules, schema.ACLRule{
+		Domains: []string{"two-factor.example.org"},
+		Policy:  "two_factor",
+	})
+
+	// Reload the providers since the configuration is indirect.
+	mock.Ctx.Providers.SessionProvider = session.NewProvider(mock.Ctx.Configuration.Session, nil)
+	mock.Ctx.Providers.Authorizer = authorization.NewAuthorizer(mock.Ctx.Configuration.AccessControl)
+
+	// The session of the first domain does not authenticate the user on the second one.
+	mock.Ctx.Request.Header.SetCookie("authelia_session", "abc")
+	mock.Ctx.Request.Header.Set("X-Original-URL", "https://two-factor.example.org")
+
+	VerifyGet(verifyGetCfg)(mock.Ctx)
+
+	assert.Equal(t, "Found. Redirecting to https://login.example.org?rd=https%3A%2F%2Ftwo-factor.example.org",
+		string(mock.Ctx.Response.Body()))
+	assert.Equal(t, 302, mock.Ctx.Response.StatusCode())
+}
+
+func TestShouldReplyUnauthorizedWhenTargetIsNotUnderAnyCookieDomain(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	mock.Ctx.Configuration.Session.Domain = "example.com"
+	mock.Ctx.Configuration.Session.Cookies = []schema.SessionCookieConfiguration{{Domain: "example.org"}}
+
+	mock.Ctx.Request.Header.Set("X-Original-URL", "https://two-factor.example.net")
+
+	VerifyGet(verifyGetCfg)(mock.Ctx)
+
+	assert.Equal(t, 401, mock.Ctx.Response.StatusCode())
+	assert.Equal(t, "The target URL https://two-factor.example.net is not under any of the protected domains example.com, example.org",
+		mock.Hook.LastEntry().Message)
+}
+
+func TestShouldSelectMostSpecificProtectedDomain(t *testing.T) {
+	targetURL, err := url.ParseRequestURI("https://app.eu.example.com")
+	require.NoError(t, err)
+
+	domain, found := getProtectedDomain(targetURL, []schema.SessionCookieConfiguration{
+		{Domain: "example.com"}, {Domain: "eu.example.com"}, {Domain: "example.org"},
+	})
+	assert.True(t, found)
+	assert.Equal(t, "eu.example.com", domain.Domain)
 }
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/session"
+)
+
+// notifyClonedDevice warns the user that one of their security keys might have been cloned. The notification is
+// best effort: the sign in is refused anyway and a failure is only logged. The description of the key is optional.
+func notifyClonedDevice(ctx *middlewares.AutheliaCtx, userSession session.UserSession, description string) {
+	if ctx.Configuration.Webauthn == nil || !ctx.Configuration.Webauthn.NotifyClonedDevices {
+		return
+	}
+
+	if len(userSession.Emails) == 0 {
+		ctx.Logger.Debugf("User %s has no email address, unable to notify about the possibly cloned device", userSession.Username)
+		return
+	}
+
+	key := "A security key"
+	if description != "" {
+		key = fmt.Sprintf("The security key %q", description)
+	}
+
+	body := fmt.Sprintf(clonedDeviceNotificationBody, key, userSession.Username)
+
+	err := ctx.Providers.Notifier.Send(userSession.Emails[0], clonedDeviceNotificationTitle, body, "")
+	if err != nil {
+		ctx.Logger.Errorf("Unable to notify user %s about the possibly cloned device: %s", userSession.Username, err)
+	}
+}
+
+// notifyPasswordChanged tells the user their password has just been changed so that they can react if they did not
+// change it. The notification is best effort: the password is changed anyway and a failure is only logged.
+func notifyPasswordChanged(ctx *middlewares.AutheliaCtx, userSession session.UserSession) {
+	if len(userSession.Emails) == 0 {
+		ctx.Logger.Debugf("User %s has no email address, unable to notify about the password change", userSession.Username)
+		return
+	}
+
+	body := fmt.Sprintf(passwordChangedNotificationBody, userSession.Username)
+
+	err := ctx.Providers.Notifier.Send(userSession.Emails[0], passwordChangedNotificationTitle, body, "")
+	if err != nil {
+		ctx.Logger.Errorf("Unable to notify user %s about the password change: %s", userSession.Username, err)
+	}
+}
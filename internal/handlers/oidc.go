@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/authorization"
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/oidc"
+)
+
+// oidcRedirectURI appends the parameters to the query of the client redirect URI, empty values are omitted.
+func oidcRedirectURI(redirectURI string, parameters map[string]string) (string, error) {
+	uri, err := url.Parse(redirectURI)
+	if err != nil {
+		return "", err
+	}
+
+	query := uri.Query()
+
+	for key, value := range parameters {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+
+	uri.RawQuery = query.Encode()
+
+	return uri.String(), nil
+}
+
+// oidcErrorRedirectURI returns the client redirect URI carrying an error as described in RFC6749 section 4.1.2.1.
+func oidcErrorRedirectURI(redirectURI, state, errorCode, description string) (string, error) {
+	return oidcRedirectURI(redirectURI, map[string]string{
+		"error":             errorCode,
+		"error_description": description,
+		"state":             state,
+	})
+}
+
+// oidcRedirectError redirects the user agent to the client with an error.
+func oidcRedirectError(ctx *middlewares.AutheliaCtx, redirectURI, state, errorCode, description string, err error) {
+	ctx.Logger.Errorf("OpenID Connect authorization request failed with %s: %v", errorCode, err)
+
+	uri, err := oidcErrorRedirectURI(redirectURI, state, errorCode, description)
+	if err != nil {
+		oidcRespondError(ctx, fasthttp.StatusBadRequest, oidc.ErrorInvalidRequest, oidcInvalidRedirectURIMessage, err)
+		return
+	}
+
+	ctx.Redirect(uri, fasthttp.StatusFound)
+}
+
+// oidcRespondError replies to the client with an error as described in RFC6749 section 5.2.
+func oidcRespondError(ctx *middlewares.AutheliaCtx, statusCode int, errorCode, description string, err error) {
+	ctx.Logger.Errorf("OpenID Connect request failed with %s: %v", errorCode, err)
+
+	oidcRespondJSON(ctx, statusCode, oidc.ErrorResponse{Error: errorCode, ErrorDescription: description})
+}
+
+// oidcRespondJSON replies with the raw JSON representation of the value as expected by OpenID Connect clients.
+func oidcRespondJSON(ctx *middlewares.AutheliaCtx, statusCode int, value interface{}) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		ctx.Logger.Errorf("Unable to marshal OpenID Connect response: %v", err)
+		ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+
+		return
+	}
+
+	ctx.SetStatusCode(statusCode)
+	ctx.SetContentType("application/json")
+	ctx.SetBody(b)
+}
+
+// isOIDCAuthorizationRequestRequiringTwoFactor returns true if the target URL is an OpenID Connect
+// authorization request of a client requiring two factor authentication.
+func isOIDCAuthorizationRequestRequiringTwoFactor(ctx *middlewares.AutheliaCtx, targetURL *url.URL) bool {
+	if ctx.Providers.OpenIDConnect == nil || !strings.HasSuffix(targetURL.Path, oidc.AuthorizationPath) {
+		return false
+	}
+
+	client, err := ctx.Providers.OpenIDConnect.GetClient(targetURL.Query().Get("client_id"))
+	if err != nil {
+		return false
+	}
+
+	return client.Policy == authorization.TwoFactor
+}
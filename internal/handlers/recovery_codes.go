@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strings"
+
+	"github.com/authelia/authelia/internal/authentication"
+)
+
+// generateRecoveryCodes generates a set of random recovery codes formatted as two groups of characters separated by
+// a dash, e.g. 7kq2m-x9c4t. The codes are drawn again until they all have a different lookup.
+func generateRecoveryCodes(count int) ([]string, error) {
+	codes := make([]string, 0, count)
+	lookups := make(map[string]bool, count)
+	max := big.NewInt(int64(len(recoveryCodeCharacters)))
+
+	for len(codes) < count {
+		b := make([]byte, recoveryCodeLength)
+
+		for j := range b {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, err
+			}
+
+			b[j] = recoveryCodeCharacters[n.Int64()]
+		}
+
+		code := string(b[:recoveryCodeLength/2]) + "-" + string(b[recoveryCodeLength/2:])
+
+		lookup := recoveryCodeLookup(code)
+		if lookups[lookup] {
+			continue
+		}
+
+		lookups[lookup] = true
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// normalizeRecoveryCode removes the separators and the case the user might have typed a recovery code with.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// recoveryCodeLookup returns the first characters of a recovery code, stored in clear to find its hash.
+func recoveryCodeLookup(code string) string {
+	normalized := normalizeRecoveryCode(code)
+
+	if len(normalized) < recoveryCodeLookupLength {
+		return normalized
+	}
+
+	return normalized[:recoveryCodeLookupLength]
+}
+
+// hashRecoveryCode hashes a recovery code with argon2id so that the codes cannot be used if the database leaks.
+func hashRecoveryCode(code string) (string, error) {
+	return authentication.HashPassword(normalizeRecoveryCode(code), "", authentication.HashingAlgorithmArgon2id,
+		authentication.HashingDefaultArgon2idTime, authentication.HashingDefaultArgon2idMemory,
+		authentication.HashingDefaultArgon2idParallelism, authentication.HashingDefaultArgon2idKeyLength, recoveryCodeSaltLength)
+}
+
+// checkRecoveryCode checks whether a recovery code typed by a user matches a hash.
+func checkRecoveryCode(code, hash string) (bool, error) {
+	return authentication.CheckPassword(normalizeRecoveryCode(code), hash)
+}
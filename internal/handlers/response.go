@@ -7,7 +7,10 @@ import (
 	"github.com/valyala/fasthttp"
 
 	"github.com/authelia/authelia/internal/authorization"
+	"github.com/authelia/authelia/internal/metrics"
 	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/regulation"
+	"github.com/authelia/authelia/internal/session"
 	"github.com/authelia/authelia/internal/utils"
 )
 
@@ -49,7 +52,14 @@ func Handle1FAResponse(ctx *middlewares.AutheliaCtx, targetURI, requestMethod st
 		return
 	}
 
-	safeRedirection := utils.IsRedirectionSafe(*targetURL, ctx.Configuration.Session.Domain)
+	if isOIDCAuthorizationRequestRequiringTwoFactor(ctx, targetURL) {
+		ctx.Logger.Warnf("OpenID Connect client of %s requires 2FA, cannot be redirected yet", targetURI)
+		ctx.ReplyOK()
+
+		return
+	}
+
+	safeRedirection := isRedirectionSafe(ctx, *targetURL)
 
 	if !safeRedirection {
 		if !ctx.Providers.Authorizer.IsSecondFactorEnabled() && ctx.Configuration.DefaultRedirectionURL != "" {
@@ -96,7 +106,7 @@ func Handle2FAResponse(ctx *middlewares.AutheliaCtx, targetURI string) {
 		return
 	}
 
-	if targetURL != nil && utils.IsRedirectionSafe(*targetURL, ctx.Configuration.Session.Domain) {
+	if targetURL != nil && isRedirectionSafe(ctx, *targetURL) {
 		err := ctx.SetJSONBody(redirectResponse{Redirect: targetURI})
 		if err != nil {
 			ctx.Logger.Errorf("Unable to set redirection URL in body: %s", err)
@@ -111,3 +121,34 @@ func handleAuthenticationUnauthorized(ctx *middlewares.AutheliaCtx, err error, m
 	ctx.SetStatusCode(fasthttp.StatusUnauthorized)
 	ctx.Error(err, message)
 }
+
+// regulateSecondFactor replies with an error and returns false if the user is not allowed to authenticate with a
+// second factor method.
+func regulateSecondFactor(ctx *middlewares.AutheliaCtx, method, username string) bool {
+	bannedUntil, err := ctx.Providers.Regulator.Regulate(username)
+	if err == nil {
+		return true
+	}
+
+	if err == regulation.ErrUserIsBanned {
+		recordBan(ctx, metrics.FactorSecond, method, username, "")
+		handleAuthenticationUnauthorized(ctx, fmt.Errorf("User %s is banned until %s", username, bannedUntil), userBannedMessage)
+		return false
+	}
+
+	handleAuthenticationUnauthorized(ctx, fmt.Errorf("Unable to regulate authentication: %s", err), mfaValidationFailedMessage)
+
+	return false
+}
+
+// isRedirectionSafe checks that the users can be redirected to the URL, i.e. it belongs to one of the domains
+// protected with a session cookie.
+func isRedirectionSafe(ctx *middlewares.AutheliaCtx, targetURL url.URL) bool {
+	for _, domain := range session.CookieDomains(ctx.Configuration.Session) {
+		if utils.IsRedirectionSafe(targetURL, domain.Domain) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,15 +1,19 @@
 package handlers
 
 import (
+	"crypto/subtle"
 	"time"
 
 	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
+
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/utils"
 )
 
 // TOTPVerifier is the interface for verifying TOTPs.
 type TOTPVerifier interface {
-	Verify(token, secret string) (bool, error)
+	Verify(token string, device models.TOTPDevice, now time.Time) (step uint64, valid bool, err error)
 }
 
 // TOTPVerifierImpl the production implementation for TOTP verification.
@@ -18,14 +22,35 @@ type TOTPVerifierImpl struct {
 	Skew   uint
 }
 
-// Verify verifies TOTPs.
-func (tv *TOTPVerifierImpl) Verify(token, secret string) (bool, error) {
+// Verify verifies TOTPs with the parameters the device has been registered with. The configured period is used
+// for the devices registered before the period was stored. When the passcode is valid, the time step it has been
+// generated for is returned so that it can be refused if it is replayed. The time steps are counted from now.
+func (tv *TOTPVerifierImpl) Verify(token string, device models.TOTPDevice, now time.Time) (step uint64, valid bool, err error) {
+	period := device.Period
+	if period == 0 {
+		period = tv.Period
+	}
+
 	opts := totp.ValidateOpts{
-		Period:    tv.Period,
-		Skew:      tv.Skew,
-		Digits:    otp.DigitsSix,
-		Algorithm: otp.AlgorithmSHA1,
+		Period:    period,
+		Digits:    otp.Digits(device.Digits),
+		Algorithm: utils.OTPAlgorithm(device.Algorithm),
+	}
+
+	current := uint64(now.UTC().Unix()) / uint64(period)
+
+	for i := -int64(tv.Skew); i <= int64(tv.Skew); i++ {
+		candidate := uint64(int64(current) + i)
+
+		code, err := totp.GenerateCodeCustom(device.Secret, time.Unix(int64(candidate*uint64(period)), 0).UTC(), opts)
+		if err != nil {
+			return 0, false, err
+		}
+
+		if subtle.ConstantTimeCompare([]byte(code), []byte(token)) == 1 {
+			return candidate, true, nil
+		}
 	}
 
-	return totp.ValidateCustom(token, secret, time.Now().UTC(), opts)
+	return 0, false, nil
 }
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/models"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+
+func TestShouldVerifyTOTPWithDeviceParameters(t *testing.T) {
+	verifier := &TOTPVerifierImpl{Period: 30, Skew: 0}
+	device := models.TOTPDevice{Secret: testTOTPSecret, Algorithm: "SHA512", Digits: 8, Period: 60}
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	token, err := totp.GenerateCodeCustom(testTOTPSecret, now, totp.ValidateOpts{
+		Period:    60,
+		Digits:    otp.DigitsEight,
+		Algorithm: otp.AlgorithmSHA512,
+	})
+	require.NoError(t, err)
+
+	step, valid, err := verifier.Verify(token, device, now)
+	require.NoError(t, err)
+	assert.True(t, valid)
+	assert.Equal(t, uint64(now.Unix())/60, step)
+
+	device.Algorithm = "SHA1"
+
+	_, valid, err = verifier.Verify(token, device, now)
+	require.NoError(t, err)
+	assert.False(t, valid)
+}
+
+func TestShouldVerifyTOTPWithConfiguredPeriodWhenDeviceHasNone(t *testing.T) {
+	verifier := &TOTPVerifierImpl{Period: 30, Skew: 0}
+	device := models.TOTPDevice{Secret: testTOTPSecret, Algorithm: "SHA1", Digits: 6}
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	token, err := totp.GenerateCode(testTOTPSecret, now)
+	require.NoError(t, err)
+
+	step, valid, err := verifier.Verify(token, device, now)
+	require.NoError(t, err)
+	assert.True(t, valid)
+	assert.Equal(t, uint64(now.Unix())/30, step)
+}
+
+func TestShouldReturnStepOfPasscodeWithinSkew(t *testing.T) {
+	verifier := &TOTPVerifierImpl{Period: 30, Skew: 1}
+	device := models.TOTPDevice{Secret: testTOTPSecret, Algorithm: "SHA1", Digits: 6}
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	previous := now.Add(-30 * time.Second)
+
+	token, err := totp.GenerateCode(testTOTPSecret, previous)
+	require.NoError(t, err)
+
+	step, valid, err := verifier.Verify(token, device, now)
+	require.NoError(t, err)
+	assert.True(t, valid)
+	assert.Equal(t, uint64(previous.Unix())/30, step)
+}
+
+func TestShouldVerifyTOTPAtGivenTime(t *testing.T) {
+	verifier := &TOTPVerifierImpl{Period: 30, Skew: 1}
+	device := models.TOTPDevice{Secret: testTOTPSecret, Algorithm: "SHA1", Digits: 6}
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	token, err := totp.GenerateCode(testTOTPSecret, now)
+	require.NoError(t, err)
+
+	_, valid, err := verifier.Verify(token, device, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, valid)
+
+	step, valid, err := verifier.Verify(token, device, now.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.True(t, valid)
+	assert.Equal(t, uint64(now.Unix())/30, step)
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"encoding/json"
+
 	"github.com/tstranex/u2f"
 
 	"github.com/authelia/authelia/internal/authentication"
@@ -32,14 +34,92 @@ type signTOTPRequestBody struct {
 	TargetURL string `json:"targetURL"`
 }
 
+// registerTOTPRequestBody model of the request body of the TOTP identity verification finish endpoint.
+type registerTOTPRequestBody struct {
+	Description string `json:"description"`
+}
+
+// totpDeviceResponse model of a TOTP device returned by the TOTP devices endpoint.
+type totpDeviceResponse struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	CreatedAt   int64  `json:"created_at"`
+	LastUsedAt  int64  `json:"last_used_at"`
+}
+
+// registerU2FRequestBody model of the request body of U2F registration endpoint.
+type registerU2FRequestBody struct {
+	u2f.RegisterResponse
+
+	// The name given to the device by the user.
+	Description string `json:"description"`
+}
+
+// u2fDeviceResponse model of a U2F device returned by the U2F devices endpoint.
+type u2fDeviceResponse struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	CreatedAt   int64  `json:"created_at"`
+	LastUsedAt  int64  `json:"last_used_at"`
+}
+
+// webauthnDeviceResponse model of a Webauthn device returned by the admin API.
+type webauthnDeviceResponse struct {
+	ID              string `json:"id"`
+	AttestationType string `json:"attestation_type"`
+	CreatedAt       int64  `json:"created_at"`
+}
+
 // signU2FRequestBody model of the request body of U2F authentication endpoint.
 type signU2FRequestBody struct {
 	SignResponse u2f.SignResponse `json:"signResponse"`
 	TargetURL    string           `json:"targetURL"`
 }
 
+// signWebauthnRequestBody model of the request body of Webauthn authentication endpoint.
+type signWebauthnRequestBody struct {
+	Credential json.RawMessage `json:"credential"`
+	TargetURL  string          `json:"targetURL"`
+}
+
+// signRecoveryCodeRequestBody model of the request body of the recovery code authentication endpoint.
+type signRecoveryCodeRequestBody struct {
+	Code      string `json:"code" valid:"required"`
+	TargetURL string `json:"targetURL"`
+}
+
+// recoveryCodesResponse model of the response sent once the recovery codes have been generated.
+type recoveryCodesResponse struct {
+	Codes []string `json:"codes"`
+}
+
+// signEmailOTPRequestBody model of the request body of the email one-time passcode authentication endpoint.
+type signEmailOTPRequestBody struct {
+	Token     string `json:"token" valid:"required"`
+	TargetURL string `json:"targetURL"`
+}
+
+// signDuoRequestBody model of the request body of the Duo authentication endpoint. The device and the factor default
+// to auto and push.
 type signDuoRequestBody struct {
 	TargetURL string `json:"targetURL"`
+	Device    string `json:"device"`
+	Factor    string `json:"factor"`
+	Passcode  string `json:"passcode"`
+}
+
+// duoDevicesResponse model of the response of the Duo devices endpoint.
+type duoDevicesResponse struct {
+	Result    string      `json:"result"`
+	Devices   []duoDevice `json:"devices,omitempty"`
+	EnrollURL string      `json:"enroll_url,omitempty"`
+}
+
+// duoDevice model of a Duo device and the factors the user can authenticate with it.
+type duoDevice struct {
+	Device      string   `json:"device"`
+	DisplayName string   `json:"display_name"`
+	Factors     []string `json:"factors"`
 }
 
 // firstFactorRequestBody represents the JSON body received by the endpoint.
@@ -81,3 +161,60 @@ type resetPasswordStep1RequestBody struct {
 type resetPasswordStep2RequestBody struct {
 	Password string `json:"password"`
 }
+
+// changePasswordRequestBody model of the request body of the password change endpoint.
+type changePasswordRequestBody struct {
+	CurrentPassword     string `json:"current_password" valid:"required"`
+	NewPassword         string `json:"new_password" valid:"required"`
+	RevokeOtherSessions bool   `json:"revoke_other_sessions"`
+}
+
+// OIDCConsentScope is the model of a scope the user is asked to consent to.
+type OIDCConsentScope struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// OIDCConsentGetResponseBody is the model of the response sent by the OpenID Connect consent endpoint.
+type OIDCConsentGetResponseBody struct {
+	ClientID          string             `json:"client_id"`
+	ClientDescription string             `json:"client_description"`
+	Scopes            []OIDCConsentScope `json:"scopes"`
+}
+
+// oidcConsentPostRequestBody model of the request body received by the OpenID Connect consent endpoint.
+type oidcConsentPostRequestBody struct {
+	ClientID       string `json:"client_id" valid:"required"`
+	AcceptOrReject string `json:"accept_or_reject" valid:"required"`
+}
+
+// adminUserMethodsResponse model of the second factor methods of a user returned by the admin API.
+type adminUserMethodsResponse struct {
+	Method          string                   `json:"method"`
+	TOTPDevices     []totpDeviceResponse     `json:"totp_devices"`
+	U2FDevices      []u2fDeviceResponse      `json:"u2f_devices"`
+	WebauthnDevices []webauthnDeviceResponse `json:"webauthn_devices"`
+	RecoveryCodes   int                      `json:"recovery_codes"`
+}
+
+// adminAuthenticationAttempt model of an authentication attempt returned by the admin API.
+type adminAuthenticationAttempt struct {
+	Successful bool  `json:"successful"`
+	Time       int64 `json:"time"`
+}
+
+// adminAuthenticationAttemptsResponse model of the recent authentication attempts of a user returned by the admin API.
+type adminAuthenticationAttemptsResponse struct {
+	BannedUntil int64                        `json:"banned_until"`
+	Attempts    []adminAuthenticationAttempt `json:"attempts"`
+}
+
+// userSessionResponse model of a session of the user returned by the user sessions endpoint.
+type userSessionResponse struct {
+	ID           string `json:"id"`
+	CreatedAt    int64  `json:"created_at"`
+	LastActivity int64  `json:"last_activity"`
+	RemoteIP     string `json:"remote_ip"`
+	UserAgent    string `json:"user_agent"`
+	Current      bool   `json:"current"`
+}
@@ -4,19 +4,44 @@ import (
 	"crypto/elliptic"
 
 	"github.com/tstranex/u2f"
+
+	"github.com/authelia/authelia/internal/models"
 )
 
 // U2FVerifier is the interface for verifying U2F keys.
 type U2FVerifier interface {
-	Verify(keyHandle []byte, publicKey []byte, signResponse u2f.SignResponse, challenge u2f.Challenge) error
+	Verify(keyHandle []byte, publicKey []byte, counter uint32, signResponse u2f.SignResponse, challenge u2f.Challenge) (uint32, error)
 }
 
 // U2FVerifierImpl the production implementation for U2F key verification.
 type U2FVerifierImpl struct{}
 
-// Verify verifies U2F keys.
-func (uv *U2FVerifierImpl) Verify(keyHandle []byte, publicKey []byte,
-	signResponse u2f.SignResponse, challenge u2f.Challenge) error {
+// Verify verifies U2F keys and returns the new signature counter of the key. The counter must increase with every
+// signature, otherwise errU2FCounterNotIncreasing is returned since the key might have been cloned. Keys which do not
+// implement the counter and always return zero are tolerated.
+func (uv *U2FVerifierImpl) Verify(keyHandle []byte, publicKey []byte, counter uint32,
+	signResponse u2f.SignResponse, challenge u2f.Challenge) (uint32, error) {
+	registration := newU2FRegistration(keyHandle, publicKey)
+
+	newCounter, err := registration.Authenticate(
+		signResponse, challenge, counter)
+	if err == u2f.ErrCounterTooLow {
+		return 0, errU2FCounterNotIncreasing
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if newCounter == counter && newCounter != 0 {
+		return 0, errU2FCounterNotIncreasing
+	}
+
+	return newCounter, nil
+}
+
+// newU2FRegistration creates the registration expected by the U2F library from a key handle and a public key.
+func newU2FRegistration(keyHandle []byte, publicKey []byte) u2f.Registration {
 	var registration u2f.Registration
 	registration.KeyHandle = keyHandle
 	x, y := elliptic.Unmarshal(elliptic.P256(), publicKey)
@@ -24,9 +49,16 @@ func (uv *U2FVerifierImpl) Verify(keyHandle []byte, publicKey []byte,
 	registration.PubKey.X = x
 	registration.PubKey.Y = y
 
-	// TODO(c.michaud): store the counter to help detecting cloned U2F keys.
-	_, err := registration.Authenticate(
-		signResponse, challenge, 0)
+	return registration
+}
+
+// newU2FRegistrations creates the registrations expected by the U2F library from the devices of a user.
+func newU2FRegistrations(devices []models.U2FDevice) []u2f.Registration {
+	registrations := make([]u2f.Registration, len(devices))
+
+	for i, device := range devices {
+		registrations[i] = newU2FRegistration(device.KeyHandle, device.PublicKey)
+	}
 
-	return err
+	return registrations
 }
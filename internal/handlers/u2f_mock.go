@@ -35,15 +35,16 @@ func (m *MockU2FVerifier) EXPECT() *MockU2FVerifierMockRecorder {
 }
 
 // Verify mocks base method
-func (m *MockU2FVerifier) Verify(keyHandle, publicKey []byte, signResponse u2f.SignResponse, challenge u2f.Challenge) error {
+func (m *MockU2FVerifier) Verify(keyHandle, publicKey []byte, counter uint32, signResponse u2f.SignResponse, challenge u2f.Challenge) (uint32, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Verify", keyHandle, publicKey, signResponse, challenge)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "Verify", keyHandle, publicKey, counter, signResponse, challenge)
+	ret0, _ := ret[0].(uint32)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Verify indicates an expected call of Verify
-func (mr *MockU2FVerifierMockRecorder) Verify(keyHandle, publicKey, signResponse, challenge interface{}) *gomock.Call {
+func (mr *MockU2FVerifierMockRecorder) Verify(keyHandle, publicKey, counter, signResponse, challenge interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockU2FVerifier)(nil).Verify), keyHandle, publicKey, signResponse, challenge)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockU2FVerifier)(nil).Verify), keyHandle, publicKey, counter, signResponse, challenge)
 }
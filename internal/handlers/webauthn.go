@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"fmt"
+	"net"
+
+	"github.com/duo-labs/webauthn/protocol"
+	"github.com/duo-labs/webauthn/protocol/webauthncose"
+	"github.com/duo-labs/webauthn/webauthn"
+	"github.com/fxamacker/cbor/v2"
+
+	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/session"
+	"github.com/authelia/authelia/internal/storage"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// webauthnLegacyAttestationType is the attestation type given to the devices registered with the U2F API.
+const webauthnLegacyAttestationType = "fido-u2f"
+
+// webauthnUser is the representation of an Authelia user and its credentials expected by the Webauthn library.
+type webauthnUser struct {
+	Username    string
+	DisplayName string
+	Credentials []webauthn.Credential
+
+	// The IDs of the credentials registered with the U2F API. Those credentials are scoped to the U2F appID
+	// and can only be asserted thanks to the appid extension.
+	LegacyCredentialIDs [][]byte
+}
+
+// WebAuthnID implements webauthn.User.
+func (u *webauthnUser) WebAuthnID() []byte {
+	return []byte(u.Username)
+}
+
+// WebAuthnName implements webauthn.User.
+func (u *webauthnUser) WebAuthnName() string {
+	return u.Username
+}
+
+// WebAuthnDisplayName implements webauthn.User.
+func (u *webauthnUser) WebAuthnDisplayName() string {
+	if u.DisplayName == "" {
+		return u.Username
+	}
+
+	return u.DisplayName
+}
+
+// WebAuthnIcon implements webauthn.User.
+func (u *webauthnUser) WebAuthnIcon() string {
+	return ""
+}
+
+// WebAuthnCredentials implements webauthn.User.
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.Credentials
+}
+
+// HasLegacyCredentials returns true if the user registered a device with the U2F API.
+func (u *webauthnUser) HasLegacyCredentials() bool {
+	return len(u.LegacyCredentialIDs) > 0
+}
+
+// IsLegacyCredential returns true if the credential has been registered with the U2F API.
+func (u *webauthnUser) IsLegacyCredential(kid []byte) bool {
+	for _, id := range u.LegacyCredentialIDs {
+		if bytes.Equal(id, kid) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// CredentialDescriptors returns the descriptors of all the credentials of the user.
+func (u *webauthnUser) CredentialDescriptors() []protocol.CredentialDescriptor {
+	descriptors := make([]protocol.CredentialDescriptor, len(u.Credentials))
+
+	for i, credential := range u.Credentials {
+		descriptors[i] = protocol.CredentialDescriptor{
+			Type:         protocol.PublicKeyCredentialType,
+			CredentialID: credential.ID,
+		}
+	}
+
+	return descriptors
+}
+
+// getWebauthnUser loads the Webauthn devices and the U2F devices registered by the user of the session.
+func getWebauthnUser(ctx *middlewares.AutheliaCtx, userSession session.UserSession) (*webauthnUser, error) {
+	user := &webauthnUser{
+		Username:    userSession.Username,
+		DisplayName: userSession.DisplayName,
+	}
+
+	devices, err := ctx.Providers.StorageProvider.LoadWebauthnDevicesByUsername(userSession.Username)
+	if err != nil && err != storage.ErrNoWebauthnDevice {
+		return nil, fmt.Errorf("Unable to load Webauthn devices: %s", err)
+	}
+
+	for _, device := range devices {
+		user.Credentials = append(user.Credentials, webauthnCredentialFromDevice(device))
+	}
+
+	u2fDevices, err := ctx.Providers.StorageProvider.LoadU2FDevicesByUsername(userSession.Username)
+	if err != nil && err != storage.ErrNoU2FDeviceHandle {
+		return nil, fmt.Errorf("Unable to load U2F devices: %s", err)
+	}
+
+	for _, device := range u2fDevices {
+		credential, err := webauthnCredentialFromU2FDevice(device.KeyHandle, device.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+
+		credential.Authenticator.SignCount = device.Counter
+
+		user.Credentials = append(user.Credentials, *credential)
+		user.LegacyCredentialIDs = append(user.LegacyCredentialIDs, device.KeyHandle)
+	}
+
+	return user, nil
+}
+
+func webauthnCredentialFromDevice(device models.WebauthnDevice) webauthn.Credential {
+	return webauthn.Credential{
+		ID:              device.KID,
+		PublicKey:       device.PublicKey,
+		AttestationType: device.AttestationType,
+		Authenticator: webauthn.Authenticator{
+			AAGUID:    device.AAGUID,
+			SignCount: device.SignCount,
+		},
+	}
+}
+
+// webauthnCredentialFromU2FDevice converts a device registered with the U2F API into a Webauthn credential.
+// U2F stores the raw P-256 point of the public key while Webauthn expects a COSE key.
+func webauthnCredentialFromU2FDevice(keyHandle, publicKey []byte) (*webauthn.Credential, error) {
+	x, y := elliptic.Unmarshal(elliptic.P256(), publicKey)
+	if x == nil {
+		return nil, fmt.Errorf("Unable to parse the public key of U2F device")
+	}
+
+	coordinateSize := (elliptic.P256().Params().BitSize + 7) / 8
+
+	key, err := cbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		// The P-256 curve is identified by 1 in the COSE registry.
+		Curve:  1,
+		XCoord: x.FillBytes(make([]byte, coordinateSize)),
+		YCoord: y.FillBytes(make([]byte, coordinateSize)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to encode the public key of U2F device: %s", err)
+	}
+
+	return &webauthn.Credential{
+		ID:              keyHandle,
+		PublicKey:       key,
+		AttestationType: webauthnLegacyAttestationType,
+	}, nil
+}
+
+// getWebauthnAppID returns the U2F appID the legacy devices have been registered with.
+func getWebauthnAppID(ctx *middlewares.AutheliaCtx) (string, error) {
+	if ctx.XForwardedProto() == nil {
+		return "", errMissingXForwardedProto
+	}
+
+	if ctx.XForwardedHost() == nil {
+		return "", errMissingXForwardedHost
+	}
+
+	return fmt.Sprintf("%s://%s", ctx.XForwardedProto(), ctx.XForwardedHost()), nil
+}
+
+// newWebauthn creates the Webauthn relying party of the portal, identified by the host Authelia is served at.
+func newWebauthn(ctx *middlewares.AutheliaCtx) (*webauthn.WebAuthn, error) {
+	origin, err := getWebauthnAppID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rpID := string(ctx.XForwardedHost())
+	if host, _, err := net.SplitHostPort(rpID); err == nil {
+		rpID = host
+	}
+
+	timeout, err := utils.ParseDurationString(ctx.Configuration.Webauthn.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return webauthn.New(&webauthn.Config{
+		RPDisplayName:         ctx.Configuration.Webauthn.DisplayName,
+		RPID:                  rpID,
+		RPOrigin:              origin,
+		AttestationPreference: protocol.ConveyancePreference(ctx.Configuration.Webauthn.AttestationConveyancePreference),
+		AuthenticatorSelection: protocol.AuthenticatorSelection{
+			RequireResidentKey: protocol.ResidentKeyUnrequired(),
+			UserVerification:   protocol.UserVerificationRequirement(ctx.Configuration.Webauthn.UserVerification),
+		},
+		Timeout: int(timeout.Milliseconds()),
+	})
+}
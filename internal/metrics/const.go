@@ -0,0 +1,21 @@
+package metrics
+
+const (
+	namespace = "authelia"
+
+	// FactorFirst is the label value of first factor authentication attempts.
+	FactorFirst = "1fa"
+	// FactorSecond is the label value of second factor authentication attempts.
+	FactorSecond = "2fa"
+
+	// DecisionAuthorized is the label value of the verify decisions granting access.
+	DecisionAuthorized = "authorized"
+	// DecisionUnauthorized is the label value of the verify decisions requiring authentication.
+	DecisionUnauthorized = "unauthorized"
+	// DecisionForbidden is the label value of the verify decisions denying access.
+	DecisionForbidden = "forbidden"
+
+	providerUser     = "user"
+	providerStorage  = "storage"
+	providerNotifier = "notifier"
+)
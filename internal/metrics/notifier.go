@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/authelia/authelia/internal/notification"
+)
+
+// Notifier is a notifier recording the latency of the calls made to the wrapped notifier.
+type Notifier struct {
+	notifier notification.Notifier
+	recorder Provider
+}
+
+// NewNotifier wraps the notifier so that the latency of its calls is recorded.
+func NewNotifier(notifier notification.Notifier, recorder Provider) *Notifier {
+	return &Notifier{notifier: notifier, recorder: recorder}
+}
+
+func (n *Notifier) observe(call string, start time.Time) {
+	n.recorder.RecordProviderCall(providerNotifier, call, time.Since(start))
+}
+
+// Send a notification to the given recipient.
+func (n *Notifier) Send(recipient, subject, body, htmlBody string) error {
+	defer n.observe("Send", time.Now())
+	return n.notifier.Send(recipient, subject, body, htmlBody)
+}
+
+// StartupCheck checks the notifier is able to send notifications.
+func (n *Notifier) StartupCheck() (bool, error) {
+	defer n.observe("StartupCheck", time.Now())
+	return n.notifier.StartupCheck()
+}
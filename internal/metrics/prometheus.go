@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// Prometheus is a metrics provider exposing the metrics in the Prometheus text format.
+type Prometheus struct {
+	registry *prometheus.Registry
+
+	firstFactor   *prometheus.CounterVec
+	secondFactor  *prometheus.CounterVec
+	verify        *prometheus.CounterVec
+	bans          prometheus.Counter
+	providerCalls *prometheus.HistogramVec
+	requests      *prometheus.HistogramVec
+}
+
+// NewPrometheus creates a metrics provider backed by a dedicated Prometheus registry.
+func NewPrometheus() *Prometheus {
+	p := &Prometheus{
+		registry: prometheus.NewRegistry(),
+		firstFactor: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "authentication_first_factor_total",
+			Help:      "The number of first factor authentication attempts.",
+		}, []string{"method", "success"}),
+		secondFactor: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "authentication_second_factor_total",
+			Help:      "The number of second factor authentication attempts.",
+		}, []string{"method", "success"}),
+		verify: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "verify_decisions_total",
+			Help:      "The number of decisions taken by the verify endpoint.",
+		}, []string{"decision"}),
+		bans: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "regulation_bans_total",
+			Help:      "The number of authentication attempts refused because the user is banned.",
+		}),
+		providerCalls: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "provider_call_duration_seconds",
+			Help:      "The latency of the calls made to the user, storage and notifier providers.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"provider", "call"}),
+		requests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_duration_seconds",
+			Help:      "The time taken to reply to the HTTP requests.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "code"}),
+	}
+
+	p.registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		p.firstFactor,
+		p.secondFactor,
+		p.verify,
+		p.bans,
+		p.providerCalls,
+		p.requests,
+	)
+
+	return p
+}
+
+// RecordAuthentication records the outcome of an authentication attempt.
+func (p *Prometheus) RecordAuthentication(factor, method string, success bool) {
+	counter := p.firstFactor
+	if factor == FactorSecond {
+		counter = p.secondFactor
+	}
+
+	counter.WithLabelValues(method, strconv.FormatBool(success)).Inc()
+}
+
+// RecordVerifyDecision records a decision taken by the verify endpoint.
+func (p *Prometheus) RecordVerifyDecision(decision string) {
+	p.verify.WithLabelValues(decision).Inc()
+}
+
+// RecordBan records an authentication attempt refused by the regulation.
+func (p *Prometheus) RecordBan() {
+	p.bans.Inc()
+}
+
+// RecordProviderCall records the latency of a call made to a provider.
+func (p *Prometheus) RecordProviderCall(provider, call string, elapsed time.Duration) {
+	p.providerCalls.WithLabelValues(provider, call).Observe(elapsed.Seconds())
+}
+
+// RecordRequest records the duration of an HTTP request.
+func (p *Prometheus) RecordRequest(method string, statusCode int, elapsed time.Duration) {
+	p.requests.WithLabelValues(method, strconv.Itoa(statusCode)).Observe(elapsed.Seconds())
+}
+
+// Handler returns the handler serving the metrics in the Prometheus text format.
+func (p *Prometheus) Handler() fasthttp.RequestHandler {
+	return fasthttpadaptor.NewFastHTTPHandler(promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{}))
+}
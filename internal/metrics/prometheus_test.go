@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/valyala/fasthttp"
+)
+
+func TestShouldRecordAuthenticationPerFactorAndMethod(t *testing.T) {
+	p := NewPrometheus()
+
+	p.RecordAuthentication(FactorFirst, "password", true)
+	p.RecordAuthentication(FactorFirst, "password", false)
+	p.RecordAuthentication(FactorFirst, "password", false)
+	p.RecordAuthentication(FactorSecond, "totp", true)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(p.firstFactor.WithLabelValues("password", "true")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(p.firstFactor.WithLabelValues("password", "false")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(p.secondFactor.WithLabelValues("totp", "true")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(p.secondFactor.WithLabelValues("totp", "false")))
+}
+
+func TestShouldRecordVerifyDecisionsAndBans(t *testing.T) {
+	p := NewPrometheus()
+
+	p.RecordVerifyDecision(DecisionAuthorized)
+	p.RecordVerifyDecision(DecisionAuthorized)
+	p.RecordVerifyDecision(DecisionForbidden)
+	p.RecordBan()
+
+	assert.Equal(t, float64(2), testutil.ToFloat64(p.verify.WithLabelValues(DecisionAuthorized)))
+	assert.Equal(t, float64(0), testutil.ToFloat64(p.verify.WithLabelValues(DecisionUnauthorized)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(p.verify.WithLabelValues(DecisionForbidden)))
+	assert.Equal(t, float64(1), testutil.ToFloat64(p.bans))
+}
+
+func TestShouldServeMetricsInPrometheusTextFormat(t *testing.T) {
+	p := NewPrometheus()
+
+	p.RecordVerifyDecision(DecisionUnauthorized)
+	p.RecordProviderCall(providerStorage, "LoadPreferred2FAMethod", 20*time.Millisecond)
+	p.RecordRequest("GET", 200, 5*time.Millisecond)
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.SetRequestURI("/metrics")
+	p.Handler()(ctx)
+
+	body := string(ctx.Response.Body())
+
+	assert.Equal(t, fasthttp.StatusOK, ctx.Response.StatusCode())
+	assert.Contains(t, body, `authelia_verify_decisions_total{decision="unauthorized"} 1`)
+	assert.Contains(t, body, `authelia_provider_call_duration_seconds_count{call="LoadPreferred2FAMethod",provider="storage"} 1`)
+	assert.Contains(t, body, `authelia_request_duration_seconds_bucket{code="200",method="GET",le="0.005"} 1`)
+	assert.Contains(t, body, "go_goroutines")
+}
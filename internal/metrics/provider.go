@@ -0,0 +1,18 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+// Provider is an interface recording the runtime metrics of Authelia and exposing them.
+type Provider interface {
+	RecordAuthentication(factor, method string, success bool)
+	RecordVerifyDecision(decision string)
+	RecordBan()
+	RecordProviderCall(provider, call string, elapsed time.Duration)
+	RecordRequest(method string, statusCode int, elapsed time.Duration)
+
+	Handler() fasthttp.RequestHandler
+}
@@ -0,0 +1,76 @@
+package metrics_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/metrics"
+	"github.com/authelia/authelia/internal/mocks"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+func scrape(provider metrics.Provider) string {
+	ctx := &fasthttp.RequestCtx{}
+	provider.Handler()(ctx)
+
+	return string(ctx.Response.Body())
+}
+
+func TestShouldRecordUserProviderCalls(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	recorder := metrics.NewPrometheus()
+	userProviderMock := mocks.NewMockUserProvider(ctrl)
+	userProviderMock.EXPECT().CheckUserPassword("john", "password").Return(true, nil)
+	userProviderMock.EXPECT().UpdatePassword("john", "new").Return(fmt.Errorf("failure"))
+
+	provider := metrics.NewUserProvider(userProviderMock, recorder)
+
+	ok, err := provider.CheckUserPassword("john", "password")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.EqualError(t, provider.UpdatePassword("john", "new"), "failure")
+
+	body := scrape(recorder)
+	assert.Contains(t, body, `authelia_provider_call_duration_seconds_count{call="CheckUserPassword",provider="user"} 1`)
+	assert.Contains(t, body, `authelia_provider_call_duration_seconds_count{call="UpdatePassword",provider="user"} 1`)
+}
+
+func TestShouldRecordStorageProviderCalls(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	recorder := metrics.NewPrometheus()
+	storageMock := storage.NewMockProvider(ctrl)
+	storageMock.EXPECT().LoadPreferred2FAMethod("john").Return("totp", nil).Times(2)
+
+	provider := metrics.NewStorageProvider(storageMock, recorder)
+
+	for i := 0; i < 2; i++ {
+		method, err := provider.LoadPreferred2FAMethod("john")
+		require.NoError(t, err)
+		assert.Equal(t, "totp", method)
+	}
+
+	assert.Contains(t, scrape(recorder), `authelia_provider_call_duration_seconds_count{call="LoadPreferred2FAMethod",provider="storage"} 2`)
+}
+
+func TestShouldRecordNotifierCalls(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	recorder := metrics.NewPrometheus()
+	notifierMock := mocks.NewMockNotifier(ctrl)
+	notifierMock.EXPECT().Send("john@example.com", "subject", "body", "<p>body</p>").Return(nil)
+
+	notifier := metrics.NewNotifier(notifierMock, recorder)
+
+	require.NoError(t, notifier.Send("john@example.com", "subject", "body", "<p>body</p>"))
+	assert.Contains(t, scrape(recorder), `authelia_provider_call_duration_seconds_count{call="Send",provider="notifier"} 1`)
+}
@@ -0,0 +1,227 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/storage"
+)
+
+// StorageProvider is a storage provider recording the latency of the calls made to the wrapped provider.
+type StorageProvider struct {
+	provider storage.Provider
+	recorder Provider
+}
+
+// NewStorageProvider wraps the storage provider so that the latency of its calls is recorded.
+func NewStorageProvider(provider storage.Provider, recorder Provider) *StorageProvider {
+	return &StorageProvider{provider: provider, recorder: recorder}
+}
+
+func (p *StorageProvider) observe(call string, start time.Time) {
+	p.recorder.RecordProviderCall(providerStorage, call, time.Since(start))
+}
+
+// LoadPreferred2FAMethod calls LoadPreferred2FAMethod on the wrapped provider and records its latency.
+func (p *StorageProvider) LoadPreferred2FAMethod(username string) (string, error) {
+	defer p.observe("LoadPreferred2FAMethod", time.Now())
+	return p.provider.LoadPreferred2FAMethod(username)
+}
+
+// SavePreferred2FAMethod calls SavePreferred2FAMethod on the wrapped provider and records its latency.
+func (p *StorageProvider) SavePreferred2FAMethod(username string, method string) error {
+	defer p.observe("SavePreferred2FAMethod", time.Now())
+	return p.provider.SavePreferred2FAMethod(username, method)
+}
+
+// FindIdentityVerificationToken calls FindIdentityVerificationToken on the wrapped provider and records its latency.
+func (p *StorageProvider) FindIdentityVerificationToken(token string) (bool, error) {
+	defer p.observe("FindIdentityVerificationToken", time.Now())
+	return p.provider.FindIdentityVerificationToken(token)
+}
+
+// SaveIdentityVerificationToken calls SaveIdentityVerificationToken on the wrapped provider and records its latency.
+func (p *StorageProvider) SaveIdentityVerificationToken(token string) error {
+	defer p.observe("SaveIdentityVerificationToken", time.Now())
+	return p.provider.SaveIdentityVerificationToken(token)
+}
+
+// RemoveIdentityVerificationToken calls RemoveIdentityVerificationToken on the wrapped provider and records its latency.
+func (p *StorageProvider) RemoveIdentityVerificationToken(token string) error {
+	defer p.observe("RemoveIdentityVerificationToken", time.Now())
+	return p.provider.RemoveIdentityVerificationToken(token)
+}
+
+// SaveTOTPDevice calls SaveTOTPDevice on the wrapped provider and records its latency.
+func (p *StorageProvider) SaveTOTPDevice(device models.TOTPDevice) error {
+	defer p.observe("SaveTOTPDevice", time.Now())
+	return p.provider.SaveTOTPDevice(device)
+}
+
+// LoadTOTPDevicesByUsername calls LoadTOTPDevicesByUsername on the wrapped provider and records its latency.
+func (p *StorageProvider) LoadTOTPDevicesByUsername(username string) ([]models.TOTPDevice, error) {
+	defer p.observe("LoadTOTPDevicesByUsername", time.Now())
+	return p.provider.LoadTOTPDevicesByUsername(username)
+}
+
+// UpdateTOTPDeviceLastStep calls UpdateTOTPDeviceLastStep on the wrapped provider and records its latency.
+func (p *StorageProvider) UpdateTOTPDeviceLastStep(username string, id string, step uint64, lastUsedAt time.Time) error {
+	defer p.observe("UpdateTOTPDeviceLastStep", time.Now())
+	return p.provider.UpdateTOTPDeviceLastStep(username, id, step, lastUsedAt)
+}
+
+// DeleteTOTPDevice calls DeleteTOTPDevice on the wrapped provider and records its latency.
+func (p *StorageProvider) DeleteTOTPDevice(username string, id string) error {
+	defer p.observe("DeleteTOTPDevice", time.Now())
+	return p.provider.DeleteTOTPDevice(username, id)
+}
+
+// DeleteTOTPDevicesByUsername calls DeleteTOTPDevicesByUsername on the wrapped provider and records its latency.
+func (p *StorageProvider) DeleteTOTPDevicesByUsername(username string) error {
+	defer p.observe("DeleteTOTPDevicesByUsername", time.Now())
+	return p.provider.DeleteTOTPDevicesByUsername(username)
+}
+
+// SaveU2FDevice calls SaveU2FDevice on the wrapped provider and records its latency.
+func (p *StorageProvider) SaveU2FDevice(device models.U2FDevice) error {
+	defer p.observe("SaveU2FDevice", time.Now())
+	return p.provider.SaveU2FDevice(device)
+}
+
+// LoadU2FDevicesByUsername calls LoadU2FDevicesByUsername on the wrapped provider and records its latency.
+func (p *StorageProvider) LoadU2FDevicesByUsername(username string) ([]models.U2FDevice, error) {
+	defer p.observe("LoadU2FDevicesByUsername", time.Now())
+	return p.provider.LoadU2FDevicesByUsername(username)
+}
+
+// UpdateU2FDeviceCounter calls UpdateU2FDeviceCounter on the wrapped provider and records its latency.
+func (p *StorageProvider) UpdateU2FDeviceCounter(username string, keyHandle []byte, counter uint32, lastUsedAt time.Time) error {
+	defer p.observe("UpdateU2FDeviceCounter", time.Now())
+	return p.provider.UpdateU2FDeviceCounter(username, keyHandle, counter, lastUsedAt)
+}
+
+// DeleteU2FDevice calls DeleteU2FDevice on the wrapped provider and records its latency.
+func (p *StorageProvider) DeleteU2FDevice(username string, keyHandle []byte) error {
+	defer p.observe("DeleteU2FDevice", time.Now())
+	return p.provider.DeleteU2FDevice(username, keyHandle)
+}
+
+// SaveWebauthnDevice calls SaveWebauthnDevice on the wrapped provider and records its latency.
+func (p *StorageProvider) SaveWebauthnDevice(device models.WebauthnDevice) error {
+	defer p.observe("SaveWebauthnDevice", time.Now())
+	return p.provider.SaveWebauthnDevice(device)
+}
+
+// LoadWebauthnDevicesByUsername calls LoadWebauthnDevicesByUsername on the wrapped provider and records its latency.
+func (p *StorageProvider) LoadWebauthnDevicesByUsername(username string) ([]models.WebauthnDevice, error) {
+	defer p.observe("LoadWebauthnDevicesByUsername", time.Now())
+	return p.provider.LoadWebauthnDevicesByUsername(username)
+}
+
+// UpdateWebauthnDeviceSignCount calls UpdateWebauthnDeviceSignCount on the wrapped provider and records its latency.
+func (p *StorageProvider) UpdateWebauthnDeviceSignCount(username string, kid []byte, signCount uint32) error {
+	defer p.observe("UpdateWebauthnDeviceSignCount", time.Now())
+	return p.provider.UpdateWebauthnDeviceSignCount(username, kid, signCount)
+}
+
+// DeleteWebauthnDevice calls DeleteWebauthnDevice on the wrapped provider and records its latency.
+func (p *StorageProvider) DeleteWebauthnDevice(username string, kid []byte) error {
+	defer p.observe("DeleteWebauthnDevice", time.Now())
+	return p.provider.DeleteWebauthnDevice(username, kid)
+}
+
+// SaveRecoveryCodes calls SaveRecoveryCodes on the wrapped provider and records its latency.
+func (p *StorageProvider) SaveRecoveryCodes(username string, codes []models.RecoveryCode) error {
+	defer p.observe("SaveRecoveryCodes", time.Now())
+	return p.provider.SaveRecoveryCodes(username, codes)
+}
+
+// LoadRecoveryCodesByUsername calls LoadRecoveryCodesByUsername on the wrapped provider and records its latency.
+func (p *StorageProvider) LoadRecoveryCodesByUsername(username string) ([]models.RecoveryCode, error) {
+	defer p.observe("LoadRecoveryCodesByUsername", time.Now())
+	return p.provider.LoadRecoveryCodesByUsername(username)
+}
+
+// DeleteRecoveryCode calls DeleteRecoveryCode on the wrapped provider and records its latency.
+func (p *StorageProvider) DeleteRecoveryCode(username string, hash string) error {
+	defer p.observe("DeleteRecoveryCode", time.Now())
+	return p.provider.DeleteRecoveryCode(username, hash)
+}
+
+// AppendAuthenticationLog calls AppendAuthenticationLog on the wrapped provider and records its latency.
+func (p *StorageProvider) AppendAuthenticationLog(attempt models.AuthenticationAttempt) error {
+	defer p.observe("AppendAuthenticationLog", time.Now())
+	return p.provider.AppendAuthenticationLog(attempt)
+}
+
+// LoadLatestAuthenticationLogs calls LoadLatestAuthenticationLogs on the wrapped provider and records its latency.
+func (p *StorageProvider) LoadLatestAuthenticationLogs(username string, fromDate time.Time) ([]models.AuthenticationAttempt, error) {
+	defer p.observe("LoadLatestAuthenticationLogs", time.Now())
+	return p.provider.LoadLatestAuthenticationLogs(username, fromDate)
+}
+
+// DeleteFailedAuthenticationLogs calls DeleteFailedAuthenticationLogs on the wrapped provider and records its latency.
+func (p *StorageProvider) DeleteFailedAuthenticationLogs(username string, fromDate time.Time) error {
+	defer p.observe("DeleteFailedAuthenticationLogs", time.Now())
+	return p.provider.DeleteFailedAuthenticationLogs(username, fromDate)
+}
+
+// AppendEmailOTPLog calls AppendEmailOTPLog on the wrapped provider and records its latency.
+func (p *StorageProvider) AppendEmailOTPLog(log models.EmailOTPLog) error {
+	defer p.observe("AppendEmailOTPLog", time.Now())
+	return p.provider.AppendEmailOTPLog(log)
+}
+
+// LoadLatestEmailOTPLogs calls LoadLatestEmailOTPLogs on the wrapped provider and records its latency.
+func (p *StorageProvider) LoadLatestEmailOTPLogs(username string, fromDate time.Time) ([]models.EmailOTPLog, error) {
+	defer p.observe("LoadLatestEmailOTPLogs", time.Now())
+	return p.provider.LoadLatestEmailOTPLogs(username, fromDate)
+}
+
+// SaveOAuth2AuthorizationCode calls SaveOAuth2AuthorizationCode on the wrapped provider and records its latency.
+func (p *StorageProvider) SaveOAuth2AuthorizationCode(code models.OAuth2AuthorizationCode) error {
+	defer p.observe("SaveOAuth2AuthorizationCode", time.Now())
+	return p.provider.SaveOAuth2AuthorizationCode(code)
+}
+
+// LoadOAuth2AuthorizationCode calls LoadOAuth2AuthorizationCode on the wrapped provider and records its latency.
+func (p *StorageProvider) LoadOAuth2AuthorizationCode(signature string) (*models.OAuth2AuthorizationCode, error) {
+	defer p.observe("LoadOAuth2AuthorizationCode", time.Now())
+	return p.provider.LoadOAuth2AuthorizationCode(signature)
+}
+
+// DeleteOAuth2AuthorizationCode calls DeleteOAuth2AuthorizationCode on the wrapped provider and records its latency.
+func (p *StorageProvider) DeleteOAuth2AuthorizationCode(signature string) error {
+	defer p.observe("DeleteOAuth2AuthorizationCode", time.Now())
+	return p.provider.DeleteOAuth2AuthorizationCode(signature)
+}
+
+// SaveOAuth2RefreshToken calls SaveOAuth2RefreshToken on the wrapped provider and records its latency.
+func (p *StorageProvider) SaveOAuth2RefreshToken(token models.OAuth2RefreshToken) error {
+	defer p.observe("SaveOAuth2RefreshToken", time.Now())
+	return p.provider.SaveOAuth2RefreshToken(token)
+}
+
+// LoadOAuth2RefreshToken calls LoadOAuth2RefreshToken on the wrapped provider and records its latency.
+func (p *StorageProvider) LoadOAuth2RefreshToken(signature string) (*models.OAuth2RefreshToken, error) {
+	defer p.observe("LoadOAuth2RefreshToken", time.Now())
+	return p.provider.LoadOAuth2RefreshToken(signature)
+}
+
+// DeleteOAuth2RefreshToken calls DeleteOAuth2RefreshToken on the wrapped provider and records its latency.
+func (p *StorageProvider) DeleteOAuth2RefreshToken(signature string) error {
+	defer p.observe("DeleteOAuth2RefreshToken", time.Now())
+	return p.provider.DeleteOAuth2RefreshToken(signature)
+}
+
+// SaveOAuth2Consent calls SaveOAuth2Consent on the wrapped provider and records its latency.
+func (p *StorageProvider) SaveOAuth2Consent(consent models.OAuth2Consent) error {
+	defer p.observe("SaveOAuth2Consent", time.Now())
+	return p.provider.SaveOAuth2Consent(consent)
+}
+
+// LoadOAuth2Consent calls LoadOAuth2Consent on the wrapped provider and records its latency.
+func (p *StorageProvider) LoadOAuth2Consent(username string, clientID string) (*models.OAuth2Consent, error) {
+	defer p.observe("LoadOAuth2Consent", time.Now())
+	return p.provider.LoadOAuth2Consent(username, clientID)
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/authelia/authelia/internal/authentication"
+)
+
+// UserProvider is a user provider recording the latency of the calls made to the wrapped provider.
+type UserProvider struct {
+	provider authentication.UserProvider
+	recorder Provider
+}
+
+// NewUserProvider wraps the user provider so that the latency of its calls is recorded.
+func NewUserProvider(provider authentication.UserProvider, recorder Provider) *UserProvider {
+	return &UserProvider{provider: provider, recorder: recorder}
+}
+
+func (p *UserProvider) observe(call string, start time.Time) {
+	p.recorder.RecordProviderCall(providerUser, call, time.Since(start))
+}
+
+// CheckUserPassword checks if provided password matches for the given user.
+func (p *UserProvider) CheckUserPassword(username string, password string) (bool, error) {
+	defer p.observe("CheckUserPassword", time.Now())
+	return p.provider.CheckUserPassword(username, password)
+}
+
+// GetDetails retrieve the groups a user belongs to.
+func (p *UserProvider) GetDetails(username string) (*authentication.UserDetails, error) {
+	defer p.observe("GetDetails", time.Now())
+	return p.provider.GetDetails(username)
+}
+
+// UpdatePassword update the password of the given user.
+func (p *UserProvider) UpdatePassword(username string, newPassword string) error {
+	defer p.observe("UpdatePassword", time.Now())
+	return p.provider.UpdatePassword(username, newPassword)
+}
@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"github.com/authelia/authelia/internal/audit"
+)
+
+// Audit writes the event to the audit log when it is enabled, the time and the client details being taken from the
+// request.
+func (c *AutheliaCtx) Audit(event audit.Event) {
+	if c.Providers.Audit == nil {
+		return
+	}
+
+	event.Time = c.Clock.Now()
+	event.RemoteIP = c.RemoteIP().String()
+	event.UserAgent = string(c.UserAgent())
+
+	c.Providers.Audit.Log(event)
+}
+
+func (c *AutheliaCtx) auditIdentityVerification(eventType, username, action, outcome string) {
+	c.Audit(audit.Event{
+		Type:     eventType,
+		Username: username,
+		Method:   action,
+		Outcome:  outcome,
+	})
+}
@@ -53,7 +53,12 @@ func AutheliaMiddleware(configuration schema.Configuration, providers Providers)
 
 // Error reply with an error and display the stack trace in the logs.
 func (c *AutheliaCtx) Error(err error, message string) {
-	b, marshalErr := json.Marshal(ErrorResponse{Status: "KO", Message: message})
+	c.ErrorWithData(err, message, nil)
+}
+
+// ErrorWithData reply with an error along with data describing it and display the stack trace in the logs.
+func (c *AutheliaCtx) ErrorWithData(err error, message string, data interface{}) {
+	b, marshalErr := json.Marshal(ErrorResponse{Status: "KO", Message: message, Data: data})
 
 	if marshalErr != nil {
 		c.Logger.Error(marshalErr)
@@ -112,6 +117,22 @@ func (c *AutheliaCtx) XOriginalURL() []byte {
 	return c.RequestCtx.Request.Header.Peek(xOriginalURLHeader)
 }
 
+// ExternalRootURL return the URL Authelia is reachable at from the X-Forwarded-Proto and X-Forwarded-Host headers
+// and the configured server path.
+func (c *AutheliaCtx) ExternalRootURL() (string, error) {
+	protocol := c.XForwardedProto()
+	if protocol == nil {
+		return "", errMissingXForwardedProto
+	}
+
+	host := c.XForwardedHost()
+	if host == nil {
+		return "", errMissingXForwardedHost
+	}
+
+	return fmt.Sprintf("%s://%s%s", protocol, host, c.Configuration.Server.Path), nil
+}
+
 // GetSession return the user session. Any update will be saved in cache.
 func (c *AutheliaCtx) GetSession() session.UserSession {
 	userSession, err := c.Providers.SessionProvider.GetSession(c.RequestCtx)
@@ -123,9 +144,26 @@ func (c *AutheliaCtx) GetSession() session.UserSession {
 	return userSession
 }
 
-// SaveSession save the content of the session.
+// SaveSession save the content of the session. The sessions of identified users are recorded in the session index.
 func (c *AutheliaCtx) SaveSession(userSession session.UserSession) error {
-	return c.Providers.SessionProvider.SaveSession(c.RequestCtx, userSession)
+	if err := c.Providers.SessionProvider.SaveSession(c.RequestCtx, userSession); err != nil {
+		return err
+	}
+
+	if userSession.Username == "" {
+		return nil
+	}
+
+	err := c.Providers.SessionProvider.IndexSession(c.RequestCtx, userSession.Username, session.IndexEntry{
+		LastActivity: c.Clock.Now().Unix(),
+		RemoteIP:     c.RemoteIP().String(),
+		UserAgent:    string(c.UserAgent()),
+	})
+	if err != nil {
+		c.Logger.Errorf("Unable to record the session of user %s in the session index: %s", userSession.Username, err)
+	}
+
+	return nil
 }
 
 // ReplyOK is a helper method to reply ok.
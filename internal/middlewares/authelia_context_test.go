@@ -33,3 +33,23 @@ func TestShouldCallNextWithAutheliaCtx(t *testing.T) {
 
 	assert.True(t, nextCalled)
 }
+
+func TestShouldGetExternalRootURLFromForwardedHeaders(t *testing.T) {
+	mock := mocks.NewMockAutheliaCtx(t)
+	defer mock.Close()
+
+	_, err := mock.Ctx.ExternalRootURL()
+	assert.EqualError(t, err, "Missing header X-Forwarded-Proto")
+
+	mock.Ctx.Request.Header.Set("X-Forwarded-Proto", "https")
+
+	_, err = mock.Ctx.ExternalRootURL()
+	assert.EqualError(t, err, "Missing header X-Forwarded-Host")
+
+	mock.Ctx.Request.Header.Set("X-Forwarded-Host", "auth.example.com")
+	mock.Ctx.Configuration.Server.Path = "/authelia"
+
+	rootURL, err := mock.Ctx.ExternalRootURL()
+	assert.NoError(t, err)
+	assert.Equal(t, "https://auth.example.com/authelia", rootURL)
+}
@@ -8,6 +8,7 @@ import (
 
 	jwt "github.com/dgrijalva/jwt-go"
 
+	"github.com/authelia/authelia/internal/audit"
 	"github.com/authelia/authelia/internal/templates"
 )
 
@@ -23,6 +24,7 @@ func IdentityVerificationStart(args IdentityVerificationStartArgs) RequestHandle
 		if err != nil {
 			// In that case we reply ok to avoid user enumeration.
 			ctx.Logger.Error(err)
+			ctx.auditIdentityVerification(audit.EventIdentityVerificationStart, "", args.ActionClaim, audit.OutcomeFailure)
 			ctx.ReplyOK()
 
 			return
@@ -108,6 +110,8 @@ func IdentityVerificationStart(args IdentityVerificationStartArgs) RequestHandle
 			return
 		}
 
+		ctx.auditIdentityVerification(audit.EventIdentityVerificationStart, identity.Username, args.ActionClaim, audit.OutcomeSuccess)
+
 		ctx.ReplyOK()
 	}
 }
@@ -139,6 +143,7 @@ func IdentityVerificationFinish(args IdentityVerificationFinishArgs, next func(c
 		}
 
 		if !found {
+			ctx.auditIdentityVerification(audit.EventIdentityVerificationFinish, "", args.ActionClaim, audit.OutcomeFailure)
 			ctx.Error(fmt.Errorf("Token is not in DB, it might have already been used"),
 				identityVerificationTokenAlreadyUsedMessage)
 			return
@@ -157,6 +162,7 @@ func IdentityVerificationFinish(args IdentityVerificationFinishArgs, next func(c
 					return
 				case ve.Errors&(jwt.ValidationErrorExpired|jwt.ValidationErrorNotValidYet) != 0:
 					// Token is either expired or not active yet
+					ctx.auditIdentityVerification(audit.EventIdentityVerificationFinish, "", args.ActionClaim, audit.OutcomeFailure)
 					ctx.Error(fmt.Errorf("Token expired"), identityVerificationTokenHasExpiredMessage)
 					return
 				default:
@@ -178,11 +184,13 @@ func IdentityVerificationFinish(args IdentityVerificationFinishArgs, next func(c
 
 		// Verify that the action claim in the token is the one expected for the given endpoint.
 		if claims.Action != args.ActionClaim {
+			ctx.auditIdentityVerification(audit.EventIdentityVerificationFinish, claims.Username, args.ActionClaim, audit.OutcomeFailure)
 			ctx.Error(fmt.Errorf("This token has not been generated for this kind of action"), operationFailedMessage)
 			return
 		}
 
 		if args.IsTokenUserValidFunc != nil && !args.IsTokenUserValidFunc(ctx, claims.Username) {
+			ctx.auditIdentityVerification(audit.EventIdentityVerificationFinish, claims.Username, args.ActionClaim, audit.OutcomeFailure)
 			ctx.Error(fmt.Errorf("This token has not been generated for this user"), operationFailedMessage)
 			return
 		}
@@ -194,6 +202,8 @@ func IdentityVerificationFinish(args IdentityVerificationFinishArgs, next func(c
 			return
 		}
 
+		ctx.auditIdentityVerification(audit.EventIdentityVerificationFinish, claims.Username, args.ActionClaim, audit.OutcomeSuccess)
+
 		next(ctx, claims.Username)
 	}
 }
@@ -1,6 +1,8 @@
 package middlewares_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
@@ -8,8 +10,10 @@ import (
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/authelia/authelia/internal/audit"
 	"github.com/authelia/authelia/internal/middlewares"
 	"github.com/authelia/authelia/internal/mocks"
 	"github.com/authelia/authelia/internal/session"
@@ -314,6 +318,47 @@ func (s *IdentityVerificationFinishProcess) TestShouldReturn200OnFinishComplete(
 	assert.Equal(s.T(), 200, s.mock.Ctx.Response.StatusCode())
 }
 
+func (s *IdentityVerificationFinishProcess) TestShouldAuditWrongUserAndCompletion() {
+	buffer := new(bytes.Buffer)
+	s.mock.Ctx.Providers.Audit = audit.NewJSONLogger(buffer)
+
+	token := createToken(s.mock.Ctx.Configuration.JWTSecret, "john", "EXP_ACTION",
+		time.Now().Add(1*time.Minute))
+	s.mock.Ctx.Request.SetBodyString(fmt.Sprintf("{\"token\":\"%s\"}", token))
+
+	s.mock.StorageProviderMock.EXPECT().
+		FindIdentityVerificationToken(gomock.Eq(token)).
+		Return(true, nil).
+		Times(2)
+
+	s.mock.StorageProviderMock.EXPECT().
+		RemoveIdentityVerificationToken(gomock.Eq(token)).
+		Return(nil)
+
+	args := newFinishArgs()
+	args.IsTokenUserValidFunc = func(ctx *middlewares.AutheliaCtx, username string) bool { return false }
+	middlewares.IdentityVerificationFinish(args, next)(s.mock.Ctx)
+	middlewares.IdentityVerificationFinish(newFinishArgs(), next)(s.mock.Ctx)
+
+	var events []audit.Event
+
+	decoder := json.NewDecoder(buffer)
+	for decoder.More() {
+		var event audit.Event
+
+		require.NoError(s.T(), decoder.Decode(&event))
+
+		events = append(events, event)
+	}
+
+	require.Len(s.T(), events, 2)
+	assert.Equal(s.T(), audit.EventIdentityVerificationFinish, events[0].Type)
+	assert.Equal(s.T(), "john", events[0].Username)
+	assert.Equal(s.T(), "EXP_ACTION", events[0].Method)
+	assert.Equal(s.T(), audit.OutcomeFailure, events[0].Outcome)
+	assert.Equal(s.T(), audit.OutcomeSuccess, events[1].Outcome)
+}
+
 func TestRunIdentityVerificationFinish(t *testing.T) {
 	s := new(IdentityVerificationFinishProcess)
 	suite.Run(t, s)
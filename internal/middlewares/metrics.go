@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"time"
+
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/metrics"
+)
+
+// MetricsRequestMiddleware records the duration of the requests served by the next handler.
+func MetricsRequestMiddleware(provider metrics.Provider, next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(ctx *fasthttp.RequestCtx) {
+		start := time.Now()
+
+		next(ctx)
+
+		provider.RecordRequest(string(ctx.Method()), ctx.Response.StatusCode(), time.Since(start))
+	}
+}
+
+// RecordAuthentication records the outcome of an authentication attempt when the metrics are enabled.
+func (c *AutheliaCtx) RecordAuthentication(factor, method string, success bool) {
+	if c.Providers.Metrics != nil {
+		c.Providers.Metrics.RecordAuthentication(factor, method, success)
+	}
+}
+
+// RecordVerifyDecision records a decision of the verify endpoint when the metrics are enabled.
+func (c *AutheliaCtx) RecordVerifyDecision(decision string) {
+	if c.Providers.Metrics != nil {
+		c.Providers.Metrics.RecordVerifyDecision(decision)
+	}
+}
+
+// RecordBan records an attempt refused by the regulation when the metrics are enabled.
+func (c *AutheliaCtx) RecordBan() {
+	if c.Providers.Metrics != nil {
+		c.Providers.Metrics.RecordBan()
+	}
+}
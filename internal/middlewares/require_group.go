@@ -0,0 +1,23 @@
+package middlewares
+
+import (
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// RequireGroup check if user has completed the second factor and is a member of the given group before executing the
+// next handler.
+func RequireGroup(group string) Middleware {
+	return func(next RequestHandler) RequestHandler {
+		return func(ctx *AutheliaCtx) {
+			userSession := ctx.GetSession()
+
+			if userSession.AuthenticationLevel < authentication.TwoFactor || !utils.IsStringInSlice(group, userSession.Groups) {
+				ctx.ReplyForbidden()
+				return
+			}
+
+			next(ctx)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// OAuth2AuthorizationCode represents an authorization code issued by the OpenID Connect provider.
+type OAuth2AuthorizationCode struct {
+	// The hashed representation of the code handed to the client.
+	Signature string
+	// The client the code has been issued to.
+	ClientID string
+	// The user who authorized the client.
+	Username string
+	// The scopes granted to the client.
+	Scopes []string
+	// The redirect URI used in the authorization request.
+	RedirectURI string
+	// The nonce provided by the client in the authorization request.
+	Nonce string
+	// The PKCE code challenge and method provided by the client in the authorization request.
+	CodeChallenge       string
+	CodeChallengeMethod string
+	// The authentication methods used by the user (pwd, mfa).
+	AMR []string
+	// The time after which the code can no longer be exchanged.
+	ExpiresAt time.Time
+}
+
+// OAuth2RefreshToken represents a refresh token issued by the OpenID Connect provider.
+type OAuth2RefreshToken struct {
+	// The hashed representation of the token handed to the client.
+	Signature string
+	// The client the token has been issued to.
+	ClientID string
+	// The user who authorized the client.
+	Username string
+	// The scopes granted to the client.
+	Scopes []string
+	// The authentication methods used by the user (pwd, mfa).
+	AMR []string
+	// The time after which the token can no longer be used.
+	ExpiresAt time.Time
+}
+
+// OAuth2Consent represents the consent given by a user to an OpenID Connect client.
+type OAuth2Consent struct {
+	// The user who gave the consent.
+	Username string
+	// The client the consent has been given to.
+	ClientID string
+	// The scopes the user consented to.
+	Scopes []string
+	// The time the consent has been given.
+	GrantedAt time.Time
+}
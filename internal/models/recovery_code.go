@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// RecoveryCode represents a single-use recovery code a user can sign in with when their devices are not available.
+type RecoveryCode struct {
+	// The user the code belongs to.
+	Username string
+	// The first characters of the code, used to find the only hash the code has to be checked against. Empty for the
+	// codes generated before it was stored.
+	Lookup string
+	// The argon2id hash of the code, the code itself is only displayed once to the user.
+	Hash string
+	// The time the code has been generated at.
+	CreatedAt time.Time
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// TOTPDevice represents a TOTP device, i.e. an authenticator app or a hardware token, registered by a user.
+type TOTPDevice struct {
+	// The user the device belongs to.
+	Username string
+	// The identifier of the device, unique amongst the devices of the user.
+	ID string
+	// The name given to the device by the user.
+	Description string
+	// The base32 encoded secret shared with the device.
+	Secret string
+	// The HMAC algorithm used to compute the passcodes: SHA1, SHA256 or SHA512.
+	Algorithm string
+	// The number of digits of the passcodes.
+	Digits uint
+	// The period in seconds a passcode is current for. It is zero for the devices registered before it was recorded,
+	// in which case the configured period applies.
+	Period uint
+	// The time step of the last passcode accepted from the device. Passcodes of this step or of a previous one are
+	// refused so that they cannot be replayed.
+	LastStep uint64
+	// The time the device has been registered at. It is zero for the devices registered before it was recorded.
+	CreatedAt time.Time
+	// The time the device has last been used to sign in. It is zero if the device has never been used.
+	LastUsedAt time.Time
+}
@@ -11,3 +11,13 @@ type AuthenticationAttempt struct {
 	// The time of the attempt.
 	Time time.Time
 }
+
+// EmailOTPLog represent a one-time passcode sent by email to a user or a failed attempt to use it.
+type EmailOTPLog struct {
+	// The user the passcode has been sent to.
+	Username string
+	// The event logged: a passcode sent or a failed attempt.
+	Event string
+	// The time of the event.
+	Time time.Time
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// U2FDevice represents a U2F security key registered by a user.
+type U2FDevice struct {
+	// The user the device belongs to.
+	Username string
+	// The name given to the device by the user.
+	Description string
+	// The key handle generated by the device during registration.
+	KeyHandle []byte
+	// The public key of the device as an uncompressed P-256 point.
+	PublicKey []byte
+	// The signature counter returned by the device the last time it has been used to sign in.
+	Counter uint32
+	// The time the device has been registered at. It is zero for the devices registered before it was recorded.
+	CreatedAt time.Time
+	// The time the device has last been used to sign in. It is zero if the device has never been used.
+	LastUsedAt time.Time
+}
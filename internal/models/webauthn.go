@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// WebauthnDevice represents a Webauthn credential registered by a user.
+type WebauthnDevice struct {
+	// The user the credential belongs to.
+	Username string
+	// The credential ID generated by the authenticator.
+	KID []byte
+	// The COSE encoded public key of the credential.
+	PublicKey []byte
+	// The attestation format used to register the credential.
+	AttestationType string
+	// The AAGUID identifying the model of the authenticator.
+	AAGUID []byte
+	// The signature counter of the authenticator.
+	SignCount uint32
+	// The time the credential has been registered at.
+	CreatedAt time.Time
+}
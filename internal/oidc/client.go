@@ -0,0 +1,59 @@
+package oidc
+
+import (
+	"crypto/subtle"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/authorization"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// IsAuthenticationLevelSufficient returns true if the authentication level of a user satisfies the client policy.
+func (c Client) IsAuthenticationLevelSufficient(level authentication.Level) bool {
+	switch c.Policy {
+	case authorization.OneFactor:
+		return level >= authentication.OneFactor
+	case authorization.TwoFactor:
+		return level >= authentication.TwoFactor
+	}
+
+	return false
+}
+
+// RequiredAuthenticationLevel returns the authentication level a user must reach before being redirected to the client.
+func (c Client) RequiredAuthenticationLevel() authentication.Level {
+	if c.Policy == authorization.OneFactor {
+		return authentication.OneFactor
+	}
+
+	return authentication.TwoFactor
+}
+
+// IsRedirectURIAllowed returns true if the redirect URI has been registered for the client.
+func (c Client) IsRedirectURIAllowed(redirectURI string) bool {
+	return utils.IsStringInSlice(redirectURI, c.RedirectURIs)
+}
+
+// IsScopeAllowed returns true if all the scopes have been registered for the client.
+func (c Client) IsScopeAllowed(scopes []string) bool {
+	return IsScopeSubset(scopes, c.Scopes)
+}
+
+// IsGrantTypeAllowed returns true if the grant type has been registered for the client.
+func (c Client) IsGrantTypeAllowed(grantType string) bool {
+	return utils.IsStringInSlice(grantType, c.GrantTypes)
+}
+
+// IsResponseTypeAllowed returns true if the response type has been registered for the client.
+func (c Client) IsResponseTypeAllowed(responseType string) bool {
+	return utils.IsStringInSlice(responseType, c.ResponseTypes)
+}
+
+// ValidateSecret returns true if the secret matches the one of the client. Public clients don't have any secret.
+func (c Client) ValidateSecret(secret string) bool {
+	if c.Public {
+		return secret == ""
+	}
+
+	return subtle.ConstantTimeCompare([]byte(c.Secret), []byte(secret)) == 1
+}
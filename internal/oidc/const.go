@@ -0,0 +1,62 @@
+package oidc
+
+// Scopes supported by the OpenID Connect provider.
+const (
+	ScopeOpenID        = "openid"
+	ScopeProfile       = "profile"
+	ScopeEmail         = "email"
+	ScopeGroups        = "groups"
+	ScopeOfflineAccess = "offline_access"
+)
+
+// Grant types supported by the OpenID Connect provider.
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+)
+
+// ResponseTypeCode is the only response type supported by the OpenID Connect provider.
+const ResponseTypeCode = "code"
+
+// PKCE code challenge methods supported by the OpenID Connect provider.
+const (
+	PKCEMethodPlain = "plain"
+	PKCEMethodS256  = "S256"
+)
+
+// Authentication method references placed in the amr claim.
+const (
+	AMRPassword    = "pwd"
+	AMRMultiFactor = "mfa"
+)
+
+// Endpoints of the OpenID Connect provider relative to the external root URL of Authelia.
+const (
+	WellKnownConfigurationPath = "/.well-known/openid-configuration"
+	AuthorizationPath          = "/api/oidc/authorize"
+	TokenPath                  = "/api/oidc/token"
+	UserinfoPath               = "/api/oidc/userinfo"
+	JWKSPath                   = "/api/oidc/jwks"
+	ConsentPath                = "/api/oidc/consent"
+)
+
+// Errors defined by RFC6749 and OpenID Connect Core returned to the clients.
+const (
+	ErrorInvalidRequest          = "invalid_request"
+	ErrorInvalidClient           = "invalid_client"
+	ErrorInvalidGrant            = "invalid_grant"
+	ErrorInvalidScope            = "invalid_scope"
+	ErrorInvalidToken            = "invalid_token"
+	ErrorUnauthorizedClient      = "unauthorized_client"
+	ErrorUnsupportedGrantType    = "unsupported_grant_type"
+	ErrorUnsupportedResponseType = "unsupported_response_type"
+	ErrorAccessDenied            = "access_denied"
+	ErrorServerError             = "server_error"
+	ErrorLoginRequired           = "login_required"
+	ErrorConsentRequired         = "consent_required"
+	ErrorInsufficientScope       = "insufficient_scope"
+)
+
+const signingAlgorithmRS256 = "RS256"
+
+const tokenEntropy = 32
@@ -0,0 +1,11 @@
+package oidc
+
+import "errors"
+
+var (
+	// ErrClientNotFound error thrown when the client is not registered in the provider.
+	ErrClientNotFound = errors.New("client not found")
+
+	// ErrInvalidToken error thrown when a token has not been issued by the provider or has expired.
+	ErrInvalidToken = errors.New("token is invalid")
+)
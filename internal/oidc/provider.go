@@ -0,0 +1,187 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	jwt "github.com/dgrijalva/jwt-go"
+
+	"github.com/authelia/authelia/internal/authorization"
+	"github.com/authelia/authelia/internal/configuration/schema"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// NewOpenIDConnectProvider creates the OpenID Connect provider from its configuration.
+// It returns nil if the provider is not configured.
+func NewOpenIDConnectProvider(configuration *schema.OpenIDConnectConfiguration) (*OpenIDConnectProvider, error) {
+	if configuration == nil {
+		return nil, nil
+	}
+
+	privateKey, err := parseRSAPrivateKey(configuration.IssuerPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the issuer private key: %w", err)
+	}
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyIDSum := sha256.Sum256(publicKeyDER)
+
+	provider := &OpenIDConnectProvider{
+		privateKey: privateKey,
+		keyID:      hex.EncodeToString(keyIDSum[:8]),
+		clients:    make(map[string]*Client),
+	}
+
+	if provider.AccessTokenLifespan, err = utils.ParseDurationString(configuration.AccessTokenLifespan); err != nil {
+		return nil, fmt.Errorf("unable to parse access_token_lifespan: %w", err)
+	}
+
+	if provider.AuthorizeCodeLifespan, err = utils.ParseDurationString(configuration.AuthorizeCodeLifespan); err != nil {
+		return nil, fmt.Errorf("unable to parse authorize_code_lifespan: %w", err)
+	}
+
+	if provider.IDTokenLifespan, err = utils.ParseDurationString(configuration.IDTokenLifespan); err != nil {
+		return nil, fmt.Errorf("unable to parse id_token_lifespan: %w", err)
+	}
+
+	if provider.RefreshTokenLifespan, err = utils.ParseDurationString(configuration.RefreshTokenLifespan); err != nil {
+		return nil, fmt.Errorf("unable to parse refresh_token_lifespan: %w", err)
+	}
+
+	for _, client := range configuration.Clients {
+		provider.clients[client.ID] = &Client{
+			ID:            client.ID,
+			Description:   client.Description,
+			Secret:        client.Secret,
+			Public:        client.Public,
+			Policy:        authorization.PolicyToLevel(client.Policy),
+			RedirectURIs:  client.RedirectURIs,
+			Scopes:        client.Scopes,
+			GrantTypes:    client.GrantTypes,
+			ResponseTypes: client.ResponseTypes,
+		}
+	}
+
+	return provider, nil
+}
+
+func parseRSAPrivateKey(key string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(key))
+	if block == nil {
+		return nil, errors.New("the key is not PEM encoded")
+	}
+
+	if privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return privateKey, nil
+	}
+
+	privateKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaPrivateKey, ok := privateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the key is not an RSA private key")
+	}
+
+	return rsaPrivateKey, nil
+}
+
+// GetClient returns the client registered with the given id.
+func (p *OpenIDConnectProvider) GetClient(id string) (*Client, error) {
+	client, ok := p.clients[id]
+	if !ok {
+		return nil, ErrClientNotFound
+	}
+
+	return client, nil
+}
+
+// SignToken signs the claims with the issuer private key and returns the compact JWT.
+func (p *OpenIDConnectProvider) SignToken(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.keyID
+
+	return token.SignedString(p.privateKey)
+}
+
+// ParseAccessToken verifies an access token issued by the provider and returns its claims.
+func (p *OpenIDConnectProvider) ParseAccessToken(accessToken, issuer string) (*AccessTokenClaims, error) {
+	token, err := jwt.ParseWithClaims(accessToken, &AccessTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if token.Method != jwt.SigningMethodRS256 {
+			return nil, fmt.Errorf("unexpected signing method: %s", token.Header["alg"])
+		}
+
+		return &p.privateKey.PublicKey, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	claims, ok := token.Claims.(*AccessTokenClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	if !claims.VerifyIssuer(issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer %s", ErrInvalidToken, claims.Issuer)
+	}
+
+	if _, err := p.GetClient(claims.ClientID); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return claims, nil
+}
+
+// KeySet returns the JSON Web Key Set containing the public key used to verify the tokens issued by the provider.
+func (p *OpenIDConnectProvider) KeySet() JSONWebKeySet {
+	publicKey := p.privateKey.PublicKey
+
+	return JSONWebKeySet{
+		Keys: []JSONWebKey{
+			{
+				KeyType:   "RSA",
+				Use:       "sig",
+				Algorithm: signingAlgorithmRS256,
+				KeyID:     p.keyID,
+				Modulus:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			},
+		},
+	}
+}
+
+// WellKnownConfiguration returns the discovery document of the provider for the given issuer.
+func (p *OpenIDConnectProvider) WellKnownConfiguration(issuer string) WellKnownConfiguration {
+	return WellKnownConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + AuthorizationPath,
+		TokenEndpoint:                     issuer + TokenPath,
+		UserinfoEndpoint:                  issuer + UserinfoPath,
+		JWKSURI:                           issuer + JWKSPath,
+		ScopesSupported:                   []string{ScopeOpenID, ScopeOfflineAccess, ScopeProfile, ScopeEmail, ScopeGroups},
+		ResponseTypesSupported:            []string{ResponseTypeCode},
+		GrantTypesSupported:               []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{signingAlgorithmRS256},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{PKCEMethodS256, PKCEMethodPlain},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "nonce", "amr", "at_hash",
+			"name", "preferred_username", "email", "email_verified", "groups",
+		},
+	}
+}
@@ -0,0 +1,164 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/authorization"
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+const testIssuer = "https://auth.example.com"
+
+func newTestConfiguration(t *testing.T) *schema.OpenIDConnectConfiguration {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+
+	return &schema.OpenIDConnectConfiguration{
+		IssuerPrivateKey:      string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		AccessTokenLifespan:   "1h",
+		AuthorizeCodeLifespan: "1m",
+		IDTokenLifespan:       "1h",
+		RefreshTokenLifespan:  "90m",
+		Clients: []schema.OpenIDConnectClientConfiguration{
+			{
+				ID:            "myapp",
+				Description:   "My Application",
+				Secret:        "secret",
+				Policy:        "one_factor",
+				RedirectURIs:  []string{"https://app.example.com/callback"},
+				Scopes:        []string{"openid", "profile"},
+				GrantTypes:    []string{"authorization_code"},
+				ResponseTypes: []string{"code"},
+			},
+		},
+	}
+}
+
+func TestShouldNotCreateProviderWhenNotConfigured(t *testing.T) {
+	provider, err := NewOpenIDConnectProvider(nil)
+
+	assert.NoError(t, err)
+	assert.Nil(t, provider)
+}
+
+func TestShouldFailToCreateProviderWithInvalidConfiguration(t *testing.T) {
+	configuration := newTestConfiguration(t)
+	configuration.IssuerPrivateKey = "abc"
+
+	_, err := NewOpenIDConnectProvider(configuration)
+	assert.EqualError(t, err, "unable to parse the issuer private key: the key is not PEM encoded")
+
+	configuration = newTestConfiguration(t)
+	configuration.RefreshTokenLifespan = "abc"
+
+	_, err = NewOpenIDConnectProvider(configuration)
+	assert.Error(t, err)
+}
+
+func TestShouldCreateProvider(t *testing.T) {
+	provider, err := NewOpenIDConnectProvider(newTestConfiguration(t))
+	require.NoError(t, err)
+
+	assert.Equal(t, time.Hour, provider.AccessTokenLifespan)
+	assert.Equal(t, time.Minute, provider.AuthorizeCodeLifespan)
+	assert.Equal(t, time.Hour, provider.IDTokenLifespan)
+	assert.Equal(t, time.Minute*90, provider.RefreshTokenLifespan)
+
+	client, err := provider.GetClient("myapp")
+	require.NoError(t, err)
+	assert.Equal(t, "My Application", client.Description)
+	assert.Equal(t, authorization.OneFactor, client.Policy)
+	assert.Equal(t, authentication.OneFactor, client.RequiredAuthenticationLevel())
+
+	_, err = provider.GetClient("unknown")
+	assert.Equal(t, ErrClientNotFound, err)
+}
+
+func TestShouldExposePublicKeyInKeySet(t *testing.T) {
+	provider, err := NewOpenIDConnectProvider(newTestConfiguration(t))
+	require.NoError(t, err)
+
+	keySet := provider.KeySet()
+	require.Len(t, keySet.Keys, 1)
+
+	key := keySet.Keys[0]
+	assert.Equal(t, "RSA", key.KeyType)
+	assert.Equal(t, "RS256", key.Algorithm)
+	assert.Equal(t, provider.keyID, key.KeyID)
+
+	modulus, err := base64.RawURLEncoding.DecodeString(key.Modulus)
+	require.NoError(t, err)
+	assert.Equal(t, 0, provider.privateKey.N.Cmp(new(big.Int).SetBytes(modulus)))
+	assert.Equal(t, "AQAB", key.Exponent)
+}
+
+func TestShouldSignAndParseAccessToken(t *testing.T) {
+	provider, err := NewOpenIDConnectProvider(newTestConfiguration(t))
+	require.NoError(t, err)
+
+	token, err := provider.SignToken(AccessTokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    testIssuer,
+			Subject:   "john",
+			Audience:  "myapp",
+			ExpiresAt: time.Now().Add(time.Minute).Unix(),
+		},
+		ClientID: "myapp",
+		Scope:    "openid profile",
+	})
+	require.NoError(t, err)
+
+	claims, err := provider.ParseAccessToken(token, testIssuer)
+	require.NoError(t, err)
+	assert.Equal(t, "john", claims.Subject)
+	assert.Equal(t, "openid profile", claims.Scope)
+
+	_, err = provider.ParseAccessToken(token, "https://other.example.com")
+	assert.EqualError(t, err, "token is invalid: unexpected issuer https://auth.example.com")
+
+	expired, err := provider.SignToken(AccessTokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    testIssuer,
+			Subject:   "john",
+			ExpiresAt: time.Now().Add(-time.Minute).Unix(),
+		},
+		ClientID: "myapp",
+	})
+	require.NoError(t, err)
+
+	_, err = provider.ParseAccessToken(expired, testIssuer)
+	assert.Error(t, err)
+
+	hmacToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, AccessTokenClaims{ClientID: "myapp"}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	_, err = provider.ParseAccessToken(hmacToken, testIssuer)
+	assert.Error(t, err)
+}
+
+func TestShouldBuildWellKnownConfiguration(t *testing.T) {
+	provider, err := NewOpenIDConnectProvider(newTestConfiguration(t))
+	require.NoError(t, err)
+
+	wellKnown := provider.WellKnownConfiguration(testIssuer)
+
+	assert.Equal(t, testIssuer, wellKnown.Issuer)
+	assert.Equal(t, "https://auth.example.com/api/oidc/authorize", wellKnown.AuthorizationEndpoint)
+	assert.Equal(t, "https://auth.example.com/api/oidc/token", wellKnown.TokenEndpoint)
+	assert.Equal(t, "https://auth.example.com/api/oidc/userinfo", wellKnown.UserinfoEndpoint)
+	assert.Equal(t, "https://auth.example.com/api/oidc/jwks", wellKnown.JWKSURI)
+	assert.Equal(t, []string{"code"}, wellKnown.ResponseTypesSupported)
+	assert.Equal(t, []string{"S256", "plain"}, wellKnown.CodeChallengeMethodsSupported)
+}
@@ -0,0 +1,117 @@
+package oidc
+
+import (
+	"crypto/rsa"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+
+	"github.com/authelia/authelia/internal/authorization"
+)
+
+// OpenIDConnectProvider is the OpenID Connect provider built on top of the Authelia sessions.
+type OpenIDConnectProvider struct {
+	privateKey *rsa.PrivateKey
+	keyID      string
+	clients    map[string]*Client
+
+	AccessTokenLifespan   time.Duration
+	AuthorizeCodeLifespan time.Duration
+	IDTokenLifespan       time.Duration
+	RefreshTokenLifespan  time.Duration
+}
+
+// Client represents a client registered in the OpenID Connect provider.
+type Client struct {
+	ID            string
+	Description   string
+	Secret        string
+	Public        bool
+	Policy        authorization.Level
+	RedirectURIs  []string
+	Scopes        []string
+	GrantTypes    []string
+	ResponseTypes []string
+}
+
+// AccessTokenClaims are the claims of the access tokens issued by the provider.
+type AccessTokenClaims struct {
+	jwt.StandardClaims
+
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+}
+
+// UserinfoClaims are the claims about the user disclosed in ID tokens and by the userinfo endpoint
+// depending on the scopes granted to the client.
+type UserinfoClaims struct {
+	Name              string   `json:"name,omitempty"`
+	PreferredUsername string   `json:"preferred_username,omitempty"`
+	Email             string   `json:"email,omitempty"`
+	EmailVerified     *bool    `json:"email_verified,omitempty"`
+	Groups            []string `json:"groups,omitempty"`
+}
+
+// IDTokenClaims are the claims of the ID tokens issued by the provider.
+type IDTokenClaims struct {
+	jwt.StandardClaims
+	UserinfoClaims
+
+	Nonce           string   `json:"nonce,omitempty"`
+	AMR             []string `json:"amr,omitempty"`
+	AccessTokenHash string   `json:"at_hash,omitempty"`
+}
+
+// UserinfoResponse is the response of the userinfo endpoint.
+type UserinfoResponse struct {
+	Subject string `json:"sub"`
+	UserinfoClaims
+}
+
+// TokenResponse is the response of the token endpoint.
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+// ErrorResponse is the response returned to clients when a request fails as described in RFC6749.
+type ErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// JSONWebKey is the JSON representation of a RSA public key as described in RFC7517.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+// JSONWebKeySet is the JSON representation of a set of keys as described in RFC7517.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// WellKnownConfiguration is the OpenID Connect discovery document.
+type WellKnownConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
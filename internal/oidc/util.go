@@ -0,0 +1,102 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// GenerateToken generates an opaque token suitable for authorization codes and refresh tokens.
+func GenerateToken() (string, error) {
+	b := make([]byte, tokenEntropy)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the signature under which an opaque token is persisted so that a leak of
+// the database does not leak usable tokens.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// AccessTokenHash computes the at_hash claim of an ID token signed with RS256.
+func AccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
+
+// VerifyCodeChallenge verifies the PKCE code verifier against the challenge sent in the authorization request (RFC7636).
+func VerifyCodeChallenge(challenge, method, verifier string) bool {
+	if challenge == "" {
+		return verifier == ""
+	}
+
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+
+	switch method {
+	case PKCEMethodS256:
+		sum := sha256.Sum256([]byte(verifier))
+		verifier = base64.RawURLEncoding.EncodeToString(sum[:])
+	case PKCEMethodPlain, "":
+	default:
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(challenge), []byte(verifier)) == 1
+}
+
+// IsScopeSubset returns true if all the scopes are in the allowed scopes.
+func IsScopeSubset(scopes, allowed []string) bool {
+	for _, scope := range scopes {
+		if !utils.IsStringInSlice(scope, allowed) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// AuthenticationMethodReferences returns the amr claim corresponding to an authentication level.
+func AuthenticationMethodReferences(level authentication.Level) []string {
+	if level >= authentication.TwoFactor {
+		return []string{AMRPassword, AMRMultiFactor}
+	}
+
+	return []string{AMRPassword}
+}
+
+// NewUserinfoClaims returns the claims about the user the client is allowed to know given the granted scopes.
+func NewUserinfoClaims(details *authentication.UserDetails, scopes []string) UserinfoClaims {
+	claims := UserinfoClaims{}
+
+	if utils.IsStringInSlice(ScopeProfile, scopes) {
+		claims.Name = details.DisplayName
+		claims.PreferredUsername = details.Username
+	}
+
+	if utils.IsStringInSlice(ScopeEmail, scopes) && len(details.Emails) != 0 {
+		verified := true
+		claims.Email = details.Emails[0]
+		claims.EmailVerified = &verified
+	}
+
+	if utils.IsStringInSlice(ScopeGroups, scopes) {
+		claims.Groups = details.Groups
+	}
+
+	return claims
+}
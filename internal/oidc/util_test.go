@@ -0,0 +1,107 @@
+package oidc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/authorization"
+)
+
+func TestShouldGenerateUniqueTokens(t *testing.T) {
+	token1, err := GenerateToken()
+	require.NoError(t, err)
+
+	token2, err := GenerateToken()
+	require.NoError(t, err)
+
+	assert.Len(t, token1, 43)
+	assert.NotEqual(t, token1, token2)
+	assert.Len(t, HashToken(token1), 64)
+	assert.NotEqual(t, HashToken(token1), HashToken(token2))
+}
+
+func TestShouldVerifyCodeChallenge(t *testing.T) {
+	// Example taken from RFC7636 Appendix B.
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	assert.True(t, VerifyCodeChallenge(challenge, PKCEMethodS256, verifier))
+	assert.False(t, VerifyCodeChallenge(challenge, PKCEMethodS256, verifier[1:]+"a"))
+	assert.False(t, VerifyCodeChallenge(challenge, PKCEMethodPlain, verifier))
+	assert.True(t, VerifyCodeChallenge(verifier, PKCEMethodPlain, verifier))
+	assert.True(t, VerifyCodeChallenge(verifier, "", verifier))
+	assert.False(t, VerifyCodeChallenge(verifier, "S512", verifier))
+	assert.False(t, VerifyCodeChallenge(challenge, PKCEMethodS256, ""))
+	assert.False(t, VerifyCodeChallenge("short", PKCEMethodPlain, "short"))
+	assert.True(t, VerifyCodeChallenge("", "", ""))
+	assert.False(t, VerifyCodeChallenge("", "", verifier))
+}
+
+func TestShouldComputeAccessTokenHash(t *testing.T) {
+	// Example taken from OpenID Connect Core 1.0 Appendix A.3.
+	assert.Equal(t, "77QmUPtjPfzWtF2AnpK9RQ", AccessTokenHash("jHkWEdUXMU1BwAsC4vtUsZwnNvTIxEl0z9K3vx5KF0Y"))
+}
+
+func TestShouldReturnAuthenticationMethodReferences(t *testing.T) {
+	assert.Equal(t, []string{"pwd"}, AuthenticationMethodReferences(authentication.OneFactor))
+	assert.Equal(t, []string{"pwd", "mfa"}, AuthenticationMethodReferences(authentication.TwoFactor))
+}
+
+func TestShouldFilterUserinfoClaimsByScopes(t *testing.T) {
+	details := &authentication.UserDetails{
+		Username:    "john",
+		DisplayName: "John Doe",
+		Emails:      []string{"john@example.com", "jdoe@example.com"},
+		Groups:      []string{"admins", "dev"},
+	}
+
+	claims := NewUserinfoClaims(details, []string{ScopeOpenID})
+	assert.Equal(t, UserinfoClaims{}, claims)
+
+	claims = NewUserinfoClaims(details, []string{ScopeOpenID, ScopeProfile, ScopeEmail, ScopeGroups})
+	assert.Equal(t, "John Doe", claims.Name)
+	assert.Equal(t, "john", claims.PreferredUsername)
+	assert.Equal(t, "john@example.com", claims.Email)
+	require.NotNil(t, claims.EmailVerified)
+	assert.True(t, *claims.EmailVerified)
+	assert.Equal(t, []string{"admins", "dev"}, claims.Groups)
+}
+
+func TestShouldValidateClientProperties(t *testing.T) {
+	client := Client{
+		ID:            "myapp",
+		Secret:        "secret",
+		Policy:        authorization.TwoFactor,
+		RedirectURIs:  []string{"https://app.example.com/callback"},
+		Scopes:        []string{"openid", "groups"},
+		GrantTypes:    []string{"authorization_code"},
+		ResponseTypes: []string{"code"},
+	}
+
+	assert.True(t, client.ValidateSecret("secret"))
+	assert.False(t, client.ValidateSecret("secre"))
+	assert.False(t, client.ValidateSecret(""))
+
+	assert.False(t, client.IsAuthenticationLevelSufficient(authentication.NotAuthenticated))
+	assert.False(t, client.IsAuthenticationLevelSufficient(authentication.OneFactor))
+	assert.True(t, client.IsAuthenticationLevelSufficient(authentication.TwoFactor))
+
+	assert.True(t, client.IsRedirectURIAllowed("https://app.example.com/callback"))
+	assert.False(t, client.IsRedirectURIAllowed("https://app.example.com/callback/"))
+
+	assert.True(t, client.IsScopeAllowed([]string{"openid"}))
+	assert.False(t, client.IsScopeAllowed([]string{"openid", "email"}))
+
+	assert.True(t, client.IsGrantTypeAllowed("authorization_code"))
+	assert.False(t, client.IsGrantTypeAllowed("refresh_token"))
+	assert.True(t, client.IsResponseTypeAllowed("code"))
+
+	client.Public = true
+	client.Secret = ""
+
+	assert.True(t, client.ValidateSecret(""))
+	assert.False(t, client.ValidateSecret("secret"))
+}
@@ -1,6 +1,31 @@
 package regulation
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // ErrUserIsBanned user is banned error message.
 var ErrUserIsBanned = fmt.Errorf("User is banned")
+
+// ErrTooManyEmailOTPSent is returned when too many one-time passcodes have been sent by email to a user recently.
+var ErrTooManyEmailOTPSent = fmt.Errorf("Too many email one-time passcodes have been sent")
+
+// ErrTooManyEmailOTPAttempts is returned when the latest one-time passcode sent by email to a user has been tried too
+// many times.
+var ErrTooManyEmailOTPAttempts = fmt.Errorf("Too many attempts with the email one-time passcode")
+
+// The events of the email one-time passcode log.
+const (
+	EmailOTPEventSent   = "sent"
+	EmailOTPEventFailed = "failed"
+)
+
+// EmailOTPMaxSends is the number of passcodes which can be sent by email to a user within EmailOTPSendWindow, and
+// EmailOTPMaxAttempts the number of times the latest of them can be tried. The passcodes must expire within
+// EmailOTPSendWindow for their attempts to be counted.
+const (
+	EmailOTPMaxSends    = 3
+	EmailOTPSendWindow  = 15 * time.Minute
+	EmailOTPMaxAttempts = 3
+)
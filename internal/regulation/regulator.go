@@ -50,6 +50,15 @@ func (r *Regulator) Mark(username string, successful bool) error {
 	})
 }
 
+// Unban lifts the ban of a given user by deleting the failed authentication attempts which could have led to it.
+func (r *Regulator) Unban(username string) error {
+	if !r.enabled {
+		return nil
+	}
+
+	return r.storageProvider.DeleteFailedAuthenticationLogs(username, r.clock.Now().Add(-r.banTime))
+}
+
 // Regulate regulate the authentication attempts for a given user.
 // This method returns ErrUserIsBanned if the user is banned along with the time until when
 // the user is banned.
@@ -98,3 +107,69 @@ func (r *Regulator) Regulate(username string) (time.Time, error) {
 
 	return time.Time{}, nil
 }
+
+// MarkEmailOTP records a one-time passcode sent by email to a user or a failed attempt to use it. Unlike the
+// authentication attempts, the email one-time passcodes are limited even when the regulation is disabled.
+func (r *Regulator) MarkEmailOTP(username string, event string) error {
+	return r.storageProvider.AppendEmailOTPLog(models.EmailOTPLog{
+		Username: username,
+		Event:    event,
+		Time:     r.clock.Now(),
+	})
+}
+
+// RegulateEmailOTPSend returns ErrTooManyEmailOTPSent if EmailOTPMaxSends passcodes have been sent by email to a given
+// user within EmailOTPSendWindow.
+func (r *Regulator) RegulateEmailOTPSend(username string) error {
+	logs, err := r.storageProvider.LoadLatestEmailOTPLogs(username, r.clock.Now().Add(-EmailOTPSendWindow))
+	if err != nil {
+		return err
+	}
+
+	sent := 0
+
+	for _, log := range logs {
+		if log.Event == EmailOTPEventSent {
+			sent++
+		}
+	}
+
+	if sent >= EmailOTPMaxSends {
+		return ErrTooManyEmailOTPSent
+	}
+
+	return nil
+}
+
+// RegulateEmailOTPAttempt returns ErrTooManyEmailOTPAttempts if the latest passcode sent by email to a given user has
+// been tried EmailOTPMaxAttempts times already.
+func (r *Regulator) RegulateEmailOTPAttempt(username string) error {
+	logs, err := r.storageProvider.LoadLatestEmailOTPLogs(username, r.clock.Now().Add(-EmailOTPSendWindow))
+	if err != nil {
+		return err
+	}
+
+	var sentAt time.Time
+
+	for _, log := range logs {
+		if log.Event == EmailOTPEventSent && log.Time.After(sentAt) {
+			sentAt = log.Time
+		}
+	}
+
+	// The failures logged within the same second as the latest passcode are counted against it since the log does not
+	// tell them apart.
+	failed := 0
+
+	for _, log := range logs {
+		if log.Event == EmailOTPEventFailed && !log.Time.Before(sentAt) {
+			failed++
+		}
+	}
+
+	if failed >= EmailOTPMaxAttempts {
+		return ErrTooManyEmailOTPAttempts
+	}
+
+	return nil
+}
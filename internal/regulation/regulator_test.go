@@ -257,6 +257,20 @@ func TestRunRegulatorSuite(t *testing.T) {
 }
 
 // This test checks that the regulator is disabled when configuration is set to 0.
+func (s *RegulatorSuite) TestShouldUnbanUserByDeletingFailedAttemptsWithinBanTime() {
+	s.storageMock.EXPECT().
+		DeleteFailedAuthenticationLogs(gomock.Eq("john"), gomock.Eq(s.clock.Now().Add(-180*time.Second))).
+		Return(nil)
+
+	regulator := regulation.NewRegulator(&s.configuration, s.storageMock, &s.clock)
+	assert.NoError(s.T(), regulator.Unban("john"))
+}
+
+func (s *RegulatorSuite) TestShouldNotTouchStorageWhenUnbanningWithRegulatorDisabled() {
+	regulator := regulation.NewRegulator(nil, s.storageMock, &s.clock)
+	assert.NoError(s.T(), regulator.Unban("john"))
+}
+
 func (s *RegulatorSuite) TestShouldHaveRegulatorDisabled() {
 	attemptsInDB := []models.AuthenticationAttempt{
 		{
@@ -302,3 +316,73 @@ func (s *RegulatorSuite) TestShouldHaveRegulatorDisabled() {
 	_, err = regulator.Regulate("john")
 	assert.Equal(s.T(), regulation.ErrUserIsBanned, err)
 }
+
+func (s *RegulatorSuite) TestShouldMarkEmailOTP() {
+	s.storageMock.EXPECT().
+		AppendEmailOTPLog(gomock.Eq(models.EmailOTPLog{
+			Username: "john",
+			Event:    regulation.EmailOTPEventSent,
+			Time:     s.clock.Now(),
+		})).
+		Return(nil)
+
+	regulator := regulation.NewRegulator(nil, s.storageMock, &s.clock)
+
+	err := regulator.MarkEmailOTP("john", regulation.EmailOTPEventSent)
+	s.Assert().NoError(err)
+}
+
+func (s *RegulatorSuite) TestShouldLimitEmailOTPSentEvenWhenRegulationIsDisabled() {
+	logs := []models.EmailOTPLog{
+		{Username: "john", Event: regulation.EmailOTPEventFailed, Time: s.clock.Now().Add(-1 * time.Minute)},
+		{Username: "john", Event: regulation.EmailOTPEventSent, Time: s.clock.Now().Add(-2 * time.Minute)},
+		{Username: "john", Event: regulation.EmailOTPEventSent, Time: s.clock.Now().Add(-5 * time.Minute)},
+	}
+
+	s.storageMock.EXPECT().
+		LoadLatestEmailOTPLogs(gomock.Eq("john"), gomock.Eq(s.clock.Now().Add(-regulation.EmailOTPSendWindow))).
+		Return(logs, nil)
+
+	regulator := regulation.NewRegulator(nil, s.storageMock, &s.clock)
+
+	s.Assert().NoError(regulator.RegulateEmailOTPSend("john"))
+
+	logs = append(logs, models.EmailOTPLog{Username: "john", Event: regulation.EmailOTPEventSent, Time: s.clock.Now().Add(-10 * time.Minute)})
+
+	s.storageMock.EXPECT().
+		LoadLatestEmailOTPLogs(gomock.Eq("john"), gomock.Any()).
+		Return(logs, nil)
+
+	s.Assert().Equal(regulation.ErrTooManyEmailOTPSent, regulator.RegulateEmailOTPSend("john"))
+}
+
+func (s *RegulatorSuite) TestShouldLimitAttemptsOfLatestEmailOTP() {
+	logs := []models.EmailOTPLog{
+		{Username: "john", Event: regulation.EmailOTPEventFailed, Time: s.clock.Now().Add(-1 * time.Minute)},
+		{Username: "john", Event: regulation.EmailOTPEventSent, Time: s.clock.Now().Add(-2 * time.Minute)},
+		{Username: "john", Event: regulation.EmailOTPEventFailed, Time: s.clock.Now().Add(-3 * time.Minute)},
+		{Username: "john", Event: regulation.EmailOTPEventFailed, Time: s.clock.Now().Add(-4 * time.Minute)},
+		{Username: "john", Event: regulation.EmailOTPEventFailed, Time: s.clock.Now().Add(-5 * time.Minute)},
+		{Username: "john", Event: regulation.EmailOTPEventSent, Time: s.clock.Now().Add(-6 * time.Minute)},
+	}
+
+	s.storageMock.EXPECT().
+		LoadLatestEmailOTPLogs(gomock.Eq("john"), gomock.Eq(s.clock.Now().Add(-regulation.EmailOTPSendWindow))).
+		Return(logs, nil)
+
+	regulator := regulation.NewRegulator(nil, s.storageMock, &s.clock)
+
+	// Only the failure after the latest passcode counts.
+	s.Assert().NoError(regulator.RegulateEmailOTPAttempt("john"))
+
+	logs = append([]models.EmailOTPLog{
+		{Username: "john", Event: regulation.EmailOTPEventFailed, Time: s.clock.Now()},
+		{Username: "john", Event: regulation.EmailOTPEventFailed, Time: s.clock.Now()},
+	}, logs...)
+
+	s.storageMock.EXPECT().
+		LoadLatestEmailOTPLogs(gomock.Eq("john"), gomock.Any()).
+		Return(logs, nil)
+
+	s.Assert().Equal(regulation.ErrTooManyEmailOTPAttempts, regulator.RegulateEmailOTPAttempt("john"))
+}
@@ -22,7 +22,9 @@ import (
 	"github.com/authelia/authelia/internal/duo"
 	"github.com/authelia/authelia/internal/handlers"
 	"github.com/authelia/authelia/internal/logging"
+	"github.com/authelia/authelia/internal/metrics"
 	"github.com/authelia/authelia/internal/middlewares"
+	"github.com/authelia/authelia/internal/oidc"
 )
 
 //go:embed public_html
@@ -58,6 +60,8 @@ func StartServer(configuration schema.Configuration, providers middlewares.Provi
 	r.GET("/api/health", autheliaMiddleware(handlers.HealthGet))
 	r.GET("/api/state", autheliaMiddleware(handlers.StateGet))
 
+	r.GET("/api/password-policy", autheliaMiddleware(handlers.PasswordPolicyGet))
+
 	r.GET("/api/configuration", autheliaMiddleware(
 		middlewares.RequireFirstFactor(handlers.ConfigurationGet)))
 
@@ -83,6 +87,16 @@ func StartServer(configuration schema.Configuration, providers middlewares.Provi
 		middlewares.RequireFirstFactor(handlers.UserInfoGet)))
 	r.POST("/api/user/info/2fa_method", autheliaMiddleware(
 		middlewares.RequireFirstFactor(handlers.MethodPreferencePost)))
+	r.POST("/api/user/password", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.UserPasswordPost)))
+
+	// Sessions of the user.
+	r.GET("/api/user/sessions", autheliaMiddleware(
+		middlewares.RequireTwoFactor(handlers.UserSessionsGet)))
+	r.DELETE("/api/user/sessions", autheliaMiddleware(
+		middlewares.RequireTwoFactor(handlers.UserSessionsDelete)))
+	r.DELETE("/api/user/sessions/{id}", autheliaMiddleware(
+		middlewares.RequireTwoFactor(handlers.UserSessionDelete)))
 
 	// TOTP related endpoints.
 	r.POST("/api/secondfactor/totp/identity/start", autheliaMiddleware(
@@ -95,6 +109,27 @@ func StartServer(configuration schema.Configuration, providers middlewares.Provi
 			Skew:   uint(*configuration.TOTP.Skew),
 		}))))
 
+	r.GET("/api/secondfactor/totp/devices", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorTOTPDevicesGet)))
+	r.DELETE("/api/secondfactor/totp/devices/{id}", autheliaMiddleware(
+		middlewares.RequireTwoFactor(handlers.SecondFactorTOTPDeviceDelete)))
+
+	// Email one-time passcode related endpoints, only registered when the passcodes are sent by a real mail server.
+	if configuration.Notifier != nil && configuration.Notifier.SMTP != nil {
+		r.POST("/api/secondfactor/email/start", autheliaMiddleware(
+			middlewares.RequireFirstFactor(handlers.SecondFactorEmailOTPStart)))
+		r.POST("/api/secondfactor/email", autheliaMiddleware(
+			middlewares.RequireFirstFactor(handlers.SecondFactorEmailOTPPost)))
+	}
+
+	// Recovery codes related endpoints.
+	r.POST("/api/secondfactor/recovery_codes/identity/start", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorRecoveryCodesIdentityStart)))
+	r.POST("/api/secondfactor/recovery_codes/identity/finish", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorRecoveryCodesIdentityFinish)))
+	r.POST("/api/secondfactor/recovery_code", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorRecoveryCodePost)))
+
 	// U2F related endpoints.
 	r.POST("/api/secondfactor/u2f/identity/start", autheliaMiddleware(
 		middlewares.RequireFirstFactor(handlers.SecondFactorU2FIdentityStart)))
@@ -110,6 +145,25 @@ func StartServer(configuration schema.Configuration, providers middlewares.Provi
 	r.POST("/api/secondfactor/u2f/sign", autheliaMiddleware(
 		middlewares.RequireFirstFactor(handlers.SecondFactorU2FSignPost(&handlers.U2FVerifierImpl{}))))
 
+	r.GET("/api/secondfactor/u2f/devices", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorU2FDevicesGet)))
+	r.DELETE("/api/secondfactor/u2f/devices/{id}", autheliaMiddleware(
+		middlewares.RequireTwoFactor(handlers.SecondFactorU2FDeviceDelete)))
+
+	// Webauthn related endpoints.
+	r.POST("/api/secondfactor/webauthn/identity/start", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorWebauthnIdentityStart)))
+	r.POST("/api/secondfactor/webauthn/identity/finish", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorWebauthnIdentityFinish)))
+
+	r.POST("/api/secondfactor/webauthn/attestation", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorWebauthnAttestationPost)))
+
+	r.GET("/api/secondfactor/webauthn/assertion", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorWebauthnAssertionGet)))
+	r.POST("/api/secondfactor/webauthn/assertion", autheliaMiddleware(
+		middlewares.RequireFirstFactor(handlers.SecondFactorWebauthnAssertionPost)))
+
 	// Configure DUO api endpoint only if configuration exists.
 	if configuration.DuoAPI != nil {
 		var duoAPI duo.API
@@ -125,16 +179,48 @@ func StartServer(configuration schema.Configuration, providers middlewares.Provi
 				configuration.DuoAPI.Hostname, ""))
 		}
 
+		r.GET("/api/secondfactor/duo_devices", autheliaMiddleware(
+			middlewares.RequireFirstFactor(handlers.SecondFactorDuoDevicesGet(duoAPI))))
 		r.POST("/api/secondfactor/duo", autheliaMiddleware(
 			middlewares.RequireFirstFactor(handlers.SecondFactorDuoPost(duoAPI))))
 	}
 
+	// Configure OpenID Connect endpoints only if the provider is configured.
+	if providers.OpenIDConnect != nil {
+		r.GET(oidc.WellKnownConfigurationPath, autheliaMiddleware(handlers.OpenIDConnectWellKnownConfigurationGet))
+		r.GET(oidc.JWKSPath, autheliaMiddleware(handlers.OpenIDConnectJWKsGet))
+
+		r.GET(oidc.AuthorizationPath, autheliaMiddleware(handlers.OpenIDConnectAuthorizationGet))
+
+		r.GET(oidc.ConsentPath, autheliaMiddleware(
+			middlewares.RequireFirstFactor(handlers.OpenIDConnectConsentGet)))
+		r.POST(oidc.ConsentPath, autheliaMiddleware(
+			middlewares.RequireFirstFactor(handlers.OpenIDConnectConsentPost)))
+
+		r.POST(oidc.TokenPath, autheliaMiddleware(handlers.OpenIDConnectTokenPost))
+
+		r.GET(oidc.UserinfoPath, autheliaMiddleware(handlers.OpenIDConnectUserinfo))
+		r.POST(oidc.UserinfoPath, autheliaMiddleware(handlers.OpenIDConnectUserinfo))
+	}
+
 	// If trace is set, enable pprofhandler and expvarhandler.
 	if configuration.LogLevel == "trace" {
 		r.GET("/debug/pprof/{name?}", pprofhandler.PprofHandler)
 		r.GET("/debug/vars", expvarhandler.ExpvarHandler)
 	}
 
+	if configuration.Server.Admin.Enabled {
+		if configuration.Server.Admin.Address == "" {
+			registerAdminRoutes(r, autheliaMiddleware, configuration.Server.Admin.Group)
+		} else {
+			go startAdminServer(configuration, autheliaMiddleware)
+		}
+	}
+
+	if providers.Metrics != nil && configuration.Server.Metrics.Address == "" {
+		r.GET("/metrics", providers.Metrics.Handler())
+	}
+
 	r.NotFound = serveIndexHandler
 
 	handler := middlewares.LogRequestMiddleware(r.Handler)
@@ -142,6 +228,14 @@ func StartServer(configuration schema.Configuration, providers middlewares.Provi
 		handler = middlewares.StripPathMiddleware(handler)
 	}
 
+	if providers.Metrics != nil {
+		handler = middlewares.MetricsRequestMiddleware(providers.Metrics, handler)
+
+		if configuration.Server.Metrics.Address != "" {
+			go startMetricsServer(configuration.Server.Metrics.Address, providers.Metrics)
+		}
+	}
+
 	server := &fasthttp.Server{
 		ErrorHandler:          autheliaErrorHandler,
 		Handler:               handler,
@@ -181,3 +275,56 @@ func StartServer(configuration schema.Configuration, providers middlewares.Provi
 		logger.Fatal(server.Serve(listener))
 	}
 }
+
+// startMetricsServer serves the metrics on a dedicated listener so that they are not exposed alongside the portal.
+func startMetricsServer(address string, provider metrics.Provider) {
+	logger := logging.Logger()
+
+	r := router.New()
+	r.GET("/metrics", provider.Handler())
+
+	server := &fasthttp.Server{
+		Handler:               r.Handler,
+		NoDefaultServerHeader: true,
+	}
+
+	logger.Infof("Authelia is exposing its metrics on %s/metrics", address)
+	logger.Fatal(server.ListenAndServe(address))
+}
+
+func registerAdminRoutes(r *router.Router, autheliaMiddleware func(middlewares.RequestHandler) fasthttp.RequestHandler, group string) {
+	requireAdmin := middlewares.RequireGroup(group)
+
+	r.GET("/api/admin/users/{username}/methods", autheliaMiddleware(requireAdmin(handlers.AdminUserMethodsGet)))
+	r.DELETE("/api/admin/users/{username}/totp_devices/{id}", autheliaMiddleware(requireAdmin(handlers.AdminTOTPDeviceDelete)))
+	r.DELETE("/api/admin/users/{username}/u2f_devices/{id}", autheliaMiddleware(requireAdmin(handlers.AdminU2FDeviceDelete)))
+	r.DELETE("/api/admin/users/{username}/webauthn_devices/{id}", autheliaMiddleware(requireAdmin(handlers.AdminWebauthnDeviceDelete)))
+	r.PUT("/api/admin/users/{username}/preferred_method", autheliaMiddleware(requireAdmin(handlers.AdminPreferredMethodPut)))
+	r.GET("/api/admin/users/{username}/authentication_attempts", autheliaMiddleware(requireAdmin(handlers.AdminAuthenticationAttemptsGet)))
+	r.DELETE("/api/admin/users/{username}/ban", autheliaMiddleware(requireAdmin(handlers.AdminBanDelete)))
+	r.DELETE("/api/admin/users/{username}/sessions", autheliaMiddleware(requireAdmin(handlers.AdminSessionsDelete)))
+}
+
+// startAdminServer serves the admin API on a dedicated listener so that it can be kept out of reach of the users.
+func startAdminServer(configuration schema.Configuration, autheliaMiddleware func(middlewares.RequestHandler) fasthttp.RequestHandler) {
+	logger := logging.Logger()
+
+	r := router.New()
+	registerAdminRoutes(r, autheliaMiddleware, configuration.Server.Admin.Group)
+
+	server := &fasthttp.Server{
+		ErrorHandler:          autheliaErrorHandler,
+		Handler:               middlewares.LogRequestMiddleware(r.Handler),
+		NoDefaultServerHeader: true,
+		ReadBufferSize:        configuration.Server.ReadBufferSize,
+		WriteBufferSize:       configuration.Server.WriteBufferSize,
+	}
+
+	if configuration.TLSCert != "" && configuration.TLSKey != "" {
+		logger.Infof("Authelia is listening for TLS connections to the admin API on %s", configuration.Server.Admin.Address)
+		logger.Fatal(server.ListenAndServeTLS(configuration.Server.Admin.Address, configuration.TLSCert, configuration.TLSKey))
+	} else {
+		logger.Infof("Authelia is listening for non-TLS connections to the admin API on %s", configuration.Server.Admin.Address)
+		logger.Fatal(server.ListenAndServe(configuration.Server.Admin.Address))
+	}
+}
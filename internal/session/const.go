@@ -2,6 +2,12 @@ package session
 
 const userSessionStorerKey = "UserSession"
 
+// sessionDomainUserValueKey is the user value of the request holding the index of its cookie domain.
+const sessionDomainUserValueKey = "authelia_session_domain"
+
+// sessionIndexKeyPrefix is the prefix of the Redis keys of the session index.
+const sessionIndexKeyPrefix = "authelia-session-index"
+
 const testDomain = "example.com"
 const testExpiration = "40"
 const testName = "my_session"
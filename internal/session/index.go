@@ -0,0 +1,189 @@
+package session
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Index keeps track of the sessions of each user so that they can be listed and revoked.
+type Index interface {
+	// Save records a session of a user, keeping the creation time of the session if it is already recorded.
+	Save(username string, entry IndexEntry) error
+	// List returns the sessions recorded for a user ordered by creation time.
+	List(username string) ([]IndexEntry, error)
+	// Remove removes sessions of a user from the index.
+	Remove(username string, ids ...string) error
+}
+
+// PublicID returns the identifier of the session which can be disclosed, the session ID itself being a secret.
+func (e IndexEntry) PublicID() string {
+	digest := sha256.Sum256([]byte(e.ID))
+
+	return hex.EncodeToString(digest[:16])
+}
+
+func sortIndexEntries(entries []IndexEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt < entries[j].CreatedAt
+	})
+}
+
+// MemoryIndex is a session index kept in memory, used along with the memory session provider.
+type MemoryIndex struct {
+	mutex   sync.Mutex
+	entries map[string]map[string]IndexEntry
+}
+
+// NewMemoryIndex creates a session index kept in memory.
+func NewMemoryIndex() *MemoryIndex {
+	return &MemoryIndex{entries: make(map[string]map[string]IndexEntry)}
+}
+
+// Save records a session of a user.
+func (i *MemoryIndex) Save(username string, entry IndexEntry) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	sessions, ok := i.entries[username]
+	if !ok {
+		sessions = make(map[string]IndexEntry)
+		i.entries[username] = sessions
+	}
+
+	if existing, ok := sessions[entry.ID]; ok && existing.CreatedAt != 0 {
+		entry.CreatedAt = existing.CreatedAt
+	}
+
+	sessions[entry.ID] = entry
+
+	return nil
+}
+
+// List returns the sessions recorded for a user.
+func (i *MemoryIndex) List(username string) ([]IndexEntry, error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	entries := make([]IndexEntry, 0, len(i.entries[username]))
+
+	for _, entry := range i.entries[username] {
+		entries = append(entries, entry)
+	}
+
+	sortIndexEntries(entries)
+
+	return entries, nil
+}
+
+// Remove removes sessions of a user.
+func (i *MemoryIndex) Remove(username string, ids ...string) error {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	for _, id := range ids {
+		delete(i.entries[username], id)
+	}
+
+	if len(i.entries[username]) == 0 {
+		delete(i.entries, username)
+	}
+
+	return nil
+}
+
+// RedisIndex is a session index kept in Redis, one hash per user mapping the session IDs to their details.
+type RedisIndex struct {
+	client     redis.UniversalClient
+	keyPrefix  string
+	expiration time.Duration
+}
+
+// NewRedisIndex creates a session index kept in Redis. The index of a user expires after the given duration without
+// any of their sessions being saved.
+func NewRedisIndex(client redis.UniversalClient, keyPrefix string, expiration time.Duration) *RedisIndex {
+	return &RedisIndex{
+		client:     client,
+		keyPrefix:  keyPrefix,
+		expiration: expiration,
+	}
+}
+
+func (i *RedisIndex) key(username string) string {
+	return fmt.Sprintf("%s:%s", i.keyPrefix, username)
+}
+
+// Save records a session of a user.
+func (i *RedisIndex) Save(username string, entry IndexEntry) error {
+	ctx := context.Background()
+	key := i.key(username)
+
+	existing, err := i.client.HGet(ctx, key, entry.ID).Bytes()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	if len(existing) != 0 {
+		var existingEntry IndexEntry
+
+		if err = json.Unmarshal(existing, &existingEntry); err == nil && existingEntry.CreatedAt != 0 {
+			entry.CreatedAt = existingEntry.CreatedAt
+		}
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err = i.client.HSet(ctx, key, entry.ID, data).Err(); err != nil {
+		return err
+	}
+
+	if i.expiration > 0 {
+		return i.client.Expire(ctx, key, i.expiration).Err()
+	}
+
+	return nil
+}
+
+// List returns the sessions recorded for a user.
+func (i *RedisIndex) List(username string) ([]IndexEntry, error) {
+	values, err := i.client.HGetAll(context.Background(), i.key(username)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]IndexEntry, 0, len(values))
+
+	for id, value := range values {
+		var entry IndexEntry
+
+		if err = json.Unmarshal([]byte(value), &entry); err != nil {
+			return nil, fmt.Errorf("Unable to parse index entry of session of user %s: %s", username, err)
+		}
+
+		entry.ID = id
+		entries = append(entries, entry)
+	}
+
+	sortIndexEntries(entries)
+
+	return entries, nil
+}
+
+// Remove removes sessions of a user.
+func (i *RedisIndex) Remove(username string, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return i.client.HDel(context.Background(), i.key(username), ids...).Err()
+}
@@ -0,0 +1,83 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/valyala/fasthttp"
+
+	"github.com/authelia/authelia/internal/configuration/schema"
+)
+
+func TestShouldKeepCreationTimeOfSessionsInMemoryIndex(t *testing.T) {
+	index := NewMemoryIndex()
+
+	require.NoError(t, index.Save(testUsername, IndexEntry{ID: "b", CreatedAt: 20, LastActivity: 20}))
+	require.NoError(t, index.Save(testUsername, IndexEntry{ID: "a", CreatedAt: 10, LastActivity: 10}))
+	require.NoError(t, index.Save(testUsername, IndexEntry{ID: "a", CreatedAt: 30, LastActivity: 30, RemoteIP: "10.0.0.1"}))
+
+	entries, err := index.List(testUsername)
+	require.NoError(t, err)
+
+	assert.Equal(t, []IndexEntry{
+		{ID: "a", CreatedAt: 10, LastActivity: 30, RemoteIP: "10.0.0.1"},
+		{ID: "b", CreatedAt: 20, LastActivity: 20},
+	}, entries)
+
+	require.NoError(t, index.Remove(testUsername, "a", "b"))
+
+	entries, err = index.List(testUsername)
+	require.NoError(t, err)
+	assert.Len(t, entries, 0)
+}
+
+func TestShouldNotDiscloseSessionIDInPublicID(t *testing.T) {
+	entry := IndexEntry{ID: "session-id"}
+
+	assert.Len(t, entry.PublicID(), 32)
+	assert.NotContains(t, entry.PublicID(), entry.ID)
+	assert.Equal(t, entry.PublicID(), IndexEntry{ID: "session-id"}.PublicID())
+	assert.NotEqual(t, entry.PublicID(), IndexEntry{ID: "other-session-id"}.PublicID())
+}
+
+func TestShouldListAndDestroySessionsOfUser(t *testing.T) {
+	configuration := schema.SessionConfiguration{}
+	configuration.Domain = testDomain
+	configuration.Name = testName
+	configuration.Expiration = testExpiration
+
+	provider := NewProvider(configuration, nil)
+
+	ctxs := []*fasthttp.RequestCtx{{}, {}, {}}
+
+	for i, ctx := range ctxs {
+		userSession, err := provider.GetSession(ctx)
+		require.NoError(t, err)
+
+		userSession.Username = testUsername
+
+		require.NoError(t, provider.SaveSession(ctx, userSession))
+		require.NoError(t, provider.IndexSession(ctx, testUsername, IndexEntry{LastActivity: int64(i)}))
+	}
+
+	// A destroyed session is no longer listed.
+	require.NoError(t, provider.DestroySession(ctxs[2]))
+
+	entries, err := provider.ListSessions(testUsername)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, provider.CurrentSessionID(ctxs[0]), entries[0].ID)
+	assert.Equal(t, provider.CurrentSessionID(ctxs[1]), entries[1].ID)
+
+	require.NoError(t, provider.DestroyUserSessions(testUsername, entries[1].ID))
+
+	entries, err = provider.ListSessions(testUsername)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, provider.CurrentSessionID(ctxs[0]), entries[0].ID)
+
+	userSession, err := provider.GetSession(ctxs[1])
+	require.NoError(t, err)
+	assert.Equal(t, "", userSession.Username)
+}
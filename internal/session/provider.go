@@ -3,11 +3,14 @@ package session
 import (
 	"crypto/x509"
 	"encoding/json"
+	"net"
+	"strings"
 	"time"
 
 	fasthttpsession "github.com/fasthttp/session/v2"
 	"github.com/fasthttp/session/v2/providers/memory"
 	"github.com/fasthttp/session/v2/providers/redis"
+	goredis "github.com/go-redis/redis/v8"
 	"github.com/valyala/fasthttp"
 
 	"github.com/authelia/authelia/internal/configuration/schema"
@@ -17,9 +20,19 @@ import (
 
 // Provider a session provider.
 type Provider struct {
+	domains      []providerDomain
+	sessionStore fasthttpsession.Provider
+	index        Index
+	RememberMe   time.Duration
+	Inactivity   time.Duration
+}
+
+// providerDomain is a domain protected with its own session cookie. The sessions of all the domains are kept in the
+// same store.
+type providerDomain struct {
+	name          string
+	cookieName    string
 	sessionHolder *fasthttpsession.Session
-	RememberMe    time.Duration
-	Inactivity    time.Duration
 }
 
 // NewProvider instantiate a session provider given a configuration.
@@ -27,7 +40,14 @@ func NewProvider(configuration schema.SessionConfiguration, certPool *x509.CertP
 	providerConfig := NewProviderConfig(configuration, certPool)
 
 	provider := new(Provider)
-	provider.sessionHolder = fasthttpsession.New(providerConfig.config)
+
+	for _, domainConfig := range providerConfig.domainConfigs {
+		provider.domains = append(provider.domains, providerDomain{
+			name:          domainConfig.Domain,
+			cookieName:    domainConfig.CookieName,
+			sessionHolder: fasthttpsession.New(domainConfig),
+		})
+	}
 
 	logger := logging.Logger()
 
@@ -47,35 +67,55 @@ func NewProvider(configuration schema.SessionConfiguration, certPool *x509.CertP
 
 	var providerImpl fasthttpsession.Provider
 
+	// The index of the sessions of a user must outlive the longest of their sessions.
+	indexExpiration := provider.RememberMe
+
+	for _, domainConfig := range providerConfig.domainConfigs {
+		if domainConfig.Expiration > indexExpiration {
+			indexExpiration = domainConfig.Expiration
+		}
+	}
+
 	switch {
 	case providerConfig.redisConfig != nil:
 		providerImpl, err = redis.New(*providerConfig.redisConfig)
 		if err != nil {
 			logger.Fatal(err)
 		}
+
+		provider.index = NewRedisIndex(newRedisIndexClient(providerConfig.redisConfig), sessionIndexKeyPrefix, indexExpiration)
 	case providerConfig.redisSentinelConfig != nil:
 		providerImpl, err = redis.NewFailoverCluster(*providerConfig.redisSentinelConfig)
 		if err != nil {
 			logger.Fatal(err)
 		}
+
+		provider.index = NewRedisIndex(newRedisSentinelIndexClient(providerConfig.redisSentinelConfig), sessionIndexKeyPrefix, indexExpiration)
 	default:
 		providerImpl, err = memory.New(memory.Config{})
 		if err != nil {
 			logger.Fatal(err)
 		}
+
+		provider.index = NewMemoryIndex()
 	}
 
-	err = provider.sessionHolder.SetProvider(providerImpl)
-	if err != nil {
-		logger.Fatal(err)
+	for _, domain := range provider.domains {
+		if err = domain.sessionHolder.SetProvider(providerImpl); err != nil {
+			logger.Fatal(err)
+		}
 	}
 
+	provider.sessionStore = providerImpl
+
 	return provider
 }
 
 // GetSession return the user session from a request.
 func (p *Provider) GetSession(ctx *fasthttp.RequestCtx) (UserSession, error) {
-	store, err := p.sessionHolder.Get(ctx)
+	domain := p.domain(ctx)
+
+	store, err := domain.sessionHolder.Get(ctx)
 
 	if err != nil {
 		return NewDefaultUserSession(), err
@@ -87,6 +127,7 @@ func (p *Provider) GetSession(ctx *fasthttp.RequestCtx) (UserSession, error) {
 	// and save it in the store.
 	if !ok {
 		userSession := NewDefaultUserSession()
+		userSession.Domain = domain.name
 		store.Set(userSessionStorerKey, userSession)
 
 		return userSession, nil
@@ -99,12 +140,34 @@ func (p *Provider) GetSession(ctx *fasthttp.RequestCtx) (UserSession, error) {
 		return NewDefaultUserSession(), err
 	}
 
+	// The sessions stored before they were bound to a domain belong to the first domain, the only one which could be
+	// configured then. Their domain is recorded the next time they are saved.
+	if userSession.Domain == "" {
+		userSession.Domain = p.domains[0].name
+	}
+
+	// The sessions of all the domains share the same store, a session created for another domain must not
+	// authenticate the user on this one.
+	if userSession.Domain != domain.name {
+		logging.Logger().Debugf("Session of domain '%s' used on domain '%s', treating it as anonymous", userSession.Domain, domain.name)
+
+		userSession = NewDefaultUserSession()
+		userSession.Domain = domain.name
+	}
+
 	return userSession, nil
 }
 
 // SaveSession save the user session.
 func (p *Provider) SaveSession(ctx *fasthttp.RequestCtx, userSession UserSession) error {
-	store, err := p.sessionHolder.Get(ctx)
+	domain := p.domain(ctx)
+	sessionHolder := domain.sessionHolder
+
+	if userSession.Domain == "" {
+		userSession.Domain = domain.name
+	}
+
+	store, err := sessionHolder.Get(ctx)
 
 	if err != nil {
 		return err
@@ -118,7 +181,7 @@ func (p *Provider) SaveSession(ctx *fasthttp.RequestCtx, userSession UserSession
 
 	store.Set(userSessionStorerKey, userSessionJSON)
 
-	err = p.sessionHolder.Save(ctx, store)
+	err = sessionHolder.Save(ctx, store)
 
 	if err != nil {
 		return err
@@ -129,18 +192,20 @@ func (p *Provider) SaveSession(ctx *fasthttp.RequestCtx, userSession UserSession
 
 // RegenerateSession regenerate a session ID.
 func (p *Provider) RegenerateSession(ctx *fasthttp.RequestCtx) error {
-	err := p.sessionHolder.Regenerate(ctx)
+	err := p.domain(ctx).sessionHolder.Regenerate(ctx)
 	return err
 }
 
 // DestroySession destroy a session ID and delete the cookie.
 func (p *Provider) DestroySession(ctx *fasthttp.RequestCtx) error {
-	return p.sessionHolder.Destroy(ctx)
+	return p.domain(ctx).sessionHolder.Destroy(ctx)
 }
 
 // UpdateExpiration update the expiration of the cookie and session.
 func (p *Provider) UpdateExpiration(ctx *fasthttp.RequestCtx, expiration time.Duration) error {
-	store, err := p.sessionHolder.Get(ctx)
+	sessionHolder := p.domain(ctx).sessionHolder
+
+	store, err := sessionHolder.Get(ctx)
 
 	if err != nil {
 		return err
@@ -152,12 +217,12 @@ func (p *Provider) UpdateExpiration(ctx *fasthttp.RequestCtx, expiration time.Du
 		return err
 	}
 
-	return p.sessionHolder.Save(ctx, store)
+	return sessionHolder.Save(ctx, store)
 }
 
 // GetExpiration get the expiration of the current session.
 func (p *Provider) GetExpiration(ctx *fasthttp.RequestCtx) (time.Duration, error) {
-	store, err := p.sessionHolder.Get(ctx)
+	store, err := p.domain(ctx).sessionHolder.Get(ctx)
 
 	if err != nil {
 		return time.Duration(0), err
@@ -165,3 +230,155 @@ func (p *Provider) GetExpiration(ctx *fasthttp.RequestCtx) (time.Duration, error
 
 	return store.GetExpiration(), nil
 }
+
+// SelectDomain selects the cookie domain of the session of the request. By default, the domain is the one the
+// host of the request belongs to.
+func (p *Provider) SelectDomain(ctx *fasthttp.RequestCtx, domain string) {
+	for i := range p.domains {
+		if p.domains[i].name == domain {
+			ctx.SetUserValue(sessionDomainUserValueKey, i)
+			return
+		}
+	}
+}
+
+// domain returns the cookie domain of the session of the request, the selected one or the most specific domain the
+// host of the request belongs to. The X-Forwarded-Host header set by the proxy is only used when it is a single host
+// belonging to one of the domains, the Host header is used otherwise. The first domain is used when the host belongs
+// to none of them.
+func (p *Provider) domain(ctx *fasthttp.RequestCtx) *providerDomain {
+	if i, ok := ctx.UserValue(sessionDomainUserValueKey).(int); ok {
+		return &p.domains[i]
+	}
+
+	for _, host := range [][]byte{ctx.Request.Header.Peek("X-Forwarded-Host"), ctx.Request.Header.Host()} {
+		if i, ok := p.domainOfHost(string(host)); ok {
+			return &p.domains[i]
+		}
+	}
+
+	return &p.domains[0]
+}
+
+// domainOfHost returns the index of the most specific domain a host belongs to, the host being either the domain
+// itself or one of its subdomains.
+func (p *Provider) domainOfHost(host string) (int, bool) {
+	hostname := strings.ToLower(host)
+	if h, _, err := net.SplitHostPort(hostname); err == nil {
+		hostname = h
+	}
+
+	if hostname == "" || strings.ContainsAny(hostname, ", ") {
+		return 0, false
+	}
+
+	selected, found := 0, false
+
+	for i, domain := range p.domains {
+		name := strings.ToLower(domain.name)
+
+		if hostname != name && !strings.HasSuffix(hostname, "."+name) {
+			continue
+		}
+
+		if !found || len(name) > len(p.domains[selected].name) {
+			selected, found = i, true
+		}
+	}
+
+	return selected, found
+}
+
+// IndexSession records the session of the request in the index of the sessions of the user.
+func (p *Provider) IndexSession(ctx *fasthttp.RequestCtx, username string, entry IndexEntry) error {
+	id := p.CurrentSessionID(ctx)
+	if id == "" {
+		return nil
+	}
+
+	entry.ID = id
+	entry.CreatedAt = entry.LastActivity
+
+	return p.index.Save(username, entry)
+}
+
+// CurrentSessionID returns the ID of the session of the request.
+func (p *Provider) CurrentSessionID(ctx *fasthttp.RequestCtx) string {
+	return string(ctx.Request.Header.Cookie(p.domain(ctx).cookieName))
+}
+
+// ListSessions returns the sessions of a user. The sessions which have expired or have been destroyed are removed
+// from the index.
+func (p *Provider) ListSessions(username string) ([]IndexEntry, error) {
+	entries, err := p.index.List(username)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		active []IndexEntry
+		stale  []string
+	)
+
+	for _, entry := range entries {
+		data, err := p.sessionStore.Get([]byte(entry.ID))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(data) == 0 {
+			stale = append(stale, entry.ID)
+			continue
+		}
+
+		active = append(active, entry)
+	}
+
+	if err = p.index.Remove(username, stale...); err != nil {
+		return nil, err
+	}
+
+	return active, nil
+}
+
+// DestroyUserSessions destroys sessions of a user given their IDs.
+func (p *Provider) DestroyUserSessions(username string, ids ...string) error {
+	for _, id := range ids {
+		if err := p.sessionStore.Destroy([]byte(id)); err != nil {
+			return err
+		}
+	}
+
+	return p.index.Remove(username, ids...)
+}
+
+func newRedisIndexClient(config *redis.Config) goredis.UniversalClient {
+	return goredis.NewClient(&goredis.Options{
+		Network:      config.Network,
+		Addr:         config.Addr,
+		Username:     config.Username,
+		Password:     config.Password,
+		DB:           config.DB,
+		PoolSize:     config.PoolSize,
+		MinIdleConns: config.MinIdleConns,
+		IdleTimeout:  config.IdleTimeout,
+		TLSConfig:    config.TLSConfig,
+	})
+}
+
+func newRedisSentinelIndexClient(config *redis.FailoverConfig) goredis.UniversalClient {
+	return goredis.NewFailoverClusterClient(&goredis.FailoverOptions{
+		MasterName:       config.MasterName,
+		SentinelAddrs:    config.SentinelAddrs,
+		SentinelPassword: config.SentinelPassword,
+		RouteByLatency:   config.RouteByLatency,
+		RouteRandomly:    config.RouteRandomly,
+		Username:         config.Username,
+		Password:         config.Password,
+		DB:               config.DB,
+		PoolSize:         config.PoolSize,
+		MinIdleConns:     config.MinIdleConns,
+		IdleTimeout:      config.IdleTimeout,
+		TLSConfig:        config.TLSConfig,
+	})
+}
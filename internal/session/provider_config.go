@@ -115,10 +115,48 @@ func NewProviderConfig(configuration schema.SessionConfiguration, certPool *x509
 		providerName = "memory"
 	}
 
+	domains := CookieDomains(configuration)
+	domainConfigs := make([]session.Config, len(domains))
+
+	for i, domain := range domains {
+		domainConfigs[i] = config
+		domainConfigs[i].CookieName = domain.Name
+		domainConfigs[i].Domain = domain.Domain
+
+		// Ignore the error as it will be handled by validator.
+		domainConfigs[i].Expiration, _ = utils.ParseDurationString(domain.Expiration)
+	}
+
 	return ProviderConfig{
 		config,
+		domainConfigs,
 		redisConfig,
 		redisSentinelConfig,
 		providerName,
 	}
 }
+
+// CookieDomains returns the domains protected with a session cookie, the domain of the session first. The name and
+// the expiration of the cookies default to the ones of the session.
+func CookieDomains(configuration schema.SessionConfiguration) []schema.SessionCookieConfiguration {
+	domains := []schema.SessionCookieConfiguration{{
+		Domain:     configuration.Domain,
+		Name:       configuration.Name,
+		Expiration: configuration.Expiration,
+		PortalURL:  configuration.PortalURL,
+	}}
+
+	for _, cookie := range configuration.Cookies {
+		if cookie.Name == "" {
+			cookie.Name = configuration.Name
+		}
+
+		if cookie.Expiration == "" {
+			cookie.Expiration = configuration.Expiration
+		}
+
+		domains = append(domains, cookie)
+	}
+
+	return domains
+}
@@ -22,7 +22,10 @@ func TestShouldInitializerSession(t *testing.T) {
 	session, err := provider.GetSession(ctx)
 	require.NoError(t, err)
 
-	assert.Equal(t, NewDefaultUserSession(), session)
+	expected := NewDefaultUserSession()
+	expected.Domain = testDomain
+
+	assert.Equal(t, expected, session)
 }
 
 func TestShouldUpdateSession(t *testing.T) {
@@ -46,10 +49,61 @@ func TestShouldUpdateSession(t *testing.T) {
 
 	assert.Equal(t, UserSession{
 		Username:            testUsername,
+		Domain:              testDomain,
 		AuthenticationLevel: authentication.TwoFactor,
 	}, session)
 }
 
+func TestShouldTreatSessionOfAnotherDomainAsAnonymous(t *testing.T) {
+	configuration := schema.SessionConfiguration{}
+	configuration.Domain = testDomain
+	configuration.Name = testName
+	configuration.Expiration = testExpiration
+	configuration.Cookies = []schema.SessionCookieConfiguration{{Domain: "example.org", Name: "my_session_org"}}
+
+	provider := NewProvider(configuration, nil)
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.Set("X-Forwarded-Host", "login.example.com")
+
+	session, err := provider.GetSession(ctx)
+	require.NoError(t, err)
+
+	session.Username = testUsername
+	session.AuthenticationLevel = authentication.TwoFactor
+	require.NoError(t, provider.SaveSession(ctx, session))
+
+	cookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(cookie)
+
+	cookie.SetKey(testName)
+	require.True(t, ctx.Response.Header.Cookie(cookie))
+
+	id := string(cookie.Value())
+
+	// The session of example.com is presented with the cookie of example.org.
+	other := &fasthttp.RequestCtx{}
+	other.Request.Header.Set("X-Forwarded-Host", "login.example.org")
+	other.Request.Header.SetCookie("my_session_org", id)
+
+	session, err = provider.GetSession(other)
+	require.NoError(t, err)
+
+	assert.Equal(t, "", session.Username)
+	assert.Equal(t, authentication.NotAuthenticated, session.AuthenticationLevel)
+	assert.Equal(t, "example.org", session.Domain)
+
+	// The same session is still valid on its own domain.
+	same := &fasthttp.RequestCtx{}
+	same.Request.Header.Set("X-Forwarded-Host", "login.example.com")
+	same.Request.Header.SetCookie(testName, id)
+
+	session, err = provider.GetSession(same)
+	require.NoError(t, err)
+
+	assert.Equal(t, testUsername, session.Username)
+}
+
 func TestShouldDestroySessionAndWipeSessionData(t *testing.T) {
 	ctx := &fasthttp.RequestCtx{}
 	configuration := schema.SessionConfiguration{}
@@ -80,3 +134,150 @@ func TestShouldDestroySessionAndWipeSessionData(t *testing.T) {
 	assert.Equal(t, "", newUserSession.Username)
 	assert.Equal(t, authentication.NotAuthenticated, newUserSession.AuthenticationLevel)
 }
+
+func TestShouldUseCookieOfDomainOfRequest(t *testing.T) {
+	configuration := schema.SessionConfiguration{}
+	configuration.Domain = testDomain
+	configuration.Name = testName
+	configuration.Expiration = testExpiration
+	configuration.Cookies = []schema.SessionCookieConfiguration{{Domain: "example.org", Name: "my_session_org"}}
+
+	provider := NewProvider(configuration, nil)
+
+	testCases := []struct {
+		host, cookieName, cookieDomain string
+	}{
+		{"login.example.com", testName, testDomain},
+		{"login.example.org", "my_session_org", "example.org"},
+		{"login.example.net", testName, testDomain},
+		{"example.org:8080", "my_session_org", "example.org"},
+		{"LOGIN.Example.ORG", "my_session_org", "example.org"},
+		// The domain must match on a label boundary.
+		{"login.evilexample.org", testName, testDomain},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.host, func(t *testing.T) {
+			ctx := &fasthttp.RequestCtx{}
+			ctx.Request.Header.Set("X-Forwarded-Host", tc.host)
+
+			session, err := provider.GetSession(ctx)
+			require.NoError(t, err)
+
+			session.Username = testUsername
+			require.NoError(t, provider.SaveSession(ctx, session))
+
+			cookie := fasthttp.AcquireCookie()
+			defer fasthttp.ReleaseCookie(cookie)
+
+			cookie.SetKey(tc.cookieName)
+			require.True(t, ctx.Response.Header.Cookie(cookie))
+			assert.Equal(t, tc.cookieDomain, string(cookie.Domain()))
+		})
+	}
+}
+
+func TestShouldUseHostWhenForwardedHostIsNotInDomains(t *testing.T) {
+	configuration := schema.SessionConfiguration{}
+	configuration.Domain = testDomain
+	configuration.Name = testName
+	configuration.Expiration = testExpiration
+	configuration.Cookies = []schema.SessionCookieConfiguration{{Domain: "example.org", Name: "my_session_org"}}
+
+	provider := NewProvider(configuration, nil)
+
+	testCases := []struct {
+		name, forwardedHost, host, expected string
+	}{
+		{"ShouldUseForwardedHost", "login.example.org", "authelia:9091", "example.org"},
+		{"ShouldIgnoreUnknownForwardedHost", "login.evilexample.com", "login.example.org", "example.org"},
+		{"ShouldIgnoreSeveralForwardedHosts", "login.example.com, login.example.org", "login.example.org", "example.org"},
+		{"ShouldFallbackToFirstDomain", "attacker.net", "authelia:9091", testDomain},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := &fasthttp.RequestCtx{}
+			ctx.Request.Header.Set("X-Forwarded-Host", tc.forwardedHost)
+			ctx.Request.Header.SetHost(tc.host)
+
+			assert.Equal(t, tc.expected, provider.domain(ctx).name)
+		})
+	}
+}
+
+func TestShouldBindSessionWithoutDomainToFirstDomain(t *testing.T) {
+	configuration := schema.SessionConfiguration{}
+	configuration.Domain = testDomain
+	configuration.Name = testName
+	configuration.Expiration = testExpiration
+	configuration.Cookies = []schema.SessionCookieConfiguration{{Domain: "example.org", Name: "my_session_org"}}
+
+	provider := NewProvider(configuration, nil)
+
+	// A session stored before the sessions were bound to a domain.
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.Set("X-Forwarded-Host", "login.example.com")
+
+	domain := provider.domain(ctx)
+	store, err := domain.sessionHolder.Get(ctx)
+	require.NoError(t, err)
+
+	store.Set(userSessionStorerKey, []byte(`{"Username":"john","AuthenticationLevel":2}`))
+	require.NoError(t, domain.sessionHolder.Save(ctx, store))
+
+	cookie := fasthttp.AcquireCookie()
+	defer fasthttp.ReleaseCookie(cookie)
+
+	cookie.SetKey(testName)
+	require.True(t, ctx.Response.Header.Cookie(cookie))
+
+	id := string(cookie.Value())
+
+	legacy := &fasthttp.RequestCtx{}
+	legacy.Request.Header.Set("X-Forwarded-Host", "login.example.com")
+	legacy.Request.Header.SetCookie(testName, id)
+
+	session, err := provider.GetSession(legacy)
+	require.NoError(t, err)
+
+	assert.Equal(t, testUsername, session.Username)
+	assert.Equal(t, authentication.TwoFactor, session.AuthenticationLevel)
+	assert.Equal(t, testDomain, session.Domain)
+
+	// The domain is recorded when the session is saved.
+	require.NoError(t, provider.SaveSession(legacy, session))
+
+	store, err = domain.sessionHolder.Get(legacy)
+	require.NoError(t, err)
+	assert.Contains(t, string(store.Get(userSessionStorerKey).([]byte)), `"Domain":"example.com"`)
+
+	// The session is not valid on the other domains.
+	other := &fasthttp.RequestCtx{}
+	other.Request.Header.Set("X-Forwarded-Host", "login.example.org")
+	other.Request.Header.SetCookie("my_session_org", id)
+
+	session, err = provider.GetSession(other)
+	require.NoError(t, err)
+
+	assert.Equal(t, "", session.Username)
+	assert.Equal(t, authentication.NotAuthenticated, session.AuthenticationLevel)
+}
+
+func TestShouldUseCookieOfSelectedDomain(t *testing.T) {
+	configuration := schema.SessionConfiguration{}
+	configuration.Domain = testDomain
+	configuration.Name = testName
+	configuration.Expiration = testExpiration
+	configuration.Cookies = []schema.SessionCookieConfiguration{{Domain: "example.org", Name: "my_session_org"}}
+
+	provider := NewProvider(configuration, nil)
+
+	ctx := &fasthttp.RequestCtx{}
+	ctx.Request.Header.Set("X-Forwarded-Host", "login.example.com")
+	ctx.Request.Header.SetCookie("my_session_org", "abc")
+
+	provider.SelectDomain(ctx, "example.org")
+
+	assert.Equal(t, "abc", provider.CurrentSessionID(ctx))
+}
@@ -3,6 +3,7 @@ package session
 import (
 	"time"
 
+	"github.com/duo-labs/webauthn/webauthn"
 	"github.com/fasthttp/session/v2"
 	"github.com/fasthttp/session/v2/providers/redis"
 	"github.com/tstranex/u2f"
@@ -13,6 +14,7 @@ import (
 // ProviderConfig is the configuration used to create the session provider.
 type ProviderConfig struct {
 	config              session.Config
+	domainConfigs       []session.Config
 	redisConfig         *redis.Config
 	redisSentinelConfig *redis.FailoverConfig
 	providerName        string
@@ -20,8 +22,10 @@ type ProviderConfig struct {
 
 // U2FRegistration is a serializable version of a U2F registration.
 type U2FRegistration struct {
-	KeyHandle []byte
-	PublicKey []byte
+	Description string
+	KeyHandle   []byte
+	PublicKey   []byte
+	Counter     uint32
 }
 
 // UserSession is the structure representing the session of a user.
@@ -32,26 +36,68 @@ type UserSession struct {
 	Groups []string
 	Emails []string
 
+	// The cookie domain the session has been created for, a session presented with the cookie of another domain is
+	// considered anonymous.
+	Domain string
+
 	KeepMeLoggedIn      bool
 	AuthenticationLevel authentication.Level
 	LastActivity        int64
 
+	// The time the user completed the second factor, sensitive operations such as changing the password require it
+	// to be recent.
+	SecondFactorAt int64
+
 	// The challenge generated in first step of U2F registration (after identity verification) or authentication.
 	// This is used reused in the second phase to check that the challenge has been completed.
 	U2FChallenge *u2f.Challenge
-	// The registration representing a U2F device in DB set after identity verification.
+	// The registrations representing the U2F devices of the user in DB.
 	// This is used in second phase of a U2F authentication.
-	U2FRegistration *U2FRegistration
+	U2FRegistrations []U2FRegistration
+
+	// The session data generated at the beginning of a Webauthn registration (after identity verification) or
+	// assertion ceremony and checked when the ceremony completes.
+	Webauthn *webauthn.SessionData
+
+	// The one-time passcode sent by email, checked when the user types it.
+	EmailOTP *EmailOTPChallenge
 
 	// This boolean is set to true after identity verification and checked
 	// while doing the query actually updating the password.
 	PasswordResetUsername *string
 
+	// The OpenID Connect authorization request waiting for the consent of the user.
+	OIDCWorkflowSession *OIDCWorkflowSession
+
 	RefreshTTL time.Time
 }
 
+// EmailOTPChallenge is the one-time passcode sent to a user by email. Only the digest of the passcode is kept.
+type EmailOTPChallenge struct {
+	Digest    string
+	ExpiresAt int64
+}
+
+// OIDCWorkflowSession is the OpenID Connect authorization request kept in session while the user gives consent.
+type OIDCWorkflowSession struct {
+	ClientID                   string
+	RequestedScopes            []string
+	AuthURI                    string
+	RequiredAuthorizationLevel authentication.Level
+	CreatedTimestamp           int64
+}
+
 // Identity identity of the user who is being verified.
 type Identity struct {
 	Username string
 	Email    string
 }
+
+// IndexEntry is a session of a user recorded in the session index.
+type IndexEntry struct {
+	ID           string `json:"id"`
+	CreatedAt    int64  `json:"created_at"`
+	LastActivity int64  `json:"last_activity"`
+	RemoteIP     string `json:"remote_ip"`
+	UserAgent    string `json:"user_agent"`
+}
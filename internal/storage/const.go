@@ -1,12 +1,21 @@
 package storage
 
-import (
-	"fmt"
+const storageSchemaCurrentVersion = SchemaVersion(12)
+
+// SchemaLatestVersion is the version of the schema the providers upgrade the database to.
+const SchemaLatestVersion = storageSchemaCurrentVersion
+
+// The names of the providers, the statements of the migrations are given for each of them.
+const (
+	providerSQLite   = "sqlite"
+	providerMySQL    = "mysql"
+	providerPostgres = "postgres"
 )
 
-const storageSchemaCurrentVersion = SchemaVersion(1)
-const storageSchemaUpgradeMessage = "Storage schema upgraded to v"
-const storageSchemaUpgradeErrorText = "storage schema upgrade failed at v"
+// storageSchemaMigrationLockName is the name of the lock taken while the schema is migrated and
+// storageSchemaMigrationLockTimeout the number of seconds to wait for it when the database supports it.
+const storageSchemaMigrationLockName = "authelia_schema_migration"
+const storageSchemaMigrationLockTimeout = 60
 
 // Keep table names in lower case because some DB does not support upper case.
 const userPreferencesTableName = "user_preferences"
@@ -15,25 +24,25 @@ const totpSecretsTableName = "totp_secrets"
 const u2fDeviceHandlesTableName = "u2f_devices"
 const authenticationLogsTableName = "authentication_logs"
 const configTableName = "config"
+const oauth2AuthorizationCodesTableName = "oauth2_authorization_codes"
+const oauth2RefreshTokensTableName = "oauth2_refresh_tokens"
+const oauth2ConsentTableName = "oauth2_consent"
+const webauthnDevicesTableName = "webauthn_devices"
+const recoveryCodesTableName = "recovery_codes"
+const emailOTPLogsTableName = "email_otp_logs"
+
+// u2fDevicesUpgradeDescription is the name given to the U2F devices registered before devices could be named.
+const u2fDevicesUpgradeDescription = "Primary"
+
+// totpSecretsUpgradeID and totpSecretsUpgradeDescription are the ID and the name given to the TOTP devices
+// registered before a user could register several of them.
+const totpSecretsUpgradeID = "primary"
+const totpSecretsUpgradeDescription = "Primary"
 
-// sqlUpgradeCreateTableStatements is a map of the schema version number, plus a map of the table name and the statement used to create it.
-// The statement is fmt.Sprintf'd with the table name as the first argument.
-var sqlUpgradeCreateTableStatements = map[SchemaVersion]map[string]string{
-	SchemaVersion(1): {
-		userPreferencesTableName:            "CREATE TABLE %s (username VARCHAR(100) PRIMARY KEY, second_factor_method VARCHAR(11))",
-		identityVerificationTokensTableName: "CREATE TABLE %s (token VARCHAR(512))",
-		totpSecretsTableName:                "CREATE TABLE %s (username VARCHAR(100) PRIMARY KEY, secret VARCHAR(64))",
-		u2fDeviceHandlesTableName:           "CREATE TABLE %s (username VARCHAR(100) PRIMARY KEY, keyHandle TEXT, publicKey TEXT)",
-		authenticationLogsTableName:         "CREATE TABLE %s (username VARCHAR(100), successful BOOL, time INTEGER)",
-		configTableName:                     "CREATE TABLE %s (category VARCHAR(32) NOT NULL, key_name VARCHAR(32) NOT NULL, value TEXT, PRIMARY KEY (category, key_name))",
-	},
-}
-
-// sqlUpgradesCreateTableIndexesStatements is a map of t he schema version number, plus a slice of statements to create all of the indexes.
-var sqlUpgradesCreateTableIndexesStatements = map[SchemaVersion][]string{
-	SchemaVersion(1): {
-		fmt.Sprintf("CREATE INDEX IF NOT EXISTS usr_time_idx ON %s (username, time)", authenticationLogsTableName),
-	},
-}
+// encryptionCheckCategory and encryptionCheckKeyName identify the value of the config table encrypted with the
+// encryption key to check it.
+const encryptionCheckCategory = "encryption"
+const encryptionCheckKeyName = "check"
 
 const unitTestUser = "john"
+const unitTestEncryptionKey = "a_not_so_secure_encryption_key"
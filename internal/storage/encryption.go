@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/authelia/authelia/internal/utils"
+)
+
+// encryptedColumn describes a column encrypted with the encryption key and the statements to re-encrypt its rows. The
+// rows are identified by the username and a second identifier. The encode and decode functions convert the plaintext
+// to and from the representation the column had before being encrypted.
+type encryptedColumn struct {
+	table  string
+	query  string
+	update string
+
+	encode func(plaintext []byte) string
+	decode func(value string) ([]byte, error)
+}
+
+func newEncryptionKey(key string) [32]byte {
+	return sha256.Sum256([]byte(key))
+}
+
+// encryptedColumns returns the columns encrypted with the encryption key.
+func (p *SQLProvider) encryptedColumns() []encryptedColumn {
+	return []encryptedColumn{
+		{totpSecretsTableName, p.sqlGetTOTPSecrets, p.sqlUpdateTOTPSecret, encodePlaintext, decodePlaintext},
+		{u2fDeviceHandlesTableName, p.sqlGetU2FPublicKeys, p.sqlUpdateU2FPublicKey, base64.StdEncoding.EncodeToString, base64.StdEncoding.DecodeString},
+		{webauthnDevicesTableName, p.sqlGetWebauthnPublicKeys, p.sqlUpdateWebauthnPublicKey, base64.StdEncoding.EncodeToString, base64.StdEncoding.DecodeString},
+	}
+}
+
+func encodePlaintext(plaintext []byte) string {
+	return string(plaintext)
+}
+
+func decodePlaintext(value string) ([]byte, error) {
+	return []byte(value), nil
+}
+
+func encryptWithKey(plaintext []byte, key *[32]byte) (string, error) {
+	ciphertext, err := utils.Encrypt(plaintext, key)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+func decryptWithKey(value string, key *[32]byte) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.Decrypt(ciphertext, key)
+}
+
+func (p *SQLProvider) encrypt(plaintext []byte) (string, error) {
+	return encryptWithKey(plaintext, &p.key)
+}
+
+func (p *SQLProvider) decrypt(value string) ([]byte, error) {
+	plaintext, err := decryptWithKey(value, &p.key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrEncryptionKeyMismatch, err)
+	}
+
+	return plaintext, nil
+}
+
+// updateColumn replaces the values of an encrypted column by the result of the convert function.
+func (p *SQLProvider) updateColumn(tx transaction, column encryptedColumn, convert func(value string) (string, error)) error {
+	rows, err := tx.Query(column.query)
+	if err != nil {
+		return err
+	}
+
+	var values [][3]string
+
+	for rows.Next() {
+		var value [3]string
+
+		if err := rows.Scan(&value[0], &value[1], &value[2]); err != nil {
+			_ = rows.Close()
+			return err
+		}
+
+		values = append(values, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return err
+	}
+
+	// The rows are closed before being updated since some drivers do not support several statements at once.
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	for _, value := range values {
+		converted, err := convert(value[2])
+		if err != nil {
+			return fmt.Errorf("Unable to convert a row of table %s: %w", column.table, err)
+		}
+
+		if _, err := tx.Exec(column.update, converted, value[0], value[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encryptColumn encrypts the values of a column which were not encrypted yet.
+func (p *SQLProvider) encryptColumn(tx transaction, column encryptedColumn) error {
+	return p.updateColumn(tx, column, func(value string) (string, error) {
+		plaintext, err := column.decode(value)
+		if err != nil {
+			return "", err
+		}
+
+		return p.encrypt(plaintext)
+	})
+}
+
+// decryptColumn restores the values of a column to their representation before being encrypted.
+func (p *SQLProvider) decryptColumn(tx transaction, column encryptedColumn) error {
+	return p.updateColumn(tx, column, func(value string) (string, error) {
+		plaintext, err := p.decrypt(value)
+		if err != nil {
+			return "", err
+		}
+
+		return column.encode(plaintext), nil
+	})
+}
+
+// reencryptColumn re-encrypts the values of a column with another key.
+func (p *SQLProvider) reencryptColumn(tx transaction, column encryptedColumn, key *[32]byte) error {
+	return p.updateColumn(tx, column, func(value string) (string, error) {
+		plaintext, err := p.decrypt(value)
+		if err != nil {
+			return "", err
+		}
+
+		return encryptWithKey(plaintext, key)
+	})
+}
+
+// saveEncryptionCheck saves a value encrypted with the key so that a wrong key can be detected before any data is
+// decrypted with it.
+func (p *SQLProvider) saveEncryptionCheck(tx transaction, key *[32]byte) error {
+	value, err := encryptWithKey([]byte(utils.RandomString(32, utils.AlphaNumericCharacters)), key)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(p.sqlConfigSetValue, encryptionCheckCategory, encryptionCheckKeyName, value)
+
+	return err
+}
+
+// CheckEncryptionKey checks that the data of the database has been encrypted with the encryption key of the provider.
+func (p *SQLProvider) CheckEncryptionKey() error {
+	var value string
+
+	err := p.db.QueryRow(p.sqlConfigGetValue, encryptionCheckCategory, encryptionCheckKeyName).Scan(&value)
+
+	switch {
+	case err == sql.ErrNoRows:
+		return ErrNoEncryptionCheck
+	case err != nil:
+		return err
+	}
+
+	_, err = p.decrypt(value)
+
+	return err
+}
+
+// ChangeEncryptionKey re-encrypts the data of the database with a new encryption key. The data is re-encrypted in a
+// single transaction so that it is never encrypted with several keys.
+func (p *SQLProvider) ChangeEncryptionKey(newKey string) error {
+	if err := p.CheckEncryptionKey(); err != nil {
+		return err
+	}
+
+	key := newEncryptionKey(newKey)
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, column := range p.encryptedColumns() {
+		if err := p.reencryptColumn(tx, column, &key); err != nil {
+			return p.rollback(tx, err)
+		}
+	}
+
+	if err := p.saveEncryptionCheck(tx, &key); err != nil {
+		return p.rollback(tx, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	p.key = key
+
+	return nil
+}
@@ -6,6 +6,43 @@ var (
 	// ErrNoU2FDeviceHandle error thrown when no U2F device handle has been found in DB.
 	ErrNoU2FDeviceHandle = errors.New("No U2F device handle found")
 
+	// ErrNoWebauthnDevice error thrown when no Webauthn device has been found in DB.
+	ErrNoWebauthnDevice = errors.New("No Webauthn device found")
+
 	// ErrNoTOTPSecret error thrown when no TOTP secret has been found in DB.
 	ErrNoTOTPSecret = errors.New("No TOTP secret registered")
+
+	// ErrTOTPStepAlreadyUsed error thrown when a TOTP passcode of a time step already used is recorded.
+	ErrTOTPStepAlreadyUsed = errors.New("TOTP time step has already been used")
+
+	// ErrSignCountNotIncreasing error thrown when the signature counter of a security key recorded after a sign in is
+	// not greater than the stored one, the key might have been cloned.
+	ErrSignCountNotIncreasing = errors.New("The signature counter of the device did not increase")
+
+	// ErrNoRecoveryCode error thrown when no recovery code has been found in DB.
+	ErrNoRecoveryCode = errors.New("No recovery code found")
+
+	// ErrNoOAuth2AuthorizationCode error thrown when no OAuth 2.0 authorization code has been found in DB.
+	ErrNoOAuth2AuthorizationCode = errors.New("No OAuth 2.0 authorization code found")
+
+	// ErrNoOAuth2RefreshToken error thrown when no OAuth 2.0 refresh token has been found in DB.
+	ErrNoOAuth2RefreshToken = errors.New("No OAuth 2.0 refresh token found")
+
+	// ErrEncryptionKeyMismatch error thrown when data of the DB cannot be decrypted with the encryption key.
+	ErrEncryptionKeyMismatch = errors.New("The encryption key does not match the one the data has been encrypted with")
+
+	// ErrNoEncryptionCheck error thrown when the DB has no value to check the encryption key against.
+	ErrNoEncryptionCheck = errors.New("No encryption check value found, the schema must be upgraded")
+
+	// ErrSchemaNewerThanSupported error thrown when the schema of the DB is newer than the latest one known.
+	ErrSchemaNewerThanSupported = errors.New("The schema of the database is newer than the one supported by this version of Authelia")
+
+	// ErrUnknownSchemaVersion error thrown when migrating the schema to a version which does not exist.
+	ErrUnknownSchemaVersion = errors.New("Unknown schema version")
+
+	// ErrSchemaMigrationLocked error thrown when the lock of the schema migrations cannot be acquired.
+	ErrSchemaMigrationLocked = errors.New("Unable to acquire the lock of the schema migrations, another instance may be migrating the schema")
+
+	// ErrNoOAuth2Consent error thrown when no OAuth 2.0 consent has been found in DB.
+	ErrNoOAuth2Consent = errors.New("No OAuth 2.0 consent found")
 )
@@ -0,0 +1,279 @@
+package storage
+
+import (
+	"fmt"
+)
+
+// migrationStatements is a map of the name of a provider and the statements of a migration in its dialect. The
+// statements of the empty name are used by the providers which have no statements of their own.
+type migrationStatements map[string][]string
+
+func (s migrationStatements) forProvider(name string) []string {
+	if statements, ok := s[name]; ok {
+		return statements
+	}
+
+	return s[""]
+}
+
+// migration describes how to upgrade the schema from the previous version to its version and how to downgrade it
+// back. The data functions migrate what cannot be migrated with plain statements, upData runs after the up
+// statements and downData before the down statements.
+type migration struct {
+	version SchemaVersion
+	name    string
+
+	up   migrationStatements
+	down migrationStatements
+
+	// data describes what the data functions do, it is shown instead of their statements.
+	data     string
+	upData   func(p *SQLProvider, tx transaction) error
+	downData func(p *SQLProvider, tx transaction) error
+}
+
+// recreateTable returns the statements recreating a table with a new create statement. The rows are copied with the
+// insert statement, fmt.Sprintf'd with the temporary and the original table names, before the original table is
+// dropped and the temporary one takes its name.
+func recreateTable(table, createStatement, insertStatement string) []string {
+	tmpTable := table + "_tmp"
+
+	return []string{
+		fmt.Sprintf(createStatement, tmpTable),
+		fmt.Sprintf(insertStatement, tmpTable, table),
+		fmt.Sprintf("DROP TABLE %s", table),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", tmpTable, table),
+	}
+}
+
+func dropTables(tables ...string) []string {
+	statements := make([]string, 0, len(tables))
+
+	for _, table := range tables {
+		statements = append(statements, fmt.Sprintf("DROP TABLE %s", table))
+	}
+
+	return statements
+}
+
+// The statements creating the tables recreated by the migrations, fmt.Sprintf'd with the table name.
+const (
+	sqlCreateU2FDevicesTableV1 = "CREATE TABLE %s (username VARCHAR(100) PRIMARY KEY, keyHandle TEXT, publicKey TEXT)"
+	sqlCreateU2FDevicesTableV4 = "CREATE TABLE %s (username VARCHAR(100) NOT NULL, description VARCHAR(64) NOT NULL, key_handle VARCHAR(512) NOT NULL, public_key TEXT NOT NULL, created_at INTEGER, last_used_at INTEGER, PRIMARY KEY (username, key_handle))"
+
+	sqlCreateTOTPSecretsTableV1  = "CREATE TABLE %s (username VARCHAR(100) PRIMARY KEY, secret VARCHAR(64))"
+	sqlCreateTOTPSecretsTableV6  = "CREATE TABLE %s (username VARCHAR(100) NOT NULL, id VARCHAR(32) NOT NULL, description VARCHAR(64) NOT NULL, secret VARCHAR(64) NOT NULL, created_at INTEGER, last_used_at INTEGER, PRIMARY KEY (username, id))"
+	sqlCreateTOTPSecretsTableV7  = "CREATE TABLE %s (username VARCHAR(100) NOT NULL, id VARCHAR(32) NOT NULL, description VARCHAR(64) NOT NULL, secret VARCHAR(64) NOT NULL, algorithm VARCHAR(6) NOT NULL DEFAULT 'SHA1', digits INTEGER NOT NULL DEFAULT 6, period INTEGER NOT NULL DEFAULT 0, created_at INTEGER, last_used_at INTEGER, PRIMARY KEY (username, id))"
+	sqlCreateTOTPSecretsTableV8  = "CREATE TABLE %s (username VARCHAR(100) NOT NULL, id VARCHAR(32) NOT NULL, description VARCHAR(64) NOT NULL, secret VARCHAR(64) NOT NULL, algorithm VARCHAR(6) NOT NULL DEFAULT 'SHA1', digits INTEGER NOT NULL DEFAULT 6, period INTEGER NOT NULL DEFAULT 0, last_step BIGINT NOT NULL DEFAULT 0, created_at INTEGER, last_used_at INTEGER, PRIMARY KEY (username, id))"
+	sqlCreateTOTPSecretsTableV10 = "CREATE TABLE %s (username VARCHAR(100) NOT NULL, id VARCHAR(32) NOT NULL, description VARCHAR(64) NOT NULL, secret VARCHAR(256) NOT NULL, algorithm VARCHAR(6) NOT NULL DEFAULT 'SHA1', digits INTEGER NOT NULL DEFAULT 6, period INTEGER NOT NULL DEFAULT 0, last_step BIGINT NOT NULL DEFAULT 0, created_at INTEGER, last_used_at INTEGER, PRIMARY KEY (username, id))"
+
+	sqlCreateRecoveryCodesTableV9 = "CREATE TABLE %s (username VARCHAR(100) NOT NULL, hash VARCHAR(256) NOT NULL, created_at INTEGER, PRIMARY KEY (username, hash))"
+)
+
+// The columns of the tables recreated by the migrations.
+const (
+	u2fDevicesColumnsV4    = "username, description, key_handle, public_key, created_at, last_used_at"
+	totpSecretsColumnsV6   = "username, id, description, secret, created_at, last_used_at"
+	totpSecretsColumnsV7   = "username, id, description, secret, algorithm, digits, period, created_at, last_used_at"
+	totpSecretsColumnsV8   = "username, id, description, secret, algorithm, digits, period, last_step, created_at, last_used_at"
+	recoveryCodesColumnsV9 = "username, hash, created_at"
+	copyColumnsStatement   = "INSERT INTO %%s (%[1]s) SELECT %[1]s FROM %%s"
+)
+
+const authenticationLogsIndexName = "usr_time_idx"
+const emailOTPLogsIndexName = "email_otp_usr_time_idx"
+
+func copyColumns(columns string) string {
+	return fmt.Sprintf(copyColumnsStatement, columns)
+}
+
+// alterStatements returns the statements altering a table for MySQL and PostgreSQL, and the statements recreating it
+// for the other providers since SQLite cannot drop columns.
+func alterStatements(alter, recreate []string) migrationStatements {
+	return migrationStatements{
+		"":               recreate,
+		providerMySQL:    alter,
+		providerPostgres: alter,
+	}
+}
+
+// migrations is the ordered list of the migrations of the schema, the migration at index i upgrades the schema to
+// version i+1.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "Create the initial tables",
+		// The tables may have been created before the schema was versioned.
+		up: migrationStatements{"": {
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (username VARCHAR(100), successful BOOL, time INTEGER)", authenticationLogsTableName),
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (category VARCHAR(32) NOT NULL, key_name VARCHAR(32) NOT NULL, value TEXT, PRIMARY KEY (category, key_name))", configTableName),
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (token VARCHAR(512))", identityVerificationTokensTableName),
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (username VARCHAR(100) PRIMARY KEY, secret VARCHAR(64))", totpSecretsTableName),
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (username VARCHAR(100) PRIMARY KEY, keyHandle TEXT, publicKey TEXT)", u2fDeviceHandlesTableName),
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (username VARCHAR(100) PRIMARY KEY, second_factor_method VARCHAR(11))", userPreferencesTableName),
+		}},
+		down: migrationStatements{"": dropTables(userPreferencesTableName, u2fDeviceHandlesTableName, totpSecretsTableName,
+			identityVerificationTokensTableName, configTableName, authenticationLogsTableName)},
+		data: fmt.Sprintf("Create the index %s of table %s if it does not exist", authenticationLogsIndexName, authenticationLogsTableName),
+		upData: func(p *SQLProvider, tx transaction) error {
+			return p.createIndexIfNotExists(tx, authenticationLogsTableName, authenticationLogsIndexName, "username, time")
+		},
+	},
+	{
+		version: 2,
+		name:    "Create the OpenID Connect tables",
+		up: migrationStatements{"": {
+			fmt.Sprintf("CREATE TABLE %s (signature VARCHAR(64) PRIMARY KEY, client_id VARCHAR(100) NOT NULL, username VARCHAR(100) NOT NULL, scopes TEXT, redirect_uri TEXT, nonce TEXT, code_challenge VARCHAR(128), code_challenge_method VARCHAR(8), amr VARCHAR(32), expires_at INTEGER)", oauth2AuthorizationCodesTableName),
+			fmt.Sprintf("CREATE TABLE %s (username VARCHAR(100) NOT NULL, client_id VARCHAR(100) NOT NULL, scopes TEXT, granted_at INTEGER, PRIMARY KEY (username, client_id))", oauth2ConsentTableName),
+			fmt.Sprintf("CREATE TABLE %s (signature VARCHAR(64) PRIMARY KEY, client_id VARCHAR(100) NOT NULL, username VARCHAR(100) NOT NULL, scopes TEXT, amr VARCHAR(32), expires_at INTEGER)", oauth2RefreshTokensTableName),
+		}},
+		down: migrationStatements{"": dropTables(oauth2RefreshTokensTableName, oauth2ConsentTableName, oauth2AuthorizationCodesTableName)},
+	},
+	{
+		version: 3,
+		name:    "Create the Webauthn devices table",
+		up: migrationStatements{"": {
+			fmt.Sprintf("CREATE TABLE %s (username VARCHAR(100) NOT NULL, kid VARCHAR(512) NOT NULL, public_key TEXT NOT NULL, attestation_type VARCHAR(32), aaguid VARCHAR(64), sign_count INTEGER, created_at INTEGER, PRIMARY KEY (username, kid))", webauthnDevicesTableName),
+		}},
+		down: migrationStatements{"": dropTables(webauthnDevicesTableName)},
+	},
+	{
+		version: 4,
+		name:    "Register several U2F devices per user",
+		up: migrationStatements{"": recreateTable(u2fDeviceHandlesTableName, sqlCreateU2FDevicesTableV4,
+			"INSERT INTO %s ("+u2fDevicesColumnsV4+") "+
+				"SELECT username, '"+u2fDevicesUpgradeDescription+"', keyHandle, publicKey, 0, 0 FROM %s")},
+		// Only the first device of each user is kept.
+		down: migrationStatements{"": recreateTable(u2fDeviceHandlesTableName, sqlCreateU2FDevicesTableV1,
+			"INSERT INTO %s (username, keyHandle, publicKey) "+
+				"SELECT d.username, d.key_handle, d.public_key FROM %[2]s d "+
+				"WHERE d.key_handle = (SELECT MIN(o.key_handle) FROM %[2]s o WHERE o.username = d.username)")},
+	},
+	{
+		version: 5,
+		name:    "Store the signature counter of the U2F devices",
+		up: migrationStatements{"": {
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN counter INTEGER NOT NULL DEFAULT 0", u2fDeviceHandlesTableName),
+		}},
+		down: alterStatements(
+			[]string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN counter", u2fDeviceHandlesTableName)},
+			recreateTable(u2fDeviceHandlesTableName, sqlCreateU2FDevicesTableV4, copyColumns(u2fDevicesColumnsV4))),
+	},
+	{
+		version: 6,
+		name:    "Register several TOTP devices per user",
+		up: migrationStatements{"": recreateTable(totpSecretsTableName, sqlCreateTOTPSecretsTableV6,
+			"INSERT INTO %s ("+totpSecretsColumnsV6+") "+
+				"SELECT username, '"+totpSecretsUpgradeID+"', '"+totpSecretsUpgradeDescription+"', secret, 0, 0 FROM %s")},
+		// Only the first device of each user is kept.
+		down: migrationStatements{"": recreateTable(totpSecretsTableName, sqlCreateTOTPSecretsTableV1,
+			"INSERT INTO %s (username, secret) "+
+				"SELECT t.username, t.secret FROM %[2]s t "+
+				"WHERE t.id = (SELECT MIN(o.id) FROM %[2]s o WHERE o.username = t.username)")},
+	},
+	{
+		version: 7,
+		name:    "Store the parameters of the TOTP devices",
+		// The devices already registered used the SHA1 algorithm and 6 digits, their period is unknown and left to 0
+		// so that the period of the configuration keeps being used for them.
+		up: migrationStatements{"": {
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN algorithm VARCHAR(6) NOT NULL DEFAULT 'SHA1'", totpSecretsTableName),
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN digits INTEGER NOT NULL DEFAULT 6", totpSecretsTableName),
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN period INTEGER NOT NULL DEFAULT 0", totpSecretsTableName),
+		}},
+		down: alterStatements(
+			[]string{
+				fmt.Sprintf("ALTER TABLE %s DROP COLUMN period", totpSecretsTableName),
+				fmt.Sprintf("ALTER TABLE %s DROP COLUMN digits", totpSecretsTableName),
+				fmt.Sprintf("ALTER TABLE %s DROP COLUMN algorithm", totpSecretsTableName),
+			},
+			recreateTable(totpSecretsTableName, sqlCreateTOTPSecretsTableV6, copyColumns(totpSecretsColumnsV6))),
+	},
+	{
+		version: 8,
+		name:    "Prevent the TOTP passcodes from being replayed",
+		up: migrationStatements{"": {
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN last_step BIGINT NOT NULL DEFAULT 0", totpSecretsTableName),
+		}},
+		down: alterStatements(
+			[]string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN last_step", totpSecretsTableName)},
+			recreateTable(totpSecretsTableName, sqlCreateTOTPSecretsTableV7, copyColumns(totpSecretsColumnsV7))),
+	},
+	{
+		version: 9,
+		name:    "Create the recovery codes table",
+		up: migrationStatements{"": {
+			fmt.Sprintf(sqlCreateRecoveryCodesTableV9, recoveryCodesTableName),
+		}},
+		down: migrationStatements{"": dropTables(recoveryCodesTableName)},
+	},
+	{
+		version: 10,
+		name:    "Encrypt the TOTP secrets and the public keys of the security keys",
+		up: migrationStatements{"": recreateTable(totpSecretsTableName, sqlCreateTOTPSecretsTableV10,
+			copyColumns(totpSecretsColumnsV8))},
+		down: migrationStatements{"": recreateTable(totpSecretsTableName, sqlCreateTOTPSecretsTableV8,
+			copyColumns(totpSecretsColumnsV8))},
+		data: "Encrypt the TOTP secrets and the public keys of the security keys with the encryption key, " +
+			"or decrypt them when downgrading",
+		upData: func(p *SQLProvider, tx transaction) error {
+			for _, column := range p.encryptedColumns() {
+				if err := p.encryptColumn(tx, column); err != nil {
+					return fmt.Errorf("Unable to encrypt table %s: %v", column.table, err)
+				}
+			}
+
+			return p.saveEncryptionCheck(tx, &p.key)
+		},
+		downData: func(p *SQLProvider, tx transaction) error {
+			for _, column := range p.encryptedColumns() {
+				if err := p.decryptColumn(tx, column); err != nil {
+					return fmt.Errorf("Unable to decrypt table %s: %v", column.table, err)
+				}
+			}
+
+			_, err := tx.Exec(p.sqlConfigDeleteValue, encryptionCheckCategory, encryptionCheckKeyName)
+
+			return err
+		},
+	},
+	{
+		version: 11,
+		name:    "Create the email one-time passcodes log table",
+		up: migrationStatements{"": {
+			fmt.Sprintf("CREATE TABLE %s (username VARCHAR(100) NOT NULL, event VARCHAR(16) NOT NULL, time INTEGER NOT NULL)", emailOTPLogsTableName),
+			fmt.Sprintf("CREATE INDEX %s ON %s (username, time)", emailOTPLogsIndexName, emailOTPLogsTableName),
+		}},
+		down: migrationStatements{"": dropTables(emailOTPLogsTableName)},
+	},
+	{
+		version: 12,
+		name:    "Store the lookup of the recovery codes",
+		// The codes generated before have no lookup and keep being checked against every code typed by their user.
+		up: migrationStatements{"": {
+			fmt.Sprintf("ALTER TABLE %s ADD COLUMN lookup VARCHAR(8) NOT NULL DEFAULT ''", recoveryCodesTableName),
+		}},
+		down: alterStatements(
+			[]string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN lookup", recoveryCodesTableName)},
+			recreateTable(recoveryCodesTableName, sqlCreateRecoveryCodesTableV9, copyColumns(recoveryCodesColumnsV9))),
+	},
+}
+
+// createIndexIfNotExists creates an index unless it exists. Not every database supports CREATE INDEX IF NOT EXISTS.
+func (p *SQLProvider) createIndexIfNotExists(tx transaction, table, index, columns string) error {
+	var exists bool
+
+	if err := tx.QueryRow(p.sqlTestIndexExistence, table, index).Scan(&exists); err != nil {
+		return err
+	}
+
+	if exists {
+		return nil
+	}
+
+	if _, err := tx.Exec(fmt.Sprintf("CREATE INDEX %s ON %s (%s)", index, table, columns)); err != nil {
+		return fmt.Errorf("Unable to create index %s: %v", index, err)
+	}
+
+	return nil
+}
@@ -16,13 +16,11 @@ type PostgreSQLProvider struct {
 }
 
 // NewPostgreSQLProvider a PostgreSQL provider.
-func NewPostgreSQLProvider(configuration schema.PostgreSQLStorageConfiguration) *PostgreSQLProvider {
+func NewPostgreSQLProvider(configuration schema.PostgreSQLStorageConfiguration, encryptionKey string) *PostgreSQLProvider {
 	provider := PostgreSQLProvider{
 		SQLProvider{
-			name: "postgres",
-
-			sqlUpgradesCreateTableStatements:        sqlUpgradeCreateTableStatements,
-			sqlUpgradesCreateTableIndexesStatements: sqlUpgradesCreateTableIndexesStatements,
+			name: providerPostgres,
+			key:  newEncryptionKey(encryptionKey),
 
 			sqlGetPreferencesByUsername:     fmt.Sprintf("SELECT second_factor_method FROM %s WHERE username=$1", userPreferencesTableName),
 			sqlUpsertSecondFactorPreference: fmt.Sprintf("INSERT INTO %s (username, second_factor_method) VALUES ($1, $2) ON CONFLICT (username) DO UPDATE SET second_factor_method=$2", userPreferencesTableName),
@@ -31,20 +29,60 @@ func NewPostgreSQLProvider(configuration schema.PostgreSQLStorageConfiguration)
 			sqlInsertIdentityVerificationToken:        fmt.Sprintf("INSERT INTO %s (token) VALUES ($1)", identityVerificationTokensTableName),
 			sqlDeleteIdentityVerificationToken:        fmt.Sprintf("DELETE FROM %s WHERE token=$1", identityVerificationTokensTableName),
 
-			sqlGetTOTPSecretByUsername: fmt.Sprintf("SELECT secret FROM %s WHERE username=$1", totpSecretsTableName),
-			sqlUpsertTOTPSecret:        fmt.Sprintf("INSERT INTO %s (username, secret) VALUES ($1, $2) ON CONFLICT (username) DO UPDATE SET secret=$2", totpSecretsTableName),
-			sqlDeleteTOTPSecret:        fmt.Sprintf("DELETE FROM %s WHERE username=$1", totpSecretsTableName),
+			sqlInsertTOTPDevice:            fmt.Sprintf("INSERT INTO %s (username, id, description, secret, algorithm, digits, period, created_at, last_used_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)", totpSecretsTableName),
+			sqlGetTOTPDevicesByUsername:    fmt.Sprintf("SELECT id, description, secret, algorithm, digits, period, last_step, created_at, last_used_at FROM %s WHERE username=$1 ORDER BY created_at", totpSecretsTableName),
+			sqlUpdateTOTPDeviceLastStep:    fmt.Sprintf("UPDATE %s SET last_step=$1, last_used_at=$2 WHERE username=$3 AND id=$4 AND last_step<$5", totpSecretsTableName),
+			sqlDeleteTOTPDevice:            fmt.Sprintf("DELETE FROM %s WHERE username=$1 AND id=$2", totpSecretsTableName),
+			sqlDeleteTOTPDevicesByUsername: fmt.Sprintf("DELETE FROM %s WHERE username=$1", totpSecretsTableName),
+			sqlGetTOTPSecrets:              fmt.Sprintf("SELECT username, id, secret FROM %s", totpSecretsTableName),
+			sqlUpdateTOTPSecret:            fmt.Sprintf("UPDATE %s SET secret=$1 WHERE username=$2 AND id=$3", totpSecretsTableName),
+
+			sqlInsertU2FDevice:         fmt.Sprintf("INSERT INTO %s (username, description, key_handle, public_key, counter, created_at, last_used_at) VALUES ($1, $2, $3, $4, $5, $6, $7)", u2fDeviceHandlesTableName),
+			sqlGetU2FDevicesByUsername: fmt.Sprintf("SELECT description, key_handle, public_key, counter, created_at, last_used_at FROM %s WHERE username=$1 ORDER BY created_at", u2fDeviceHandlesTableName),
+			sqlUpdateU2FDeviceCounter:  fmt.Sprintf("UPDATE %s SET counter=$1, last_used_at=$2 WHERE username=$3 AND key_handle=$4 AND (counter<$5 OR counter=0)", u2fDeviceHandlesTableName),
+			sqlDeleteU2FDevice:         fmt.Sprintf("DELETE FROM %s WHERE username=$1 AND key_handle=$2", u2fDeviceHandlesTableName),
+			sqlGetU2FPublicKeys:        fmt.Sprintf("SELECT username, key_handle, public_key FROM %s", u2fDeviceHandlesTableName),
+			sqlUpdateU2FPublicKey:      fmt.Sprintf("UPDATE %s SET public_key=$1 WHERE username=$2 AND key_handle=$3", u2fDeviceHandlesTableName),
+
+			sqlInsertWebauthnDevice:          fmt.Sprintf("INSERT INTO %s (username, kid, public_key, attestation_type, aaguid, sign_count, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)", webauthnDevicesTableName),
+			sqlGetWebauthnDevicesByUsername:  fmt.Sprintf("SELECT kid, public_key, attestation_type, aaguid, sign_count, created_at FROM %s WHERE username=$1 ORDER BY created_at", webauthnDevicesTableName),
+			sqlUpdateWebauthnDeviceSignCount: fmt.Sprintf("UPDATE %s SET sign_count=$1 WHERE username=$2 AND kid=$3 AND (sign_count<$4 OR sign_count=0)", webauthnDevicesTableName),
+			sqlDeleteWebauthnDevice:          fmt.Sprintf("DELETE FROM %s WHERE username=$1 AND kid=$2", webauthnDevicesTableName),
+			sqlGetWebauthnPublicKeys:         fmt.Sprintf("SELECT username, kid, public_key FROM %s", webauthnDevicesTableName),
+			sqlUpdateWebauthnPublicKey:       fmt.Sprintf("UPDATE %s SET public_key=$1 WHERE username=$2 AND kid=$3", webauthnDevicesTableName),
+
+			sqlInsertRecoveryCode:            fmt.Sprintf("INSERT INTO %s (username, lookup, hash, created_at) VALUES ($1, $2, $3, $4)", recoveryCodesTableName),
+			sqlGetRecoveryCodesByUsername:    fmt.Sprintf("SELECT lookup, hash, created_at FROM %s WHERE username=$1", recoveryCodesTableName),
+			sqlDeleteRecoveryCode:            fmt.Sprintf("DELETE FROM %s WHERE username=$1 AND hash=$2", recoveryCodesTableName),
+			sqlDeleteRecoveryCodesByUsername: fmt.Sprintf("DELETE FROM %s WHERE username=$1", recoveryCodesTableName),
+
+			sqlInsertAuthenticationLog:        fmt.Sprintf("INSERT INTO %s (username, successful, time) VALUES ($1, $2, $3)", authenticationLogsTableName),
+			sqlGetLatestAuthenticationLogs:    fmt.Sprintf("SELECT successful, time FROM %s WHERE time>$1 AND username=$2 ORDER BY time DESC", authenticationLogsTableName),
+			sqlDeleteFailedAuthenticationLogs: fmt.Sprintf("DELETE FROM %s WHERE username=$1 AND successful=$2 AND time>$3", authenticationLogsTableName),
+
+			sqlInsertEmailOTPLog:     fmt.Sprintf("INSERT INTO %s (username, event, time) VALUES ($1, $2, $3)", emailOTPLogsTableName),
+			sqlGetLatestEmailOTPLogs: fmt.Sprintf("SELECT event, time FROM %s WHERE time>$1 AND username=$2 ORDER BY time DESC", emailOTPLogsTableName),
+
+			sqlInsertOAuth2AuthorizationCode: fmt.Sprintf("INSERT INTO %s (signature, client_id, username, scopes, redirect_uri, nonce, code_challenge, code_challenge_method, amr, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)", oauth2AuthorizationCodesTableName),
+			sqlGetOAuth2AuthorizationCode:    fmt.Sprintf("SELECT client_id, username, scopes, redirect_uri, nonce, code_challenge, code_challenge_method, amr, expires_at FROM %s WHERE signature=$1", oauth2AuthorizationCodesTableName),
+			sqlDeleteOAuth2AuthorizationCode: fmt.Sprintf("DELETE FROM %s WHERE signature=$1", oauth2AuthorizationCodesTableName),
+
+			sqlInsertOAuth2RefreshToken: fmt.Sprintf("INSERT INTO %s (signature, client_id, username, scopes, amr, expires_at) VALUES ($1, $2, $3, $4, $5, $6)", oauth2RefreshTokensTableName),
+			sqlGetOAuth2RefreshToken:    fmt.Sprintf("SELECT client_id, username, scopes, amr, expires_at FROM %s WHERE signature=$1", oauth2RefreshTokensTableName),
+			sqlDeleteOAuth2RefreshToken: fmt.Sprintf("DELETE FROM %s WHERE signature=$1", oauth2RefreshTokensTableName),
+
+			sqlUpsertOAuth2Consent: fmt.Sprintf("INSERT INTO %s (username, client_id, scopes, granted_at) VALUES ($1, $2, $3, $4) ON CONFLICT (username, client_id) DO UPDATE SET scopes=$3, granted_at=$4", oauth2ConsentTableName),
+			sqlGetOAuth2Consent:    fmt.Sprintf("SELECT scopes, granted_at FROM %s WHERE username=$1 AND client_id=$2", oauth2ConsentTableName),
 
-			sqlGetU2FDeviceHandleByUsername: fmt.Sprintf("SELECT keyHandle, publicKey FROM %s WHERE username=$1", u2fDeviceHandlesTableName),
-			sqlUpsertU2FDeviceHandle:        fmt.Sprintf("INSERT INTO %s (username, keyHandle, publicKey) VALUES ($1, $2, $3) ON CONFLICT (username) DO UPDATE SET keyHandle=$2, publicKey=$3", u2fDeviceHandlesTableName),
+			sqlGetExistingTables: "SELECT table_name FROM information_schema.tables WHERE table_type='BASE TABLE' AND table_schema='public'",
 
-			sqlInsertAuthenticationLog:     fmt.Sprintf("INSERT INTO %s (username, successful, time) VALUES ($1, $2, $3)", authenticationLogsTableName),
-			sqlGetLatestAuthenticationLogs: fmt.Sprintf("SELECT successful, time FROM %s WHERE time>$1 AND username=$2 ORDER BY time DESC", authenticationLogsTableName),
+			sqlTestIndexExistence: "SELECT EXISTS (SELECT * FROM pg_indexes WHERE schemaname='public' AND tablename=$1 AND indexname=$2)",
 
-			sqlGetExistingTables: "SELECT table_name FROM information_schema.tables WHERE table_type='BASE TABLE' AND table_schema='public'",
+			sqlLockSchemaMigrations: fmt.Sprintf("SELECT COUNT(*) FROM pg_advisory_xact_lock(hashtext('%s'))", storageSchemaMigrationLockName),
 
-			sqlConfigSetValue: fmt.Sprintf("INSERT INTO %s (category, key_name, value) VALUES ($1, $2, $3) ON CONFLICT (category, key_name) DO UPDATE SET value=$3", configTableName),
-			sqlConfigGetValue: fmt.Sprintf("SELECT value FROM %s WHERE category=$1 AND key_name=$2", configTableName),
+			sqlConfigSetValue:    fmt.Sprintf("INSERT INTO %s (category, key_name, value) VALUES ($1, $2, $3) ON CONFLICT (category, key_name) DO UPDATE SET value=$3", configTableName),
+			sqlConfigGetValue:    fmt.Sprintf("SELECT value FROM %s WHERE category=$1 AND key_name=$2", configTableName),
+			sqlConfigDeleteValue: fmt.Sprintf("DELETE FROM %s WHERE category=$1 AND key_name=$2", configTableName),
 		},
 	}
 
@@ -80,9 +118,7 @@ func NewPostgreSQLProvider(configuration schema.PostgreSQLStorageConfiguration)
 		provider.log.Fatalf("Unable to connect to SQL database: %v", err)
 	}
 
-	if err := provider.initialize(db); err != nil {
-		provider.log.Fatalf("Unable to initialize SQL database: %v", err)
-	}
+	provider.connect(db)
 
 	return &provider
 }
@@ -16,13 +16,51 @@ type Provider interface {
 	SaveIdentityVerificationToken(token string) error
 	RemoveIdentityVerificationToken(token string) error
 
-	SaveTOTPSecret(username string, secret string) error
-	LoadTOTPSecret(username string) (string, error)
-	DeleteTOTPSecret(username string) error
+	SaveTOTPDevice(device models.TOTPDevice) error
+	LoadTOTPDevicesByUsername(username string) ([]models.TOTPDevice, error)
+	UpdateTOTPDeviceLastStep(username string, id string, step uint64, lastUsedAt time.Time) error
+	DeleteTOTPDevice(username string, id string) error
+	DeleteTOTPDevicesByUsername(username string) error
 
-	SaveU2FDeviceHandle(username string, keyHandle []byte, publicKey []byte) error
-	LoadU2FDeviceHandle(username string) (keyHandle []byte, publicKey []byte, err error)
+	SaveU2FDevice(device models.U2FDevice) error
+	LoadU2FDevicesByUsername(username string) ([]models.U2FDevice, error)
+	UpdateU2FDeviceCounter(username string, keyHandle []byte, counter uint32, lastUsedAt time.Time) error
+	DeleteU2FDevice(username string, keyHandle []byte) error
+
+	SaveWebauthnDevice(device models.WebauthnDevice) error
+	LoadWebauthnDevicesByUsername(username string) ([]models.WebauthnDevice, error)
+	UpdateWebauthnDeviceSignCount(username string, kid []byte, signCount uint32) error
+	DeleteWebauthnDevice(username string, kid []byte) error
+
+	SaveRecoveryCodes(username string, codes []models.RecoveryCode) error
+	LoadRecoveryCodesByUsername(username string) ([]models.RecoveryCode, error)
+	DeleteRecoveryCode(username string, hash string) error
 
 	AppendAuthenticationLog(attempt models.AuthenticationAttempt) error
 	LoadLatestAuthenticationLogs(username string, fromDate time.Time) ([]models.AuthenticationAttempt, error)
+	DeleteFailedAuthenticationLogs(username string, fromDate time.Time) error
+
+	AppendEmailOTPLog(log models.EmailOTPLog) error
+	LoadLatestEmailOTPLogs(username string, fromDate time.Time) ([]models.EmailOTPLog, error)
+
+	SaveOAuth2AuthorizationCode(code models.OAuth2AuthorizationCode) error
+	LoadOAuth2AuthorizationCode(signature string) (*models.OAuth2AuthorizationCode, error)
+	DeleteOAuth2AuthorizationCode(signature string) error
+
+	SaveOAuth2RefreshToken(token models.OAuth2RefreshToken) error
+	LoadOAuth2RefreshToken(signature string) (*models.OAuth2RefreshToken, error)
+	DeleteOAuth2RefreshToken(signature string) error
+
+	SaveOAuth2Consent(consent models.OAuth2Consent) error
+	LoadOAuth2Consent(username string, clientID string) (*models.OAuth2Consent, error)
+}
+
+// Migrator is implemented by the providers able to report and migrate the schema of their database, and to manage
+// the key the sensitive data of the database is encrypted with.
+type Migrator interface {
+	SchemaInfo() (version SchemaVersion, tables []string, err error)
+	Migrate() error
+	MigrateTo(version SchemaVersion, dryRun bool) (migrations []SchemaMigration, err error)
+	CheckEncryptionKey() error
+	ChangeEncryptionKey(newKey string) error
 }
@@ -108,77 +108,232 @@ func (mr *MockProviderMockRecorder) RemoveIdentityVerificationToken(token interf
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveIdentityVerificationToken", reflect.TypeOf((*MockProvider)(nil).RemoveIdentityVerificationToken), token)
 }
 
-// SaveTOTPSecret mocks base method
-func (m *MockProvider) SaveTOTPSecret(username, secret string) error {
+// SaveTOTPDevice mocks base method
+func (m *MockProvider) SaveTOTPDevice(device models.TOTPDevice) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveTOTPSecret", username, secret)
+	ret := m.ctrl.Call(m, "SaveTOTPDevice", device)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SaveTOTPSecret indicates an expected call of SaveTOTPSecret
-func (mr *MockProviderMockRecorder) SaveTOTPSecret(username, secret interface{}) *gomock.Call {
+// SaveTOTPDevice indicates an expected call of SaveTOTPDevice
+func (mr *MockProviderMockRecorder) SaveTOTPDevice(device interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTOTPSecret", reflect.TypeOf((*MockProvider)(nil).SaveTOTPSecret), username, secret)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTOTPDevice", reflect.TypeOf((*MockProvider)(nil).SaveTOTPDevice), device)
 }
 
-// LoadTOTPSecret mocks base method
-func (m *MockProvider) LoadTOTPSecret(username string) (string, error) {
+// LoadTOTPDevicesByUsername mocks base method
+func (m *MockProvider) LoadTOTPDevicesByUsername(username string) ([]models.TOTPDevice, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "LoadTOTPSecret", username)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "LoadTOTPDevicesByUsername", username)
+	ret0, _ := ret[0].([]models.TOTPDevice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadTOTPDevicesByUsername indicates an expected call of LoadTOTPDevicesByUsername
+func (mr *MockProviderMockRecorder) LoadTOTPDevicesByUsername(username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadTOTPDevicesByUsername", reflect.TypeOf((*MockProvider)(nil).LoadTOTPDevicesByUsername), username)
+}
+
+// UpdateTOTPDeviceLastStep mocks base method
+func (m *MockProvider) UpdateTOTPDeviceLastStep(username, id string, step uint64, lastUsedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateTOTPDeviceLastStep", username, id, step, lastUsedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateTOTPDeviceLastStep indicates an expected call of UpdateTOTPDeviceLastStep
+func (mr *MockProviderMockRecorder) UpdateTOTPDeviceLastStep(username, id, step, lastUsedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateTOTPDeviceLastStep", reflect.TypeOf((*MockProvider)(nil).UpdateTOTPDeviceLastStep), username, id, step, lastUsedAt)
+}
+
+// DeleteTOTPDevice mocks base method
+func (m *MockProvider) DeleteTOTPDevice(username, id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTOTPDevice", username, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTOTPDevice indicates an expected call of DeleteTOTPDevice
+func (mr *MockProviderMockRecorder) DeleteTOTPDevice(username, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTOTPDevice", reflect.TypeOf((*MockProvider)(nil).DeleteTOTPDevice), username, id)
+}
+
+// DeleteTOTPDevicesByUsername mocks base method
+func (m *MockProvider) DeleteTOTPDevicesByUsername(username string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTOTPDevicesByUsername", username)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTOTPDevicesByUsername indicates an expected call of DeleteTOTPDevicesByUsername
+func (mr *MockProviderMockRecorder) DeleteTOTPDevicesByUsername(username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTOTPDevicesByUsername", reflect.TypeOf((*MockProvider)(nil).DeleteTOTPDevicesByUsername), username)
+}
+
+// SaveU2FDevice mocks base method
+func (m *MockProvider) SaveU2FDevice(device models.U2FDevice) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveU2FDevice", device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveU2FDevice indicates an expected call of SaveU2FDevice
+func (mr *MockProviderMockRecorder) SaveU2FDevice(device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveU2FDevice", reflect.TypeOf((*MockProvider)(nil).SaveU2FDevice), device)
+}
+
+// LoadU2FDevicesByUsername mocks base method
+func (m *MockProvider) LoadU2FDevicesByUsername(username string) ([]models.U2FDevice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadU2FDevicesByUsername", username)
+	ret0, _ := ret[0].([]models.U2FDevice)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadU2FDevicesByUsername indicates an expected call of LoadU2FDevicesByUsername
+func (mr *MockProviderMockRecorder) LoadU2FDevicesByUsername(username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadU2FDevicesByUsername", reflect.TypeOf((*MockProvider)(nil).LoadU2FDevicesByUsername), username)
+}
+
+// UpdateU2FDeviceCounter mocks base method
+func (m *MockProvider) UpdateU2FDeviceCounter(username string, keyHandle []byte, counter uint32, lastUsedAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateU2FDeviceCounter", username, keyHandle, counter, lastUsedAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateU2FDeviceCounter indicates an expected call of UpdateU2FDeviceCounter
+func (mr *MockProviderMockRecorder) UpdateU2FDeviceCounter(username, keyHandle, counter, lastUsedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateU2FDeviceCounter", reflect.TypeOf((*MockProvider)(nil).UpdateU2FDeviceCounter), username, keyHandle, counter, lastUsedAt)
+}
+
+// DeleteU2FDevice mocks base method
+func (m *MockProvider) DeleteU2FDevice(username string, keyHandle []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteU2FDevice", username, keyHandle)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteU2FDevice indicates an expected call of DeleteU2FDevice
+func (mr *MockProviderMockRecorder) DeleteU2FDevice(username, keyHandle interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteU2FDevice", reflect.TypeOf((*MockProvider)(nil).DeleteU2FDevice), username, keyHandle)
+}
+
+// SaveWebauthnDevice mocks base method
+func (m *MockProvider) SaveWebauthnDevice(device models.WebauthnDevice) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveWebauthnDevice", device)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveWebauthnDevice indicates an expected call of SaveWebauthnDevice
+func (mr *MockProviderMockRecorder) SaveWebauthnDevice(device interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveWebauthnDevice", reflect.TypeOf((*MockProvider)(nil).SaveWebauthnDevice), device)
+}
+
+// LoadWebauthnDevicesByUsername mocks base method
+func (m *MockProvider) LoadWebauthnDevicesByUsername(username string) ([]models.WebauthnDevice, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadWebauthnDevicesByUsername", username)
+	ret0, _ := ret[0].([]models.WebauthnDevice)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// LoadTOTPSecret indicates an expected call of LoadTOTPSecret
-func (mr *MockProviderMockRecorder) LoadTOTPSecret(username interface{}) *gomock.Call {
+// LoadWebauthnDevicesByUsername indicates an expected call of LoadWebauthnDevicesByUsername
+func (mr *MockProviderMockRecorder) LoadWebauthnDevicesByUsername(username interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadWebauthnDevicesByUsername", reflect.TypeOf((*MockProvider)(nil).LoadWebauthnDevicesByUsername), username)
+}
+
+// UpdateWebauthnDeviceSignCount mocks base method
+func (m *MockProvider) UpdateWebauthnDeviceSignCount(username string, kid []byte, signCount uint32) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateWebauthnDeviceSignCount", username, kid, signCount)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateWebauthnDeviceSignCount indicates an expected call of UpdateWebauthnDeviceSignCount
+func (mr *MockProviderMockRecorder) UpdateWebauthnDeviceSignCount(username, kid, signCount interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadTOTPSecret", reflect.TypeOf((*MockProvider)(nil).LoadTOTPSecret), username)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateWebauthnDeviceSignCount", reflect.TypeOf((*MockProvider)(nil).UpdateWebauthnDeviceSignCount), username, kid, signCount)
 }
 
-// DeleteTOTPSecret mocks base method
-func (m *MockProvider) DeleteTOTPSecret(username string) error {
+// DeleteWebauthnDevice mocks base method
+func (m *MockProvider) DeleteWebauthnDevice(username string, kid []byte) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteTOTPSecret", username)
+	ret := m.ctrl.Call(m, "DeleteWebauthnDevice", username, kid)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteTOTPSecret indicates an expected call of DeleteTOTPSecret
-func (mr *MockProviderMockRecorder) DeleteTOTPSecret(username interface{}) *gomock.Call {
+// DeleteWebauthnDevice indicates an expected call of DeleteWebauthnDevice
+func (mr *MockProviderMockRecorder) DeleteWebauthnDevice(username, kid interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTOTPSecret", reflect.TypeOf((*MockProvider)(nil).DeleteTOTPSecret), username)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebauthnDevice", reflect.TypeOf((*MockProvider)(nil).DeleteWebauthnDevice), username, kid)
 }
 
-// SaveU2FDeviceHandle mocks base method
-func (m *MockProvider) SaveU2FDeviceHandle(username string, keyHandle, publicKey []byte) error {
+// SaveRecoveryCodes mocks base method
+func (m *MockProvider) SaveRecoveryCodes(username string, codes []models.RecoveryCode) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SaveU2FDeviceHandle", username, keyHandle, publicKey)
+	ret := m.ctrl.Call(m, "SaveRecoveryCodes", username, codes)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// SaveU2FDeviceHandle indicates an expected call of SaveU2FDeviceHandle
-func (mr *MockProviderMockRecorder) SaveU2FDeviceHandle(username, keyHandle, publicKey interface{}) *gomock.Call {
+// SaveRecoveryCodes indicates an expected call of SaveRecoveryCodes
+func (mr *MockProviderMockRecorder) SaveRecoveryCodes(username, codes interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveU2FDeviceHandle", reflect.TypeOf((*MockProvider)(nil).SaveU2FDeviceHandle), username, keyHandle, publicKey)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveRecoveryCodes", reflect.TypeOf((*MockProvider)(nil).SaveRecoveryCodes), username, codes)
 }
 
-// LoadU2FDeviceHandle mocks base method
-func (m *MockProvider) LoadU2FDeviceHandle(username string) ([]byte, []byte, error) {
+// LoadRecoveryCodesByUsername mocks base method
+func (m *MockProvider) LoadRecoveryCodesByUsername(username string) ([]models.RecoveryCode, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "LoadU2FDeviceHandle", username)
-	ret0, _ := ret[0].([]byte)
-	ret1, _ := ret[1].([]byte)
-	ret2, _ := ret[2].(error)
-	return ret0, ret1, ret2
+	ret := m.ctrl.Call(m, "LoadRecoveryCodesByUsername", username)
+	ret0, _ := ret[0].([]models.RecoveryCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// LoadU2FDeviceHandle indicates an expected call of LoadU2FDeviceHandle
-func (mr *MockProviderMockRecorder) LoadU2FDeviceHandle(username interface{}) *gomock.Call {
+// LoadRecoveryCodesByUsername indicates an expected call of LoadRecoveryCodesByUsername
+func (mr *MockProviderMockRecorder) LoadRecoveryCodesByUsername(username interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadU2FDeviceHandle", reflect.TypeOf((*MockProvider)(nil).LoadU2FDeviceHandle), username)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadRecoveryCodesByUsername", reflect.TypeOf((*MockProvider)(nil).LoadRecoveryCodesByUsername), username)
+}
+
+// DeleteRecoveryCode mocks base method
+func (m *MockProvider) DeleteRecoveryCode(username, hash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRecoveryCode", username, hash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRecoveryCode indicates an expected call of DeleteRecoveryCode
+func (mr *MockProviderMockRecorder) DeleteRecoveryCode(username, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRecoveryCode", reflect.TypeOf((*MockProvider)(nil).DeleteRecoveryCode), username, hash)
 }
 
 // AppendAuthenticationLog mocks base method
@@ -209,3 +364,161 @@ func (mr *MockProviderMockRecorder) LoadLatestAuthenticationLogs(username, fromD
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadLatestAuthenticationLogs", reflect.TypeOf((*MockProvider)(nil).LoadLatestAuthenticationLogs), username, fromDate)
 }
+
+// DeleteFailedAuthenticationLogs mocks base method
+func (m *MockProvider) DeleteFailedAuthenticationLogs(username string, fromDate time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFailedAuthenticationLogs", username, fromDate)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteFailedAuthenticationLogs indicates an expected call of DeleteFailedAuthenticationLogs
+func (mr *MockProviderMockRecorder) DeleteFailedAuthenticationLogs(username, fromDate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFailedAuthenticationLogs", reflect.TypeOf((*MockProvider)(nil).DeleteFailedAuthenticationLogs), username, fromDate)
+}
+
+// AppendEmailOTPLog mocks base method
+func (m *MockProvider) AppendEmailOTPLog(log models.EmailOTPLog) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AppendEmailOTPLog", log)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AppendEmailOTPLog indicates an expected call of AppendEmailOTPLog
+func (mr *MockProviderMockRecorder) AppendEmailOTPLog(log interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppendEmailOTPLog", reflect.TypeOf((*MockProvider)(nil).AppendEmailOTPLog), log)
+}
+
+// LoadLatestEmailOTPLogs mocks base method
+func (m *MockProvider) LoadLatestEmailOTPLogs(username string, fromDate time.Time) ([]models.EmailOTPLog, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadLatestEmailOTPLogs", username, fromDate)
+	ret0, _ := ret[0].([]models.EmailOTPLog)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadLatestEmailOTPLogs indicates an expected call of LoadLatestEmailOTPLogs
+func (mr *MockProviderMockRecorder) LoadLatestEmailOTPLogs(username, fromDate interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadLatestEmailOTPLogs", reflect.TypeOf((*MockProvider)(nil).LoadLatestEmailOTPLogs), username, fromDate)
+}
+
+// SaveOAuth2AuthorizationCode mocks base method
+func (m *MockProvider) SaveOAuth2AuthorizationCode(code models.OAuth2AuthorizationCode) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveOAuth2AuthorizationCode", code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveOAuth2AuthorizationCode indicates an expected call of SaveOAuth2AuthorizationCode
+func (mr *MockProviderMockRecorder) SaveOAuth2AuthorizationCode(code interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOAuth2AuthorizationCode", reflect.TypeOf((*MockProvider)(nil).SaveOAuth2AuthorizationCode), code)
+}
+
+// LoadOAuth2AuthorizationCode mocks base method
+func (m *MockProvider) LoadOAuth2AuthorizationCode(signature string) (*models.OAuth2AuthorizationCode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadOAuth2AuthorizationCode", signature)
+	ret0, _ := ret[0].(*models.OAuth2AuthorizationCode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadOAuth2AuthorizationCode indicates an expected call of LoadOAuth2AuthorizationCode
+func (mr *MockProviderMockRecorder) LoadOAuth2AuthorizationCode(signature interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadOAuth2AuthorizationCode", reflect.TypeOf((*MockProvider)(nil).LoadOAuth2AuthorizationCode), signature)
+}
+
+// DeleteOAuth2AuthorizationCode mocks base method
+func (m *MockProvider) DeleteOAuth2AuthorizationCode(signature string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOAuth2AuthorizationCode", signature)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOAuth2AuthorizationCode indicates an expected call of DeleteOAuth2AuthorizationCode
+func (mr *MockProviderMockRecorder) DeleteOAuth2AuthorizationCode(signature interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOAuth2AuthorizationCode", reflect.TypeOf((*MockProvider)(nil).DeleteOAuth2AuthorizationCode), signature)
+}
+
+// SaveOAuth2RefreshToken mocks base method
+func (m *MockProvider) SaveOAuth2RefreshToken(token models.OAuth2RefreshToken) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveOAuth2RefreshToken", token)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveOAuth2RefreshToken indicates an expected call of SaveOAuth2RefreshToken
+func (mr *MockProviderMockRecorder) SaveOAuth2RefreshToken(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOAuth2RefreshToken", reflect.TypeOf((*MockProvider)(nil).SaveOAuth2RefreshToken), token)
+}
+
+// LoadOAuth2RefreshToken mocks base method
+func (m *MockProvider) LoadOAuth2RefreshToken(signature string) (*models.OAuth2RefreshToken, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadOAuth2RefreshToken", signature)
+	ret0, _ := ret[0].(*models.OAuth2RefreshToken)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadOAuth2RefreshToken indicates an expected call of LoadOAuth2RefreshToken
+func (mr *MockProviderMockRecorder) LoadOAuth2RefreshToken(signature interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadOAuth2RefreshToken", reflect.TypeOf((*MockProvider)(nil).LoadOAuth2RefreshToken), signature)
+}
+
+// DeleteOAuth2RefreshToken mocks base method
+func (m *MockProvider) DeleteOAuth2RefreshToken(signature string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOAuth2RefreshToken", signature)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOAuth2RefreshToken indicates an expected call of DeleteOAuth2RefreshToken
+func (mr *MockProviderMockRecorder) DeleteOAuth2RefreshToken(signature interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOAuth2RefreshToken", reflect.TypeOf((*MockProvider)(nil).DeleteOAuth2RefreshToken), signature)
+}
+
+// SaveOAuth2Consent mocks base method
+func (m *MockProvider) SaveOAuth2Consent(consent models.OAuth2Consent) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveOAuth2Consent", consent)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveOAuth2Consent indicates an expected call of SaveOAuth2Consent
+func (mr *MockProviderMockRecorder) SaveOAuth2Consent(consent interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveOAuth2Consent", reflect.TypeOf((*MockProvider)(nil).SaveOAuth2Consent), consent)
+}
+
+// LoadOAuth2Consent mocks base method
+func (m *MockProvider) LoadOAuth2Consent(username, clientID string) (*models.OAuth2Consent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LoadOAuth2Consent", username, clientID)
+	ret0, _ := ret[0].(*models.OAuth2Consent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LoadOAuth2Consent indicates an expected call of LoadOAuth2Consent
+func (mr *MockProviderMockRecorder) LoadOAuth2Consent(username, clientID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadOAuth2Consent", reflect.TypeOf((*MockProvider)(nil).LoadOAuth2Consent), username, clientID)
+}
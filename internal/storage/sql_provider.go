@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -19,8 +20,8 @@ type SQLProvider struct {
 	log  *logrus.Logger
 	name string
 
-	sqlUpgradesCreateTableStatements        map[SchemaVersion]map[string]string
-	sqlUpgradesCreateTableIndexesStatements map[SchemaVersion][]string
+	// key encrypts the TOTP secrets and the public keys of the security keys.
+	key [32]byte
 
 	sqlGetPreferencesByUsername     string
 	sqlUpsertSecondFactorPreference string
@@ -29,113 +30,333 @@ type SQLProvider struct {
 	sqlInsertIdentityVerificationToken        string
 	sqlDeleteIdentityVerificationToken        string
 
-	sqlGetTOTPSecretByUsername string
-	sqlUpsertTOTPSecret        string
-	sqlDeleteTOTPSecret        string
+	sqlInsertTOTPDevice            string
+	sqlGetTOTPDevicesByUsername    string
+	sqlUpdateTOTPDeviceLastStep    string
+	sqlDeleteTOTPDevice            string
+	sqlDeleteTOTPDevicesByUsername string
+	sqlGetTOTPSecrets              string
+	sqlUpdateTOTPSecret            string
+
+	sqlInsertU2FDevice         string
+	sqlGetU2FDevicesByUsername string
+	sqlUpdateU2FDeviceCounter  string
+	sqlDeleteU2FDevice         string
+	sqlGetU2FPublicKeys        string
+	sqlUpdateU2FPublicKey      string
+
+	sqlInsertWebauthnDevice          string
+	sqlGetWebauthnDevicesByUsername  string
+	sqlUpdateWebauthnDeviceSignCount string
+	sqlDeleteWebauthnDevice          string
+	sqlGetWebauthnPublicKeys         string
+	sqlUpdateWebauthnPublicKey       string
+
+	sqlInsertRecoveryCode            string
+	sqlGetRecoveryCodesByUsername    string
+	sqlDeleteRecoveryCode            string
+	sqlDeleteRecoveryCodesByUsername string
+
+	sqlInsertAuthenticationLog        string
+	sqlGetLatestAuthenticationLogs    string
+	sqlDeleteFailedAuthenticationLogs string
+
+	sqlInsertEmailOTPLog     string
+	sqlGetLatestEmailOTPLogs string
+
+	sqlInsertOAuth2AuthorizationCode string
+	sqlGetOAuth2AuthorizationCode    string
+	sqlDeleteOAuth2AuthorizationCode string
+
+	sqlInsertOAuth2RefreshToken string
+	sqlGetOAuth2RefreshToken    string
+	sqlDeleteOAuth2RefreshToken string
+
+	sqlUpsertOAuth2Consent string
+	sqlGetOAuth2Consent    string
+
+	sqlGetExistingTables  string
+	sqlTestIndexExistence string
+
+	// sqlLockSchemaMigrations returns 1 once the lock of the schema migrations is acquired, the lock is released at
+	// the end of the transaction unless sqlUnlockSchemaMigrations is set.
+	sqlLockSchemaMigrations   string
+	sqlUnlockSchemaMigrations string
+
+	sqlConfigSetValue    string
+	sqlConfigGetValue    string
+	sqlConfigDeleteValue string
+}
 
-	sqlGetU2FDeviceHandleByUsername string
-	sqlUpsertU2FDeviceHandle        string
+func (p *SQLProvider) connect(db *sql.DB) {
+	p.db = db
+	p.log = logging.Logger()
+}
 
-	sqlInsertAuthenticationLog     string
-	sqlGetLatestAuthenticationLogs string
+func (p *SQLProvider) initialize(db *sql.DB) error {
+	p.connect(db)
 
-	sqlGetExistingTables string
+	return p.Migrate()
+}
 
-	sqlConfigSetValue string
-	sqlConfigGetValue string
+// SchemaInfo returns the version of the schema and the tables of the database.
+func (p *SQLProvider) SchemaInfo() (version SchemaVersion, tables []string, err error) {
+	return p.getSchemaBasicDetails(p.db)
 }
 
-func (p *SQLProvider) initialize(db *sql.DB) error {
-	p.db = db
-	p.log = logging.Logger()
+// Migrate upgrades the schema of the database to the latest version.
+func (p *SQLProvider) Migrate() error {
+	_, err := p.MigrateTo(SchemaLatestVersion, false)
 
-	return p.upgrade()
+	return err
 }
 
-func (p *SQLProvider) getSchemaBasicDetails() (version SchemaVersion, tables []string, err error) {
-	rows, err := p.db.Query(p.sqlGetExistingTables)
+// MigrateTo upgrades or downgrades the schema of the database to a version and returns the migrations applied, or
+// the migrations which would be applied in dry run mode. The migrations are applied in a single transaction holding
+// the lock of the schema migrations so that several instances do not migrate the schema at once. Note that MySQL
+// commits the transaction whenever a table is created or altered.
+func (p *SQLProvider) MigrateTo(target SchemaVersion, dryRun bool) ([]SchemaMigration, error) {
+	if target < 0 || target > SchemaLatestVersion {
+		return nil, fmt.Errorf("%w: v%d", ErrUnknownSchemaVersion, target)
+	}
+
+	p.log.Debug("Storage schema is being checked to verify it is up to date")
+
+	version, _, err := p.getSchemaBasicDetails(p.db)
 	if err != nil {
-		return version, tables, err
+		return nil, err
 	}
 
-	defer rows.Close()
+	steps, err := planMigrations(version, target)
+	if err != nil {
+		return nil, err
+	}
+
+	if dryRun {
+		return p.describeMigrations(steps), nil
+	}
+
+	if len(steps) == 0 {
+		p.log.Debug("Storage schema is up to date")
+		return nil, nil
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = p.lockSchemaMigrations(tx); err != nil {
+		return nil, p.rollback(tx, err)
+	}
+
+	// Another instance may have migrated the schema while the lock was being acquired.
+	if version, _, err = p.getSchemaBasicDetails(tx); err != nil {
+		return nil, p.abortSchemaMigration(tx, err)
+	}
+
+	if steps, err = planMigrations(version, target); err != nil {
+		return nil, p.abortSchemaMigration(tx, err)
+	}
+
+	p.log.Debugf("Storage schema is v%d, migrating it to v%d", version, target)
+
+	for _, step := range steps {
+		if err = p.applyMigration(tx, step); err != nil {
+			return nil, p.abortSchemaMigration(tx, step.failure(err))
+		}
+	}
+
+	if err = p.unlockSchemaMigrations(tx); err != nil {
+		return nil, p.rollback(tx, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	if len(steps) != 0 {
+		p.log.Infof("Storage schema migration from v%d to v%d completed", version, target)
+	}
+
+	return p.describeMigrations(steps), nil
+}
+
+func (p *SQLProvider) getSchemaBasicDetails(q transaction) (version SchemaVersion, tables []string, err error) {
+	rows, err := q.Query(p.sqlGetExistingTables)
+	if err != nil {
+		return version, tables, err
+	}
 
 	var table string
 
 	for rows.Next() {
 		err := rows.Scan(&table)
 		if err != nil {
+			_ = rows.Close()
 			return version, tables, err
 		}
 
 		tables = append(tables, table)
 	}
 
+	if err := rows.Close(); err != nil {
+		return version, tables, err
+	}
+
 	if utils.IsStringInSlice(configTableName, tables) {
-		rows, err := p.db.Query(p.sqlConfigGetValue, "schema", "version")
-		if err != nil {
+		err := q.QueryRow(p.sqlConfigGetValue, "schema", "version").Scan(&version)
+		if err != nil && err != sql.ErrNoRows {
 			return version, tables, err
 		}
-
-		for rows.Next() {
-			err := rows.Scan(&version)
-			if err != nil {
-				return version, tables, err
-			}
-		}
 	}
 
 	return version, tables, nil
 }
 
-func (p *SQLProvider) upgrade() error {
-	p.log.Debug("Storage schema is being checked to verify it is up to date")
+// migrationStep is a migration applied in one direction.
+type migrationStep struct {
+	migration *migration
+	up        bool
+}
 
-	version, tables, err := p.getSchemaBasicDetails()
-	if err != nil {
-		return err
+// failure formats an error which occurred while applying the step.
+func (s migrationStep) failure(err error) error {
+	if s.up {
+		return fmt.Errorf("storage schema upgrade failed at v%d: %v", s.migration.version, err)
 	}
 
-	if version < storageSchemaCurrentVersion {
-		p.log.Debugf("Storage schema is v%d, latest is v%d", version, storageSchemaCurrentVersion)
+	return fmt.Errorf("storage schema downgrade failed at v%d: %v", s.migration.version, err)
+}
 
-		tx, err := p.db.Begin()
-		if err != nil {
+// planMigrations returns the steps migrating the schema from a version to another.
+func planMigrations(version, target SchemaVersion) (steps []migrationStep, err error) {
+	if version > SchemaLatestVersion {
+		return nil, fmt.Errorf("%w: the schema is v%d but the latest version supported is v%d",
+			ErrSchemaNewerThanSupported, version, SchemaLatestVersion)
+	}
+
+	for i := int(version); i < int(target); i++ {
+		steps = append(steps, migrationStep{&migrations[i], true})
+	}
+
+	for i := int(version) - 1; i >= int(target); i-- {
+		steps = append(steps, migrationStep{&migrations[i], false})
+	}
+
+	return steps, nil
+}
+
+func (p *SQLProvider) applyMigration(tx transaction, step migrationStep) error {
+	m := step.migration
+
+	if !step.up && m.downData != nil {
+		if err := m.downData(p, tx); err != nil {
 			return err
 		}
+	}
 
-		switch version {
-		case 0:
-			err := p.upgradeSchemaToVersion001(tx, tables)
-			if err != nil {
-				return p.handleUpgradeFailure(tx, 1, err)
-			}
+	statements := m.down.forProvider(p.name)
+	if step.up {
+		statements = m.up.forProvider(p.name)
+	}
+
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	if step.up && m.upData != nil {
+		if err := m.upData(p, tx); err != nil {
+			return err
+		}
+	}
+
+	if !step.up {
+		p.log.Debugf("Storage schema downgraded to v%d", m.version-1)
+
+		// The version is not saved once the config table has been dropped.
+		if m.version == 1 {
+			return nil
+		}
+
+		return p.setSchemaVersion(tx, m.version-1)
+	}
+
+	p.log.Debugf("Storage schema upgraded to v%d", m.version)
+
+	return p.setSchemaVersion(tx, m.version)
+}
+
+func (p *SQLProvider) setSchemaVersion(tx transaction, version SchemaVersion) error {
+	_, err := tx.Exec(p.sqlConfigSetValue, "schema", "version", version.ToString())
+	return err
+}
 
-			fallthrough
-		default:
-			err := tx.Commit()
-			if err != nil {
-				return err
+func (p *SQLProvider) describeMigrations(steps []migrationStep) []SchemaMigration {
+	described := make([]SchemaMigration, 0, len(steps))
+
+	for _, step := range steps {
+		m := step.migration
+		description := SchemaMigration{Version: m.version, Name: m.name, Up: step.up}
+
+		if step.up {
+			description.Statements = m.up.forProvider(p.name)
+
+			if m.upData != nil {
+				description.Data = m.data
 			}
+		} else {
+			description.Statements = m.down.forProvider(p.name)
 
-			p.log.Infof("Storage schema upgrade to v%d completed", storageSchemaCurrentVersion)
+			if m.downData != nil {
+				description.Data = m.data
+			}
 		}
-	} else {
-		p.log.Debug("Storage schema is up to date")
+
+		described = append(described, description)
+	}
+
+	return described
+}
+
+func (p *SQLProvider) lockSchemaMigrations(tx transaction) error {
+	if p.sqlLockSchemaMigrations == "" {
+		return nil
+	}
+
+	var locked sql.NullInt64
+
+	if err := tx.QueryRow(p.sqlLockSchemaMigrations).Scan(&locked); err != nil {
+		return err
+	}
+
+	if !locked.Valid || locked.Int64 != 1 {
+		return ErrSchemaMigrationLocked
 	}
 
 	return nil
 }
 
-func (p *SQLProvider) handleUpgradeFailure(tx *sql.Tx, version SchemaVersion, err error) error {
-	rollbackErr := tx.Rollback()
-	formattedErr := fmt.Errorf("%s%d: %v", storageSchemaUpgradeErrorText, version, err)
+func (p *SQLProvider) unlockSchemaMigrations(tx transaction) error {
+	if p.sqlUnlockSchemaMigrations == "" {
+		return nil
+	}
+
+	var released sql.NullInt64
+
+	return tx.QueryRow(p.sqlUnlockSchemaMigrations).Scan(&released)
+}
 
-	if rollbackErr != nil {
-		return fmt.Errorf("rollback error occurred: %v (inner error %v)", rollbackErr, formattedErr)
+// abortSchemaMigration releases the lock of the schema migrations and rolls back the transaction after err occurred.
+// The lock is released first since it outlives the transaction with MySQL.
+func (p *SQLProvider) abortSchemaMigration(tx *sql.Tx, err error) error {
+	if unlockErr := p.unlockSchemaMigrations(tx); unlockErr != nil {
+		p.log.Errorf("Unable to release the lock of the schema migrations: %v", unlockErr)
 	}
 
-	return formattedErr
+	return p.rollback(tx, err)
 }
 
 // LoadPreferred2FAMethod load the preferred method for 2FA from the database.
@@ -187,66 +408,400 @@ func (p *SQLProvider) RemoveIdentityVerificationToken(token string) error {
 	return err
 }
 
-// SaveTOTPSecret save a TOTP secret of a given user in the database.
-func (p *SQLProvider) SaveTOTPSecret(username string, secret string) error {
-	_, err := p.db.Exec(p.sqlUpsertTOTPSecret, username, secret)
+// SaveTOTPDevice save a registered TOTP device.
+func (p *SQLProvider) SaveTOTPDevice(device models.TOTPDevice) error {
+	secret, err := p.encrypt([]byte(device.Secret))
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.Exec(p.sqlInsertTOTPDevice,
+		device.Username,
+		device.ID,
+		device.Description,
+		secret,
+		device.Algorithm,
+		device.Digits,
+		device.Period,
+		unixTimestamp(device.CreatedAt),
+		unixTimestamp(device.LastUsedAt))
+
 	return err
 }
 
-// LoadTOTPSecret load a TOTP secret given a username from the database.
-func (p *SQLProvider) LoadTOTPSecret(username string) (string, error) {
-	var secret string
-	if err := p.db.QueryRow(p.sqlGetTOTPSecretByUsername, username).Scan(&secret); err != nil {
-		if err == sql.ErrNoRows {
-			return "", ErrNoTOTPSecret
+// LoadTOTPDevicesByUsername load all the TOTP devices registered by a given user.
+func (p *SQLProvider) LoadTOTPDevicesByUsername(username string) ([]models.TOTPDevice, error) {
+	rows, err := p.db.Query(p.sqlGetTOTPDevicesByUsername, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := make([]models.TOTPDevice, 0, 1)
+
+	for rows.Next() {
+		var secret string
+
+		var createdAt, lastUsedAt int64
+
+		device := models.TOTPDevice{
+			Username: username,
 		}
 
-		return "", err
+		if err := rows.Scan(&device.ID, &device.Description, &secret, &device.Algorithm, &device.Digits, &device.Period,
+			&device.LastStep, &createdAt, &lastUsedAt); err != nil {
+			return nil, err
+		}
+
+		plaintext, err := p.decrypt(secret)
+		if err != nil {
+			return nil, err
+		}
+
+		device.Secret = string(plaintext)
+
+		device.CreatedAt = timeFromUnixTimestamp(createdAt)
+		device.LastUsedAt = timeFromUnixTimestamp(lastUsedAt)
+
+		devices = append(devices, device)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(devices) == 0 {
+		return nil, ErrNoTOTPSecret
+	}
+
+	return devices, nil
+}
+
+// UpdateTOTPDeviceLastStep record the time step of the last passcode accepted from a TOTP device and the time it has
+// been used to sign in. The update is conditioned by the step being greater than the recorded one so that a passcode
+// cannot be accepted twice even by several instances sharing the database, ErrTOTPStepAlreadyUsed is returned
+// otherwise.
+func (p *SQLProvider) UpdateTOTPDeviceLastStep(username string, id string, step uint64, lastUsedAt time.Time) error {
+	result, err := p.db.Exec(p.sqlUpdateTOTPDeviceLastStep, step, unixTimestamp(lastUsedAt), username, id, step)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrTOTPStepAlreadyUsed
+	}
+
+	return nil
+}
+
+// DeleteTOTPDevice delete a TOTP device registered by a given user.
+func (p *SQLProvider) DeleteTOTPDevice(username string, id string) error {
+	result, err := p.db.Exec(p.sqlDeleteTOTPDevice, username, id)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
 	}
 
-	return secret, nil
+	if affected == 0 {
+		return ErrNoTOTPSecret
+	}
+
+	return nil
 }
 
-// DeleteTOTPSecret delete a TOTP secret from the database given a username.
-func (p *SQLProvider) DeleteTOTPSecret(username string) error {
-	_, err := p.db.Exec(p.sqlDeleteTOTPSecret, username)
+// DeleteTOTPDevicesByUsername delete all the TOTP devices registered by a given user.
+func (p *SQLProvider) DeleteTOTPDevicesByUsername(username string) error {
+	_, err := p.db.Exec(p.sqlDeleteTOTPDevicesByUsername, username)
 	return err
 }
 
-// SaveU2FDeviceHandle save a registered U2F device registration blob.
-func (p *SQLProvider) SaveU2FDeviceHandle(username string, keyHandle []byte, publicKey []byte) error {
-	_, err := p.db.Exec(p.sqlUpsertU2FDeviceHandle,
-		username,
-		base64.StdEncoding.EncodeToString(keyHandle),
-		base64.StdEncoding.EncodeToString(publicKey))
+// SaveU2FDevice save a registered U2F device.
+func (p *SQLProvider) SaveU2FDevice(device models.U2FDevice) error {
+	publicKey, err := p.encrypt(device.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.Exec(p.sqlInsertU2FDevice,
+		device.Username,
+		device.Description,
+		base64.StdEncoding.EncodeToString(device.KeyHandle),
+		publicKey,
+		device.Counter,
+		unixTimestamp(device.CreatedAt),
+		unixTimestamp(device.LastUsedAt))
 
 	return err
 }
 
-// LoadU2FDeviceHandle load a U2F device registration blob for a given username.
-func (p *SQLProvider) LoadU2FDeviceHandle(username string) ([]byte, []byte, error) {
-	var keyHandleBase64, publicKeyBase64 string
-	if err := p.db.QueryRow(p.sqlGetU2FDeviceHandleByUsername, username).Scan(&keyHandleBase64, &publicKeyBase64); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil, ErrNoU2FDeviceHandle
+// LoadU2FDevicesByUsername load all the U2F devices registered by a given user.
+func (p *SQLProvider) LoadU2FDevicesByUsername(username string) ([]models.U2FDevice, error) {
+	rows, err := p.db.Query(p.sqlGetU2FDevicesByUsername, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := make([]models.U2FDevice, 0, 1)
+
+	for rows.Next() {
+		var keyHandle, publicKey string
+
+		var createdAt, lastUsedAt int64
+
+		device := models.U2FDevice{
+			Username: username,
+		}
+
+		if err := rows.Scan(&device.Description, &keyHandle, &publicKey, &device.Counter, &createdAt, &lastUsedAt); err != nil {
+			return nil, err
+		}
+
+		if device.KeyHandle, err = base64.StdEncoding.DecodeString(keyHandle); err != nil {
+			return nil, err
+		}
+
+		if device.PublicKey, err = p.decrypt(publicKey); err != nil {
+			return nil, err
 		}
 
-		return nil, nil, err
+		device.CreatedAt = timeFromUnixTimestamp(createdAt)
+		device.LastUsedAt = timeFromUnixTimestamp(lastUsedAt)
+
+		devices = append(devices, device)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	keyHandle, err := base64.StdEncoding.DecodeString(keyHandleBase64)
+	if len(devices) == 0 {
+		return nil, ErrNoU2FDeviceHandle
+	}
+
+	return devices, nil
+}
 
+// UpdateU2FDeviceCounter record the signature counter of a U2F device and the time it has last been used to sign in.
+// The update is conditioned by the counter being greater than the recorded one, unless the device does not implement
+// the counter, so that concurrent sign ins cannot lower it. ErrSignCountNotIncreasing is returned otherwise.
+func (p *SQLProvider) UpdateU2FDeviceCounter(username string, keyHandle []byte, counter uint32, lastUsedAt time.Time) error {
+	result, err := p.db.Exec(p.sqlUpdateU2FDeviceCounter,
+		counter, unixTimestamp(lastUsedAt), username, base64.StdEncoding.EncodeToString(keyHandle), counter)
 	if err != nil {
-		return nil, nil, err
+		return err
 	}
 
-	publicKey, err := base64.StdEncoding.DecodeString(publicKeyBase64)
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
 
+	if affected == 0 {
+		return ErrSignCountNotIncreasing
+	}
+
+	return nil
+}
+
+// DeleteU2FDevice delete a U2F device registered by a given user.
+func (p *SQLProvider) DeleteU2FDevice(username string, keyHandle []byte) error {
+	result, err := p.db.Exec(p.sqlDeleteU2FDevice, username, base64.StdEncoding.EncodeToString(keyHandle))
 	if err != nil {
-		return nil, nil, err
+		return err
 	}
 
-	return keyHandle, publicKey, nil
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrNoU2FDeviceHandle
+	}
+
+	return nil
+}
+
+// SaveWebauthnDevice save a registered Webauthn device.
+func (p *SQLProvider) SaveWebauthnDevice(device models.WebauthnDevice) error {
+	publicKey, err := p.encrypt(device.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.db.Exec(p.sqlInsertWebauthnDevice,
+		device.Username,
+		base64.StdEncoding.EncodeToString(device.KID),
+		publicKey,
+		device.AttestationType,
+		base64.StdEncoding.EncodeToString(device.AAGUID),
+		device.SignCount,
+		device.CreatedAt.Unix())
+
+	return err
+}
+
+// LoadWebauthnDevicesByUsername load all the Webauthn devices registered by a given user.
+func (p *SQLProvider) LoadWebauthnDevicesByUsername(username string) ([]models.WebauthnDevice, error) {
+	rows, err := p.db.Query(p.sqlGetWebauthnDevicesByUsername, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := make([]models.WebauthnDevice, 0, 1)
+
+	for rows.Next() {
+		var kid, publicKey, aaguid string
+
+		var createdAt int64
+
+		device := models.WebauthnDevice{
+			Username: username,
+		}
+
+		if err := rows.Scan(&kid, &publicKey, &device.AttestationType, &aaguid, &device.SignCount, &createdAt); err != nil {
+			return nil, err
+		}
+
+		if device.KID, err = base64.StdEncoding.DecodeString(kid); err != nil {
+			return nil, err
+		}
+
+		if device.PublicKey, err = p.decrypt(publicKey); err != nil {
+			return nil, err
+		}
+
+		if device.AAGUID, err = base64.StdEncoding.DecodeString(aaguid); err != nil {
+			return nil, err
+		}
+
+		device.CreatedAt = time.Unix(createdAt, 0)
+
+		devices = append(devices, device)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(devices) == 0 {
+		return nil, ErrNoWebauthnDevice
+	}
+
+	return devices, nil
+}
+
+// UpdateWebauthnDeviceSignCount update the signature counter of a Webauthn device after a successful assertion. Like
+// UpdateU2FDeviceCounter, ErrSignCountNotIncreasing is returned when the counter is not greater than the recorded one.
+func (p *SQLProvider) UpdateWebauthnDeviceSignCount(username string, kid []byte, signCount uint32) error {
+	result, err := p.db.Exec(p.sqlUpdateWebauthnDeviceSignCount, signCount, username, base64.StdEncoding.EncodeToString(kid), signCount)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrSignCountNotIncreasing
+	}
+
+	return nil
+}
+
+// DeleteWebauthnDevice delete a Webauthn device registered by a given user.
+func (p *SQLProvider) DeleteWebauthnDevice(username string, kid []byte) error {
+	result, err := p.db.Exec(p.sqlDeleteWebauthnDevice, username, base64.StdEncoding.EncodeToString(kid))
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return ErrNoWebauthnDevice
+	}
+
+	return nil
+}
+
+// SaveRecoveryCodes replace the recovery codes of a given user by a new set of codes.
+func (p *SQLProvider) SaveRecoveryCodes(username string, codes []models.RecoveryCode) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(p.sqlDeleteRecoveryCodesByUsername, username); err != nil {
+		return p.rollback(tx, err)
+	}
+
+	for _, code := range codes {
+		if _, err := tx.Exec(p.sqlInsertRecoveryCode, username, code.Lookup, code.Hash, unixTimestamp(code.CreatedAt)); err != nil {
+			return p.rollback(tx, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// LoadRecoveryCodesByUsername load the recovery codes a given user has not used yet.
+func (p *SQLProvider) LoadRecoveryCodesByUsername(username string) ([]models.RecoveryCode, error) {
+	rows, err := p.db.Query(p.sqlGetRecoveryCodesByUsername, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	codes := make([]models.RecoveryCode, 0, 10)
+
+	for rows.Next() {
+		var createdAt int64
+
+		code := models.RecoveryCode{
+			Username: username,
+		}
+
+		if err := rows.Scan(&code.Lookup, &code.Hash, &createdAt); err != nil {
+			return nil, err
+		}
+
+		code.CreatedAt = timeFromUnixTimestamp(createdAt)
+
+		codes = append(codes, code)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(codes) == 0 {
+		return nil, ErrNoRecoveryCode
+	}
+
+	return codes, nil
+}
+
+// DeleteRecoveryCode delete a recovery code of a given user once it has been used.
+// It returns ErrNoRecoveryCode if the code did not exist (e.g. it has already been used).
+func (p *SQLProvider) DeleteRecoveryCode(username string, hash string) error {
+	return p.deleteSingleRow(p.sqlDeleteRecoveryCode, ErrNoRecoveryCode, username, hash)
 }
 
 // AppendAuthenticationLog append a mark to the authentication log.
@@ -283,3 +838,223 @@ func (p *SQLProvider) LoadLatestAuthenticationLogs(username string, fromDate tim
 
 	return attempts, nil
 }
+
+// DeleteFailedAuthenticationLogs delete the failed attempts of a given user made after the given date, lifting the
+// ban they would otherwise result in.
+func (p *SQLProvider) DeleteFailedAuthenticationLogs(username string, fromDate time.Time) error {
+	_, err := p.db.Exec(p.sqlDeleteFailedAuthenticationLogs, username, false, fromDate.Unix())
+	return err
+}
+
+// AppendEmailOTPLog append a one-time passcode sent by email or a failed attempt to use it to the email one-time
+// passcode log.
+func (p *SQLProvider) AppendEmailOTPLog(log models.EmailOTPLog) error {
+	_, err := p.db.Exec(p.sqlInsertEmailOTPLog, log.Username, log.Event, log.Time.Unix())
+	return err
+}
+
+// LoadLatestEmailOTPLogs retrieve the entries of the email one-time passcode log of a given user made after the given
+// date, the latest first.
+func (p *SQLProvider) LoadLatestEmailOTPLogs(username string, fromDate time.Time) ([]models.EmailOTPLog, error) {
+	var t int64
+
+	rows, err := p.db.Query(p.sqlGetLatestEmailOTPLogs, fromDate.Unix(), username)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	logs := make([]models.EmailOTPLog, 0, 10)
+
+	for rows.Next() {
+		log := models.EmailOTPLog{
+			Username: username,
+		}
+
+		if err = rows.Scan(&log.Event, &t); err != nil {
+			return nil, err
+		}
+
+		log.Time = time.Unix(t, 0)
+		logs = append(logs, log)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return logs, nil
+}
+
+// SaveOAuth2AuthorizationCode save an OpenID Connect authorization code.
+func (p *SQLProvider) SaveOAuth2AuthorizationCode(code models.OAuth2AuthorizationCode) error {
+	_, err := p.db.Exec(p.sqlInsertOAuth2AuthorizationCode,
+		code.Signature,
+		code.ClientID,
+		code.Username,
+		strings.Join(code.Scopes, " "),
+		code.RedirectURI,
+		code.Nonce,
+		code.CodeChallenge,
+		code.CodeChallengeMethod,
+		strings.Join(code.AMR, " "),
+		code.ExpiresAt.Unix())
+
+	return err
+}
+
+// LoadOAuth2AuthorizationCode load an OpenID Connect authorization code given its signature.
+func (p *SQLProvider) LoadOAuth2AuthorizationCode(signature string) (*models.OAuth2AuthorizationCode, error) {
+	var scopes, amr string
+
+	var expiresAt int64
+
+	code := models.OAuth2AuthorizationCode{
+		Signature: signature,
+	}
+
+	if err := p.db.QueryRow(p.sqlGetOAuth2AuthorizationCode, signature).Scan(&code.ClientID, &code.Username, &scopes,
+		&code.RedirectURI, &code.Nonce, &code.CodeChallenge, &code.CodeChallengeMethod, &amr, &expiresAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNoOAuth2AuthorizationCode
+		}
+
+		return nil, err
+	}
+
+	code.Scopes = strings.Fields(scopes)
+	code.AMR = strings.Fields(amr)
+	code.ExpiresAt = time.Unix(expiresAt, 0)
+
+	return &code, nil
+}
+
+// DeleteOAuth2AuthorizationCode delete an OpenID Connect authorization code given its signature.
+// It returns ErrNoOAuth2AuthorizationCode if the code did not exist (e.g. it has already been used).
+func (p *SQLProvider) DeleteOAuth2AuthorizationCode(signature string) error {
+	return p.deleteSingleRow(p.sqlDeleteOAuth2AuthorizationCode, ErrNoOAuth2AuthorizationCode, signature)
+}
+
+// SaveOAuth2RefreshToken save an OpenID Connect refresh token.
+func (p *SQLProvider) SaveOAuth2RefreshToken(token models.OAuth2RefreshToken) error {
+	_, err := p.db.Exec(p.sqlInsertOAuth2RefreshToken,
+		token.Signature,
+		token.ClientID,
+		token.Username,
+		strings.Join(token.Scopes, " "),
+		strings.Join(token.AMR, " "),
+		token.ExpiresAt.Unix())
+
+	return err
+}
+
+// LoadOAuth2RefreshToken load an OpenID Connect refresh token given its signature.
+func (p *SQLProvider) LoadOAuth2RefreshToken(signature string) (*models.OAuth2RefreshToken, error) {
+	var scopes, amr string
+
+	var expiresAt int64
+
+	token := models.OAuth2RefreshToken{
+		Signature: signature,
+	}
+
+	if err := p.db.QueryRow(p.sqlGetOAuth2RefreshToken, signature).Scan(&token.ClientID, &token.Username, &scopes, &amr, &expiresAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNoOAuth2RefreshToken
+		}
+
+		return nil, err
+	}
+
+	token.Scopes = strings.Fields(scopes)
+	token.AMR = strings.Fields(amr)
+	token.ExpiresAt = time.Unix(expiresAt, 0)
+
+	return &token, nil
+}
+
+// DeleteOAuth2RefreshToken delete an OpenID Connect refresh token given its signature.
+// It returns ErrNoOAuth2RefreshToken if the token did not exist (e.g. it has already been used).
+func (p *SQLProvider) DeleteOAuth2RefreshToken(signature string) error {
+	return p.deleteSingleRow(p.sqlDeleteOAuth2RefreshToken, ErrNoOAuth2RefreshToken, signature)
+}
+
+// SaveOAuth2Consent save the consent given by a user to an OpenID Connect client.
+func (p *SQLProvider) SaveOAuth2Consent(consent models.OAuth2Consent) error {
+	_, err := p.db.Exec(p.sqlUpsertOAuth2Consent,
+		consent.Username,
+		consent.ClientID,
+		strings.Join(consent.Scopes, " "),
+		consent.GrantedAt.Unix())
+
+	return err
+}
+
+// LoadOAuth2Consent load the consent given by a user to an OpenID Connect client.
+func (p *SQLProvider) LoadOAuth2Consent(username string, clientID string) (*models.OAuth2Consent, error) {
+	var scopes string
+
+	var grantedAt int64
+
+	if err := p.db.QueryRow(p.sqlGetOAuth2Consent, username, clientID).Scan(&scopes, &grantedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNoOAuth2Consent
+		}
+
+		return nil, err
+	}
+
+	return &models.OAuth2Consent{
+		Username:  username,
+		ClientID:  clientID,
+		Scopes:    strings.Fields(scopes),
+		GrantedAt: time.Unix(grantedAt, 0),
+	}, nil
+}
+
+// rollback rolls back a transaction after err occurred.
+func (p *SQLProvider) rollback(tx *sql.Tx, err error) error {
+	if rollbackErr := tx.Rollback(); rollbackErr != nil {
+		return fmt.Errorf("rollback error occurred: %v (inner error %v)", rollbackErr, err)
+	}
+
+	return err
+}
+
+// deleteSingleRow executes a delete statement and returns errNotFound if no row has been affected.
+func (p *SQLProvider) deleteSingleRow(query string, errNotFound error, args ...interface{}) error {
+	result, err := p.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return errNotFound
+	}
+
+	return nil
+}
+
+// unixTimestamp converts a time to the timestamp stored in DB, the zero time being stored as 0.
+func unixTimestamp(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
+
+// timeFromUnixTimestamp converts a timestamp stored in DB to a time, 0 being the zero time.
+func timeFromUnixTimestamp(timestamp int64) time.Time {
+	if timestamp == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(timestamp, 0)
+}
@@ -3,8 +3,8 @@ package storage
 import (
 	"database/sql/driver"
 	"encoding/base64"
+	"errors"
 	"fmt"
-	"sort"
 	"testing"
 	"time"
 
@@ -13,80 +13,642 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/authelia/authelia/internal/authentication"
+	"github.com/authelia/authelia/internal/configuration/schema"
 	"github.com/authelia/authelia/internal/models"
+	"github.com/authelia/authelia/internal/utils"
 )
 
-const currentSchemaMockSchemaVersion = "1"
+const currentSchemaMockSchemaVersion = "12"
 
-func TestSQLInitializeDatabase(t *testing.T) {
-	provider, mock := NewSQLMockProvider()
+// The tables the rows are copied to while a table is recreated.
+const (
+	u2fDevicesTmpTableName  = "u2f_devices_tmp"
+	totpSecretsTmpTableName = "totp_secrets_tmp"
+)
+
+// encryptedArgument matches the values encrypted with a given key.
+type encryptedArgument struct {
+	key       [32]byte
+	plaintext string
+}
+
+func (a encryptedArgument) Match(v driver.Value) bool {
+	value, ok := v.(string)
+	if !ok {
+		return false
+	}
+
+	plaintext, err := decryptWithKey(value, &a.key)
+
+	return err == nil && string(plaintext) == a.plaintext
+}
 
+func encryptedWith(key, plaintext string) encryptedArgument {
+	return encryptedArgument{newEncryptionKey(key), plaintext}
+}
+
+func mustEncrypt(t *testing.T, key, plaintext string) string {
+	encryptionKey := newEncryptionKey(key)
+
+	value, err := encryptWithKey([]byte(plaintext), &encryptionKey)
+	require.NoError(t, err)
+
+	return value
+}
+
+func expectCreateTables(mock sqlmock.Sqlmock, tables ...string) {
+	for _, table := range tables {
+		mock.ExpectExec(
+			fmt.Sprintf("CREATE TABLE %s .*", table)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+}
+
+func expectSetSchemaVersion(mock sqlmock.Sqlmock, version string) {
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", version).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+// expectSchemaDetails expects the tables of the database and the version of the schema to be retrieved.
+func expectSchemaDetails(mock sqlmock.Sqlmock, version string, tables ...string) {
 	rows := sqlmock.NewRows([]string{"name"})
+	for _, table := range tables {
+		rows.AddRow(table)
+	}
+
 	mock.ExpectQuery(
 		"SELECT name FROM sqlite_master WHERE type='table'").
 		WillReturnRows(rows)
 
-	mock.ExpectBegin()
+	if utils.IsStringInSlice(configTableName, tables) {
+		mock.ExpectQuery(
+			fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+			WithArgs("schema", "version").
+			WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(version))
+	}
+}
+
+func expectSchemaUpgradeToVersion001(mock sqlmock.Sqlmock, indexExists bool) {
+	for _, table := range []string{authenticationLogsTableName, configTableName, identityVerificationTokensTableName,
+		totpSecretsTableName, u2fDeviceHandlesTableName, userPreferencesTableName} {
+		mock.ExpectExec(
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s .*", table)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	mock.ExpectQuery(
+		"SELECT EXISTS \\(SELECT \\* FROM sqlite_master WHERE type='index' AND tbl_name=\\? AND name=\\?\\)").
+		WithArgs(authenticationLogsTableName, "usr_time_idx").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(indexExists))
+
+	if !indexExists {
+		mock.ExpectExec(
+			fmt.Sprintf("CREATE INDEX usr_time_idx ON %s \\(username, time\\)", authenticationLogsTableName)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	expectSetSchemaVersion(mock, "1")
+}
+
+func expectSchemaUpgradeToVersion(mock sqlmock.Sqlmock, version SchemaVersion, tables ...string) {
+	expectCreateTables(mock, tables...)
+	expectSetSchemaVersion(mock, version.ToString())
+}
+
+func expectSchemaUpgradeToVersion004(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", u2fDevicesTmpTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("INSERT INTO %s \\(username, description, key_handle, public_key, created_at, last_used_at\\) "+
+			"SELECT username, 'Primary', keyHandle, publicKey, 0, 0 FROM %s", u2fDevicesTmpTableName, u2fDeviceHandlesTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("DROP TABLE %s", u2fDeviceHandlesTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", u2fDevicesTmpTableName, u2fDeviceHandlesTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "4").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func expectSchemaUpgradeToVersion005(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN counter INTEGER NOT NULL DEFAULT 0", u2fDeviceHandlesTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "5").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func expectSchemaUpgradeToVersion006(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", totpSecretsTmpTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("INSERT INTO %s \\(username, id, description, secret, created_at, last_used_at\\) "+
+			"SELECT username, 'primary', 'Primary', secret, 0, 0 FROM %s", totpSecretsTmpTableName, totpSecretsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("DROP TABLE %s", totpSecretsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", totpSecretsTmpTableName, totpSecretsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "6").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func expectSchemaUpgradeToVersion007(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN algorithm VARCHAR\\(6\\) NOT NULL DEFAULT 'SHA1'", totpSecretsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN digits INTEGER NOT NULL DEFAULT 6", totpSecretsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN period INTEGER NOT NULL DEFAULT 0", totpSecretsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "7").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func expectSchemaUpgradeToVersion008(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN last_step BIGINT NOT NULL DEFAULT 0", totpSecretsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "8").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func expectSchemaUpgradeToVersion010(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", totpSecretsTmpTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("INSERT INTO %s \\(username, id, description, secret, algorithm, digits, period, last_step, created_at, last_used_at\\) "+
+			"SELECT username, id, description, secret, algorithm, digits, period, last_step, created_at, last_used_at FROM %s", totpSecretsTmpTableName, totpSecretsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("DROP TABLE %s", totpSecretsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", totpSecretsTmpTableName, totpSecretsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, id, secret FROM %s", totpSecretsTableName)).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "id", "secret"}))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, key_handle, public_key FROM %s", u2fDeviceHandlesTableName)).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "key_handle", "public_key"}))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, kid, public_key FROM %s", webauthnDevicesTableName)).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "kid", "public_key"}))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs(encryptionCheckCategory, encryptionCheckKeyName, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "10").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func expectSchemaUpgradeFromVersion001(mock sqlmock.Sqlmock) {
+	expectSchemaUpgradeToVersion(mock, 2, oauth2AuthorizationCodesTableName, oauth2ConsentTableName, oauth2RefreshTokensTableName)
+	expectSchemaUpgradeToVersion(mock, 3, webauthnDevicesTableName)
+	expectSchemaUpgradeToVersion004(mock)
+	expectSchemaUpgradeToVersion005(mock)
+	expectSchemaUpgradeToVersion006(mock)
+	expectSchemaUpgradeToVersion007(mock)
+	expectSchemaUpgradeToVersion008(mock)
+	expectSchemaUpgradeToVersion(mock, 9, recoveryCodesTableName)
+	expectSchemaUpgradeToVersion010(mock)
+	expectSchemaUpgradeToVersion011(mock)
+	expectSchemaUpgradeToVersion012(mock)
+}
+
+func expectSchemaUpgradeToVersion011(mock sqlmock.Sqlmock) {
+	expectCreateTables(mock, emailOTPLogsTableName)
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE INDEX email_otp_usr_time_idx ON %s \\(username, time\\)", emailOTPLogsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	expectSetSchemaVersion(mock, "11")
+}
+
+func expectSchemaUpgradeToVersion012(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN lookup VARCHAR\\(8\\) NOT NULL DEFAULT ''", recoveryCodesTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	expectSetSchemaVersion(mock, "12")
+}
+
+func TestSQLMigrationsShouldBeOrdered(t *testing.T) {
+	require.Len(t, migrations, int(SchemaLatestVersion))
+
+	for i, m := range migrations {
+		assert.Equal(t, SchemaVersion(i+1), m.version)
+		assert.NotEmpty(t, m.name)
+		assert.NotEmpty(t, m.up.forProvider(providerSQLite), "migration to v%d has no up statements", m.version)
+		assert.NotEmpty(t, m.down.forProvider(providerSQLite), "migration to v%d has no down statements", m.version)
+	}
+}
+
+func TestSQLInitializeDatabase(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	expectSchemaDetails(mock, "")
+
+	mock.ExpectBegin()
+
+	expectSchemaDetails(mock, "")
+	expectSchemaUpgradeToVersion001(mock, false)
+	expectSchemaUpgradeFromVersion001(mock)
+
+	mock.ExpectCommit()
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLUpgradeDatabase(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	tables := []string{userPreferencesTableName, identityVerificationTokensTableName, totpSecretsTableName,
+		u2fDeviceHandlesTableName, authenticationLogsTableName}
+
+	expectSchemaDetails(mock, "", tables...)
+
+	mock.ExpectBegin()
+
+	expectSchemaDetails(mock, "", tables...)
+	expectSchemaUpgradeToVersion001(mock, true)
+	expectSchemaUpgradeFromVersion001(mock)
+
+	mock.ExpectCommit()
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLUpgradeDatabaseShouldRollbackOnFailure(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	expectSchemaDetails(mock, "8", configTableName)
+
+	mock.ExpectBegin()
+
+	expectSchemaDetails(mock, "8", configTableName)
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s .*", recoveryCodesTableName)).
+		WillReturnError(errors.New("table already exists"))
+
+	mock.ExpectRollback()
+
+	err := provider.initialize(provider.db)
+	assert.EqualError(t, err, "storage schema upgrade failed at v9: table already exists")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLUpgradeDatabaseShouldSkipMigrationsAppliedByAnotherInstance(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	expectSchemaDetails(mock, "9", configTableName)
+
+	mock.ExpectBegin()
+
+	expectSchemaDetails(mock, currentSchemaMockSchemaVersion, configTableName)
+
+	mock.ExpectCommit()
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderShouldRefuseSchemaNewerThanSupported(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	expectSchemaDetails(mock, "13", configTableName)
+
+	err := provider.initialize(provider.db)
+	assert.True(t, errors.Is(err, ErrSchemaNewerThanSupported))
+	assert.EqualError(t, err, "The schema of the database is newer than the one supported by this version of Authelia: "+
+		"the schema is v13 but the latest version supported is v12")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderShouldRefuseUnknownTargetVersion(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	provider.connect(provider.db)
+
+	migrations, err := provider.MigrateTo(SchemaLatestVersion+1, false)
+	assert.EqualError(t, err, "Unknown schema version: v13")
+	assert.Nil(t, migrations)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderShouldDescribeMigrationsInDryRun(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	provider.connect(provider.db)
+
+	expectSchemaDetails(mock, "8", configTableName)
+
+	migrations, err := provider.MigrateTo(SchemaLatestVersion, true)
+	require.NoError(t, err)
+	require.Len(t, migrations, 4)
+
+	assert.Equal(t, SchemaVersion(9), migrations[0].Version)
+	assert.Equal(t, "Create the recovery codes table", migrations[0].Name)
+	assert.True(t, migrations[0].Up)
+	assert.Equal(t, []string{"CREATE TABLE recovery_codes (username VARCHAR(100) NOT NULL, hash VARCHAR(256) NOT NULL, " +
+		"created_at INTEGER, PRIMARY KEY (username, hash))"}, migrations[0].Statements)
+	assert.Equal(t, "", migrations[0].Data)
+
+	assert.Equal(t, SchemaVersion(10), migrations[1].Version)
+	assert.Len(t, migrations[1].Statements, 4)
+	assert.NotEqual(t, "", migrations[1].Data)
+
+	assert.Equal(t, SchemaVersion(11), migrations[2].Version)
+	assert.Equal(t, []string{"CREATE TABLE email_otp_logs (username VARCHAR(100) NOT NULL, event VARCHAR(16) NOT NULL, " +
+		"time INTEGER NOT NULL)", "CREATE INDEX email_otp_usr_time_idx ON email_otp_logs (username, time)"},
+		migrations[2].Statements)
+
+	assert.Equal(t, SchemaVersion(12), migrations[3].Version)
+	assert.Equal(t, []string{"ALTER TABLE recovery_codes ADD COLUMN lookup VARCHAR(8) NOT NULL DEFAULT ''"}, migrations[3].Statements)
+
+	expectSchemaDetails(mock, "8", configTableName)
+
+	migrations, err = provider.MigrateTo(8, true)
+	require.NoError(t, err)
+	assert.Len(t, migrations, 0)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLDowngradeDatabase(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	provider.connect(provider.db)
+
+	expectSchemaDetails(mock, currentSchemaMockSchemaVersion, configTableName)
+
+	mock.ExpectBegin()
+
+	expectSchemaDetails(mock, currentSchemaMockSchemaVersion, configTableName)
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s_tmp \\(username VARCHAR\\(100\\) NOT NULL, hash VARCHAR\\(256\\) NOT NULL, .*\\)", recoveryCodesTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("INSERT INTO %[1]s_tmp \\(username, hash, created_at\\) SELECT username, hash, created_at FROM %[1]s", recoveryCodesTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("DROP TABLE %s", recoveryCodesTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %[1]s_tmp RENAME TO %[1]s", recoveryCodesTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	expectSetSchemaVersion(mock, "11")
+
+	mock.ExpectExec(
+		fmt.Sprintf("DROP TABLE %s", emailOTPLogsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	expectSetSchemaVersion(mock, "10")
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, id, secret FROM %s", totpSecretsTableName)).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "id", "secret"}).
+			AddRow(unitTestUser, "primary", mustEncrypt(t, unitTestEncryptionKey, "JBSWY3DPEHPK3PXP")))
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET secret=\\? WHERE username=\\? AND id=\\?", totpSecretsTableName)).
+		WithArgs("JBSWY3DPEHPK3PXP", unitTestUser, "primary").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, key_handle, public_key FROM %s", u2fDeviceHandlesTableName)).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "key_handle", "public_key"}).
+			AddRow(unitTestUser, "YWJj", mustEncrypt(t, unitTestEncryptionKey, "123")))
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET public_key=\\? WHERE username=\\? AND key_handle=\\?", u2fDeviceHandlesTableName)).
+		WithArgs("MTIz", unitTestUser, "YWJj").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, kid, public_key FROM %s", webauthnDevicesTableName)).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "kid", "public_key"}))
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs(encryptionCheckCategory, encryptionCheckKeyName).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("CREATE TABLE %s \\(.* secret VARCHAR\\(64\\) NOT NULL, .*\\)", totpSecretsTmpTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("INSERT INTO %s \\(username, id, description, secret, algorithm, digits, period, last_step, created_at, last_used_at\\) "+
+			"SELECT username, id, description, secret, algorithm, digits, period, last_step, created_at, last_used_at FROM %s", totpSecretsTmpTableName, totpSecretsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("DROP TABLE %s", totpSecretsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", totpSecretsTmpTableName, totpSecretsTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	expectSetSchemaVersion(mock, "9")
+
+	mock.ExpectExec(
+		fmt.Sprintf("DROP TABLE %s", recoveryCodesTableName)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	expectSetSchemaVersion(mock, "8")
+
+	mock.ExpectCommit()
+
+	migrations, err := provider.MigrateTo(8, false)
+	require.NoError(t, err)
+	require.Len(t, migrations, 4)
+	assert.Equal(t, SchemaVersion(12), migrations[0].Version)
+	assert.False(t, migrations[0].Up)
+	assert.Equal(t, SchemaVersion(11), migrations[1].Version)
+	assert.False(t, migrations[1].Up)
+	assert.Equal(t, SchemaVersion(10), migrations[2].Version)
+	assert.False(t, migrations[2].Up)
+	assert.Equal(t, SchemaVersion(9), migrations[3].Version)
+	assert.False(t, migrations[3].Up)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLDowngradeDatabaseShouldNotSaveVersionOnceConfigTableIsDropped(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	provider.connect(provider.db)
+
+	expectSchemaDetails(mock, "1", configTableName)
 
-	keys := make([]string, 0, len(sqlUpgradeCreateTableStatements[1]))
-	for k := range sqlUpgradeCreateTableStatements[1] {
-		keys = append(keys, k)
-	}
+	mock.ExpectBegin()
 
-	sort.Strings(keys)
+	expectSchemaDetails(mock, "1", configTableName)
 
-	for _, table := range keys {
+	for _, table := range []string{userPreferencesTableName, u2fDeviceHandlesTableName, totpSecretsTableName,
+		identityVerificationTokensTableName, configTableName, authenticationLogsTableName} {
 		mock.ExpectExec(
-			fmt.Sprintf("CREATE TABLE %s .*", table)).
+			fmt.Sprintf("DROP TABLE %s", table)).
 			WillReturnResult(sqlmock.NewResult(0, 0))
 	}
 
-	mock.ExpectExec(
-		fmt.Sprintf("CREATE INDEX IF NOT EXISTS usr_time_idx ON %s .*", authenticationLogsTableName)).
-		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
 
-	mock.ExpectExec(
-		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
-		WithArgs("schema", "version", "1").
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	_, err := provider.MigrateTo(0, false)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderShouldLockSchemaMigrations(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	provider.connect(provider.db)
+
+	provider.sqlLockSchemaMigrations = "SELECT GET_LOCK('authelia_schema_migration', 60)"
+	provider.sqlUnlockSchemaMigrations = "SELECT RELEASE_LOCK('authelia_schema_migration')"
+
+	expectSchemaDetails(mock, "8", configTableName)
+
+	mock.ExpectBegin()
+
+	mock.ExpectQuery(
+		"SELECT GET_LOCK\\('authelia_schema_migration', 60\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(1))
+
+	expectSchemaDetails(mock, "8", configTableName)
+	expectSchemaUpgradeToVersion(mock, 9, recoveryCodesTableName)
+
+	mock.ExpectQuery(
+		"SELECT RELEASE_LOCK\\('authelia_schema_migration'\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"released"}).AddRow(1))
 
 	mock.ExpectCommit()
 
-	err := provider.initialize(provider.db)
+	_, err := provider.MigrateTo(9, false)
 	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestSQLUpgradeDatabase(t *testing.T) {
+func TestSQLProviderShouldFailWhenSchemaMigrationsAreLocked(t *testing.T) {
 	provider, mock := NewSQLMockProvider()
 
+	provider.connect(provider.db)
+
+	provider.sqlLockSchemaMigrations = "SELECT GET_LOCK('authelia_schema_migration', 60)"
+
+	expectSchemaDetails(mock, "8", configTableName)
+
+	mock.ExpectBegin()
+
 	mock.ExpectQuery(
-		"SELECT name FROM sqlite_master WHERE type='table'").
-		WillReturnRows(sqlmock.NewRows([]string{"name"}).
-			AddRow(userPreferencesTableName).
-			AddRow(identityVerificationTokensTableName).
-			AddRow(totpSecretsTableName).
-			AddRow(u2fDeviceHandlesTableName).
-			AddRow(authenticationLogsTableName))
+		"SELECT GET_LOCK\\('authelia_schema_migration', 60\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(0))
+
+	mock.ExpectRollback()
+
+	_, err := provider.MigrateTo(9, false)
+	assert.Equal(t, ErrSchemaMigrationLocked, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQLProviderShouldCreateMissingIndex(t *testing.T) {
+	provider := NewMySQLProvider(schema.MySQLStorageConfiguration{
+		SQLStorageConfiguration: schema.SQLStorageConfiguration{Host: "localhost", Database: "authelia"},
+	}, unitTestEncryptionKey)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	provider.connect(db)
 
 	mock.ExpectBegin()
 
-	mock.ExpectExec(
-		fmt.Sprintf("CREATE TABLE %s .*", configTableName)).
-		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(
+		"SELECT EXISTS \\(SELECT \\* FROM information_schema.statistics WHERE table_schema=database\\(\\) AND table_name=\\? AND index_name=\\?\\)").
+		WithArgs(authenticationLogsTableName, "usr_time_idx").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
 
 	mock.ExpectExec(
-		fmt.Sprintf("CREATE INDEX IF NOT EXISTS usr_time_idx ON %s .*", authenticationLogsTableName)).
+		fmt.Sprintf("CREATE INDEX usr_time_idx ON %s \\(username, time\\)", authenticationLogsTableName)).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 
-	mock.ExpectExec(
-		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
-		WithArgs("schema", "version", "1").
-		WillReturnResult(sqlmock.NewResult(1, 1))
+	tx, err := provider.db.Begin()
+	require.NoError(t, err)
 
-	mock.ExpectCommit()
+	assert.NoError(t, migrations[0].upData(&provider.SQLProvider, tx))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
 
-	err := provider.initialize(provider.db)
-	assert.NoError(t, err)
+func TestSQLMigrationsShouldDropColumnsUnlessSQLite(t *testing.T) {
+	down := migrations[7].down
+
+	assert.Equal(t, []string{"ALTER TABLE totp_secrets DROP COLUMN last_step"}, down.forProvider(providerMySQL))
+	assert.Equal(t, []string{"ALTER TABLE totp_secrets DROP COLUMN last_step"}, down.forProvider(providerPostgres))
+	assert.Equal(t, []string{
+		"CREATE TABLE totp_secrets_tmp (username VARCHAR(100) NOT NULL, id VARCHAR(32) NOT NULL, description VARCHAR(64) NOT NULL, secret VARCHAR(64) NOT NULL, algorithm VARCHAR(6) NOT NULL DEFAULT 'SHA1', digits INTEGER NOT NULL DEFAULT 6, period INTEGER NOT NULL DEFAULT 0, created_at INTEGER, last_used_at INTEGER, PRIMARY KEY (username, id))",
+		"INSERT INTO totp_secrets_tmp (username, id, description, secret, algorithm, digits, period, created_at, last_used_at) SELECT username, id, description, secret, algorithm, digits, period, created_at, last_used_at FROM totp_secrets",
+		"DROP TABLE totp_secrets",
+		"ALTER TABLE totp_secrets_tmp RENAME TO totp_secrets",
+	}, down.forProvider(providerSQLite))
 }
 
 func TestSQLProviderMethodsAuthenticationLogs(t *testing.T) {
@@ -107,7 +669,7 @@ func TestSQLProviderMethodsAuthenticationLogs(t *testing.T) {
 		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
 		WithArgs(args...).
 		WillReturnRows(sqlmock.NewRows([]string{"value"}).
-			AddRow("1"))
+			AddRow(currentSchemaMockSchemaVersion))
 
 	err := provider.initialize(provider.db)
 	assert.NoError(t, err)
@@ -154,16 +716,338 @@ func TestSQLProviderMethodsAuthenticationLogs(t *testing.T) {
 
 	// Test Blank Rows.
 	mock.ExpectQuery(
-		fmt.Sprintf("SELECT successful, time FROM %s WHERE time>\\? AND username=\\? ORDER BY time DESC", authenticationLogsTableName)).
-		WithArgs(args...).
-		WillReturnRows(sqlmock.NewRows([]string{"successful", "time"}))
+		fmt.Sprintf("SELECT successful, time FROM %s WHERE time>\\? AND username=\\? ORDER BY time DESC", authenticationLogsTableName)).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"successful", "time"}))
+
+	results, err = provider.LoadLatestAuthenticationLogs(unitTestUser, after)
+	assert.NoError(t, err)
+	assert.Len(t, results, 0)
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE username=\\? AND successful=\\? AND time>\\?", authenticationLogsTableName)).
+		WithArgs(unitTestUser, false, 1577880000).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.DeleteFailedAuthenticationLogs(unitTestUser, after)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderMethodsEmailOTPLogs(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	expectSchemaDetails(mock, currentSchemaMockSchemaVersion, configTableName, emailOTPLogsTableName)
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+
+	logs := []models.EmailOTPLog{
+		{Username: unitTestUser, Event: "sent", Time: time.Unix(1577880001, 0)},
+		{Username: unitTestUser, Event: "failed", Time: time.Unix(1577880002, 0)},
+	}
+
+	rows := sqlmock.NewRows([]string{"event", "time"})
+
+	for id, log := range logs {
+		mock.ExpectExec(
+			fmt.Sprintf("INSERT INTO %s \\(username, event, time\\) VALUES \\(\\?, \\?, \\?\\)", emailOTPLogsTableName)).
+			WithArgs(log.Username, log.Event, log.Time.Unix()).
+			WillReturnResult(sqlmock.NewResult(int64(id), 1))
+
+		err := provider.AppendEmailOTPLog(log)
+		assert.NoError(t, err)
+	}
+
+	rows.AddRow("failed", 1577880002)
+	rows.AddRow("sent", 1577880001)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT event, time FROM %s WHERE time>\\? AND username=\\? ORDER BY time DESC", emailOTPLogsTableName)).
+		WithArgs(1577880000, unitTestUser).
+		WillReturnRows(rows)
+
+	results, err := provider.LoadLatestEmailOTPLogs(unitTestUser, time.Unix(1577880000, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, []models.EmailOTPLog{logs[1], logs[0]}, results)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderMethodsPreferred(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(userPreferencesTableName).
+			AddRow(identityVerificationTokensTableName).
+			AddRow(totpSecretsTableName).
+			AddRow(u2fDeviceHandlesTableName).
+			AddRow(authenticationLogsTableName).
+			AddRow(configTableName))
+
+	args := []driver.Value{"schema", "version"}
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).
+			AddRow(currentSchemaMockSchemaVersion))
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(username, second_factor_method\\) VALUES \\(\\?, \\?\\)", userPreferencesTableName)).
+		WithArgs(unitTestUser, authentication.TOTP).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.SavePreferred2FAMethod(unitTestUser, authentication.TOTP)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT second_factor_method FROM %s WHERE username=\\?", userPreferencesTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"second_factor_method"}).AddRow(authentication.TOTP))
+
+	method, err := provider.LoadPreferred2FAMethod(unitTestUser)
+	assert.NoError(t, err)
+	assert.Equal(t, authentication.TOTP, method)
+
+	// Test Blank Rows.
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT second_factor_method FROM %s WHERE username=\\?", userPreferencesTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"second_factor_method"}))
+
+	method, err = provider.LoadPreferred2FAMethod(unitTestUser)
+	assert.NoError(t, err)
+	assert.Equal(t, "", method)
+}
+
+func TestSQLProviderMethodsTOTP(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(userPreferencesTableName).
+			AddRow(identityVerificationTokensTableName).
+			AddRow(totpSecretsTableName).
+			AddRow(u2fDeviceHandlesTableName).
+			AddRow(authenticationLogsTableName).
+			AddRow(configTableName))
+
+	args := []driver.Value{"schema", "version"}
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).
+			AddRow(currentSchemaMockSchemaVersion))
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+
+	device := models.TOTPDevice{
+		Username:    unitTestUser,
+		ID:          "k3b9Qz",
+		Description: "Phone",
+		Secret:      "abc123",
+		Algorithm:   "SHA256",
+		Digits:      8,
+		Period:      60,
+		CreatedAt:   time.Unix(1600000000, 0),
+	}
+
+	mock.ExpectExec(
+		fmt.Sprintf("INSERT INTO %s \\(username, id, description, secret, algorithm, digits, period, created_at, last_used_at\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?, \\?\\)", totpSecretsTableName)).
+		WithArgs(unitTestUser, "k3b9Qz", "Phone", encryptedWith(unitTestEncryptionKey, "abc123"), "SHA256", uint(8), uint(60), int64(1600000000), int64(0)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.SaveTOTPDevice(device)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT id, description, secret, algorithm, digits, period, last_step, created_at, last_used_at FROM %s WHERE username=\\? ORDER BY created_at", totpSecretsTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "description", "secret", "algorithm", "digits", "period", "last_step", "created_at", "last_used_at"}).
+			AddRow("primary", "Primary", mustEncrypt(t, unitTestEncryptionKey, "def456"), "SHA1", int64(6), int64(0), int64(53333336), int64(0), int64(1600000100)).
+			AddRow("k3b9Qz", "Phone", mustEncrypt(t, unitTestEncryptionKey, "abc123"), "SHA256", int64(8), int64(60), int64(0), int64(1600000000), int64(0)))
+
+	devices, err := provider.LoadTOTPDevicesByUsername(unitTestUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.TOTPDevice{
+		{
+			Username:    unitTestUser,
+			ID:          "primary",
+			Description: "Primary",
+			Secret:      "def456",
+			Algorithm:   "SHA1",
+			Digits:      6,
+			LastStep:    53333336,
+			LastUsedAt:  time.Unix(1600000100, 0),
+		},
+		device,
+	}, devices)
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET last_step=\\?, last_used_at=\\? WHERE username=\\? AND id=\\? AND last_step<\\?", totpSecretsTableName)).
+		WithArgs(uint64(26666670), int64(1600000200), unitTestUser, "k3b9Qz", uint64(26666670)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.UpdateTOTPDeviceLastStep(unitTestUser, "k3b9Qz", 26666670, time.Unix(1600000200, 0))
+	assert.NoError(t, err)
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET last_step=\\?, last_used_at=\\? WHERE username=\\? AND id=\\? AND last_step<\\?", totpSecretsTableName)).
+		WithArgs(uint64(26666670), int64(1600000210), unitTestUser, "k3b9Qz", uint64(26666670)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = provider.UpdateTOTPDeviceLastStep(unitTestUser, "k3b9Qz", 26666670, time.Unix(1600000210, 0))
+	assert.EqualError(t, err, "TOTP time step has already been used")
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE username=\\? AND id=\\?", totpSecretsTableName)).
+		WithArgs(unitTestUser, "k3b9Qz").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.DeleteTOTPDevice(unitTestUser, "k3b9Qz")
+	assert.NoError(t, err)
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE username=\\? AND id=\\?", totpSecretsTableName)).
+		WithArgs(unitTestUser, "k3b9Qz").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = provider.DeleteTOTPDevice(unitTestUser, "k3b9Qz")
+	assert.EqualError(t, err, "No TOTP secret registered")
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE username=\\?", totpSecretsTableName)).
+		WithArgs(unitTestUser).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.DeleteTOTPDevicesByUsername(unitTestUser)
+	assert.NoError(t, err)
+
+	// Test Blank Rows.
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT id, description, secret, algorithm, digits, period, last_step, created_at, last_used_at FROM %s WHERE username=\\? ORDER BY created_at", totpSecretsTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "description", "secret", "algorithm", "digits", "period", "last_step", "created_at", "last_used_at"}))
+
+	devices, err = provider.LoadTOTPDevicesByUsername(unitTestUser)
+	assert.EqualError(t, err, "No TOTP secret registered")
+	assert.Nil(t, devices)
+}
+
+func TestSQLProviderMethodsU2F(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		"SELECT name FROM sqlite_master WHERE type='table'").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).
+			AddRow(userPreferencesTableName).
+			AddRow(identityVerificationTokensTableName).
+			AddRow(totpSecretsTableName).
+			AddRow(u2fDeviceHandlesTableName).
+			AddRow(authenticationLogsTableName).
+			AddRow(configTableName))
+
+	args := []driver.Value{"schema", "version"}
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).
+			AddRow(currentSchemaMockSchemaVersion))
+
+	err := provider.initialize(provider.db)
+	assert.NoError(t, err)
+
+	device := models.U2FDevice{
+		Username:    unitTestUser,
+		Description: "Backup",
+		KeyHandle:   []byte("abc"),
+		PublicKey:   []byte("123"),
+		Counter:     3,
+		CreatedAt:   time.Unix(1600000000, 0),
+	}
+	keyHandleB64 := base64.StdEncoding.EncodeToString(device.KeyHandle)
+	publicKeyEncrypted := mustEncrypt(t, unitTestEncryptionKey, "123")
+
+	mock.ExpectExec(
+		fmt.Sprintf("INSERT INTO %s \\(username, description, key_handle, public_key, counter, created_at, last_used_at\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?, \\?\\)", u2fDeviceHandlesTableName)).
+		WithArgs(unitTestUser, "Backup", keyHandleB64, encryptedWith(unitTestEncryptionKey, "123"), uint32(3), int64(1600000000), int64(0)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.SaveU2FDevice(device)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT description, key_handle, public_key, counter, created_at, last_used_at FROM %s WHERE username=\\? ORDER BY created_at", u2fDeviceHandlesTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"description", "key_handle", "public_key", "counter", "created_at", "last_used_at"}).
+			AddRow("Primary", base64.StdEncoding.EncodeToString([]byte("def")), publicKeyEncrypted, int64(12), int64(0), int64(1600000100)).
+			AddRow("Backup", keyHandleB64, publicKeyEncrypted, int64(3), int64(1600000000), int64(0)))
+
+	devices, err := provider.LoadU2FDevicesByUsername(unitTestUser)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.U2FDevice{
+		{
+			Username:    unitTestUser,
+			Description: "Primary",
+			KeyHandle:   []byte("def"),
+			PublicKey:   []byte("123"),
+			Counter:     12,
+			LastUsedAt:  time.Unix(1600000100, 0),
+		},
+		device,
+	}, devices)
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET counter=\\?, last_used_at=\\? WHERE username=\\? AND key_handle=\\? AND \\(counter<\\? OR counter=0\\)", u2fDeviceHandlesTableName)).
+		WithArgs(uint32(4), int64(1600000200), unitTestUser, keyHandleB64, uint32(4)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.UpdateU2FDeviceCounter(unitTestUser, device.KeyHandle, 4, time.Unix(1600000200, 0))
+	assert.NoError(t, err)
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET counter=\\?, last_used_at=\\? WHERE username=\\? AND key_handle=\\? AND \\(counter<\\? OR counter=0\\)", u2fDeviceHandlesTableName)).
+		WithArgs(uint32(3), int64(1600000200), unitTestUser, keyHandleB64, uint32(3)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = provider.UpdateU2FDeviceCounter(unitTestUser, device.KeyHandle, 3, time.Unix(1600000200, 0))
+	assert.Equal(t, ErrSignCountNotIncreasing, err)
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE username=\\? AND key_handle=\\?", u2fDeviceHandlesTableName)).
+		WithArgs(unitTestUser, keyHandleB64).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.DeleteU2FDevice(unitTestUser, device.KeyHandle)
+	assert.NoError(t, err)
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE username=\\? AND key_handle=\\?", u2fDeviceHandlesTableName)).
+		WithArgs(unitTestUser, keyHandleB64).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = provider.DeleteU2FDevice(unitTestUser, device.KeyHandle)
+	assert.EqualError(t, err, "No U2F device handle found")
+
+	// Test Blank Rows.
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT description, key_handle, public_key, counter, created_at, last_used_at FROM %s WHERE username=\\? ORDER BY created_at", u2fDeviceHandlesTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"description", "key_handle", "public_key", "counter", "created_at", "last_used_at"}))
 
-	results, err = provider.LoadLatestAuthenticationLogs(unitTestUser, after)
-	assert.NoError(t, err)
-	assert.Len(t, results, 0)
+	devices, err = provider.LoadU2FDevicesByUsername(unitTestUser)
+	assert.EqualError(t, err, "No U2F device handle found")
+	assert.Nil(t, devices)
 }
 
-func TestSQLProviderMethodsPreferred(t *testing.T) {
+func TestSQLProviderMethodsIdentityVerificationTokens(t *testing.T) {
 	provider, mock := NewSQLMockProvider()
 
 	mock.ExpectQuery(
@@ -186,35 +1070,46 @@ func TestSQLProviderMethodsPreferred(t *testing.T) {
 	err := provider.initialize(provider.db)
 	assert.NoError(t, err)
 
+	fakeIdentityVerificationToken := "abc"
+
 	mock.ExpectExec(
-		fmt.Sprintf("REPLACE INTO %s \\(username, second_factor_method\\) VALUES \\(\\?, \\?\\)", userPreferencesTableName)).
-		WithArgs(unitTestUser, authentication.TOTP).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+		fmt.Sprintf("INSERT INTO %s \\(token\\) VALUES \\(\\?\\)", identityVerificationTokensTableName)).
+		WithArgs(fakeIdentityVerificationToken).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	err = provider.SavePreferred2FAMethod(unitTestUser, authentication.TOTP)
+	err = provider.SaveIdentityVerificationToken(fakeIdentityVerificationToken)
 	assert.NoError(t, err)
 
 	mock.ExpectQuery(
-		fmt.Sprintf("SELECT second_factor_method FROM %s WHERE username=\\?", userPreferencesTableName)).
-		WithArgs(unitTestUser).
-		WillReturnRows(sqlmock.NewRows([]string{"second_factor_method"}).AddRow(authentication.TOTP))
+		fmt.Sprintf("SELECT EXISTS \\(SELECT \\* FROM %s WHERE token=\\?\\)", identityVerificationTokensTableName)).
+		WithArgs(fakeIdentityVerificationToken).
+		WillReturnRows(sqlmock.NewRows([]string{"EXISTS"}).
+			AddRow(true))
 
-	method, err := provider.LoadPreferred2FAMethod(unitTestUser)
+	valid, err := provider.FindIdentityVerificationToken(fakeIdentityVerificationToken)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE token=\\?", identityVerificationTokensTableName)).
+		WithArgs(fakeIdentityVerificationToken).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.RemoveIdentityVerificationToken(fakeIdentityVerificationToken)
 	assert.NoError(t, err)
-	assert.Equal(t, authentication.TOTP, method)
 
-	// Test Blank Rows.
 	mock.ExpectQuery(
-		fmt.Sprintf("SELECT second_factor_method FROM %s WHERE username=\\?", userPreferencesTableName)).
-		WithArgs(unitTestUser).
-		WillReturnRows(sqlmock.NewRows([]string{"second_factor_method"}))
+		fmt.Sprintf("SELECT EXISTS \\(SELECT \\* FROM %s WHERE token=\\?\\)", identityVerificationTokensTableName)).
+		WithArgs(fakeIdentityVerificationToken).
+		WillReturnRows(sqlmock.NewRows([]string{"EXISTS"}).
+			AddRow(false))
 
-	method, err = provider.LoadPreferred2FAMethod(unitTestUser)
+	valid, err = provider.FindIdentityVerificationToken(fakeIdentityVerificationToken)
 	assert.NoError(t, err)
-	assert.Equal(t, "", method)
+	assert.False(t, valid)
 }
 
-func TestSQLProviderMethodsTOTP(t *testing.T) {
+func TestSQLProviderMethodsOAuth2(t *testing.T) {
 	provider, mock := NewSQLMockProvider()
 
 	mock.ExpectQuery(
@@ -225,7 +1120,10 @@ func TestSQLProviderMethodsTOTP(t *testing.T) {
 			AddRow(totpSecretsTableName).
 			AddRow(u2fDeviceHandlesTableName).
 			AddRow(authenticationLogsTableName).
-			AddRow(configTableName))
+			AddRow(configTableName).
+			AddRow(oauth2AuthorizationCodesTableName).
+			AddRow(oauth2RefreshTokensTableName).
+			AddRow(oauth2ConsentTableName))
 
 	args := []driver.Value{"schema", "version"}
 	mock.ExpectQuery(
@@ -237,46 +1135,133 @@ func TestSQLProviderMethodsTOTP(t *testing.T) {
 	err := provider.initialize(provider.db)
 	assert.NoError(t, err)
 
-	pretendSecret := "abc123"
-	args = []driver.Value{unitTestUser, pretendSecret}
+	code := models.OAuth2AuthorizationCode{
+		Signature:           "abc",
+		ClientID:            "myapp",
+		Username:            unitTestUser,
+		Scopes:              []string{"openid", "groups"},
+		RedirectURI:         "https://app.example.com/callback",
+		Nonce:               "nonce",
+		CodeChallenge:       "challenge",
+		CodeChallengeMethod: "S256",
+		AMR:                 []string{"pwd", "mfa"},
+		ExpiresAt:           time.Unix(1577880001, 0),
+	}
+
+	mock.ExpectExec(
+		fmt.Sprintf("INSERT INTO %s \\(signature, client_id, username, scopes, redirect_uri, nonce, code_challenge, code_challenge_method, amr, expires_at\\) VALUES .*", oauth2AuthorizationCodesTableName)).
+		WithArgs("abc", "myapp", unitTestUser, "openid groups", "https://app.example.com/callback", "nonce", "challenge", "S256", "pwd mfa", int64(1577880001)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.SaveOAuth2AuthorizationCode(code)
+	assert.NoError(t, err)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT client_id, username, scopes, redirect_uri, nonce, code_challenge, code_challenge_method, amr, expires_at FROM %s WHERE signature=\\?", oauth2AuthorizationCodesTableName)).
+		WithArgs("abc").
+		WillReturnRows(sqlmock.NewRows([]string{"client_id", "username", "scopes", "redirect_uri", "nonce", "code_challenge", "code_challenge_method", "amr", "expires_at"}).
+			AddRow("myapp", unitTestUser, "openid groups", "https://app.example.com/callback", "nonce", "challenge", "S256", "pwd mfa", 1577880001))
+
+	loadedCode, err := provider.LoadOAuth2AuthorizationCode("abc")
+	require.NoError(t, err)
+	assert.Equal(t, code, *loadedCode)
+
 	mock.ExpectExec(
-		fmt.Sprintf("REPLACE INTO %s \\(username, secret\\) VALUES \\(\\?, \\?\\)", totpSecretsTableName)).
-		WithArgs(args...).
+		fmt.Sprintf("DELETE FROM %s WHERE signature=\\?", oauth2AuthorizationCodesTableName)).
+		WithArgs("abc").
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	err = provider.SaveTOTPSecret(unitTestUser, pretendSecret)
+	err = provider.DeleteOAuth2AuthorizationCode("abc")
 	assert.NoError(t, err)
 
-	args = []driver.Value{unitTestUser}
+	// Test code already used.
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE signature=\\?", oauth2AuthorizationCodesTableName)).
+		WithArgs("abc").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = provider.DeleteOAuth2AuthorizationCode("abc")
+	assert.EqualError(t, err, "No OAuth 2.0 authorization code found")
+
+	// Test Blank Rows.
 	mock.ExpectQuery(
-		fmt.Sprintf("SELECT secret FROM %s WHERE username=\\?", totpSecretsTableName)).
-		WithArgs(args...).
-		WillReturnRows(sqlmock.NewRows([]string{"secret"}).AddRow(pretendSecret))
+		fmt.Sprintf("SELECT client_id, username, scopes, redirect_uri, nonce, code_challenge, code_challenge_method, amr, expires_at FROM %s WHERE signature=\\?", oauth2AuthorizationCodesTableName)).
+		WithArgs("abc").
+		WillReturnRows(sqlmock.NewRows([]string{"client_id", "username", "scopes", "redirect_uri", "nonce", "code_challenge", "code_challenge_method", "amr", "expires_at"}))
 
-	secret, err := provider.LoadTOTPSecret(unitTestUser)
+	loadedCode, err = provider.LoadOAuth2AuthorizationCode("abc")
+	assert.EqualError(t, err, "No OAuth 2.0 authorization code found")
+	assert.Nil(t, loadedCode)
+
+	mock.ExpectExec(
+		fmt.Sprintf("INSERT INTO %s \\(signature, client_id, username, scopes, amr, expires_at\\) VALUES \\(\\?, \\?, \\?, \\?, \\?, \\?\\)", oauth2RefreshTokensTableName)).
+		WithArgs("def", "myapp", unitTestUser, "openid offline_access", "pwd", int64(1577880002)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.SaveOAuth2RefreshToken(models.OAuth2RefreshToken{
+		Signature: "def",
+		ClientID:  "myapp",
+		Username:  unitTestUser,
+		Scopes:    []string{"openid", "offline_access"},
+		AMR:       []string{"pwd"},
+		ExpiresAt: time.Unix(1577880002, 0),
+	})
 	assert.NoError(t, err)
-	assert.Equal(t, pretendSecret, secret)
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT client_id, username, scopes, amr, expires_at FROM %s WHERE signature=\\?", oauth2RefreshTokensTableName)).
+		WithArgs("def").
+		WillReturnRows(sqlmock.NewRows([]string{"client_id", "username", "scopes", "amr", "expires_at"}).
+			AddRow("myapp", unitTestUser, "openid offline_access", "pwd", 1577880002))
+
+	refreshToken, err := provider.LoadOAuth2RefreshToken("def")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"openid", "offline_access"}, refreshToken.Scopes)
+	assert.Equal(t, time.Unix(1577880002, 0), refreshToken.ExpiresAt)
 
 	mock.ExpectExec(
-		fmt.Sprintf("DELETE FROM %s WHERE username=\\?", totpSecretsTableName)).
-		WithArgs(unitTestUser).
+		fmt.Sprintf("DELETE FROM %s WHERE signature=\\?", oauth2RefreshTokensTableName)).
+		WithArgs("def").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.DeleteOAuth2RefreshToken("def")
+	assert.NoError(t, err)
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(username, client_id, scopes, granted_at\\) VALUES \\(\\?, \\?, \\?, \\?\\)", oauth2ConsentTableName)).
+		WithArgs(unitTestUser, "myapp", "openid groups", int64(1577880003)).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	err = provider.DeleteTOTPSecret(unitTestUser)
+	err = provider.SaveOAuth2Consent(models.OAuth2Consent{
+		Username:  unitTestUser,
+		ClientID:  "myapp",
+		Scopes:    []string{"openid", "groups"},
+		GrantedAt: time.Unix(1577880003, 0),
+	})
 	assert.NoError(t, err)
 
 	mock.ExpectQuery(
-		fmt.Sprintf("SELECT secret FROM %s WHERE username=\\?", totpSecretsTableName)).
-		WithArgs(args...).
-		WillReturnRows(sqlmock.NewRows([]string{"secret"}))
+		fmt.Sprintf("SELECT scopes, granted_at FROM %s WHERE username=\\? AND client_id=\\?", oauth2ConsentTableName)).
+		WithArgs(unitTestUser, "myapp").
+		WillReturnRows(sqlmock.NewRows([]string{"scopes", "granted_at"}).
+			AddRow("openid groups", 1577880003))
 
-	// Test Blank Rows
-	secret, err = provider.LoadTOTPSecret(unitTestUser)
-	assert.EqualError(t, err, "No TOTP secret registered")
-	assert.Equal(t, "", secret)
+	consent, err := provider.LoadOAuth2Consent(unitTestUser, "myapp")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"openid", "groups"}, consent.Scopes)
+
+	// Test Blank Rows.
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT scopes, granted_at FROM %s WHERE username=\\? AND client_id=\\?", oauth2ConsentTableName)).
+		WithArgs(unitTestUser, "myapp").
+		WillReturnRows(sqlmock.NewRows([]string{"scopes", "granted_at"}))
+
+	consent, err = provider.LoadOAuth2Consent(unitTestUser, "myapp")
+	assert.EqualError(t, err, "No OAuth 2.0 consent found")
+	assert.Nil(t, consent)
 }
 
-func TestSQLProviderMethodsU2F(t *testing.T) {
+func TestSQLProviderMethodsWebauthn(t *testing.T) {
 	provider, mock := NewSQLMockProvider()
 
 	mock.ExpectQuery(
@@ -287,7 +1272,11 @@ func TestSQLProviderMethodsU2F(t *testing.T) {
 			AddRow(totpSecretsTableName).
 			AddRow(u2fDeviceHandlesTableName).
 			AddRow(authenticationLogsTableName).
-			AddRow(configTableName))
+			AddRow(configTableName).
+			AddRow(oauth2AuthorizationCodesTableName).
+			AddRow(oauth2RefreshTokensTableName).
+			AddRow(oauth2ConsentTableName).
+			AddRow(webauthnDevicesTableName))
 
 	args := []driver.Value{"schema", "version"}
 	mock.ExpectQuery(
@@ -299,45 +1288,78 @@ func TestSQLProviderMethodsU2F(t *testing.T) {
 	err := provider.initialize(provider.db)
 	assert.NoError(t, err)
 
-	pretendKeyHandle := []byte("abc")
-	pretendPublicKey := []byte("123")
-	pretendKeyHandleB64 := base64.StdEncoding.EncodeToString(pretendKeyHandle)
-	pretendPublicKeyB64 := base64.StdEncoding.EncodeToString(pretendPublicKey)
+	device := models.WebauthnDevice{
+		Username:        unitTestUser,
+		KID:             []byte("abc"),
+		PublicKey:       []byte("xyz"),
+		AttestationType: "packed",
+		AAGUID:          []byte("123"),
+		SignCount:       5,
+		CreatedAt:       time.Unix(1577880001, 0),
+	}
 
-	args = []driver.Value{unitTestUser, pretendKeyHandleB64, pretendPublicKeyB64}
 	mock.ExpectExec(
-		fmt.Sprintf("REPLACE INTO %s \\(username, keyHandle, publicKey\\) VALUES \\(\\?, \\?, \\?\\)", u2fDeviceHandlesTableName)).
-		WithArgs(args...).
+		fmt.Sprintf("INSERT INTO %s \\(username, kid, public_key, attestation_type, aaguid, sign_count, created_at\\) VALUES .*", webauthnDevicesTableName)).
+		WithArgs(unitTestUser, "YWJj", encryptedWith(unitTestEncryptionKey, "xyz"), "packed", "MTIz", uint32(5), int64(1577880001)).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	err = provider.SaveU2FDeviceHandle(unitTestUser, pretendKeyHandle, pretendPublicKey)
+	err = provider.SaveWebauthnDevice(device)
 	assert.NoError(t, err)
 
-	args = []driver.Value{unitTestUser}
 	mock.ExpectQuery(
-		fmt.Sprintf("SELECT keyHandle, publicKey FROM %s WHERE username=\\?", u2fDeviceHandlesTableName)).
-		WithArgs(args...).
-		WillReturnRows(sqlmock.NewRows([]string{"keyHandle", "publicKey"}).
-			AddRow(pretendKeyHandleB64, pretendPublicKeyB64))
+		fmt.Sprintf("SELECT kid, public_key, attestation_type, aaguid, sign_count, created_at FROM %s WHERE username=\\? ORDER BY created_at", webauthnDevicesTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"kid", "public_key", "attestation_type", "aaguid", "sign_count", "created_at"}).
+			AddRow("YWJj", mustEncrypt(t, unitTestEncryptionKey, "xyz"), "packed", "MTIz", 5, 1577880001))
+
+	devices, err := provider.LoadWebauthnDevicesByUsername(unitTestUser)
+	require.NoError(t, err)
+	assert.Equal(t, []models.WebauthnDevice{device}, devices)
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET sign_count=\\? WHERE username=\\? AND kid=\\? AND \\(sign_count<\\? OR sign_count=0\\)", webauthnDevicesTableName)).
+		WithArgs(uint32(6), unitTestUser, "YWJj", uint32(6)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.UpdateWebauthnDeviceSignCount(unitTestUser, []byte("abc"), 6)
+	assert.NoError(t, err)
 
-	keyHandle, publicKey, err := provider.LoadU2FDeviceHandle(unitTestUser)
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET sign_count=\\? WHERE username=\\? AND kid=\\? AND \\(sign_count<\\? OR sign_count=0\\)", webauthnDevicesTableName)).
+		WithArgs(uint32(5), unitTestUser, "YWJj", uint32(5)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = provider.UpdateWebauthnDeviceSignCount(unitTestUser, []byte("abc"), 5)
+	assert.Equal(t, ErrSignCountNotIncreasing, err)
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE username=\\? AND kid=\\?", webauthnDevicesTableName)).
+		WithArgs(unitTestUser, "YWJj").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = provider.DeleteWebauthnDevice(unitTestUser, []byte("abc"))
 	assert.NoError(t, err)
-	assert.Equal(t, pretendKeyHandle, keyHandle)
-	assert.Equal(t, pretendPublicKey, publicKey)
+
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE username=\\? AND kid=\\?", webauthnDevicesTableName)).
+		WithArgs(unitTestUser, "YWJj").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = provider.DeleteWebauthnDevice(unitTestUser, []byte("abc"))
+	assert.EqualError(t, err, "No Webauthn device found")
 
 	// Test Blank Rows.
 	mock.ExpectQuery(
-		fmt.Sprintf("SELECT keyHandle, publicKey FROM %s WHERE username=\\?", u2fDeviceHandlesTableName)).
-		WithArgs(args...).
-		WillReturnRows(sqlmock.NewRows([]string{"keyHandle", "publicKey"}))
+		fmt.Sprintf("SELECT kid, public_key, attestation_type, aaguid, sign_count, created_at FROM %s WHERE username=\\? ORDER BY created_at", webauthnDevicesTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"kid", "public_key", "attestation_type", "aaguid", "sign_count", "created_at"}))
 
-	keyHandle, publicKey, err = provider.LoadU2FDeviceHandle(unitTestUser)
-	assert.EqualError(t, err, "No U2F device handle found")
-	assert.Equal(t, []byte(nil), keyHandle)
-	assert.Equal(t, []byte(nil), publicKey)
+	devices, err = provider.LoadWebauthnDevicesByUsername(unitTestUser)
+	assert.EqualError(t, err, "No Webauthn device found")
+	assert.Nil(t, devices)
 }
 
-func TestSQLProviderMethodsIdentityVerificationTokens(t *testing.T) {
+func TestSQLProviderMethodsRecoveryCodes(t *testing.T) {
 	provider, mock := NewSQLMockProvider()
 
 	mock.ExpectQuery(
@@ -348,7 +1370,12 @@ func TestSQLProviderMethodsIdentityVerificationTokens(t *testing.T) {
 			AddRow(totpSecretsTableName).
 			AddRow(u2fDeviceHandlesTableName).
 			AddRow(authenticationLogsTableName).
-			AddRow(configTableName))
+			AddRow(configTableName).
+			AddRow(oauth2AuthorizationCodesTableName).
+			AddRow(oauth2RefreshTokensTableName).
+			AddRow(oauth2ConsentTableName).
+			AddRow(webauthnDevicesTableName).
+			AddRow(recoveryCodesTableName))
 
 	args := []driver.Value{"schema", "version"}
 	mock.ExpectQuery(
@@ -360,41 +1387,235 @@ func TestSQLProviderMethodsIdentityVerificationTokens(t *testing.T) {
 	err := provider.initialize(provider.db)
 	assert.NoError(t, err)
 
-	fakeIdentityVerificationToken := "abc"
+	codes := []models.RecoveryCode{
+		{Username: unitTestUser, Lookup: "ab", Hash: "$argon2id$abc", CreatedAt: time.Unix(1600000000, 0)},
+		{Username: unitTestUser, Lookup: "cd", Hash: "$argon2id$def", CreatedAt: time.Unix(1600000000, 0)},
+	}
 
+	mock.ExpectBegin()
 	mock.ExpectExec(
-		fmt.Sprintf("INSERT INTO %s \\(token\\) VALUES \\(\\?\\)", identityVerificationTokensTableName)).
-		WithArgs(fakeIdentityVerificationToken).
-		WillReturnResult(sqlmock.NewResult(1, 1))
+		fmt.Sprintf("DELETE FROM %s WHERE username=\\?", recoveryCodesTableName)).
+		WithArgs(unitTestUser).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(
+		fmt.Sprintf("INSERT INTO %s \\(username, lookup, hash, created_at\\) VALUES \\(\\?, \\?, \\?, \\?\\)", recoveryCodesTableName)).
+		WithArgs(unitTestUser, "ab", "$argon2id$abc", int64(1600000000)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(
+		fmt.Sprintf("INSERT INTO %s \\(username, lookup, hash, created_at\\) VALUES \\(\\?, \\?, \\?, \\?\\)", recoveryCodesTableName)).
+		WithArgs(unitTestUser, "cd", "$argon2id$def", int64(1600000000)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
 
-	err = provider.SaveIdentityVerificationToken(fakeIdentityVerificationToken)
+	err = provider.SaveRecoveryCodes(unitTestUser, codes)
 	assert.NoError(t, err)
 
+	mock.ExpectBegin()
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE username=\\?", recoveryCodesTableName)).
+		WithArgs(unitTestUser).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(
+		fmt.Sprintf("INSERT INTO %s \\(username, lookup, hash, created_at\\) VALUES \\(\\?, \\?, \\?, \\?\\)", recoveryCodesTableName)).
+		WithArgs(unitTestUser, "ab", "$argon2id$abc", int64(1600000000)).
+		WillReturnError(errors.New("duplicate key"))
+	mock.ExpectRollback()
+
+	err = provider.SaveRecoveryCodes(unitTestUser, codes)
+	assert.EqualError(t, err, "duplicate key")
+
 	mock.ExpectQuery(
-		fmt.Sprintf("SELECT EXISTS \\(SELECT \\* FROM %s WHERE token=\\?\\)", identityVerificationTokensTableName)).
-		WithArgs(fakeIdentityVerificationToken).
-		WillReturnRows(sqlmock.NewRows([]string{"EXISTS"}).
-			AddRow(true))
+		fmt.Sprintf("SELECT lookup, hash, created_at FROM %s WHERE username=\\?", recoveryCodesTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"lookup", "hash", "created_at"}).
+			AddRow("ab", "$argon2id$abc", int64(1600000000)).
+			AddRow("cd", "$argon2id$def", int64(1600000000)))
 
-	valid, err := provider.FindIdentityVerificationToken(fakeIdentityVerificationToken)
-	assert.NoError(t, err)
-	assert.True(t, valid)
+	loaded, err := provider.LoadRecoveryCodesByUsername(unitTestUser)
+	require.NoError(t, err)
+	assert.Equal(t, codes, loaded)
 
 	mock.ExpectExec(
-		fmt.Sprintf("DELETE FROM %s WHERE token=\\?", identityVerificationTokensTableName)).
-		WithArgs(fakeIdentityVerificationToken).
+		fmt.Sprintf("DELETE FROM %s WHERE username=\\? AND hash=\\?", recoveryCodesTableName)).
+		WithArgs(unitTestUser, "$argon2id$abc").
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	err = provider.RemoveIdentityVerificationToken(fakeIdentityVerificationToken)
+	err = provider.DeleteRecoveryCode(unitTestUser, "$argon2id$abc")
 	assert.NoError(t, err)
 
+	mock.ExpectExec(
+		fmt.Sprintf("DELETE FROM %s WHERE username=\\? AND hash=\\?", recoveryCodesTableName)).
+		WithArgs(unitTestUser, "$argon2id$abc").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = provider.DeleteRecoveryCode(unitTestUser, "$argon2id$abc")
+	assert.EqualError(t, err, "No recovery code found")
+
+	// Test Blank Rows.
 	mock.ExpectQuery(
-		fmt.Sprintf("SELECT EXISTS \\(SELECT \\* FROM %s WHERE token=\\?\\)", identityVerificationTokensTableName)).
-		WithArgs(fakeIdentityVerificationToken).
-		WillReturnRows(sqlmock.NewRows([]string{"EXISTS"}).
-			AddRow(false))
+		fmt.Sprintf("SELECT lookup, hash, created_at FROM %s WHERE username=\\?", recoveryCodesTableName)).
+		WithArgs(unitTestUser).
+		WillReturnRows(sqlmock.NewRows([]string{"lookup", "hash", "created_at"}))
 
-	valid, err = provider.FindIdentityVerificationToken(fakeIdentityVerificationToken)
+	loaded, err = provider.LoadRecoveryCodesByUsername(unitTestUser)
+	assert.EqualError(t, err, "No recovery code found")
+	assert.Nil(t, loaded)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLUpgradeDatabaseEncryptsExistingRows(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	tables := []string{totpSecretsTableName, u2fDeviceHandlesTableName, webauthnDevicesTableName, configTableName}
+
+	expectSchemaDetails(mock, "9", tables...)
+
+	mock.ExpectBegin()
+
+	expectSchemaDetails(mock, "9", tables...)
+
+	for _, statement := range []string{"CREATE TABLE", "INSERT INTO", "DROP TABLE", "ALTER TABLE"} {
+		mock.ExpectExec(statement).WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, id, secret FROM %s", totpSecretsTableName)).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "id", "secret"}).
+			AddRow(unitTestUser, "primary", "JBSWY3DPEHPK3PXP"))
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET secret=\\? WHERE username=\\? AND id=\\?", totpSecretsTableName)).
+		WithArgs(encryptedWith(unitTestEncryptionKey, "JBSWY3DPEHPK3PXP"), unitTestUser, "primary").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, key_handle, public_key FROM %s", u2fDeviceHandlesTableName)).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "key_handle", "public_key"}).
+			AddRow(unitTestUser, "YWJj", "MTIz"))
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET public_key=\\? WHERE username=\\? AND key_handle=\\?", u2fDeviceHandlesTableName)).
+		WithArgs(encryptedWith(unitTestEncryptionKey, "123"), unitTestUser, "YWJj").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, kid, public_key FROM %s", webauthnDevicesTableName)).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "kid", "public_key"}))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs(encryptionCheckCategory, encryptionCheckKeyName, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs("schema", "version", "10").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	expectSchemaUpgradeToVersion011(mock)
+	expectSchemaUpgradeToVersion012(mock)
+
+	mock.ExpectCommit()
+
+	err := provider.initialize(provider.db)
 	assert.NoError(t, err)
-	assert.False(t, valid)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderCheckEncryptionKey(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	query := fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)
+
+	mock.ExpectQuery(query).
+		WithArgs(encryptionCheckCategory, encryptionCheckKeyName).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(mustEncrypt(t, unitTestEncryptionKey, "check")))
+
+	assert.NoError(t, provider.CheckEncryptionKey())
+
+	mock.ExpectQuery(query).
+		WithArgs(encryptionCheckCategory, encryptionCheckKeyName).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(mustEncrypt(t, "another_encryption_key_of_the_db", "check")))
+
+	assert.True(t, errors.Is(provider.CheckEncryptionKey(), ErrEncryptionKeyMismatch))
+
+	mock.ExpectQuery(query).
+		WithArgs(encryptionCheckCategory, encryptionCheckKeyName).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}))
+
+	assert.Equal(t, ErrNoEncryptionCheck, provider.CheckEncryptionKey())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderChangeEncryptionKey(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	newKey := "a_new_not_so_secure_encryption_key"
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs(encryptionCheckCategory, encryptionCheckKeyName).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(mustEncrypt(t, unitTestEncryptionKey, "check")))
+
+	mock.ExpectBegin()
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, id, secret FROM %s", totpSecretsTableName)).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "id", "secret"}).
+			AddRow(unitTestUser, "primary", mustEncrypt(t, unitTestEncryptionKey, "JBSWY3DPEHPK3PXP")))
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET secret=\\? WHERE username=\\? AND id=\\?", totpSecretsTableName)).
+		WithArgs(encryptedWith(newKey, "JBSWY3DPEHPK3PXP"), unitTestUser, "primary").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, key_handle, public_key FROM %s", u2fDeviceHandlesTableName)).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "key_handle", "public_key"}))
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, kid, public_key FROM %s", webauthnDevicesTableName)).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "kid", "public_key"}).
+			AddRow(unitTestUser, "YWJj", mustEncrypt(t, unitTestEncryptionKey, "xyz")))
+
+	mock.ExpectExec(
+		fmt.Sprintf("UPDATE %s SET public_key=\\? WHERE username=\\? AND kid=\\?", webauthnDevicesTableName)).
+		WithArgs(encryptedWith(newKey, "xyz"), unitTestUser, "YWJj").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	mock.ExpectExec(
+		fmt.Sprintf("REPLACE INTO %s \\(category, key_name, value\\) VALUES \\(\\?, \\?, \\?\\)", configTableName)).
+		WithArgs(encryptionCheckCategory, encryptionCheckKeyName, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	mock.ExpectCommit()
+
+	err := provider.ChangeEncryptionKey(newKey)
+	require.NoError(t, err)
+	assert.Equal(t, newEncryptionKey(newKey), provider.key)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSQLProviderShouldRollbackChangeOfEncryptionKeyWhenDataIsNotDecryptable(t *testing.T) {
+	provider, mock := NewSQLMockProvider()
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT value FROM %s WHERE category=\\? AND key_name=\\?", configTableName)).
+		WithArgs(encryptionCheckCategory, encryptionCheckKeyName).
+		WillReturnRows(sqlmock.NewRows([]string{"value"}).AddRow(mustEncrypt(t, unitTestEncryptionKey, "check")))
+
+	mock.ExpectBegin()
+
+	mock.ExpectQuery(
+		fmt.Sprintf("SELECT username, id, secret FROM %s", totpSecretsTableName)).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "id", "secret"}).
+			AddRow(unitTestUser, "primary", "JBSWY3DPEHPK3PXP"))
+
+	mock.ExpectRollback()
+
+	err := provider.ChangeEncryptionKey("a_new_not_so_secure_encryption_key")
+	assert.True(t, errors.Is(err, ErrEncryptionKeyMismatch))
+	assert.Equal(t, newEncryptionKey(unitTestEncryptionKey), provider.key)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
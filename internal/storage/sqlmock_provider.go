@@ -16,9 +16,7 @@ func NewSQLMockProvider() (*SQLMockProvider, sqlmock.Sqlmock) {
 	provider := SQLMockProvider{
 		SQLProvider{
 			name: "sqlmock",
-
-			sqlUpgradesCreateTableStatements:        sqlUpgradeCreateTableStatements,
-			sqlUpgradesCreateTableIndexesStatements: sqlUpgradesCreateTableIndexesStatements,
+			key:  newEncryptionKey(unitTestEncryptionKey),
 
 			sqlGetPreferencesByUsername:     fmt.Sprintf("SELECT second_factor_method FROM %s WHERE username=?", userPreferencesTableName),
 			sqlUpsertSecondFactorPreference: fmt.Sprintf("REPLACE INTO %s (username, second_factor_method) VALUES (?, ?)", userPreferencesTableName),
@@ -27,20 +25,58 @@ func NewSQLMockProvider() (*SQLMockProvider, sqlmock.Sqlmock) {
 			sqlInsertIdentityVerificationToken:        fmt.Sprintf("INSERT INTO %s (token) VALUES (?)", identityVerificationTokensTableName),
 			sqlDeleteIdentityVerificationToken:        fmt.Sprintf("DELETE FROM %s WHERE token=?", identityVerificationTokensTableName),
 
-			sqlGetTOTPSecretByUsername: fmt.Sprintf("SELECT secret FROM %s WHERE username=?", totpSecretsTableName),
-			sqlUpsertTOTPSecret:        fmt.Sprintf("REPLACE INTO %s (username, secret) VALUES (?, ?)", totpSecretsTableName),
-			sqlDeleteTOTPSecret:        fmt.Sprintf("DELETE FROM %s WHERE username=?", totpSecretsTableName),
-
-			sqlGetU2FDeviceHandleByUsername: fmt.Sprintf("SELECT keyHandle, publicKey FROM %s WHERE username=?", u2fDeviceHandlesTableName),
-			sqlUpsertU2FDeviceHandle:        fmt.Sprintf("REPLACE INTO %s (username, keyHandle, publicKey) VALUES (?, ?, ?)", u2fDeviceHandlesTableName),
-
-			sqlInsertAuthenticationLog:     fmt.Sprintf("INSERT INTO %s (username, successful, time) VALUES (?, ?, ?)", authenticationLogsTableName),
-			sqlGetLatestAuthenticationLogs: fmt.Sprintf("SELECT successful, time FROM %s WHERE time>? AND username=? ORDER BY time DESC", authenticationLogsTableName),
+			sqlInsertTOTPDevice:            fmt.Sprintf("INSERT INTO %s (username, id, description, secret, algorithm, digits, period, created_at, last_used_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)", totpSecretsTableName),
+			sqlGetTOTPDevicesByUsername:    fmt.Sprintf("SELECT id, description, secret, algorithm, digits, period, last_step, created_at, last_used_at FROM %s WHERE username=? ORDER BY created_at", totpSecretsTableName),
+			sqlUpdateTOTPDeviceLastStep:    fmt.Sprintf("UPDATE %s SET last_step=?, last_used_at=? WHERE username=? AND id=? AND last_step<?", totpSecretsTableName),
+			sqlDeleteTOTPDevice:            fmt.Sprintf("DELETE FROM %s WHERE username=? AND id=?", totpSecretsTableName),
+			sqlDeleteTOTPDevicesByUsername: fmt.Sprintf("DELETE FROM %s WHERE username=?", totpSecretsTableName),
+			sqlGetTOTPSecrets:              fmt.Sprintf("SELECT username, id, secret FROM %s", totpSecretsTableName),
+			sqlUpdateTOTPSecret:            fmt.Sprintf("UPDATE %s SET secret=? WHERE username=? AND id=?", totpSecretsTableName),
+
+			sqlInsertU2FDevice:         fmt.Sprintf("INSERT INTO %s (username, description, key_handle, public_key, counter, created_at, last_used_at) VALUES (?, ?, ?, ?, ?, ?, ?)", u2fDeviceHandlesTableName),
+			sqlGetU2FDevicesByUsername: fmt.Sprintf("SELECT description, key_handle, public_key, counter, created_at, last_used_at FROM %s WHERE username=? ORDER BY created_at", u2fDeviceHandlesTableName),
+			sqlUpdateU2FDeviceCounter:  fmt.Sprintf("UPDATE %s SET counter=?, last_used_at=? WHERE username=? AND key_handle=? AND (counter<? OR counter=0)", u2fDeviceHandlesTableName),
+			sqlDeleteU2FDevice:         fmt.Sprintf("DELETE FROM %s WHERE username=? AND key_handle=?", u2fDeviceHandlesTableName),
+			sqlGetU2FPublicKeys:        fmt.Sprintf("SELECT username, key_handle, public_key FROM %s", u2fDeviceHandlesTableName),
+			sqlUpdateU2FPublicKey:      fmt.Sprintf("UPDATE %s SET public_key=? WHERE username=? AND key_handle=?", u2fDeviceHandlesTableName),
+
+			sqlInsertWebauthnDevice:          fmt.Sprintf("INSERT INTO %s (username, kid, public_key, attestation_type, aaguid, sign_count, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)", webauthnDevicesTableName),
+			sqlGetWebauthnDevicesByUsername:  fmt.Sprintf("SELECT kid, public_key, attestation_type, aaguid, sign_count, created_at FROM %s WHERE username=? ORDER BY created_at", webauthnDevicesTableName),
+			sqlUpdateWebauthnDeviceSignCount: fmt.Sprintf("UPDATE %s SET sign_count=? WHERE username=? AND kid=? AND (sign_count<? OR sign_count=0)", webauthnDevicesTableName),
+			sqlDeleteWebauthnDevice:          fmt.Sprintf("DELETE FROM %s WHERE username=? AND kid=?", webauthnDevicesTableName),
+			sqlGetWebauthnPublicKeys:         fmt.Sprintf("SELECT username, kid, public_key FROM %s", webauthnDevicesTableName),
+			sqlUpdateWebauthnPublicKey:       fmt.Sprintf("UPDATE %s SET public_key=? WHERE username=? AND kid=?", webauthnDevicesTableName),
+
+			sqlInsertRecoveryCode:            fmt.Sprintf("INSERT INTO %s (username, lookup, hash, created_at) VALUES (?, ?, ?, ?)", recoveryCodesTableName),
+			sqlGetRecoveryCodesByUsername:    fmt.Sprintf("SELECT lookup, hash, created_at FROM %s WHERE username=?", recoveryCodesTableName),
+			sqlDeleteRecoveryCode:            fmt.Sprintf("DELETE FROM %s WHERE username=? AND hash=?", recoveryCodesTableName),
+			sqlDeleteRecoveryCodesByUsername: fmt.Sprintf("DELETE FROM %s WHERE username=?", recoveryCodesTableName),
+
+			sqlInsertAuthenticationLog:        fmt.Sprintf("INSERT INTO %s (username, successful, time) VALUES (?, ?, ?)", authenticationLogsTableName),
+			sqlGetLatestAuthenticationLogs:    fmt.Sprintf("SELECT successful, time FROM %s WHERE time>? AND username=? ORDER BY time DESC", authenticationLogsTableName),
+			sqlDeleteFailedAuthenticationLogs: fmt.Sprintf("DELETE FROM %s WHERE username=? AND successful=? AND time>?", authenticationLogsTableName),
+
+			sqlInsertEmailOTPLog:     fmt.Sprintf("INSERT INTO %s (username, event, time) VALUES (?, ?, ?)", emailOTPLogsTableName),
+			sqlGetLatestEmailOTPLogs: fmt.Sprintf("SELECT event, time FROM %s WHERE time>? AND username=? ORDER BY time DESC", emailOTPLogsTableName),
+
+			sqlInsertOAuth2AuthorizationCode: fmt.Sprintf("INSERT INTO %s (signature, client_id, username, scopes, redirect_uri, nonce, code_challenge, code_challenge_method, amr, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", oauth2AuthorizationCodesTableName),
+			sqlGetOAuth2AuthorizationCode:    fmt.Sprintf("SELECT client_id, username, scopes, redirect_uri, nonce, code_challenge, code_challenge_method, amr, expires_at FROM %s WHERE signature=?", oauth2AuthorizationCodesTableName),
+			sqlDeleteOAuth2AuthorizationCode: fmt.Sprintf("DELETE FROM %s WHERE signature=?", oauth2AuthorizationCodesTableName),
+
+			sqlInsertOAuth2RefreshToken: fmt.Sprintf("INSERT INTO %s (signature, client_id, username, scopes, amr, expires_at) VALUES (?, ?, ?, ?, ?, ?)", oauth2RefreshTokensTableName),
+			sqlGetOAuth2RefreshToken:    fmt.Sprintf("SELECT client_id, username, scopes, amr, expires_at FROM %s WHERE signature=?", oauth2RefreshTokensTableName),
+			sqlDeleteOAuth2RefreshToken: fmt.Sprintf("DELETE FROM %s WHERE signature=?", oauth2RefreshTokensTableName),
+
+			sqlUpsertOAuth2Consent: fmt.Sprintf("REPLACE INTO %s (username, client_id, scopes, granted_at) VALUES (?, ?, ?, ?)", oauth2ConsentTableName),
+			sqlGetOAuth2Consent:    fmt.Sprintf("SELECT scopes, granted_at FROM %s WHERE username=? AND client_id=?", oauth2ConsentTableName),
 
 			sqlGetExistingTables: "SELECT name FROM sqlite_master WHERE type='table'",
 
-			sqlConfigSetValue: fmt.Sprintf("REPLACE INTO %s (category, key_name, value) VALUES (?, ?, ?)", configTableName),
-			sqlConfigGetValue: fmt.Sprintf("SELECT value FROM %s WHERE category=? AND key_name=?", configTableName),
+			sqlTestIndexExistence: "SELECT EXISTS (SELECT * FROM sqlite_master WHERE type='index' AND tbl_name=? AND name=?)",
+
+			sqlConfigSetValue:    fmt.Sprintf("REPLACE INTO %s (category, key_name, value) VALUES (?, ?, ?)", configTableName),
+			sqlConfigGetValue:    fmt.Sprintf("SELECT value FROM %s WHERE category=? AND key_name=?", configTableName),
+			sqlConfigDeleteValue: fmt.Sprintf("DELETE FROM %s WHERE category=? AND key_name=?", configTableName),
 		},
 	}
 
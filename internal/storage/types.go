@@ -13,6 +13,21 @@ func (s SchemaVersion) ToString() string {
 	return strconv.Itoa(int(s))
 }
 
+// SchemaMigration describes a migration of the schema to a version, or from it when the schema is downgraded.
+type SchemaMigration struct {
+	Version SchemaVersion
+	Name    string
+	Up      bool
+
+	// Statements are the statements the migration executes.
+	Statements []string
+
+	// Data describes the data the migration converts in addition to the statements, if any.
+	Data string
+}
+
 type transaction interface {
 	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
 }
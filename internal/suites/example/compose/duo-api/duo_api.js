@@ -3,7 +3,7 @@
  * 
  * Access is allowed by default but one can change the behavior at runtime
  * by POSTing to /allow or /deny. Then the /auth/v2/auth endpoint will act
- * accordingly.
+ * accordingly. The /auth/v2/preauth endpoint always lists the same phone.
  */
 
 const express = require("express");
@@ -26,6 +26,24 @@ app.post('/deny', (req, res) => {
   res.send('DENIED');
 });
 
+app.post('/auth/v2/preauth', (req, res) => {
+  res.json({
+    response: {
+      result: 'auth',
+      status_msg: 'Account is active',
+      devices: [{
+        device: 'DPFZRS9FB0D46QFTM891',
+        display_name: 'iOS (XXX-XXX-0100)',
+        name: 'iOS',
+        number: 'XXX-XXX-0100',
+        type: 'phone',
+        capabilities: ['auto', 'push', 'sms', 'phone', 'mobile_otp'],
+      }],
+    },
+    stat: 'OK',
+  });
+});
+
 app.post('/auth/v2/auth', (req, res) => {
   setTimeout(() => {
     let response;
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/pquerna/otp"
+)
+
+// OTPAlgorithm returns the HMAC algorithm of the OTP library matching the name of the configuration.
+func OTPAlgorithm(name string) otp.Algorithm {
+	switch name {
+	case "SHA256":
+		return otp.AlgorithmSHA256
+	case "SHA512":
+		return otp.AlgorithmSHA512
+	default:
+		return otp.AlgorithmSHA1
+	}
+}
+
+// EncodeKeyHandle encodes the key handle of a security key or the credential id of a Webauthn device with the websafe
+// base64 encoding used by the U2F API.
+func EncodeKeyHandle(keyHandle []byte) string {
+	return base64.RawURLEncoding.EncodeToString(keyHandle)
+}
+
+// DecodeKeyHandle decodes a key handle encoded with the websafe base64 encoding, padded or not.
+func DecodeKeyHandle(keyHandle string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(keyHandle, "="))
+}
@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/pquerna/otp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShouldReturnOTPAlgorithmOfConfiguration(t *testing.T) {
+	assert.Equal(t, otp.AlgorithmSHA1, OTPAlgorithm("SHA1"))
+	assert.Equal(t, otp.AlgorithmSHA256, OTPAlgorithm("SHA256"))
+	assert.Equal(t, otp.AlgorithmSHA512, OTPAlgorithm("SHA512"))
+	assert.Equal(t, otp.AlgorithmSHA1, OTPAlgorithm(""))
+}
+
+func TestShouldDecodePaddedAndUnpaddedKeyHandles(t *testing.T) {
+	assert.Equal(t, "cHJpbWFyeQ", EncodeKeyHandle([]byte("primary")))
+
+	for _, encoded := range []string{"cHJpbWFyeQ", "cHJpbWFyeQ=="} {
+		keyHandle, err := DecodeKeyHandle(encoded)
+		require.NoError(t, err)
+
+		assert.Equal(t, []byte("primary"), keyHandle)
+	}
+
+	_, err := DecodeKeyHandle("not/base64url")
+	assert.Error(t, err)
+}